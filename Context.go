@@ -1,10 +1,18 @@
 package rweb
 
 import (
+	gocontext "context"
+	"crypto/x509"
+	"encoding/json"
 	"errors"
+	"io"
+	"maps"
 	"net"
 	"net/http"
 	"strings"
+	"time"
+
+	"github.com/rohanthewiz/rweb/consts"
 )
 
 // Context is the interface for a request and its response.
@@ -26,6 +34,22 @@ type Context interface {
 	// This is how middleware passes control to subsequent handlers.
 	Next() error
 
+	// IsLast reports whether no more middleware follows the current handler,
+	// i.e. calling Next() invokes the route handler.
+	// In group middleware it reports on the group's chain.
+	IsLast() bool
+
+	// HandlerIndex returns the position of the current handler in the server's middleware chain.
+	HandlerIndex() int
+
+	// Abort stops the handler chain: subsequent calls to Next() do nothing,
+	// so no further middleware, nor the route handler, runs (e.g. after an auth failure).
+	// The response written so far is sent as usual.
+	Abort()
+
+	// IsAborted reports whether Abort has been called.
+	IsAborted() bool
+
 	// Redirect sends an HTTP redirect response to the client.
 	// Common status codes: 301 (permanent), 302 (temporary), 303 (see other).
 	Redirect(int, string) error
@@ -48,6 +72,11 @@ type Context interface {
 	// as Status() normally means "get status".
 	Status(int) Context
 
+	// CheckPrecondition evaluates the conditional request headers (If-Match, If-None-Match, etc.)
+	// against the resource's current ETag and modification time, per RFC 7232.
+	// Returns false, with the status set to 412 or 304, if the handler shouldn't proceed.
+	CheckPrecondition(etag string, modTime time.Time) bool
+
 	// Server returns the server instance, useful for accessing
 	// server-wide configuration or state.
 	Server() *Server
@@ -64,6 +93,39 @@ type Context interface {
 	// to the response with appropriate content-type header.
 	WriteJSON(interface{}) error
 
+	// WriteJSONIndent writes the given value as JSON pretty-printed with indent (e.g. "  "), for debug output.
+	WriteJSONIndent(body any, indent string) error
+
+	// JSONError sets the status and writes a JSON error response,
+	// by default {"error": {"code": status, "message": message, "details": ...}}.
+	JSONError(status int, message string, details ...any) error
+
+	// BindForm fills a struct from the request's form fields, per its `form` and `validate` tags.
+	BindForm(v any) error
+
+	// DecodeJSONLimited decodes the request's JSON body into v, refusing bodies over maxBytes,
+	// with distinct errors for oversized bodies, malformed JSON and (optionally disallowed) unknown fields.
+	DecodeJSONLimited(v any, maxBytes int64, opts ...JSONDecodeOptions) error
+
+	// StreamJSON streams a JSON response, encoding values incrementally with the given encoder
+	// as the response is written, rather than buffering the whole body. Useful for large datasets.
+	StreamJSON(fn func(enc *json.Encoder) error) error
+
+	// Flush sends the response so far to the client, switching it to chunked transfer encoding.
+	// Subsequent writes go straight to the client, so long-running handlers can report progress.
+	Flush() error
+
+	// AddServerTiming records a timing metric (e.g. "db", how long queries took, "Database"),
+	// sent in the response's Server-Timing header for browser devtools to show.
+	AddServerTiming(name string, dur time.Duration, desc string)
+
+	// Negotiate returns the offered content type that best matches the request's Accept header,
+	// respecting quality values. The first offer is the default when nothing matches.
+	Negotiate(offers ...string) string
+
+	// Render serializes data to JSON or XML, according to the request's Accept header.
+	Render(data any) error
+
 	// WriteHTML writes HTML content to the response with
 	// the text/html content-type header.
 	WriteHTML(string) error
@@ -84,6 +146,14 @@ type Context interface {
 	// Takes a channel for events and an event name for the SSE protocol.
 	SetSSE(<-chan any, string) error
 
+	// SSEFromReader streams each line read from r to the client as an SSE "message" event,
+	// until EOF or the client disconnects. Handy for tailing logs or command output.
+	SSEFromReader(r io.Reader) error
+
+	// LastEventID returns the Last-Event-ID header sent by a reconnecting SSE client,
+	// so the handler can replay events missed since that ID. Empty on a first connection.
+	LastEventID() string
+
 	// Custom data storage methods for request-scoped data.
 	// Useful for authentication state, user info, or passing data between middleware.
 
@@ -101,6 +171,14 @@ type Context interface {
 	// Delete removes a key-value pair from request-scoped storage.
 	Delete(key string)
 
+	// RequestID returns the ID assigned to the request by the RequestID middleware,
+	// or "" if the middleware isn't in use.
+	RequestID() string
+
+	// CSRFToken returns the request's token from the CSRF middleware, to embed in forms,
+	// or "" if the middleware isn't in use.
+	CSRFToken() string
+
 	// Cookie operations for managing HTTP cookies.
 	// These methods provide a simple, secure API for cookie handling.
 
@@ -131,18 +209,41 @@ type Context interface {
 	// Useful for conditional logic based on cookie presence.
 	HasCookie(name string) bool
 
+	// SetSignedCookie sets a cookie whose value is signed with the server's CookieConfig.Secret.
+	// The value is readable by the client but cannot be altered without detection.
+	SetSignedCookie(name, value string) error
+
+	// GetSignedCookie retrieves a signed cookie and verifies its signature.
+	// Returns ErrInvalidCookieSignature if the cookie has been tampered with.
+	GetSignedCookie(name string) (string, error)
+
+	// Session returns the session of the request's client, per the server's SessionCfg.
+	// The session cookie is signed, so CookieConfig.Secret must be set to save sessions.
+	Session() *Session
+
 	// WebSocket operations for upgrading HTTP connections to WebSocket protocol.
 	// These methods enable real-time bidirectional communication.
 
 	// UpgradeWebSocket upgrades the HTTP connection to WebSocket protocol.
 	// Returns a WebSocket connection that can be used for bidirectional communication.
 	// The upgrade performs the WebSocket handshake and returns an error if it fails.
-	UpgradeWebSocket() (*WSConn, error)
+	// Optional WSUpgradeOptions give the subprotocols supported, and whether compression may be used.
+	UpgradeWebSocket(opts ...WSUpgradeOptions) (*WSConn, error)
 
 	// IsWebSocketUpgrade checks if the request is a WebSocket upgrade request.
 	// Returns true if the required WebSocket headers are present.
 	IsWebSocketUpgrade() bool
 
+	// Context returns the context.Context for the request, for passing to downstream calls (DB, HTTP etc.)
+	// so they are canceled along with the request.
+	Context() gocontext.Context
+
+	// ClientIP returns the client's IP address, from proxy headers when the request came via a trusted proxy.
+	ClientIP() string
+
+	// ClientCert returns the verified client certificate from mutual TLS, or nil if there is none.
+	ClientCert() *x509.Certificate
+
 	// GetConn returns the underlying network connection.
 	// This is useful for advanced operations like protocol upgrades.
 	// Use with caution as it bypasses the framework's abstractions.
@@ -172,18 +273,43 @@ type context struct {
 	sseEventName string
 	// Cleanup callback invoked when sendSSE exits (used by SSEHub for auto-unregister)
 	sseCleanup func()
+	// Writes a streamed (chunked) response body directly to the connection, instead of the buffered body
+	streamFn func(w io.Writer) error
+	// Length of the streamed body, if known (> 0) - it's then sent with a Content-Length, rather than chunked
+	streamLen int64
+	// Releases what streamFn holds (e.g. a proxied response's body), should it never run
+	streamCleanup func()
 	// Request-scoped key-value storage for passing data between handlers
 	data map[string]any
 	// Parsed cookies from request (lazy-loaded)
 	parsedCookies map[string]*Cookie
 	// Whether cookies have been parsed from the request
 	cookiesParsed bool
+	// The client's session, once loaded by Session()
+	session *Session
+	// The pattern of the route matched, noted for the server's Metrics
+	route string
 	// Underlying network connection (for WebSocket upgrades)
 	conn net.Conn
 	// WebSocket connection (set after successful upgrade)
 	wsConn *WSConn
 	// Flag indicating if connection was upgraded to WebSocket
 	wsUpgraded bool
+	// Context for the handling of the request (e.g. with a deadline set by the Timeout middleware)
+	goCtx gocontext.Context
+	// Cancels goCtx, once the request is handled or the client goes away
+	cancelGoCtx gocontext.CancelFunc
+	// Reads the connection, watching it for the client going away while the request is handled
+	connReader *connReader
+	// Flush is a no-op, as the response must stay replaceable (e.g. under the Timeout middleware)
+	noFlush bool
+	// Whether the handler chain has been stopped with Abort()
+	aborted bool
+	// The connection is closed after this response (the client sent Connection: close, or is HTTP/1.0)
+	closeConn bool
+	// The net/http request and where its response goes, for requests served through ServeHTTP
+	httpReq    *http.Request
+	httpWriter *netHTTPWriter
 }
 
 // Clean resets the context for reuse in the next request.
@@ -194,15 +320,27 @@ func (ctx *context) Clean() {
 	// Reset slices to zero length but keep capacity for reuse
 	ctx.request.headers = ctx.request.headers[:0]
 	ctx.request.body = ctx.request.body[:0]
+	ctx.request.raw = ctx.request.raw[:0]
+	ctx.request.bodyStream = nil
+	ctx.request.partReader = nil
 	ctx.response.headers = ctx.response.headers[:0]
 	ctx.response.body = ctx.response.body[:0]
+	ctx.response.stream = nil
+	ctx.response.timings = ctx.response.timings[:0]
+	ctx.response.sent = 0
 	ctx.params = ctx.params[:0]
 
 	// Reset request state flags
 	ctx.parsedPostArgs = false
+	ctx.postArgs.Reset() // don't leak a form into the next request on the connection
+	ctx.parsedQuery = false
 
 	// Reset middleware chain position
 	ctx.handlerIndex = 0
+	ctx.aborted = false
+	ctx.session = nil
+	ctx.route = ""
+	ctx.closeConn = false
 
 	// Reset to default HTTP status
 	ctx.status = 200
@@ -230,11 +368,66 @@ func (ctx *context) Clean() {
 	ctx.sseCleanup = nil
 	ctx.sseEventsChan = nil
 	ctx.sseEventName = ""
+	ctx.releaseStream()
 
 	// Reset WebSocket state
 	ctx.wsUpgraded = false
 	ctx.wsConn = nil
 	ctx.conn = nil
+
+	ctx.goCtx = nil
+	ctx.cancelGoCtx = nil
+	ctx.connReader = nil
+	ctx.httpReq = nil
+	ctx.httpWriter = nil
+}
+
+// resetResponse discards any response written so far (status, headers, body and SSE setup),
+// so that a fresh response, such as an error page, can be written.
+func (ctx *context) resetResponse() {
+	ctx.response.headers = ctx.response.headers[:0]
+	ctx.response.body = ctx.response.body[:0]
+	ctx.response.status = 200
+
+	// The SSE stream will no longer be served, so release anything registered for it
+	if ctx.sseCleanup != nil {
+		ctx.sseCleanup()
+		ctx.sseCleanup = nil
+	}
+	ctx.sseEventsChan = nil
+	ctx.sseEventName = ""
+	ctx.releaseStream()
+}
+
+// releaseStream drops any streamed body, releasing what it holds if it wasn't sent
+func (ctx *context) releaseStream() {
+	if ctx.streamCleanup != nil {
+		ctx.streamCleanup()
+		ctx.streamCleanup = nil
+	}
+	ctx.streamFn = nil
+	ctx.streamLen = 0
+}
+
+// baseContext returns the concrete context underlying ctx,
+// unwrapping the context wrappers used for group middleware.
+// Returns nil for foreign Context implementations.
+func baseContext(ctx Context) *context {
+	for {
+		switch c := ctx.(type) {
+		case *context:
+			return c
+		case *contextWrapper:
+			ctx = c.wrappedContext
+		default:
+			return nil
+		}
+	}
+}
+
+// LastEventID returns the ID of the last SSE event a reconnecting client received.
+func (ctx *context) LastEventID() string {
+	return ctx.request.Header(consts.HeaderLastEventID)
 }
 
 // SetSSE configures the context for Server-Sent Events streaming.
@@ -259,8 +452,8 @@ func (ctx *context) Server() *Server {
 // This is the low-level method used by other write methods.
 // The bytes are appended to any existing response body content.
 func (ctx *context) Bytes(body []byte) error {
-	ctx.response.body = append(ctx.response.body, body...)
-	return nil
+	_, err := ctx.response.Write(body)
+	return err
 }
 
 // Error provides a convenient way to wrap multiple errors.
@@ -291,12 +484,51 @@ func (ctx *context) Error(messages ...any) error {
 // The handler chain includes both middleware and the final route handler.
 // Returns any error from the executed handler.
 func (ctx *context) Next() error {
+	if ctx.aborted {
+		return nil
+	}
+
 	// Move to next handler in the chain
 	ctx.handlerIndex++
 	// Execute the handler at the current index
 	return ctx.server.handlers[ctx.handlerIndex](ctx)
 }
 
+// IsLast reports whether the next handler is the final (route) handler.
+// The route handler itself is also last, since nothing follows it.
+func (ctx *context) IsLast() bool {
+	lastMiddleware := len(ctx.server.handlers) - 2
+	switch {
+	case int(ctx.handlerIndex) < lastMiddleware:
+		return false
+	case int(ctx.handlerIndex) > lastMiddleware:
+		return true
+	}
+	// Next() routes the request - to any middleware of the route's own (e.g. a group's) first
+	return !ctx.server.routeHasMiddleware(ctx)
+}
+
+// Abort stops the handler chain, so that Next() no longer calls the next handler.
+// Middleware that rejects a request writes its response, then aborts:
+//
+//	if !authorized(ctx) {
+//		ctx.Abort()
+//		return ctx.SetStatus(consts.StatusUnauthorized).WriteString("Unauthorized")
+//	}
+func (ctx *context) Abort() {
+	ctx.aborted = true
+}
+
+// IsAborted reports whether the handler chain has been stopped with Abort().
+func (ctx *context) IsAborted() bool {
+	return ctx.aborted
+}
+
+// HandlerIndex returns the position of the current handler in the server's middleware chain.
+func (ctx *context) HandlerIndex() int {
+	return int(ctx.handlerIndex)
+}
+
 // Redirect redirects the client to a different location
 // with the specified status code.
 func (ctx *context) Redirect(status int, location string) error {
@@ -334,8 +566,8 @@ func (ctx *context) SetStatus(status int) Context {
 // allowing you to set custom headers before writing.
 // The string is appended to any existing response body content.
 func (ctx *context) WriteString(body string) error {
-	ctx.response.body = append(ctx.response.body, body...)
-	return nil
+	_, err := ctx.response.WriteString(body)
+	return err
 }
 
 // WriteError is a convenience method for sending error responses.
@@ -349,10 +581,10 @@ func (ctx *context) WriteError(err error, code int) error {
 
 // WriteJSON serializes the given value to JSON and writes it to the response.
 // It automatically sets the Content-Type header to "application/json".
+// The encoding is per the server's JSONEncoder config (compact, with HTML escaped, by default).
 // Returns an error if JSON marshaling fails.
 func (ctx *context) WriteJSON(body interface{}) error {
-	_, er := ctx.response.WriteJSON(body)
-	return er
+	return ctx.writeJSON(body, ctx.jsonEncoder())
 }
 
 // WriteHTML writes HTML content to the response.
@@ -430,6 +662,21 @@ func (ctx *context) Delete(key string) {
 	}
 }
 
+// GetValue retrieves a value of type T from the context's custom data storage.
+// Returns the zero value and false if the key doesn't exist or holds a value of another type,
+// so it can't panic like a bad type assertion on ctx.Get.
+// Common usage: if userId, ok := rweb.GetValue[string](ctx, "userId"); ok { ... }
+func GetValue[T any](ctx Context, key string) (T, bool) {
+	value, ok := ctx.Get(key).(T)
+	return value, ok
+}
+
+// SetValue stores a value of type T in the context's custom data storage, for retrieval with GetValue[T].
+// Common usage: rweb.SetValue(ctx, "isAdmin", true)
+func SetValue[T any](ctx Context, key string, value T) {
+	ctx.Set(key, value)
+}
+
 // parseCookies lazily parses cookies from the request headers.
 // This is called automatically by cookie getter methods.
 func (ctx *context) parseCookies() {
@@ -599,9 +846,45 @@ func (ctx *context) HasCookie(name string) bool {
 	return exists
 }
 
+// SetSignedCookie sets a cookie with an HMAC-SHA256 signed value using secure defaults.
+func (ctx *context) SetSignedCookie(name, value string) error {
+	secret := ctx.cookieSecret()
+	if len(secret) == 0 {
+		return ErrCookieSecretNotSet
+	}
+	return ctx.SetCookie(name, signCookieValue(secret, name, value))
+}
+
+// GetSignedCookie retrieves a signed cookie value by name, verifying its signature.
+func (ctx *context) GetSignedCookie(name string) (string, error) {
+	secret := ctx.cookieSecret()
+	if len(secret) == 0 {
+		return "", ErrCookieSecretNotSet
+	}
+
+	signed, err := ctx.GetCookie(name)
+	if err != nil {
+		return "", err
+	}
+	return verifyCookieValue(secret, name, signed)
+}
+
+// cookieSecret returns the server's cookie signing secret, if any
+func (ctx *context) cookieSecret() []byte {
+	if ctx.server == nil {
+		return nil
+	}
+	return ctx.server.options.Cookie.Secret
+}
+
 // UpgradeWebSocket upgrades the HTTP connection to WebSocket protocol.
 // This performs the WebSocket handshake and returns a WebSocket connection.
-func (ctx *context) UpgradeWebSocket() (*WSConn, error) {
+func (ctx *context) UpgradeWebSocket(opts ...WSUpgradeOptions) (*WSConn, error) {
+	var opt WSUpgradeOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
 	// Check if already upgraded
 	if ctx.wsUpgraded {
 		return ctx.wsConn, nil
@@ -612,17 +895,62 @@ func (ctx *context) UpgradeWebSocket() (*WSConn, error) {
 		return nil, ErrWebSocketNotUpgraded
 	}
 
+	// Refuse upgrades from pages of origins not allowed, so they can't act with the user's cookies
+	if !ctx.webSocketOriginAllowed(opt) {
+		ctx.response.SetStatus(consts.StatusForbidden)
+		return nil, ErrWebSocketOriginRefused
+	}
+
+	// Refuse the upgrade when at the connection limit. The HTTP response hasn't been sent yet,
+	// so the client gets a proper 503, with a Retry-After telling it when to reconnect.
+	if !ctx.server.acquireWebSocket() {
+		ctx.response.SetStatus(consts.StatusServiceUnavailable)
+		ctx.response.SetHeader(consts.HeaderRetryAfter, ctx.server.webSocketRetryAfter())
+		return nil, ErrWebSocketLimitReached
+	}
+
 	// Perform the WebSocket handshake
-	if err := performHandshake(ctx); err != nil {
+	if err := performHandshake(ctx, opt); err != nil {
+		ctx.server.wsConns.Add(-1)
 		return nil, err
 	}
 
+	// The connection is the WebSocket's to read from now.
+	// It also outlives the server's WriteTimeout, so the request context no longer has that deadline.
+	ctx.connReader.stopWatching()
+	ctx.connReader = nil // not to be watched again
+	if ctx.cancelGoCtx != nil {
+		ctx.cancelGoCtx()
+		ctx.goCtx, ctx.cancelGoCtx = gocontext.WithCancel(gocontext.Background())
+	}
+
+	// Served through ServeHTTP - the connection is net/http's, until we take it over
+	if ctx.conn == nil && ctx.httpWriter != nil {
+		conn, err := ctx.httpWriter.hijack()
+		if err != nil {
+			ctx.server.wsConns.Add(-1)
+			return nil, err
+		}
+		ctx.conn = conn
+	}
+
 	// Write the upgrade response immediately
 	// This must happen before any WebSocket frames are sent
 	ctx.server.writeWebSocketUpgradeResponse(ctx, ctx.conn)
 
+	// The WebSocket connection manages its own deadlines from here on
+	_ = ctx.conn.SetDeadline(time.Time{})
+
 	// Create WebSocket connection
 	ctx.wsConn = NewWSConn(ctx.conn, true)
+	ctx.wsConn.subprotocol = ctx.response.Header("Sec-WebSocket-Protocol")
+	if len(ctx.data) > 0 {
+		ctx.wsConn.data = maps.Clone(ctx.data) // e.g. the user set by auth middleware
+	}
+	if extension := ctx.response.Header(consts.HeaderSecWebSocketExtensions); extension != "" {
+		ctx.wsConn.deflate = newWSDeflate(extension, true)
+	}
+	ctx.server.addWebSocket(ctx.wsConn)
 	ctx.wsUpgraded = true
 
 	return ctx.wsConn, nil
@@ -655,6 +983,22 @@ func (ctx *context) IsWebSocketUpgrade() bool {
 	return true
 }
 
+// Context returns the context.Context for the request.
+// It is canceled when the client disconnects, or once the request is handled,
+// and has the server's WriteTimeout, if set, as a deadline (WebSockets excepted).
+// The connection is watched for the client disconnecting from the first call on,
+// so requests whose handlers don't use the context don't pay for it.
+// It also carries the deadline set by the Timeout middleware, if in use,
+// so pass it to downstream calls (database queries, HTTP requests etc.)
+// to have them abandoned along with the request.
+func (ctx *context) Context() gocontext.Context {
+	if ctx.goCtx == nil {
+		return gocontext.Background()
+	}
+	ctx.connReader.watch(ctx.cancelGoCtx)
+	return ctx.goCtx
+}
+
 // GetConn returns the underlying network connection.
 // This should be used with caution as it bypasses the framework's abstractions.
 func (ctx *context) GetConn() net.Conn {
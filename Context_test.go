@@ -1,8 +1,17 @@
 package rweb_test
 
 import (
+	"bufio"
+	gocontext "context"
 	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/rohanthewiz/assert"
 	"github.com/rohanthewiz/rweb"
@@ -70,3 +79,207 @@ func TestRedirect(t *testing.T) {
 	assert.Equal(t, response.Status(), 301)
 	assert.Equal(t, response.Header("Location"), "/target")
 }
+
+func TestIsLast(t *testing.T) {
+	s := rweb.NewServer()
+
+	var trace []string
+	record := func(name string) rweb.Handler {
+		return func(ctx rweb.Context) error {
+			trace = append(trace, fmt.Sprintf("%s:%d:%t", name, ctx.HandlerIndex(), ctx.IsLast()))
+			return ctx.Next()
+		}
+	}
+	s.Use(record("first"), record("second"))
+
+	api := s.Group("/api", record("group1"), record("group2"))
+	api.Get("/", func(ctx rweb.Context) error {
+		trace = append(trace, fmt.Sprintf("route:%t", ctx.IsLast()))
+		return nil
+	})
+
+	s.GetWith("/admin", func(ctx rweb.Context) error {
+		trace = append(trace, fmt.Sprintf("route:%t", ctx.IsLast()))
+		return nil
+	}, record("route1"), record("route2"))
+	s.Get("/plain", func(ctx rweb.Context) error {
+		trace = append(trace, fmt.Sprintf("route:%t", ctx.IsLast()))
+		return nil
+	})
+
+	response := s.Request(consts.MethodGet, "/api", nil, nil)
+	assert.Equal(t, response.Status(), 200)
+	// Group middleware runs within the server's final (router) handler, at index 2,
+	// so the server's last middleware isn't last for a group route
+	assert.Equal(t, strings.Join(trace, " "),
+		"first:0:false second:1:false group1:2:false group2:2:true route:true")
+
+	// Nor for a route with middleware of its own
+	trace = nil
+	response = s.Request(consts.MethodGet, "/admin", nil, nil)
+	assert.Equal(t, response.Status(), 200)
+	assert.Equal(t, strings.Join(trace, " "),
+		"first:0:false second:1:false route1:2:false route2:2:true route:true")
+
+	// It is when the route handler is next
+	trace = nil
+	response = s.Request(consts.MethodGet, "/plain", nil, nil)
+	assert.Equal(t, response.Status(), 200)
+	assert.Equal(t, strings.Join(trace, " "), "first:0:false second:1:true route:true")
+}
+
+func TestAbort(t *testing.T) {
+	s := rweb.NewServer()
+
+	var trace []string
+	auth := func(ctx rweb.Context) error {
+		trace = append(trace, "auth")
+		if ctx.Request().Header("X-Token") != "secret" {
+			ctx.Abort()
+			ctx.SetStatus(consts.StatusForbidden)
+		}
+		// Passing control on regardless is harmless once aborted
+		err := ctx.Next()
+		trace = append(trace, fmt.Sprintf("auth-done:%t", ctx.IsAborted()))
+		return err
+	}
+	logger := func(ctx rweb.Context) error {
+		trace = append(trace, "logger")
+		return ctx.Next()
+	}
+	s.Use(auth, logger)
+	s.Get("/", func(ctx rweb.Context) error {
+		trace = append(trace, "route")
+		return ctx.WriteString("welcome")
+	})
+
+	response := s.Request(consts.MethodGet, "/", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusForbidden)
+	assert.Equal(t, string(response.Body()), "")
+	assert.Equal(t, strings.Join(trace, " "), "auth auth-done:true")
+
+	// The next request on the context starts afresh
+	trace = nil
+	response = s.Request(consts.MethodGet, "/", []rweb.Header{{Key: "X-Token", Value: "secret"}}, nil)
+	assert.Equal(t, string(response.Body()), "welcome")
+	assert.Equal(t, strings.Join(trace, " "), "auth logger route auth-done:false")
+
+	// In a group, an aborting middleware isn't auto-continued, whatever its status
+	grp := s.Group("/admin", func(ctx rweb.Context) error {
+		ctx.Abort()
+		return ctx.WriteString("maintenance")
+	}, func(ctx rweb.Context) error {
+		trace = append(trace, "group-second")
+		return nil
+	})
+	grp.Get("/users", func(ctx rweb.Context) error {
+		trace = append(trace, "group-route")
+		return nil
+	})
+
+	trace = nil
+	response = s.Request(consts.MethodGet, "/admin/users", []rweb.Header{{Key: "X-Token", Value: "secret"}}, nil)
+	assert.Equal(t, response.Status(), consts.StatusOK)
+	assert.Equal(t, string(response.Body()), "maintenance")
+	assert.Equal(t, strings.Join(trace, " "), "auth logger auth-done:true")
+}
+
+func TestContextCancellation(t *testing.T) {
+	readyChan := make(chan struct{}, 1)
+	s := rweb.NewServer(rweb.ServerOptions{ReadyChan: readyChan, Address: "localhost:", WriteTimeout: 5 * time.Second})
+
+	gone := make(chan error, 1)
+	s.Get("/slow", func(ctx rweb.Context) error {
+		select {
+		case <-ctx.Context().Done():
+			gone <- ctx.Context().Err()
+		case <-time.After(5 * time.Second):
+			gone <- nil
+		}
+		return nil
+	})
+
+	var handled gocontext.Context
+	s.Get("/fast", func(ctx rweb.Context) error {
+		handled = ctx.Context()
+		_, hasDeadline := ctx.Context().Deadline()
+		time.Sleep(100 * time.Millisecond) // for the next request to arrive meanwhile
+		return ctx.WriteString(fmt.Sprintf("deadline:%t err:%v", hasDeadline, ctx.Context().Err()))
+	})
+
+	go func() {
+		defer syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+		<-readyChan // wait for server
+		addr := "127.0.0.1:" + s.GetListenPort()
+
+		// The context is canceled when the client goes away
+		conn, err := net.Dial("tcp", addr)
+		assert.Nil(t, err)
+		_, err = io.WriteString(conn, "GET /slow HTTP/1.1\r\nHost: localhost\r\n\r\n")
+		assert.Nil(t, err)
+		time.Sleep(50 * time.Millisecond)
+		_ = conn.Close()
+		select {
+		case err := <-gone:
+			assert.True(t, errors.Is(err, gocontext.Canceled))
+		case <-time.After(2 * time.Second):
+			t.Error("the request context wasn't canceled when the client went away")
+		}
+
+		// Watching for the client going away doesn't lose a request sent while the previous one is handled
+		conn, err = net.Dial("tcp", addr)
+		assert.Nil(t, err)
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		for range 2 {
+			_, err = io.WriteString(conn, "GET /fast HTTP/1.1\r\nHost: localhost\r\n\r\n")
+			assert.Nil(t, err)
+			time.Sleep(20 * time.Millisecond)
+		}
+		for range 2 {
+			resp, err := http.ReadResponse(reader, nil)
+			assert.Nil(t, err)
+			body, _ := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			assert.Equal(t, string(body), "deadline:true err:<nil>")
+		}
+
+		// Once handled, the request's context is done
+		assert.True(t, errors.Is(handled.Err(), gocontext.Canceled))
+	}()
+
+	_ = s.Run()
+}
+
+func TestSetStatusChaining(t *testing.T) {
+	s := rweb.NewServer()
+	s.Get("/forbidden", func(ctx rweb.Context) error {
+		return ctx.SetStatus(consts.StatusForbidden).WriteJSON(map[string]string{"error": "forbidden"})
+	})
+
+	api := s.Group("/api", func(ctx rweb.Context) error {
+		if ctx.Request().Header("X-Key") == "" {
+			ctx.Abort()
+			return ctx.SetStatus(consts.StatusUnauthorized).WriteJSON(map[string]string{"error": "no key"})
+		}
+		return ctx.SetStatus(consts.StatusAccepted).Next()
+	})
+	api.Get("/jobs", func(ctx rweb.Context) error {
+		return ctx.WriteJSON(map[string]string{"job": "queued"})
+	})
+
+	response := s.Request(consts.MethodGet, "/forbidden", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusForbidden)
+	assert.Equal(t, response.Header(consts.HeaderContentType), "application/json")
+	assert.Equal(t, string(response.Body()), `{"error":"forbidden"}`)
+
+	response = s.Request(consts.MethodGet, "/api/jobs", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusUnauthorized)
+	assert.Equal(t, string(response.Body()), `{"error":"no key"}`)
+
+	// Chaining within a group's middleware continues its chain
+	response = s.Request(consts.MethodGet, "/api/jobs", []rweb.Header{{Key: "X-Key", Value: "k"}}, nil)
+	assert.Equal(t, response.Status(), consts.StatusAccepted)
+	assert.Equal(t, string(response.Body()), `{"job":"queued"}`)
+}
@@ -1,10 +1,22 @@
 package rweb
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
 	"net/http"
+	"strings"
 	"time"
 )
 
+var (
+	// ErrCookieSecretNotSet is returned by signed cookie operations when CookieConfig.Secret is empty
+	ErrCookieSecretNotSet = errors.New("cookie secret not set")
+	// ErrInvalidCookieSignature is returned when a signed cookie is malformed or has been tampered with
+	ErrInvalidCookieSignature = errors.New("invalid cookie signature")
+)
+
 // SameSiteMode represents the SameSite cookie attribute for CSRF protection.
 // This attribute controls when cookies are sent with cross-site requests.
 type SameSiteMode int
@@ -121,5 +133,43 @@ type CookieConfig struct {
 	// EncryptionKey enables automatic cookie value encryption if set.
 	// Must be 16, 24, or 32 bytes for AES-128, AES-192, or AES-256.
 	EncryptionKey []byte
+	// Secret is the HMAC-SHA256 key used by SetSignedCookie and GetSignedCookie.
+	// Use at least 32 random bytes, and keep it stable across restarts.
+	Secret []byte
+}
+
+// signCookieValue encodes the value and appends an HMAC-SHA256 signature.
+// The signature covers the cookie name too, so a signed value can't be moved to another cookie.
+// Format: base64url(value) + "." + base64url(signature)
+func signCookieValue(secret []byte, name, value string) string {
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(value))
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(cookieMAC(secret, name, encoded))
+}
+
+// verifyCookieValue checks the signature of a signed cookie value and returns the original value
+func verifyCookieValue(secret []byte, name, signed string) (string, error) {
+	encoded, sig, found := strings.Cut(signed, ".")
+	if !found {
+		return "", ErrInvalidCookieSignature
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, cookieMAC(secret, name, encoded)) {
+		return "", ErrInvalidCookieSignature
+	}
+
+	value, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", ErrInvalidCookieSignature
+	}
+	return string(value), nil
+}
+
+func cookieMAC(secret []byte, name, encodedValue string) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(name))
+	h.Write([]byte{'='})
+	h.Write([]byte(encodedValue))
+	return h.Sum(nil)
 }
 
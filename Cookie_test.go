@@ -1,6 +1,7 @@
 package rweb_test
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"testing"
@@ -181,15 +182,29 @@ func TestMultipleCookies(t *testing.T) {
 	response := s.Request("GET", "/multi-cookie", nil, nil)
 	assert.Equal(t, 200, response.Status())
 
-	// verify cookies were set
-	// The Header() method only returns the first Set-Cookie header,
-	// but in a real HTTP response, each would be a separate header.
-	// For now, just verify the first cookie was set correctly.
+	// Header() only returns the first Set-Cookie header
 	setCookieHeader := response.Header("Set-Cookie")
 	assert.Contains(t, setCookieHeader, "cookie1=value1")
-	assert.Contains(t, setCookieHeader, "Path=/")
-	assert.Contains(t, setCookieHeader, "HttpOnly")
-	assert.Contains(t, setCookieHeader, "SameSite=Lax")
+
+	// Headers() returns every Set-Cookie header
+	setCookieHeaders := response.Headers("Set-Cookie")
+	assert.Equal(t, 3, len(setCookieHeaders))
+	for i, header := range setCookieHeaders {
+		assert.Contains(t, header, fmt.Sprintf("cookie%d=value%d", i+1, i+1))
+	}
+	assert.Equal(t, 3, len(response.Headers("set-cookie")))
+	assert.Nil(t, response.Headers("X-Not-Set"))
+
+	// Cookies() parses every Set-Cookie header
+	cookies := response.Cookies()
+	assert.Equal(t, 3, len(cookies))
+	for i, cookie := range cookies {
+		assert.Equal(t, fmt.Sprintf("cookie%d", i+1), cookie.Name)
+		assert.Equal(t, fmt.Sprintf("value%d", i+1), cookie.Value)
+		assert.Equal(t, "/", cookie.Path)
+		assert.True(t, cookie.HttpOnly)
+		assert.Equal(t, rweb.SameSiteLaxMode, cookie.SameSite)
+	}
 }
 
 // TestCookieInMiddleware tests using cookies in middleware
@@ -359,3 +374,118 @@ func TestCookieExports(t *testing.T) {
 	}
 	assert.True(t, config.HttpOnly)
 }
+
+// TestSignedCookie tests signing cookies and verifying them on read
+func TestSignedCookie(t *testing.T) {
+	s := rweb.NewServerWithOptions(rweb.WithCookie(rweb.CookieConfig{
+		Secret: []byte("0123456789abcdef0123456789abcdef"),
+	}))
+
+	s.Get("/set", func(ctx rweb.Context) error {
+		return ctx.SetSignedCookie("user", "alice; admin=true")
+	})
+
+	s.Get("/get", func(ctx rweb.Context) error {
+		value, err := ctx.GetSignedCookie("user")
+		if err != nil {
+			return ctx.WriteString("error: " + err.Error())
+		}
+		return ctx.WriteString(value)
+	})
+
+	// the signed value is written as name=value
+	response := s.Request("GET", "/set", nil, nil)
+	assert.Equal(t, 200, response.Status())
+	setCookie := response.Header("Set-Cookie")
+	assert.True(t, strings.HasPrefix(setCookie, "user="))
+	assert.Contains(t, setCookie, "HttpOnly")
+	signed := strings.TrimPrefix(strings.Split(setCookie, ";")[0], "user=")
+	assert.Contains(t, signed, ".")
+
+	// round trip
+	response = s.Request("GET", "/get", []rweb.Header{{Key: "Cookie", Value: "user=" + signed}}, nil)
+	assert.Equal(t, "alice; admin=true", string(response.Body()))
+
+	// tampered value
+	tampered := "Ym9i" + signed[strings.Index(signed, "."):] // "bob" with alice's signature
+	response = s.Request("GET", "/get", []rweb.Header{{Key: "Cookie", Value: "user=" + tampered}}, nil)
+	assert.Equal(t, "error: "+rweb.ErrInvalidCookieSignature.Error(), string(response.Body()))
+
+	// unsigned value
+	response = s.Request("GET", "/get", []rweb.Header{{Key: "Cookie", Value: "user=alice"}}, nil)
+	assert.Equal(t, "error: "+rweb.ErrInvalidCookieSignature.Error(), string(response.Body()))
+
+	// missing cookie
+	response = s.Request("GET", "/get", nil, nil)
+	assert.Equal(t, "error: cookie not found", string(response.Body()))
+}
+
+// TestSignedCookieWrongName tests that a signed value can't be moved to another cookie
+func TestSignedCookieWrongName(t *testing.T) {
+	s := rweb.NewServerWithOptions(rweb.WithCookie(rweb.CookieConfig{Secret: []byte("secret")}))
+
+	s.Get("/set", func(ctx rweb.Context) error {
+		return ctx.SetSignedCookie("role", "user")
+	})
+	s.Get("/get", func(ctx rweb.Context) error {
+		_, err := ctx.GetSignedCookie("admin_role")
+		assert.True(t, errors.Is(err, rweb.ErrInvalidCookieSignature))
+		return nil
+	})
+
+	response := s.Request("GET", "/set", nil, nil)
+	signed := strings.TrimPrefix(strings.Split(response.Header("Set-Cookie"), ";")[0], "role=")
+	s.Request("GET", "/get", []rweb.Header{{Key: "Cookie", Value: "admin_role=" + signed}}, nil)
+}
+
+// TestSignedCookieNoSecret tests that signed cookies require a secret
+func TestSignedCookieNoSecret(t *testing.T) {
+	s := rweb.NewServer()
+
+	s.Get("/", func(ctx rweb.Context) error {
+		err := ctx.SetSignedCookie("user", "alice")
+		assert.True(t, errors.Is(err, rweb.ErrCookieSecretNotSet))
+		_, err = ctx.GetSignedCookie("user")
+		assert.True(t, errors.Is(err, rweb.ErrCookieSecretNotSet))
+		return nil
+	})
+
+	response := s.Request("GET", "/", nil, nil)
+	assert.Equal(t, 200, response.Status())
+	assert.Equal(t, "", response.Header("Set-Cookie"))
+}
+
+// TestResponseCookiesAttributes tests that Cookies() parses all cookie attributes
+func TestResponseCookiesAttributes(t *testing.T) {
+	s := rweb.NewServer()
+
+	s.Get("/", func(ctx rweb.Context) error {
+		_ = ctx.SetCookieWithOptions(&rweb.Cookie{
+			Name:     "prefs",
+			Value:    "dark",
+			Path:     "/app",
+			Domain:   "example.com",
+			MaxAge:   3600,
+			SameSite: rweb.SameSiteStrictMode,
+		})
+		return ctx.DeleteCookie("old")
+	})
+
+	response := s.Request("GET", "/", nil, nil)
+	cookies := response.Cookies()
+	assert.Equal(t, 2, len(cookies))
+
+	assert.Equal(t, "prefs", cookies[0].Name)
+	assert.Equal(t, "/app", cookies[0].Path)
+	assert.Equal(t, "example.com", cookies[0].Domain)
+	assert.Equal(t, 3600, cookies[0].MaxAge)
+	assert.False(t, cookies[0].HttpOnly)
+	assert.Equal(t, rweb.SameSiteStrictMode, cookies[0].SameSite)
+
+	assert.Equal(t, "old", cookies[1].Name)
+	assert.Equal(t, -1, cookies[1].MaxAge)
+
+	// No cookies set
+	s.Get("/none", func(ctx rweb.Context) error { return nil })
+	assert.Equal(t, 0, len(s.Request("GET", "/none", nil, nil).Cookies()))
+}
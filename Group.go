@@ -1,7 +1,9 @@
 package rweb
 
 import (
+	"io/fs"
 	"path"
+	"sync/atomic"
 )
 
 // Group represents a route group with a common prefix and middleware.
@@ -86,9 +88,18 @@ func (g *Group) Trace(path string, handler Handler) {
 // reqDir is the URL path relative to the group prefix.
 // targetDir is the local filesystem directory containing the files.
 // nbrOfTokensToStrip removes URL path segments when mapping to filesystem paths.
-func (g *Group) StaticFiles(reqDir string, targetDir string, nbrOfTokensToStrip int) {
+// opts optionally configure the handling of directories, as for Server.StaticFiles.
+func (g *Group) StaticFiles(reqDir string, targetDir string, nbrOfTokensToStrip int, opts ...StaticOptions) {
 	fullPath := path.Join(g.prefix, reqDir)
-	g.server.StaticFiles(fullPath, targetDir, nbrOfTokensToStrip)
+	g.server.StaticFiles(fullPath, targetDir, nbrOfTokensToStrip, opts...)
+}
+
+// StaticFS serves static files from the provided fs.FS (e.g. an embed.FS) with the group prefix.
+// reqDir is the URL path relative to the group prefix.
+// nbrOfTokensToStrip removes URL path segments when mapping to filesystem paths.
+func (g *Group) StaticFS(reqDir string, fsys fs.FS, nbrOfTokensToStrip int) {
+	fullPath := path.Join(g.prefix, reqDir)
+	g.server.StaticFS(fullPath, fsys, nbrOfTokensToStrip)
 }
 
 // Proxy sets up a reverse proxy with the group prefix.
@@ -115,31 +126,43 @@ func (g *Group) addRoute(method, routePath string, handler Handler) {
 	// The leading "/" ensures proper path formatting
 	fullPath := path.Join("/", g.prefix, routePath)
 	
+	g.server.addRoute(method, fullPath, chainHandlers(handler, g.handlers), len(g.handlers) > 0)
+}
+
+// chainHandlers wraps handler with middlewares, which run in order ahead of it.
+// Each middleware continues the chain by calling ctx.Next(), or by simply returning nil without rejecting the request.
+func chainHandlers(handler Handler, middlewares []Handler) Handler {
 	// Build the middleware chain - start with the route handler as the final handler
 	finalHandler := handler
 	
 	// Wrap handlers in reverse order to ensure they execute in the order they were added.
 	// This creates a chain where each middleware wraps the next one.
-	for i := len(g.handlers) - 1; i >= 0; i-- {
+	for i := len(middlewares) - 1; i >= 0; i-- {
 		// Capture the current middleware and next handler in the closure
 		// to avoid closure variable issues in the loop
-		middleware := g.handlers[i]
+		middleware := middlewares[i]
 		nextHandler := finalHandler
+		isLast := i == len(middlewares)-1
 		
 		finalHandler = func(ctx Context) error {
 			// Track whether the middleware called Next() to continue the chain.
 			// This allows middleware to optionally stop the chain (e.g., for auth failures)
-			nextCalled := false
+			// (atomic, as a middleware such as Timeout may call it from another goroutine)
+			var nextCalled atomic.Bool
 			
 			// Create a context wrapper that intercepts Next() calls.
 			// This allows us to track when middleware explicitly passes control
 			// to the next handler in the chain.
 			wrapper := &contextWrapper{
-				Context: ctx,
-				next: func() error {
-					nextCalled = true
-					return nextHandler(ctx)
+				wrappedContext: ctx,
+				next: func(c Context) error {
+					nextCalled.Store(true)
+					if c.IsAborted() {
+						return nil
+					}
+					return nextHandler(c)
 				},
+				last: isLast,
 			}
 			
 			// Execute the middleware with our wrapper context
@@ -148,7 +171,8 @@ func (g *Group) addRoute(method, routePath string, handler Handler) {
 			// If middleware didn't call Next() and didn't return an error,
 			// automatically continue to the next handler.
 			// This allows middleware to work without explicitly calling Next().
-			if err == nil && !nextCalled {
+			// A middleware that aborted (e.g. rejecting the request with a 401) stops the chain though.
+			if err == nil && !nextCalled.Load() && !ctx.IsAborted() {
 				err = nextHandler(ctx)
 			}
 			
@@ -156,22 +180,46 @@ func (g *Group) addRoute(method, routePath string, handler Handler) {
 		}
 	}
 	
-	g.server.AddMethod(method, fullPath, finalHandler)
+	return finalHandler
 }
 
+// wrappedContext is Context, under a name suitable for embedding in contextWrapper
+type wrappedContext = Context
+
 // contextWrapper wraps a Context to intercept Next() calls.
 // This allows group middleware to properly track and control the execution chain,
 // ensuring that middleware can stop the chain or pass control as needed.
 type contextWrapper struct {
 	// Embedded Context provides all standard context methods
-	Context
-	// next is our custom Next() implementation that tracks calls
-	next func() error
+	// (under another name, as the field would otherwise hide the Context() method)
+	wrappedContext
+	// next is our custom Next() implementation that tracks calls.
+	// It continues the chain with the given context, normally the wrapped one.
+	next func(Context) error
+	// last is true for the group's final middleware, whose Next() invokes the route handler
+	last bool
 }
 
 // Next overrides the Context's Next method to use our custom implementation.
 // This allows the group to track when middleware explicitly passes control
 // to the next handler in the chain.
 func (w *contextWrapper) Next() error {
-	return w.next()
+	return w.next(w.wrappedContext)
+}
+
+// IsLast reports whether this is the group's final middleware.
+func (w *contextWrapper) IsLast() bool {
+	return w.last
+}
+
+// SetStatus sets the HTTP status of the response, returning the wrapper (rather than the wrapped context)
+// so that a chained call, e.g. ctx.SetStatus(202).Next(), stays within the group's chain.
+func (w *contextWrapper) SetStatus(status int) Context {
+	w.wrappedContext.SetStatus(status)
+	return w
+}
+
+// Status is the deprecated form of SetStatus.
+func (w *contextWrapper) Status(status int) Context {
+	return w.SetStatus(status)
 }
\ No newline at end of file
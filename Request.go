@@ -3,9 +3,16 @@ package rweb
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"mime"
 	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"os"
+	"slices"
 	"strings"
 
 	"github.com/rohanthewiz/rweb/consts"
@@ -19,6 +26,18 @@ type ItfRequest interface {
 	// Header returns the header value for the given key.
 	// Performs case-sensitive match first, then falls back to lowercase match if not found.
 	Header(string) string
+	// HeaderValues returns every value of a header (matched case-insensitively), in the order received,
+	// e.g. of repeated Accept or X-Forwarded-For headers. Returns nil if the header is absent.
+	HeaderValues(key string) []string
+	// HeaderMap returns the headers as a map, like the standard library's http.Header:
+	// keys are canonicalized (e.g. "Content-Type"), and each holds all the values of the header.
+	// The map is a copy, so changing it doesn't change the request.
+	HeaderMap() map[string][]string
+	// SetHeader sets a request header, replacing any existing values (matched case-insensitively).
+	// Useful in middleware that normalizes or rewrites headers for downstream handlers and proxies.
+	SetHeader(key, value string)
+	// DelHeader removes all values of a request header (matched case-insensitively).
+	DelHeader(key string)
 	Host() string
 	// Method returns the HTTP method of the request
 	Method() string
@@ -28,11 +47,24 @@ type ItfRequest interface {
 	Query() string
 	// QueryParam returns the value of a particular query string param.
 	QueryParam(string) string
+	// QueryParamDefault returns the value of a query string param, or def if it is absent or empty.
+	QueryParamDefault(name, def string) string
+	// QueryParams returns all query string params, decoded.
+	QueryParams() url.Values
 	Scheme() string
 	// Param retrieves a Path parameter's value.
 	Param(string) string
 	// PathParam retrieves a Path parameter's value.
 	PathParam(string) string
+	// ParamInt retrieves a Path parameter's value as an int.
+	// The error wraps ErrParamMissing if the param is absent or empty, or ErrParamInvalid if it isn't an integer.
+	ParamInt(string) (int, error)
+	// ParamInt64 retrieves a Path parameter's value as an int64, with errors as for ParamInt.
+	ParamInt64(string) (int64, error)
+	// ParamBool retrieves a Path parameter's value as a bool, with errors as for ParamInt.
+	ParamBool(string) (bool, error)
+	// ParamUUID retrieves a Path parameter's value, validated as a UUID and lowercased, with errors as for ParamInt.
+	ParamUUID(string) (string, error)
 	// GetPostValue retrieves the value of POST param - cannot be used for non-multipart forms
 	// use FormValue for multipart form values.
 	GetPostValue(string) string
@@ -40,7 +72,16 @@ type ItfRequest interface {
 	FormValue(string) string
 	// GetFormFile returns the first file for the provided form key
 	GetFormFile(string) (multipart.File, *multipart.FileHeader, error)
+	// SaveFormFile streams the first file for the provided form key to destPath,
+	// returning the number of bytes written.
+	SaveFormFile(field, destPath string) (int64, error)
+	// NextPart returns the next part of a multipart body, or io.EOF after the last part.
+	// Bodies over the server's MultipartStreamThreshold are read part by part from the connection, instead of buffered.
+	NextPart() (*multipart.Part, error)
 	Body() []byte
+	// Raw returns the request exactly as received - request line, headers and body.
+	// Only available when the server option CaptureRawRequest is enabled, otherwise nil.
+	Raw() []byte
 }
 
 // request represents the HTTP request used in the given context.
@@ -60,11 +101,16 @@ type request struct {
 
 	multipartForm         *multipart.Form
 	multipartFormBoundary string
+	bodyStream            io.Reader         // the unread body, when streamed from the connection
+	partReader            *multipart.Reader // the reader of NextPart
 
-	queryArgs Args
+	queryValues url.Values
+	parsedQuery bool
 
 	postArgs       Args
 	parsedPostArgs bool
+
+	raw []byte // the request as received, when ServerOptions.CaptureRawRequest is set
 }
 
 // Header returns the header value for the given key.
@@ -75,14 +121,62 @@ func (req *request) Header(key string) string {
 		if header.Key == key {
 			return header.Value
 		}
-		// Otherwise, try lowercase match
-		if header.Key == strings.ToLower(key) {
+		// Otherwise, match regardless of case (e.g. Go canonicalizes "Sec-WebSocket-Key" to "Sec-Websocket-Key")
+		if strings.EqualFold(header.Key, key) {
 			return header.Value
 		}
 	}
 	return ""
 }
 
+// HeaderValues returns all the values of a header (case-insensitive), in the order received
+func (req *request) HeaderValues(key string) (values []string) {
+	for _, header := range req.headers {
+		if strings.EqualFold(header.Key, key) {
+			values = append(values, header.Value)
+		}
+	}
+	return
+}
+
+// HeaderMap returns a copy of the headers, keyed by canonical header name
+func (req *request) HeaderMap() map[string][]string {
+	headers := make(map[string][]string, len(req.headers))
+	for _, header := range req.headers {
+		key := textproto.CanonicalMIMEHeaderKey(header.Key)
+		headers[key] = append(headers[key], header.Value)
+	}
+	return headers
+}
+
+// SetHeader sets a request header, replacing any existing values of the key (case-insensitive).
+func (req *request) SetHeader(key, value string) {
+	matchKey := func(h Header) bool { return strings.EqualFold(h.Key, key) }
+
+	if i := slices.IndexFunc(req.headers, matchKey); i < 0 {
+		req.headers = append(req.headers, Header{Key: key, Value: value})
+	} else {
+		req.headers[i] = Header{Key: key, Value: value}
+		// Drop any further values of the key
+		req.headers = append(req.headers[:i+1], slices.DeleteFunc(req.headers[i+1:], matchKey)...)
+	}
+
+	if strings.EqualFold(key, consts.HeaderContentType) {
+		req.ContentType = s2b(value)
+	}
+}
+
+// DelHeader removes all values of a request header (case-insensitive).
+func (req *request) DelHeader(key string) {
+	req.headers = slices.DeleteFunc(req.headers, func(h Header) bool {
+		return strings.EqualFold(h.Key, key)
+	})
+
+	if strings.EqualFold(key, consts.HeaderContentType) {
+		req.ContentType = nil
+	}
+}
+
 // Headers returns all the request headers.
 func (req *request) Headers() []Header {
 	return req.headers
@@ -128,11 +222,48 @@ func (req *request) Query() string {
 	return req.query
 }
 
-// QueryParam returns the value of a particular query param.
+// QueryParam returns the first value of a particular query param.
 func (req *request) QueryParam(param string) (value string) {
+	if !req.parsedQuery {
+		req.parseQuery()
+	}
+	return req.queryValues.Get(param)
+}
+
+// QueryParamDefault returns the first value of a particular query param,
+// or def if the param is absent or empty.
+func (req *request) QueryParamDefault(param, def string) string {
+	if value := req.QueryParam(param); value != "" {
+		return value
+	}
+	return def
+}
+
+// QueryParams returns all query params, URL-decoded.
+// The query string is parsed once per request and the result cached.
+// Repeated keys (?a=1&a=2) are kept in order.
+// The map is a copy, so it may be kept or changed beyond the request.
+func (req *request) QueryParams() url.Values {
+	if !req.parsedQuery {
+		req.parseQuery()
+	}
+	return maps.Clone(req.queryValues)
+}
+
+// parseQuery parses the raw query string into queryValues, decoding as Args does:
+// malformed escapes (?q=100%) are kept as is, and only & separates pairs, so ?a=1;b=2 gives a "1;b=2".
+func (req *request) parseQuery() {
+	if req.queryValues == nil {
+		req.queryValues = make(url.Values)
+	} else {
+		clear(req.queryValues)
+	}
 	var args Args
 	args.Parse(req.query)
-	return b2s(args.Peek(param))
+	args.VisitAll(func(key, value []byte) {
+		req.queryValues.Add(string(key), string(value))
+	})
+	req.parsedQuery = true
 }
 
 // Scheme returns either `http`, `https` or an empty string.
@@ -152,6 +283,16 @@ func (req *request) Body() []byte {
 	return req.body
 }
 
+// Raw returns the raw bytes of the request as received (request line, headers and body),
+// when the server's CaptureRawRequest option is enabled. Returns nil otherwise.
+// The bytes are only valid for the duration of the request - copy them to keep them.
+func (req *request) Raw() []byte {
+	if len(req.raw) == 0 {
+		return nil
+	}
+	return req.raw
+}
+
 // GetPostValue retrieves the value of a non-multipart form POST parameter.
 func (req *request) GetPostValue(key string) string {
 	return b2s(req.PostArgs().Peek(key))
@@ -163,12 +304,13 @@ func (req *request) PostArgs() *Args {
 	return &req.postArgs
 }
 
+// parsePostArgs parses a urlencoded form body, of any method (POST, PUT, PATCH...)
 func (req *request) parsePostArgs() {
 	if req.parsedPostArgs {
 		return
 	}
 
-	if !bytes.EqualFold(req.ContentType, consts.BytFormData) {
+	if !isFormURLEncoded(req.ContentType) {
 		return
 	}
 
@@ -176,7 +318,34 @@ func (req *request) parsePostArgs() {
 	req.parsedPostArgs = true
 }
 
+// isFormURLEncoded reports whether a Content-Type is application/x-www-form-urlencoded,
+// with or without parameters (e.g. "; charset=UTF-8")
+func isFormURLEncoded(contentType []byte) bool {
+	mediaType, _, _ := bytes.Cut(contentType, []byte{';'})
+	return bytes.EqualFold(bytes.TrimSpace(mediaType), consts.BytFormData)
+}
+
+// multipartLimits are the limits on the multipart forms parsed
+type multipartLimits struct {
+	maxMemory int64 // bytes of fields and files kept in memory - fields must fit
+	maxFields int
+	maxFiles  int
+}
+
+// defaultMultipartLimits are the limits applied unless the server options say otherwise
+var defaultMultipartLimits = multipartLimits{maxMemory: 32 << 20, maxFields: 1000, maxFiles: 100}
+
+// errMultipartTooLarge is returned when parsing a multipart form over the limits
+var errMultipartTooLarge = errors.New("multipart form too large")
+
+// ParseMultipartForm parses a multipart form body, within the default limits
 func (req *request) ParseMultipartForm() error {
+	return req.parseMultipartForm(defaultMultipartLimits)
+}
+
+// parseMultipartForm parses a multipart form body, returning an error wrapping errMultipartTooLarge
+// (or multipart.ErrMessageTooLarge) if it is over the limits.
+func (req *request) parseMultipartForm(limits multipartLimits) error {
 	if req.multipartForm != nil {
 		return nil
 	}
@@ -198,9 +367,14 @@ func (req *request) ParseMultipartForm() error {
 		return fmt.Errorf("no boundary found in multipart form data")
 	}
 
+	// Check the parts before the form is built, so no resources are spent on an excessive form
+	if err = checkMultipartLimits(req.body, boundary, limits); err != nil {
+		return err
+	}
+
 	// Create a new multipart reader
 	reader := multipart.NewReader(bytes.NewReader(req.body), boundary)
-	form, err := reader.ReadForm(32 << 20) // 32MB max memory
+	form, err := reader.ReadForm(limits.maxMemory)
 	if err != nil {
 		return err
 	}
@@ -209,6 +383,42 @@ func (req *request) ParseMultipartForm() error {
 	return nil
 }
 
+// checkMultipartLimits counts the fields and files of a multipart body, and the size of the fields,
+// returning an error wrapping errMultipartTooLarge as soon as a limit is exceeded.
+func checkMultipartLimits(body []byte, boundary string, limits multipartLimits) error {
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+	var fields, files int
+	var fieldBytes int64
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if part.FileName() != "" {
+			if files++; files > limits.maxFiles {
+				return fmt.Errorf("%w: more than %d files", errMultipartTooLarge, limits.maxFiles)
+			}
+			continue // the file's content is skipped by NextPart
+		}
+
+		if fields++; fields > limits.maxFields {
+			return fmt.Errorf("%w: more than %d fields", errMultipartTooLarge, limits.maxFields)
+		}
+		n, err := io.Copy(io.Discard, part)
+		if err != nil {
+			return err
+		}
+		if fieldBytes += n; fieldBytes > limits.maxMemory {
+			return fmt.Errorf("%w: fields over %d bytes", errMultipartTooLarge, limits.maxMemory)
+		}
+	}
+}
+
 // GetFormFile returns the first file for the provided form key
 func (req *request) GetFormFile(key string) (multipart.File, *multipart.FileHeader, error) {
 	// if err := req.ParseMultipartForm(); err != nil {
@@ -236,6 +446,29 @@ func (req *request) GetFormFile(key string) (multipart.File, *multipart.FileHead
 	return file, files[0], nil
 }
 
+// SaveFormFile streams the first file for the provided form key to destPath,
+// creating or truncating it, and returns the number of bytes written.
+// The file is copied in chunks rather than read into memory.
+func (req *request) SaveFormFile(field, destPath string) (written int64, err error) {
+	file, _, err := req.GetFormFile(field)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	dest, err := os.Create(destPath)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if closeErr := dest.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	return io.Copy(dest, file)
+}
+
 // FormValue returns the first value for the named component of the form data
 func (req *request) FormValue(key string) string {
 	if req.multipartForm != nil {
@@ -250,5 +483,6 @@ func (req *request) FormValue(key string) string {
 func (req *request) CleanupMultipartForm() {
 	if req.multipartForm != nil {
 		_ = req.multipartForm.RemoveAll()
+		req.multipartForm = nil // don't leak this form into the next request on the connection
 	}
 }
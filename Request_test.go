@@ -1,7 +1,14 @@
 package rweb_test
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
 	"testing"
 
 	"github.com/rohanthewiz/assert"
@@ -70,6 +77,37 @@ func TestRequestHeaderLowercaseFallback(t *testing.T) {
 	assert.Equal(t, string(response2.Body()), "exact")
 }
 
+func TestRequestHeaderValuesAndMap(t *testing.T) {
+	s := rweb.NewServer()
+
+	s.Get("/", func(ctx rweb.Context) error {
+		req := ctx.Request()
+		assert.Equal(t, strings.Join(req.HeaderValues("x-forwarded-for"), ","), "10.0.0.1,10.0.0.2,10.0.0.3")
+		assert.Equal(t, strings.Join(req.HeaderValues("ACCEPT"), ","), "text/html")
+		assert.Nil(t, req.HeaderValues("X-Missing"))
+
+		headers := req.HeaderMap()
+		assert.Equal(t, len(headers), 2)
+		assert.Equal(t, len(headers["X-Forwarded-For"]), 3)
+		assert.Equal(t, headers["Accept"][0], "text/html")
+
+		// A copy - changes don't reach the request
+		headers["Accept"][0] = "changed"
+		delete(headers, "X-Forwarded-For")
+		assert.Equal(t, req.Header("accept"), "text/html")
+		assert.Equal(t, len(req.HeaderValues("X-Forwarded-For")), 3)
+		return nil
+	})
+
+	response := s.Request(consts.MethodGet, "/", []rweb.Header{
+		{Key: "X-Forwarded-For", Value: "10.0.0.1"},
+		{Key: "accept", Value: "text/html"},
+		{Key: "x-forwarded-for", Value: "10.0.0.2"},
+		{Key: "X-FORWARDED-FOR", Value: "10.0.0.3"},
+	}, nil)
+	assert.Equal(t, response.Status(), consts.StatusOK)
+}
+
 func TestRequestParam(t *testing.T) {
 	s := rweb.NewServer()
 
@@ -84,6 +122,58 @@ func TestRequestParam(t *testing.T) {
 	assert.Equal(t, string(response.Body()), "my-article")
 }
 
+func TestRequestTypedParams(t *testing.T) {
+	s := rweb.NewServer()
+
+	s.Get("/orders/:id/:big/:flag/:ref", func(ctx rweb.Context) error {
+		req := ctx.Request()
+		id, err := req.ParamInt("id")
+		if err != nil {
+			return ctx.WriteError(err, consts.StatusBadRequest)
+		}
+		big, err := req.ParamInt64("big")
+		if err != nil {
+			return ctx.WriteError(err, consts.StatusBadRequest)
+		}
+		flag, err := req.ParamBool("flag")
+		if err != nil {
+			return ctx.WriteError(err, consts.StatusBadRequest)
+		}
+		ref, err := req.ParamUUID("ref")
+		if err != nil {
+			return ctx.WriteError(err, consts.StatusBadRequest)
+		}
+		_, err = req.ParamInt("nope")
+		assert.True(t, errors.Is(err, rweb.ErrParamMissing))
+		return ctx.WriteString(fmt.Sprintf("%d %d %t %s", id, big, flag, ref))
+	})
+
+	const ref = "123E4567-e89b-12d3-a456-426614174000"
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/orders/42/9000000000/true/" + ref, 200, "42 9000000000 true 123e4567-e89b-12d3-a456-426614174000"},
+		{"/orders/-7/0/0/" + ref, 200, "-7 0 false 123e4567-e89b-12d3-a456-426614174000"},
+		{"/orders/abc/1/true/" + ref, consts.StatusBadRequest, `invalid path param: "id" is "abc", want an integer`},
+		{"/orders/4.2/1/true/" + ref, consts.StatusBadRequest, `invalid path param: "id" is "4.2", want an integer`},
+		{"/orders/1/99999999999999999999/true/" + ref, consts.StatusBadRequest,
+			`invalid path param: "big" is "99999999999999999999", want a 64-bit integer`},
+		{"/orders/1/1/yes/" + ref, consts.StatusBadRequest, `invalid path param: "flag" is "yes", want a boolean`},
+		{"/orders/1/1/true/123e4567e89b12d3a456426614174000", consts.StatusBadRequest,
+			`invalid path param: "ref" is "123e4567e89b12d3a456426614174000", want a UUID`},
+		{"/orders/1/1/true/123e4567-e89b-12d3-a456-42661417400g", consts.StatusBadRequest,
+			`invalid path param: "ref" is "123e4567-e89b-12d3-a456-42661417400g", want a UUID`},
+	}
+
+	for _, tt := range tests {
+		response := s.Request(consts.MethodGet, tt.path, nil, nil)
+		assert.Equal(t, response.Status(), tt.status)
+		assert.Equal(t, string(response.Body()), tt.body)
+	}
+}
+
 func TestUserAgent(t *testing.T) {
 	s := rweb.NewServer()
 
@@ -107,3 +197,147 @@ func TestUserAgent(t *testing.T) {
 	assert.Equal(t, response3.Status(), 200)
 	assert.Equal(t, string(response3.Body()), "")
 }
+
+func TestRequestQueryParams(t *testing.T) {
+	s := rweb.NewServer()
+
+	s.Get("/search", func(ctx rweb.Context) error {
+		req := ctx.Request()
+		params := req.QueryParams()
+		return ctx.WriteString(fmt.Sprintf("%s|%s|%s|%s|%d|%t",
+			req.QueryParam("q"),
+			strings.Join(params["tag"], ","),
+			req.QueryParamDefault("page", "1"),
+			req.QueryParamDefault("sort", "asc"),
+			len(params),
+			params.Has("empty"),
+		))
+	})
+
+	// Encoded characters, repeated keys and an empty value
+	response := s.Request(consts.MethodGet, "/search?q=caf%C3%A9+latte&tag=a&tag=b%26c&empty=&page=", nil, nil)
+	assert.Equal(t, response.Status(), 200)
+	assert.Equal(t, string(response.Body()), "café latte|a,b&c|1|asc|4|true")
+
+	// No query string at all
+	response = s.Request(consts.MethodGet, "/search", nil, nil)
+	assert.Equal(t, response.Status(), 200)
+	assert.Equal(t, string(response.Body()), "||1|asc|0|false")
+}
+
+func TestRequestQueryParamsMalformed(t *testing.T) {
+	s := rweb.NewServer()
+
+	s.Get("/", func(ctx rweb.Context) error {
+		req := ctx.Request()
+		params := req.QueryParams()
+		params.Set("good", "changed") // a copy, so QueryParam is unaffected
+		return ctx.WriteString(strings.Join([]string{
+			req.QueryParam("good"), req.QueryParam("bad"), req.QueryParam("q"), req.QueryParam("a"),
+		}, "|"))
+	})
+
+	// Bad escapes are kept as they are, without hiding the other pairs
+	response := s.Request(consts.MethodGet, "/?bad=%zz&good=yes&q=100%", nil, nil)
+	assert.Equal(t, response.Status(), 200)
+	assert.Equal(t, string(response.Body()), "yes|%zz|100%|")
+
+	// Only & separates pairs
+	response = s.Request(consts.MethodGet, "/?a=1;b=2&good=yes", nil, nil)
+	assert.Equal(t, response.Status(), 200)
+	assert.Equal(t, string(response.Body()), "yes|||1;b=2")
+}
+
+func TestRequestSetDelHeader(t *testing.T) {
+	s := rweb.NewServer()
+
+	// Normalize headers before the handler runs
+	s.Use(func(ctx rweb.Context) error {
+		req := ctx.Request()
+		if legacy := req.Header("X-Legacy-Token"); legacy != "" {
+			req.SetHeader("Authorization", "Bearer "+legacy)
+			req.DelHeader("X-Legacy-Token")
+		}
+		req.DelHeader("x-debug")
+		return ctx.Next()
+	})
+
+	s.Get("/", func(ctx rweb.Context) error {
+		req := ctx.Request()
+		var auths []string
+		for _, hdr := range req.Headers() {
+			if strings.EqualFold(hdr.Key, "Authorization") {
+				auths = append(auths, hdr.Value)
+			}
+		}
+		return ctx.WriteString(fmt.Sprintf("%s|%s|%s|%d",
+			strings.Join(auths, ","), req.Header("X-Legacy-Token"), req.Header("X-Debug"), len(req.Headers())))
+	})
+
+	headers := []rweb.Header{
+		{Key: "authorization", Value: "Basic old"},
+		{Key: "X-Legacy-Token", Value: "abc"},
+		{Key: "X-Debug", Value: "1"},
+		{Key: "Authorization", Value: "Basic dup"},
+		{Key: "Accept", Value: "*/*"},
+	}
+	response := s.Request(consts.MethodGet, "/", headers, nil)
+	assert.Equal(t, response.Status(), 200)
+	// A single Authorization header remains, no legacy or debug header
+	assert.Equal(t, string(response.Body()), "Bearer abc|||2")
+
+	// The caller's headers are not modified
+	assert.Equal(t, headers[1].Key, "X-Legacy-Token")
+	assert.Equal(t, len(headers), 5)
+}
+
+func TestRequestRaw(t *testing.T) {
+	readyChan := make(chan struct{}, 1)
+	s := rweb.NewServerWithOptions(
+		rweb.WithAddress("localhost:"),
+		rweb.WithReadyChan(readyChan),
+		rweb.WithRawRequestCapture(),
+	)
+
+	s.Post("/webhook", func(ctx rweb.Context) error {
+		return ctx.Bytes(ctx.Request().Raw())
+	})
+
+	go func() {
+		defer syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+		<-readyChan // wait for server
+		conn, err := net.Dial(consts.ProtocolTCP, fmt.Sprintf(":%s", s.GetListenPort()))
+		assert.Nil(t, err)
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+
+		requests := []string{
+			"POST /webhook?id=1 HTTP/1.1\r\nHost: localhost\r\nX-Signature:  abc \r\n" +
+				"Content-Length: 13\r\n\r\n{\"event\": 42}",
+			"POST /webhook HTTP/1.1\r\nHost: localhost\r\nTransfer-Encoding: chunked\r\n\r\n" +
+				"5\r\nhello\r\n6\r\n world\r\n0\r\n\r\n",
+		}
+
+		// Each request on the keep-alive connection is captured byte for byte
+		for _, raw := range requests {
+			_, err = io.WriteString(conn, raw)
+			assert.Nil(t, err)
+			resp, err := http.ReadResponse(reader, nil)
+			assert.Nil(t, err)
+			body, _ := io.ReadAll(resp.Body)
+			assert.Equal(t, string(body), raw)
+		}
+	}()
+
+	err := s.Run()
+	assert.Nil(t, err)
+
+	// Not captured unless enabled
+	s2 := rweb.NewServer()
+	s2.Post("/webhook", func(ctx rweb.Context) error {
+		assert.Nil(t, ctx.Request().Raw())
+		return nil
+	})
+	s2.Request(consts.MethodPost, "/webhook", nil, nil)
+}
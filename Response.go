@@ -1,8 +1,11 @@
 package rweb
 
 import (
-	"encoding/json"
 	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
 
 	"github.com/rohanthewiz/rweb/consts"
 )
@@ -12,11 +15,29 @@ type Response interface {
 	io.Writer
 	io.StringWriter
 	Body() []byte
+	// Header returns the first value of a header - see Headers for multi-valued ones.
 	Header(string) string
+	// Headers returns every value of a header (matched case-insensitively), e.g. of each Set-Cookie header,
+	// in the order they were added. Returns nil if the header isn't set.
+	Headers(key string) []string
+	// Cookies returns the cookies set on the response, parsed from every Set-Cookie header.
+	Cookies() []*Cookie
 	SetHeader(key string, value string)
+	// SetHeaders sets several headers at once, each replacing any existing value, as SetHeader does.
+	SetHeaders(headers map[string]string)
+	// AddHeader adds a header value, keeping any existing ones (e.g. for multiple Set-Cookie headers).
+	AddHeader(key string, value string)
+	// AddHeaders adds several header values at once, as AddHeader does. Keys may repeat.
+	AddHeaders(headers ...Header)
+	// DelHeader removes all values of a response header (matched case-insensitively).
+	DelHeader(key string)
 	SetBody([]byte)
 	SetStatus(int)
 	Status() int
+	// BytesWritten returns the size of the response body, as sent to the client - flushed, streamed
+	// (e.g. StreamJSON, ServeFile) and SSE bodies included. Streamed bodies are only sent after the handlers,
+	// so are counted in full by PostResponse hooks; before then, it is what has been written so far.
+	BytesWritten() int
 }
 
 // response represents the HTTP response used in the given context.
@@ -24,6 +45,14 @@ type response struct {
 	body    []byte
 	headers []Header
 	status  uint16
+	// stream, once the response is flushed (ctx.Flush), sends body writes straight to the client as chunks
+	stream io.WriteCloser
+	// timings are the metrics for the Server-Timing header (see AddServerTiming)
+	timings []serverTiming
+	// sent counts the body bytes sent to the client other than from body - flushed, streamed or SSE
+	sent int
+	// jsonEncoder is the server's JSONEncoder config, for WriteJSON
+	jsonEncoder JSONEncoderCfg
 }
 
 // Body returns the response body.
@@ -41,6 +70,31 @@ func (res *response) Header(key string) (value string) {
 	return
 }
 
+// Headers returns all the values of a header (case-insensitive), in the order they were added
+func (res *response) Headers(key string) (values []string) {
+	for _, header := range res.headers {
+		if strings.EqualFold(header.Key, key) {
+			values = append(values, header.Value)
+		}
+	}
+	return
+}
+
+// Cookies returns the cookies set on the response, one per Set-Cookie header.
+// Malformed headers are skipped.
+// This is handy for asserting on the cookies of a synthetic Server.Request response.
+func (res *response) Cookies() (cookies []*Cookie) {
+	for _, header := range res.headers {
+		if header.Key != consts.HeaderSetCookie {
+			continue
+		}
+		if c, err := http.ParseSetCookie(header.Value); err == nil {
+			cookies = append(cookies, newCookieFromStd(c))
+		}
+	}
+	return
+}
+
 // SetHeader sets a header
 func (res *response) SetHeader(key string, value string) {
 	for i, header := range res.headers {
@@ -57,6 +111,25 @@ func (res *response) AddHeader(key string, value string) {
 	res.headers = append(res.headers, Header{Key: key, Value: value})
 }
 
+// SetHeaders sets several headers, in key order so the response is the same each time
+func (res *response) SetHeaders(headers map[string]string) {
+	for _, key := range slices.Sorted(maps.Keys(headers)) {
+		res.SetHeader(key, headers[key])
+	}
+}
+
+// AddHeaders adds several headers, in the order given
+func (res *response) AddHeaders(headers ...Header) {
+	res.headers = append(res.headers, headers...)
+}
+
+// DelHeader removes all values of a header (case-insensitive)
+func (res *response) DelHeader(key string) {
+	res.headers = slices.DeleteFunc(res.headers, func(h Header) bool {
+		return strings.EqualFold(h.Key, key)
+	})
+}
+
 // SetBody replaces the response body with the new contents.
 func (res *response) SetBody(body []byte) {
 	res.body = body
@@ -72,14 +145,42 @@ func (res *response) Status() int {
 	return int(res.status)
 }
 
+// BytesWritten returns the size of the response body sent, or to be sent (once buffered), to the client
+func (res *response) BytesWritten() int {
+	return res.sent + len(res.body)
+}
+
+// countingWriter counts the bytes written through it, into n
+type countingWriter struct {
+	w io.Writer
+	n *int
+}
+
+func (cw countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	*cw.n += n
+	return n, err
+}
+
 // Write implements the io.Writer interface.
+// Once the response is flushed, the bytes are sent to the client rather than buffered.
 func (res *response) Write(body []byte) (int, error) {
+	if res.stream != nil {
+		n, err := res.stream.Write(body)
+		res.sent += n
+		return n, err
+	}
 	res.body = append(res.body, body...)
 	return len(body), nil
 }
 
 // WriteString implements the io.StringWriter interface.
 func (res *response) WriteString(body string) (int, error) {
+	if res.stream != nil {
+		n, err := io.WriteString(res.stream, body)
+		res.sent += n
+		return n, err
+	}
 	res.body = append(res.body, body...)
 	return len(body), nil
 }
@@ -88,8 +189,9 @@ func (res *response) WriteString(body string) (int, error) {
 
 // WriteJSON writes the given JSON to the response body
 // also setting the content type to application/json.
+// The encoding is per the server's JSONEncoder config.
 func (res *response) WriteJSON(obj any) (int, error) {
-	byts, err := json.Marshal(obj)
+	byts, err := res.jsonEncoder.marshal(obj)
 	if err != nil {
 		return 0, err
 	}
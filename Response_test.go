@@ -3,7 +3,13 @@ package rweb_test
 import (
 	"bytes"
 	"compress/gzip"
+	"encoding/json"
 	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"syscall"
 	"testing"
 
 	"github.com/rohanthewiz/assert"
@@ -100,6 +106,35 @@ func TestResponseHeaderOverwrite(t *testing.T) {
 	assert.Equal(t, string(response.Body()), "")
 }
 
+func TestResponseSetAndAddHeaders(t *testing.T) {
+	s := rweb.NewServer()
+
+	s.Get("/", func(ctx rweb.Context) error {
+		ctx.Response().SetHeader(consts.HeaderCacheControl, "no-cache")
+		ctx.Response().SetHeaders(map[string]string{
+			consts.HeaderCacheControl: "no-store", // replaces
+			"X-Frame-Options":         "DENY",
+		})
+		ctx.Response().AddHeaders(
+			rweb.Header{Key: consts.HeaderSetCookie, Value: "a=1"},
+			rweb.Header{Key: consts.HeaderSetCookie, Value: "b=2"},
+		)
+		ctx.Response().AddHeader(consts.HeaderSetCookie, "c=3")
+		return nil
+	})
+
+	response := s.Request(consts.MethodGet, "/", nil, nil)
+	assert.Equal(t, response.Header(consts.HeaderCacheControl), "no-store")
+	assert.Equal(t, response.Header("X-Frame-Options"), "DENY")
+
+	// Added headers are all kept
+	cookies := response.Cookies()
+	assert.Equal(t, len(cookies), 3)
+	assert.Equal(t, cookies[0].Name, "a")
+	assert.Equal(t, cookies[1].Name, "b")
+	assert.Equal(t, cookies[2].Name, "c")
+}
+
 func TestWriteHTMLBytes(t *testing.T) {
 	s := rweb.NewServer()
 
@@ -125,3 +160,72 @@ func TestWriteTextBytes(t *testing.T) {
 	assert.Equal(t, response.Header("Content-Type"), consts.MIMETextPlain)
 	assert.Equal(t, string(response.Body()), "Hello, World!")
 }
+
+func TestBytesWritten(t *testing.T) {
+	readyChan := make(chan struct{}, 1)
+	s := rweb.NewServer(rweb.ServerOptions{ReadyChan: readyChan, Address: "localhost:"})
+
+	var mu sync.Mutex
+	written := map[string]int{}
+	s.PostResponse(func(ctx rweb.Context) {
+		mu.Lock()
+		written[ctx.Request().Path()] = ctx.Response().BytesWritten()
+		mu.Unlock()
+	})
+	var inHandler int
+	s.Use(func(ctx rweb.Context) error {
+		err := ctx.Next()
+		if ctx.Request().Path() == "/flushed" {
+			inHandler = ctx.Response().BytesWritten() // what's been written so far
+		}
+		return err
+	})
+
+	s.Get("/buffered", func(ctx rweb.Context) error {
+		return ctx.WriteString("hello")
+	})
+	s.Get("/flushed", func(ctx rweb.Context) error {
+		_ = ctx.WriteString("part 1;")
+		if err := ctx.Flush(); err != nil {
+			return err
+		}
+		_ = ctx.WriteString("part 2;")
+		_ = ctx.Flush()
+		return ctx.WriteString("end")
+	})
+	s.Get("/stream", func(ctx rweb.Context) error {
+		return ctx.StreamJSON(func(enc *json.Encoder) error {
+			for i := range 3 {
+				_ = enc.Encode(i)
+			}
+			return nil
+		})
+	})
+
+	go func() {
+		defer syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+		<-readyChan // wait for server
+		baseURL := "http://localhost:" + s.GetListenPort()
+
+		for _, path := range []string{"/buffered", "/flushed", "/stream"} {
+			resp, err := http.Get(baseURL + path)
+			assert.Nil(t, err)
+			body, _ := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+
+			mu.Lock()
+			assert.Equal(t, written[path], len(body))
+			mu.Unlock()
+		}
+		assert.Equal(t, inHandler, len("part 1;part 2;end"))
+	}()
+
+	_ = s.Run()
+
+	// Served through net/http
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(consts.MethodGet, "/stream", nil))
+	assert.Equal(t, written["/stream"], w.Body.Len())
+	assert.Equal(t, strings.Count(w.Body.String(), "\n"), 3)
+}
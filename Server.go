@@ -3,25 +3,35 @@ package rweb
 import (
 	"bufio"
 	"bytes"
+	gocontext "context"
 	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"log"
+	"mime/multipart"
 	"net"
 	"net/http"
+	"net/netip"
 	"net/url"
 	"os"
 	"os/signal"
+	"path"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
+	"time"
 
 	"github.com/rohanthewiz/element"
 	"github.com/rohanthewiz/rweb/consts"
 	"github.com/rohanthewiz/rweb/core/rtr"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
 )
 
 type ServerOptions struct {
@@ -40,15 +50,142 @@ type ServerOptions struct {
 	// Cookie holds server-wide default settings for cookies
 	Cookie CookieConfig
 	SSECfg SSECfg
+	// ExpectContinueHandler, when set, is consulted for requests carrying "Expect: 100-continue"
+	// before their body is read, so uploads can be rejected (e.g. 401, 413) based on headers alone.
+	ExpectContinueHandler ExpectContinueHandler
+	// UploadProgress, when set, provides a progress callback for multipart request bodies
+	// invoked as the body streams in, e.g. for reporting the progress of large uploads.
+	UploadProgress UploadProgressHandler
+	// ReadTimeout is the maximum duration for reading a request's headers and body.
+	// Zero means no timeout.
+	ReadTimeout time.Duration
+	// WriteTimeout is the maximum duration from the end of reading a request
+	// to the end of writing its response. It does not apply to SSE streams or WebSockets.
+	// Zero means no timeout.
+	WriteTimeout time.Duration
+	// IdleTimeout is the maximum time to wait for the next request on a keep-alive connection.
+	// If zero, ReadTimeout is used.
+	IdleTimeout time.Duration
+	// DefaultHeaders are added to every response, including error and 404 responses
+	// (e.g. security headers or a deployment version).
+	// A header of the same name set by a handler takes precedence over the default.
+	DefaultHeaders []Header
+	WebSocket      WebSocketCfg
+	// CaptureRawRequest keeps the exact bytes of each request as received
+	// (request line, headers and body), available via ctx.Request().Raw(),
+	// e.g. for verifying signatures over the raw HTTP message, or forensic logging.
+	// This costs a second copy of every request in memory, so enable it only when needed.
+	CaptureRawRequest bool
+	// ProxyClient is the HTTP client Proxy and ProxyBalanced use to reach their targets.
+	// Defaults to a shared client with a pooled, keep-alive transport.
+	ProxyClient *http.Client
+	// TrustedProxies are the addresses (IPs or CIDRs, e.g. "10.0.0.0/8") of reverse proxies and load balancers
+	// in front of the server. For connections from them, ctx.ClientIP() takes the client's address
+	// from the X-Forwarded-For or X-Real-IP header. Invalid entries are ignored, with a warning.
+	TrustedProxies []string
+	// MaxMultipartMemory is the most bytes of a multipart form's fields and files kept in memory.
+	// The fields must fit within it, while files beyond it are stored in temporary files.
+	// Defaults to 32MB.
+	MaxMultipartMemory int64
+	// MaxFormFields is the most (non-file) fields a multipart form may have. Defaults to 1000.
+	MaxFormFields int
+	// MaxFormFiles is the most files a multipart form may have. Defaults to 100.
+	// Forms over any of these limits are refused with 413 Request Entity Too Large, before any handlers run.
+	MaxFormFiles int
+	// AutoHead answers HEAD requests for paths with no HEAD handler, but a GET handler, using the GET handler.
+	// As for any HEAD request, the response is sent without its body (but with the Content-Length of the body).
+	AutoHead bool
+	// MaxConnections, when > 0, limits the number of connections served at once (WebSockets included),
+	// so a flood of connections can't exhaust the server's memory.
+	// Connections beyond the limit are answered with 503 Service Unavailable and closed.
+	MaxConnections int
+	// Session configures the sessions of ctx.Session() - the store, and the session cookie's name and lifetime
+	Session SessionCfg
+	// MultipartStreamThreshold, when > 0, is the size in bytes over which multipart bodies aren't buffered,
+	// but left on the connection for handlers to read part by part with ctx.Request().NextPart().
+	// Chunked multipart bodies, of unknown size, are always streamed then. Streamed bodies aren't captured by CaptureRawRequest.
+	MultipartStreamThreshold int64
+	// JSONErrorFormat, when set, builds the bodies of ctx.JSONError responses, for a custom error shape
+	JSONErrorFormat JSONErrorFormatter
+	// DecompressRequests transparently decompresses request bodies sent with Content-Encoding gzip or deflate,
+	// before any handlers run. Bodies that decompress to over MaxDecompressedSize are refused with 413
+	// Request Entity Too Large (so a small "zip bomb" can't exhaust memory), and undecodable ones with 400 Bad Request.
+	DecompressRequests bool
+	// MaxDecompressedSize is the most bytes a request body may decompress to. Defaults to 32MB.
+	MaxDecompressedSize int64
+	// Metrics, when set, is given the method, route, status and duration of every request, after its response
+	Metrics Metrics
+	// MaxHeaderBytes is the most bytes of a request's head - its request line and headers. Defaults to 1MB.
+	MaxHeaderBytes int
+	// MaxHeaderCount is the most headers a request may have. Defaults to 1000.
+	// Requests over either limit are refused with 431 Request Header Fields Too Large, and the connection closed.
+	MaxHeaderCount int
+	// JSONEncoder configures the encoding of JSON responses - HTML escaping and indentation
+	JSONEncoder JSONEncoderCfg
 }
 
+// ExpectContinueHandler decides, from the request method, path and headers alone,
+// whether the server wants the body of an "Expect: 100-continue" request.
+// Return consts.StatusContinue (or 0) to accept and have the client send the body.
+// Any other status rejects the request with that status, and the body is never read.
+type ExpectContinueHandler func(method string, path string, headers []Header) (status int)
+
 type SSECfg struct {
 	SendConnectedEvent bool // Whether to send "Connected" event to clients
+	// RetryMillis, when > 0, is sent as a "retry:" directive at the start of each stream,
+	// telling clients how long to wait before reconnecting
+	RetryMillis int
+	// KeepAliveInterval, when > 0, sends an SSE comment on streams that have been idle
+	// for the interval, so proxies and load balancers don't close them.
+	// Clients' EventSource ignores comments, so no events are seen.
+	KeepAliveInterval time.Duration
+	// MaxEventSize, when > 0, is the most bytes of data an event may carry.
+	// Larger events are handled per OversizePolicy, so a pathological event can't destabilize the stream.
+	MaxEventSize int
+	// OversizePolicy says what to do with events over MaxEventSize. Defaults to truncating them.
+	OversizePolicy SSEOversizePolicy
+}
+
+// SSEOversizePolicy is how SSE events larger than SSECfg.MaxEventSize are handled
+type SSEOversizePolicy int
+
+const (
+	// SSEOversizeTruncate cuts the event's data down to MaxEventSize
+	SSEOversizeTruncate SSEOversizePolicy = iota
+	// SSEOversizeDrop skips the event, logging a warning
+	SSEOversizeDrop
+	// SSEOversizeSplit sends the data as several consecutive events of the same type, each within MaxEventSize
+	SSEOversizeSplit
+)
+
+type WebSocketCfg struct {
+	// MaxConnections, when > 0, limits the number of concurrent WebSocket connections.
+	// Upgrades beyond the limit are refused with a 503 and a Retry-After header.
+	MaxConnections int
+	// RetryAfter is the back-off suggested to refused clients via Retry-After,
+	// rounded up to whole seconds. Defaults to 5 seconds.
+	RetryAfter time.Duration
+	// CheckOrigin, when set, decides whether to allow an upgrade from the request's origin
+	// (unless the upgrade's own WSUpgradeOptions.CheckOrigin does). Refused upgrades get a 403.
+	CheckOrigin func(ctx Context) bool
+	// SameOriginOnly, without a CheckOrigin, allows upgrades only from pages of the server's own origin (see SameOrigin),
+	// so another site can't open a WebSocket with a user's cookies (cross-site WebSocket hijacking).
+	SameOriginOnly bool
 }
 
 type URLOptions struct {
 	// KeepTrailingSlashes is used to determine if trailing slashes should be kept in the URL path
 	KeepTrailingSlashes bool
+	// StrictTrailingSlashes treats /users and /users/ as distinct routes (404 if only one is registered).
+	// It implies KeepTrailingSlashes.
+	StrictTrailingSlashes bool
+	// CleanPath normalizes request paths before routing, as the CleanPath middleware does:
+	// repeated slashes are collapsed and "." and ".." segments resolved (never above the root),
+	// so /api//users and /api/./users route as /api/users.
+	CleanPath bool
+	// RedirectCleanPath, with CleanPath, redirects requests to the canonical path
+	// (301 for GET and HEAD, 308 otherwise), rather than serving them under it.
+	RedirectCleanPath bool
 }
 
 // ServerOption is a functional option for configuring a Server.
@@ -112,6 +249,23 @@ func WithKeepTrailingSlashes() ServerOption {
 	}
 }
 
+// WithStrictTrailingSlashes makes routes with and without a trailing slash distinct,
+// so /users and /users/ each need to be registered. Trailing slashes are kept in URL paths.
+func WithStrictTrailingSlashes() ServerOption {
+	return func(opts *ServerOptions) {
+		opts.URLOptions.StrictTrailingSlashes = true
+	}
+}
+
+// WithCleanPath normalizes request paths before routing (see URLOptions.CleanPath).
+// With redirect, clients are sent to the canonical path instead.
+func WithCleanPath(redirect bool) ServerOption {
+	return func(opts *ServerOptions) {
+		opts.URLOptions.CleanPath = true
+		opts.URLOptions.RedirectCleanPath = redirect
+	}
+}
+
 // WithReadyChan sets a channel that will receive a signal when the server is ready to accept connections.
 // The channel should be buffered with capacity of at least 1 to avoid blocking.
 // Example: readyCh := make(chan struct{}, 1); WithReadyChan(readyCh)
@@ -143,6 +297,190 @@ func WithSSESendConnectedEvent() ServerOption {
 	}
 }
 
+// WithExpectContinueHandler sets the hook deciding whether to accept "Expect: 100-continue" request bodies.
+// Example:
+//
+//	WithExpectContinueHandler(func(method, path string, headers []rweb.Header) int {
+//	    if path == "/upload" && !validToken(headers) {
+//	        return consts.StatusUnauthorized
+//	    }
+//	    return consts.StatusContinue
+//	})
+func WithExpectContinueHandler(handler ExpectContinueHandler) ServerOption {
+	return func(opts *ServerOptions) {
+		opts.ExpectContinueHandler = handler
+	}
+}
+
+// WithUploadProgress sets the provider of progress callbacks for multipart uploads.
+// Example:
+//
+//	WithUploadProgress(func(method, path string, headers []rweb.Header) rweb.UploadProgressFunc {
+//	    return func(bytesRead, totalBytes int64) {
+//	        fmt.Printf("%s: %d of %d bytes\n", path, bytesRead, totalBytes)
+//	    }
+//	})
+func WithUploadProgress(handler UploadProgressHandler) ServerOption {
+	return func(opts *ServerOptions) {
+		opts.UploadProgress = handler
+	}
+}
+
+// WithReadTimeout sets the maximum duration for reading a request's headers and body.
+// Example: WithReadTimeout(10 * time.Second)
+func WithReadTimeout(timeout time.Duration) ServerOption {
+	return func(opts *ServerOptions) {
+		opts.ReadTimeout = timeout
+	}
+}
+
+// WithWriteTimeout sets the maximum duration for handling a request and writing its response.
+// Example: WithWriteTimeout(30 * time.Second)
+func WithWriteTimeout(timeout time.Duration) ServerOption {
+	return func(opts *ServerOptions) {
+		opts.WriteTimeout = timeout
+	}
+}
+
+// WithIdleTimeout sets how long a keep-alive connection may wait for its next request.
+// Example: WithIdleTimeout(2 * time.Minute)
+func WithIdleTimeout(timeout time.Duration) ServerOption {
+	return func(opts *ServerOptions) {
+		opts.IdleTimeout = timeout
+	}
+}
+
+// WithDefaultHeaders sets headers to add to every response, unless set by the handler.
+// Example: WithDefaultHeaders(rweb.Header{Key: "X-Content-Type-Options", Value: "nosniff"})
+func WithDefaultHeaders(headers ...Header) ServerOption {
+	return func(opts *ServerOptions) {
+		opts.DefaultHeaders = headers
+	}
+}
+
+// WithRawRequestCapture keeps the raw bytes of each request, for ctx.Request().Raw().
+// Requests are buffered twice, so only enable this when the raw bytes are needed.
+func WithRawRequestCapture() ServerOption {
+	return func(opts *ServerOptions) {
+		opts.CaptureRawRequest = true
+	}
+}
+
+// WithProxyClient sets the HTTP client used by Proxy and ProxyBalanced,
+// e.g. to tune timeouts, pool sizes or TLS settings for the upstream connections.
+// Example: WithProxyClient(&http.Client{Timeout: 30 * time.Second})
+func WithProxyClient(client *http.Client) ServerOption {
+	return func(opts *ServerOptions) {
+		opts.ProxyClient = client
+	}
+}
+
+// WithTrustedProxies sets the reverse proxies whose X-Forwarded-For and X-Real-IP headers
+// ctx.ClientIP() relies on.
+// Example: WithTrustedProxies("10.0.0.0/8", "192.168.1.10")
+func WithTrustedProxies(proxies ...string) ServerOption {
+	return func(opts *ServerOptions) {
+		opts.TrustedProxies = proxies
+	}
+}
+
+// WithMultipartLimits sets the limits on multipart forms: the bytes kept in memory,
+// and the number of fields and files. Zero leaves a limit at its default.
+func WithMultipartLimits(maxMemory int64, maxFields, maxFiles int) ServerOption {
+	return func(opts *ServerOptions) {
+		opts.MaxMultipartMemory = maxMemory
+		opts.MaxFormFields = maxFields
+		opts.MaxFormFiles = maxFiles
+	}
+}
+
+// WithMultipartStreaming streams multipart bodies over threshold bytes to handlers,
+// to read part by part with ctx.Request().NextPart(), rather than buffering them.
+// Example: WithMultipartStreaming(10 << 20)
+func WithMultipartStreaming(threshold int64) ServerOption {
+	return func(opts *ServerOptions) {
+		opts.MultipartStreamThreshold = threshold
+	}
+}
+
+// WithJSONErrorFormat sets the shape of ctx.JSONError responses.
+// Example:
+//
+//	WithJSONErrorFormat(func(status int, message string, details any) any {
+//	    return map[string]any{"status": status, "title": message, "errors": details}
+//	})
+func WithJSONErrorFormat(format JSONErrorFormatter) ServerOption {
+	return func(opts *ServerOptions) {
+		opts.JSONErrorFormat = format
+	}
+}
+
+// WithRequestDecompression decompresses gzip and deflate request bodies, up to maxSize bytes
+// (zero for the default of 32MB), before any handlers run.
+func WithRequestDecompression(maxSize int64) ServerOption {
+	return func(opts *ServerOptions) {
+		opts.DecompressRequests = true
+		opts.MaxDecompressedSize = maxSize
+	}
+}
+
+// WithMetrics sets the Metrics observing every request, e.g. WithMetrics(rweb.NewMemoryMetrics())
+func WithMetrics(metrics Metrics) ServerOption {
+	return func(opts *ServerOptions) {
+		opts.Metrics = metrics
+	}
+}
+
+// WithHeaderLimits sets the most bytes of a request's head, and the most headers it may have.
+// Zero leaves a limit at its default.
+func WithHeaderLimits(maxBytes, maxCount int) ServerOption {
+	return func(opts *ServerOptions) {
+		opts.MaxHeaderBytes = maxBytes
+		opts.MaxHeaderCount = maxCount
+	}
+}
+
+// WithJSONEncoder configures the encoding of JSON responses.
+// Example:
+//
+//	WithJSONEncoder(rweb.JSONEncoderCfg{DisableHTMLEscape: true})
+func WithJSONEncoder(cfg JSONEncoderCfg) ServerOption {
+	return func(opts *ServerOptions) {
+		opts.JSONEncoder = cfg
+	}
+}
+
+// WithAutoHead answers HEAD requests with the GET handler of the path, when there is no HEAD handler.
+func WithAutoHead() ServerOption {
+	return func(opts *ServerOptions) {
+		opts.AutoHead = true
+	}
+}
+
+// WithSessions configures the sessions of ctx.Session().
+// Example: WithSessions(rweb.SessionCfg{Store: rweb.NewCookieSessionStore(), MaxAge: 7 * 24 * time.Hour})
+func WithSessions(cfg SessionCfg) ServerOption {
+	return func(opts *ServerOptions) {
+		opts.Session = cfg
+	}
+}
+
+// WithMaxConnections limits the number of connections served at once.
+// Connections beyond the limit are refused with a 503.
+func WithMaxConnections(max int) ServerOption {
+	return func(opts *ServerOptions) {
+		opts.MaxConnections = max
+	}
+}
+
+// WithWebSocketConfig sets the WebSocket configuration.
+// Example: WithWebSocketConfig(rweb.WebSocketCfg{MaxConnections: 1000, RetryAfter: 10 * time.Second})
+func WithWebSocketConfig(cfg WebSocketCfg) ServerOption {
+	return func(opts *ServerOptions) {
+		opts.WebSocket = cfg
+	}
+}
+
 // WithOptions creates a ServerOption from a ServerOptions struct.
 // This is provided for backwards compatibility with the old configuration style.
 // Example: WithOptions(ServerOptions{Address: ":8080", Verbose: true})
@@ -158,6 +496,30 @@ func WithOptions(serverOpts ServerOptions) ServerOption {
 		opts.ReadyChan = serverOpts.ReadyChan
 		opts.Cookie = serverOpts.Cookie
 		opts.SSECfg = serverOpts.SSECfg
+		opts.ExpectContinueHandler = serverOpts.ExpectContinueHandler
+		opts.UploadProgress = serverOpts.UploadProgress
+		opts.ReadTimeout = serverOpts.ReadTimeout
+		opts.WriteTimeout = serverOpts.WriteTimeout
+		opts.IdleTimeout = serverOpts.IdleTimeout
+		opts.DefaultHeaders = serverOpts.DefaultHeaders
+		opts.WebSocket = serverOpts.WebSocket
+		opts.CaptureRawRequest = serverOpts.CaptureRawRequest
+		opts.ProxyClient = serverOpts.ProxyClient
+		opts.TrustedProxies = serverOpts.TrustedProxies
+		opts.AutoHead = serverOpts.AutoHead
+		opts.MaxMultipartMemory = serverOpts.MaxMultipartMemory
+		opts.MaxFormFields = serverOpts.MaxFormFields
+		opts.MaxFormFiles = serverOpts.MaxFormFiles
+		opts.MaxConnections = serverOpts.MaxConnections
+		opts.Session = serverOpts.Session
+		opts.MultipartStreamThreshold = serverOpts.MultipartStreamThreshold
+		opts.JSONErrorFormat = serverOpts.JSONErrorFormat
+		opts.DecompressRequests = serverOpts.DecompressRequests
+		opts.MaxDecompressedSize = serverOpts.MaxDecompressedSize
+		opts.Metrics = serverOpts.Metrics
+		opts.MaxHeaderBytes = serverOpts.MaxHeaderBytes
+		opts.MaxHeaderCount = serverOpts.MaxHeaderCount
+		opts.JSONEncoder = serverOpts.JSONEncoder
 	}
 }
 
@@ -166,6 +528,9 @@ func WithOptions(serverOpts ServerOptions) ServerOption {
 type SSEvent struct {
 	Type string // or event name
 	Data interface{}
+	// ID, if set, is sent as the event's "id:" field. Browsers send the last ID received
+	// in the Last-Event-ID header when reconnecting, so missed events can be replayed.
+	ID string
 }
 
 // sseKeepalive is a sentinel type sent by SSEHub's heartbeat goroutine.
@@ -174,21 +539,65 @@ type SSEvent struct {
 type sseKeepalive struct{}
 
 type TLSCfg struct {
-	TLSAddr  string // [Port] to listen on for TLS
+	TLSAddr  string // [Port] to listen on for TLS. Defaults to ":443" when UseTLS is set
 	CertFile string // Path to certificate file
 	KeyFile  string // Path to private key file
 	UseTLS   bool   // Whether to use TLS
+	// CertReloadInterval enables hot reloading of renewed certificates (e.g. from certbot).
+	// When set, the cert and key files are checked for changes at most once per interval,
+	// and new handshakes use the latest certificate. Zero loads the certificate once at startup.
+	CertReloadInterval time.Duration
+	// ClientCAFile is the path to a PEM bundle of CA certificates used to verify client certificates (mutual TLS).
+	// Unless RequireClientCert is set, clients without a certificate are still accepted.
+	ClientCAFile string
+	// RequireClientCert refuses connections that don't present a certificate signed by a CA in ClientCAFile
+	RequireClientCert bool
+	// AutoCert obtains and renews certificates from Let's Encrypt (ACME), instead of using CertFile and KeyFile
+	AutoCert AutoCertCfg
+	// MinVersion is the oldest TLS version accepted, e.g. tls.VersionTLS13 for TLS 1.3 only.
+	// Defaults to TLS 1.2.
+	MinVersion uint16
+	// CipherSuites restricts the cipher suites of TLS 1.2 connections, e.g. to tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384.
+	// Defaults to Go's secure suites. TLS 1.3 suites aren't configurable, and insecure suites are refused.
+	CipherSuites []uint16
+}
+
+type AutoCertCfg struct {
+	Enabled bool // Whether to obtain certificates automatically
+	// Hosts are the host names certificates may be requested for. Others are refused,
+	// so that arbitrary SNI names can't exhaust the CA's rate limits.
+	Hosts []string
+	// CacheDir is where certificates and the account key are kept between restarts.
+	// Without it, certificates are requested afresh on every start, soon hitting rate limits.
+	CacheDir string
+	// Email is an optional contact address for the CA to send expiry and problem notices to
+	Email string
 }
 
 // Server is the HTTP Server.
 type Server struct {
 	handlers     []Handler
 	contextPool  sync.Pool
-	radixRouter  *rtr.RadixRouter[Handler]
-	hashRouter   *rtr.HashRouter[Handler]
+	radixRouter  *rtr.RadixRouter[route]
+	hashRouter   *rtr.HashRouter[route]
 	errorHandler func(Context, error)
+	notFound     Handler // renders 404s, if set
+	fallback     Handler // serves requests no route matches, in place of a 404, if set
 	options      ServerOptions
-	listenAddr   string // the actual listen address used by net.Listen
+	listenAddr   string            // the actual listen address used by net.Listen
+	tlsAddr      string            // the actual listen address of the TLS listener
+	namedRoutes  map[string]string // route name -> path pattern, for URL()
+	wsConns      atomic.Int64      // active WebSocket connections
+	streamingMu  sync.Mutex
+	streaming    streamingClients // active SSE streams and WebSockets, for NotifyStreamingClients
+	preRoute     []func(Context)  // hooks run for every request before middleware and routing
+	postResponse []func(Context)  // hooks run for every request after the response is written
+	certMgrOnce  sync.Once
+	certMgr      *autocert.Manager // ACME certificate manager, when AutoCert is enabled
+	trustedNets  []netip.Prefix    // parsed TrustedProxies
+	connSlots    chan struct{}     // a slot per connection being served, when MaxConnections is set
+	activeConns  atomic.Int64      // connections being served
+	sessionStore SessionStore      // the Session.Store, or a MemorySessionStore by default
 }
 
 // NewServer creates a new HTTP server with an optional ServerOptions struct.
@@ -203,8 +612,8 @@ type Server struct {
 //
 //	s := rweb.NewServer()
 func NewServer(options ...ServerOptions) *Server {
-	radRtr := &rtr.RadixRouter[Handler]{}
-	hashRtr := rtr.NewHashRouter[Handler]()
+	radRtr := &rtr.RadixRouter[route]{}
+	hashRtr := rtr.NewHashRouter[route]()
 
 	// Initialize with default options
 	opts := ServerOptions{}
@@ -219,6 +628,12 @@ func NewServer(options ...ServerOptions) *Server {
 		fmt.Println("Ready channel capacity should be at least 1, or we may hang")
 	}
 
+	radRtr.StrictTrailingSlash = opts.URLOptions.StrictTrailingSlashes
+
+	if opts.TLS.UseTLS && opts.TLS.TLSAddr == "" {
+		opts.TLS.TLSAddr = ":443" // rather than an arbitrary port
+	}
+
 	s := &Server{
 		radixRouter: radRtr,
 		hashRouter:  hashRtr,
@@ -235,6 +650,15 @@ func NewServer(options ...ServerOptions) *Server {
 		},
 	}
 
+	s.trustedNets = parseTrustedProxies(opts.TrustedProxies)
+	if opts.MaxConnections > 0 {
+		s.connSlots = make(chan struct{}, opts.MaxConnections)
+	}
+	s.sessionStore = opts.Session.Store
+	if s.sessionStore == nil {
+		s.sessionStore = NewMemorySessionStore()
+	}
+
 	s.handlers = []Handler{
 		func(c Context) error { // default handler
 			ctx := c.(*context)
@@ -245,19 +669,36 @@ func NewServer(options ...ServerOptions) *Server {
 			}
 
 			// Try exact match first
-			hdlr = s.hashRouter.Lookup(ctx.request.method, ctx.request.path)
+			hdlr = s.hashRouter.Lookup(ctx.request.method, ctx.request.path).handler
 			if hdlr == nil {
 				if s.options.Debug {
 					fmt.Println("Route not found in hash router (it could be a dynamic route)  -- trying radix router")
 				}
-				hdlr = radRtr.LookupNoAlloc(ctx.request.method, ctx.request.path, ctx.request.addParameter)
+				hdlr = radRtr.LookupNoAlloc(ctx.request.method, ctx.request.path, ctx.request.addParameter).handler
+			}
+
+			// A HEAD request can be served by the GET handler - the body is dropped when writing the response
+			if hdlr == nil && s.options.AutoHead && ctx.request.method == consts.MethodHead {
+				hdlr = s.lookupGetForHead(ctx)
 			}
 
 			if hdlr == nil {
+				// The path may be registered for other methods
+				if allowed := s.allowedMethods(ctx.request.path); len(allowed) > 0 {
+					return s.methodNotAllowed(ctx, allowed)
+				}
+
+				if s.fallback != nil && (ctx.request.method == consts.MethodGet || ctx.request.method == consts.MethodHead) {
+					return s.fallback(c)
+				}
+
 				if s.options.Debug {
 					fmt.Println("Route not found in radix router either -- returning 404")
 				}
 				ctx.SetStatus(consts.StatusNotFound)
+				if s.notFound != nil {
+					return s.notFound(c)
+				}
 				return nil
 			}
 
@@ -292,13 +733,95 @@ func NewServerWithOptions(options ...ServerOption) *Server {
 }
 
 func (s *Server) AddMethod(method string, path string, handler Handler) {
+	s.addRoute(method, path, handler, false)
+}
+
+// route is what the routers hold for a registered route
+type route struct {
+	handler       Handler
+	hasMiddleware bool // the handler runs the route's own middleware (e.g. a group's) first
+}
+
+// String identifies the route's handler, for ListRoutes
+func (rt route) String() string {
+	return fmt.Sprintf("%v", rt.handler)
+}
+
+// addRoute registers handler for method and path.
+// hasMiddleware is set when the handler is chained behind middleware of the route's own (see chainHandlers).
+func (s *Server) addRoute(method string, path string, handler Handler, hasMiddleware bool) {
+	rt := route{handler: s.routeObserved(path, handler), hasMiddleware: hasMiddleware}
 	if strings.IndexByte(path, consts.RuneColon) < 0 && strings.IndexByte(path, consts.RuneAsterisk) < 0 {
-		s.hashRouter.Add(method, path, handler)
+		s.hashRouter.Add(method, path, rt)
 	} else {
-		s.radixRouter.Add(method, path, handler)
+		s.radixRouter.Add(method, path, rt)
 	}
 }
 
+// lookupGetForHead returns the GET handler for the path of a HEAD request, for AutoHead
+func (s *Server) lookupGetForHead(ctx *context) Handler {
+	if rt := s.hashRouter.Lookup(consts.MethodGet, ctx.request.path); rt.handler != nil {
+		return rt.handler
+	}
+	ctx.request.params = ctx.request.params[:0] // drop anything from the HEAD lookup
+	return s.radixRouter.LookupNoAlloc(consts.MethodGet, ctx.request.path, ctx.request.addParameter).handler
+}
+
+// routeHasMiddleware reports whether the route the request is for has middleware of its own
+// (e.g. a group's), to run after the server's middleware
+func (s *Server) routeHasMiddleware(ctx *context) bool {
+	find := func(method string) route {
+		if rt := s.hashRouter.Lookup(method, ctx.request.path); rt.handler != nil {
+			return rt
+		}
+		return s.radixRouter.LookupNoAlloc(method, ctx.request.path, func(string, string) {})
+	}
+
+	rt := find(ctx.request.method)
+	if rt.handler == nil && s.options.AutoHead && ctx.request.method == consts.MethodHead {
+		rt = find(consts.MethodGet)
+	}
+	return rt.hasMiddleware
+}
+
+// allowedMethods returns the methods for which a handler is registered for the given path,
+// whether as a static (hash router) or parameterized (radix router) route.
+// The methods are in the routers' order (GET, HEAD, POST, PUT...), as for an Allow header.
+func (s *Server) allowedMethods(path string) []string {
+	methods := s.hashRouter.AllowedMethods(path)
+	for _, method := range s.radixRouter.AllowedMethods(path) {
+		if !slices.Contains(methods, method) {
+			methods = append(methods, method)
+		}
+	}
+	if s.options.AutoHead && slices.Contains(methods, consts.MethodGet) && !slices.Contains(methods, consts.MethodHead) {
+		methods = append(methods, consts.MethodHead)
+	}
+	rtr.SortMethods(methods)
+	return methods
+}
+
+// methodNotAllowed responds to a request whose path is registered, but not for the request method.
+// An OPTIONS request (with no user-defined OPTIONS handler) is answered automatically
+// with the supported methods, otherwise we respond 405 Method Not Allowed.
+// In both cases the Allow header lists the methods valid for the path.
+func (s *Server) methodNotAllowed(ctx *context, allowed []string) error {
+	if ctx.request.method == consts.MethodOptions {
+		allowed = append(allowed, consts.MethodOptions)
+		rtr.SortMethods(allowed)
+		ctx.response.SetHeader(consts.HeaderAllow, strings.Join(allowed, ", "))
+		ctx.SetStatus(consts.StatusNoContent)
+		return nil
+	}
+
+	if s.options.Debug {
+		fmt.Printf("Method %q not allowed for path %q -- returning 405\n", ctx.request.method, ctx.request.path)
+	}
+	ctx.response.SetHeader(consts.HeaderAllow, strings.Join(allowed, ", "))
+	ctx.SetStatus(consts.StatusMethodNotAllowed)
+	return nil
+}
+
 // Get registers your function to be called when the given GET path has been requested.
 func (s *Server) Get(path string, handler Handler) {
 	s.AddMethod(consts.MethodGet, path, handler)
@@ -374,11 +897,21 @@ type WebSocketHandler func(*WSConn) error
 
 // WebSocket registers a WebSocket handler for the given path
 // The handler function receives a WebSocket connection after successful upgrade
+// Optional WSUpgradeOptions configure the upgrade, e.g. the subprotocols supported
 // Usage: s.WebSocket("/ws", func(ws *WSConn) error { ... })
-func (s *Server) WebSocket(path string, handler WebSocketHandler) {
+//
+//	s.WebSocket("/graphql", handler, rweb.WSUpgradeOptions{Subprotocols: []string{"graphql-transport-ws", "graphql-ws"}})
+func (s *Server) WebSocket(path string, handler WebSocketHandler, opts ...WSUpgradeOptions) {
 	s.Get(path, func(ctx Context) error {
 		// Upgrade the connection to WebSocket
-		ws, err := ctx.UpgradeWebSocket()
+		ws, err := ctx.UpgradeWebSocket(opts...)
+		if errors.Is(err, ErrWebSocketLimitReached) {
+			// Not a failure of ours - the 503 and Retry-After are already set, so the client backs off
+			return ctx.WriteText("Too many WebSocket connections, please retry later")
+		}
+		if errors.Is(err, ErrWebSocketOriginRefused) {
+			return ctx.WriteText("WebSocket origin not allowed") // the 403 is already set
+		}
 		if err != nil {
 			fmt.Printf("Failed to upgrade connection to WebSocket: %v\n", err)
 			// If upgrade fails, return error (will send appropriate HTTP error response)
@@ -391,6 +924,30 @@ func (s *Server) WebSocket(path string, handler WebSocketHandler) {
 	})
 }
 
+// acquireWebSocket claims a slot for a new WebSocket connection,
+// returning false if WebSocket.MaxConnections has been reached.
+func (s *Server) acquireWebSocket() bool {
+	maxConns := int64(s.options.WebSocket.MaxConnections)
+	for {
+		active := s.wsConns.Load()
+		if maxConns > 0 && active >= maxConns {
+			return false
+		}
+		if s.wsConns.CompareAndSwap(active, active+1) {
+			return true
+		}
+	}
+}
+
+// webSocketRetryAfter returns the Retry-After value, in seconds, for refused WebSocket upgrades
+func (s *Server) webSocketRetryAfter() string {
+	retryAfter := s.options.WebSocket.RetryAfter
+	if retryAfter <= 0 {
+		retryAfter = defaultWSRetryAfter
+	}
+	return strconv.Itoa(int((retryAfter + time.Second - 1) / time.Second))
+}
+
 // Proxy sets up a reverse proxy for the provided path prefix to the specified target URL (targetURL can include a path)
 // The pathPrefix can help us to distinguish between different proxy targets, from which we can strip any unneeded tokens (from the left)  in the handler
 // If there is any prefix left after stripping, it is added to the leftmost of the target URL.
@@ -401,89 +958,177 @@ func (s *Server) Proxy(pathPrefix string, targetURL string, prefixTokensToRemove
 		return err
 	}
 
-	urlWithoutPath := tURL.Scheme + "://" + tURL.Host
-	// We will not map to the level of the query string // qry := tURL.RawQuery
+	pathPrefix, strippedPrefix := proxyPrefixes(pathPrefix, prefixTokensToRemove)
+
+	hdlr := func(ctx Context) (err error) {
+		proxyURL := proxyTargetURL(ctx.Request(), tURL, pathPrefix, strippedPrefix)
 
+		if s.options.Verbose {
+			fmt.Printf("PROXY %q -> %q\n", ctx.Request().Path(), proxyURL)
+		}
+
+		resp, err := s.forwardProxyRequest(ctx, proxyURL)
+		if err != nil {
+			return err
+		}
+		return writeProxyResponse(ctx, resp)
+	}
+
+	s.setMethodProxyHandler(filepath.Join("/", pathPrefix, "*path"), hdlr)
+	// The wildcard route does not handle the root of the prefix, so have to handle that separately
+	s.setMethodProxyHandler(filepath.Join("/", pathPrefix), hdlr)
+	return nil
+}
+
+// proxyPrefixes normalizes a proxy's path prefix, and returns it along with
+// the part of it that is kept in the target path, after removing prefixTokensToRemove tokens.
+func proxyPrefixes(pathPrefix string, prefixTokensToRemove int) (prefix string, strippedPrefix string) {
 	// Normalize path prefix by removing any leading slashes
 	if strings.HasPrefix(pathPrefix, "/") {
 		pathPrefix = pathPrefix[1:]
 	}
 
 	// Strip off the left (most significant tokens as those can act as a switch between targets) -- keep the right side tokens here
-	strippedPrefix := pathPrefix
+	strippedPrefix = pathPrefix
 	if prefixTokensToRemove > 0 {
 		tokens := strings.Split(pathPrefix, "/")
 		if len(tokens) >= prefixTokensToRemove {
 			strippedPrefix = strings.Join(tokens[prefixTokensToRemove:], "/")
 		}
 	}
+	return pathPrefix, strippedPrefix
+}
 
-	hdlr := func(ctx Context) (err error) {
-		ctxReq := ctx.Request()
+// proxyTargetURL builds the URL on the target that the request is proxied to
+func proxyTargetURL(ctxReq ItfRequest, tURL *url.URL, pathPrefix, strippedPrefix string) string {
+	urlWithoutPath := tURL.Scheme + "://" + tURL.Host
+	// We will not map to the level of the query string // qry := tURL.RawQuery
 
-		// Get the request path minus the prefix, then add back the prefix and the targetPath, minus any dropped tokens
-		pathWoPrefix := ctxReq.Path()
-		if idx := strings.Index(ctxReq.Path(), pathPrefix); idx >= 0 {
-			pathWoPrefix = pathWoPrefix[idx+len(pathPrefix):]
-		}
+	// Get the request path minus the prefix, then add back the prefix and the targetPath, minus any dropped tokens
+	pathWoPrefix := ctxReq.Path()
+	if idx := strings.Index(ctxReq.Path(), pathPrefix); idx >= 0 {
+		pathWoPrefix = pathWoPrefix[idx+len(pathPrefix):]
+	}
 
-		proxyURL := urlWithoutPath + filepath.Join("/", strippedPrefix, tURL.Path, pathWoPrefix)
+	proxyURL := urlWithoutPath + filepath.Join("/", strippedPrefix, tURL.Path, pathWoPrefix)
 
-		if qry := ctxReq.Query(); qry != "" {
-			proxyURL = proxyURL + "?" + qry
-		}
+	if qry := ctxReq.Query(); qry != "" {
+		proxyURL = proxyURL + "?" + qry
+	}
+	return proxyURL
+}
 
-		if s.options.Verbose {
-			fmt.Printf("PROXY %q -> %q\n", ctxReq.Path(), proxyURL)
-		}
+// forwardProxyRequest sends a copy of the request to proxyURL
+func (s *Server) forwardProxyRequest(ctx Context, proxyURL string) (resp *http.Response, err error) {
+	ctxReq := ctx.Request()
+	var req *http.Request
+
+	// The upstream request is abandoned if our client goes away (or the request times out) before the response arrives.
+	// The response body is streamed after the handler returns though, past the end of its context
+	// (e.g. that of the Timeout middleware), so the body has a context of its own, ending with the body.
+	upstreamCtx, cancel := gocontext.WithCancel(gocontext.WithoutCancel(ctx.Context()))
+	stop := gocontext.AfterFunc(ctx.Context(), cancel)
+	defer stop()
+
+	if ctxReq.Body() != nil {
+		buf := bytes.NewBuffer(ctxReq.Body())
+		req, err = http.NewRequestWithContext(upstreamCtx, ctx.Request().Method(), proxyURL, buf)
+	} else {
+		req, err = http.NewRequestWithContext(upstreamCtx, ctx.Request().Method(), proxyURL, nil)
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
 
-		var req *http.Request
+	// Take the original headers too
+	for _, hdr := range ctxReq.Headers() {
+		req.Header.Set(hdr.Key, hdr.Value)
+	}
 
-		if ctxReq.Body() != nil {
-			buf := bytes.NewBuffer(ctxReq.Body())
-			req, err = http.NewRequest(ctx.Request().Method(), proxyURL, buf)
-		} else {
-			req, err = http.NewRequest(ctx.Request().Method(), proxyURL, nil)
-		}
-		if err != nil {
-			return err
-		}
+	resp, err = s.proxyClient().Do(req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelingBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
 
-		// Take the original headers too
-		for _, hdr := range ctxReq.Headers() {
-			req.Header.Set(hdr.Key, hdr.Value)
-		}
+// cancelingBody is a proxied response body that ends its upstream request's context when closed
+type cancelingBody struct {
+	io.ReadCloser
+	cancel gocontext.CancelFunc
+}
 
-		resp, err := http.DefaultClient.Do(req)
-		if err != nil {
-			return err
-		}
+func (b *cancelingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
 
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return err
+// defaultProxyClient is shared by all proxies not given their own client,
+// so upstream connections are kept alive and reused across requests
+var defaultProxyClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          256,
+		MaxIdleConnsPerHost:   64,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	},
+}
+
+// proxyClient returns the HTTP client used to reach proxy targets
+func (s *Server) proxyClient() *http.Client {
+	if s.options.ProxyClient != nil {
+		return s.options.ProxyClient
+	}
+	return defaultProxyClient
+}
+
+// writeProxyResponse copies a proxied response to our response.
+// The body is streamed through to the client as it arrives, rather than buffered.
+func writeProxyResponse(ctx Context, resp *http.Response) (err error) {
+	ctx.Response().SetStatus(resp.StatusCode)
+
+	for hdr, vals := range resp.Header {
+		// We set the body's framing ourselves - don't set it twice
+		if strings.EqualFold(consts.HeaderContentLength, hdr) || strings.EqualFold(consts.HeaderTransferEncoding, hdr) {
+			continue
 		}
-		_ = resp.Body.Close()
+		ctx.Response().SetHeader(hdr, strings.Join(vals, ","))
+	}
 
-		err = ctx.Bytes(body)
+	base := baseContext(ctx)
+	if base == nil || ctx.Request().Method() == consts.MethodHead || resp.ContentLength == 0 ||
+		resp.StatusCode == consts.StatusNoContent || resp.StatusCode == consts.StatusNotModified {
+		// No body to stream (or no way to) - buffer it
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
 		if err != nil {
 			return err
 		}
-
-		ctx.Response().SetStatus(resp.StatusCode)
-
-		for hdr, vals := range resp.Header {
-			if strings.EqualFold(consts.HeaderContentLength, hdr) { // we auto set content-length - don't set it twice
-				continue
-			}
-			ctx.Response().SetHeader(hdr, strings.Join(vals, ","))
-		}
-		return nil
+		return ctx.Bytes(body)
 	}
 
-	s.setMethodProxyHandler(filepath.Join("/", pathPrefix, "*path"), hdlr)
-	// The wildcard route does not handle the root of the prefix, so have to handle that separately
-	s.setMethodProxyHandler(filepath.Join("/", pathPrefix), hdlr)
+	// The body is closed by the response's cleanup too, should it never be sent
+	// (e.g. the response is replaced by an error page, or a Timeout gives up on it)
+	base.streamCleanup = func() { _ = resp.Body.Close() }
+	if resp.ContentLength >= 0 {
+		base.streamLen = resp.ContentLength
+	}
+	base.streamFn = func(w io.Writer) error {
+		defer resp.Body.Close()
+		_, err := io.Copy(w, resp.Body)
+		return err
+	}
 	return nil
 }
 
@@ -505,64 +1150,184 @@ func (s *Server) setMethodProxyHandler(proxyPath string, hdlr func(ctx Context)
 
 // StaticFiles maps a route to serve static files from a specified directory after optionally stripping route tokens.
 // If tokens are stripped, the leftmost tokens are removed from the request path before building the file path.
+// Pre-compressed "<file>.gz" versions of files are served to clients accepting gzip.
+// Requests for directories are handled per the optional StaticOptions (index.html, listings).
 // Examples:
 //  1. s.StaticFiles("static/images/", "/assets/images", 2)
 //  2. s.StaticFiles("/css/", "assets/css", 1)
 //  3. s.StaticFiles("/.well-known/", "/", 0)
-func (s *Server) StaticFiles(reqDir string, targetDir string, nbrOfTokensToStrip int) {
-	if len(reqDir) < 2 {
-		fmt.Println("StaticFiles request dir is too short -- not handling")
+//  4. s.StaticFiles("/docs/", "public/docs", 1, rweb.StaticOptions{DirListing: true})
+func (s *Server) StaticFiles(reqDir string, targetDir string, nbrOfTokensToStrip int, opts ...StaticOptions) {
+	route, rhTokens, ok := s.staticRoute(reqDir, nbrOfTokensToStrip)
+	if !ok {
 		return
 	}
 
-	// Build wildcard route
-	route := filepath.Join("/", reqDir, "*path")
-	if s.options.Debug {
-		fmt.Println("**-> static route:", route)
-	}
-
-	// Remove any leading "/" so we can properly split below
-	if reqDir[0] == '/' {
-		reqDir = reqDir[1:]
+	var opt StaticOptions
+	if len(opts) > 0 {
+		opt = opts[0]
 	}
 
 	// We use the wildcard parameter in the route here
 	s.Get(route, func(ctx Context) error {
-		var rhTokens []string
-		// Strip off the left -- keep the right side tokens here
-		// It is okay if we strip all
+		// Build the actual filepath now
+		fileSpec, ok := staticFilePath(targetDir, rhTokens, ctx.Request().Param("path"))
+		if !ok || opt.Authorize != nil && !opt.Authorize(ctx, strings.TrimPrefix(fileSpec, "/")) {
+			return staticForbidden(ctx)
+		}
 		if s.options.Debug {
-			fmt.Printf("**-> reqPath: %q\n", reqDir)
+			fmt.Println("**-> fileFullPath", fileSpec)
+		}
+
+		if opt.DirListing || opt.NoDirListing {
+			if info, err := os.Stat("." + fileSpec); err == nil && info.IsDir() {
+				return serveStaticDir(ctx, "."+fileSpec, opt)
+			}
+		}
+
+		body, err := readStaticFile(ctx, "."+fileSpec, os.ReadFile)
+		if err != nil {
+			return err
 		}
 
-		tokens := strings.Split(reqDir, "/")
+		return File(ctx, filepath.Base(fileSpec), body)
+	})
+}
+
+// StaticFilesAuth serves static files like StaticFiles, but only to requests that authorize allows,
+// e.g. for documents private to their owners. Others get a 403 Forbidden, without the file being read.
+// Example:
+//
+//	s.StaticFilesAuth("/docs/", "private/docs", 1, func(ctx rweb.Context, filePath string) bool {
+//		user, ok := ctx.Get("user").(string)
+//		return ok && strings.HasPrefix(filePath, "private/docs/"+user+"/")
+//	})
+func (s *Server) StaticFilesAuth(reqDir string, targetDir string, nbrOfTokensToStrip int,
+	authorize func(ctx Context, filePath string) bool, opts ...StaticOptions) {
+	var opt StaticOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	opt.Authorize = authorize
+	s.StaticFiles(reqDir, targetDir, nbrOfTokensToStrip, opt)
+}
+
+// StaticFS is like StaticFiles but serves files from the provided fs.FS (e.g. an embed.FS)
+// instead of the OS filesystem. This allows single-binary deployments with bundled assets.
+// Token stripping and .gz sidecars behave as in StaticFiles; the remaining path is resolved relative to the root of fsys.
+// Use fs.Sub to serve a subdirectory of the filesystem.
+// Example:
+//
+//	//go:embed assets
+//	var assets embed.FS
+//	s.StaticFS("/assets", assets, 0) // GET /assets/css/my.css -> assets/css/my.css
+func (s *Server) StaticFS(reqDir string, fsys fs.FS, nbrOfTokensToStrip int) {
+	route, rhTokens, ok := s.staticRoute(reqDir, nbrOfTokensToStrip)
+	if !ok {
+		return
+	}
+
+	s.Get(route, func(ctx Context) error {
+		// fs.FS paths are slash-separated and unrooted
+		fileSpec, ok := staticFilePath("", rhTokens, ctx.Request().Param("path"))
+		if !ok {
+			return staticForbidden(ctx)
+		}
+		fileSpec = strings.TrimPrefix(filepath.ToSlash(fileSpec), "/")
+		if fileSpec == "" {
+			fileSpec = "."
+		}
 		if s.options.Debug {
-			fmt.Printf("**-> tokens: %q", tokens)
+			fmt.Println("**-> fs fileFullPath", fileSpec)
 		}
 
-		// Remove unwanted tokens from the request path
-		if len(tokens) >= nbrOfTokensToStrip {
-			rhTokens = tokens[nbrOfTokensToStrip:]
-		}
-		if s.options.Debug {
-			fmt.Printf("**-> rhTokens: %q\n", rhTokens)
-		}
+		body, err := readStaticFile(ctx, fileSpec, func(name string) ([]byte, error) {
+			return fs.ReadFile(fsys, name)
+		})
+		if err != nil {
+			return err
+		}
+
+		return File(ctx, path.Base(fileSpec), body)
+	})
+}
+
+// staticFilePath resolves the path of the file for a static files request, rooted at "/":
+// targetDir, then the request dir tokens kept, then the (percent-decoded) wildcard path of the request.
+// ok is false if the request path would lead outside the directory served, e.g. with "../" or "..%2F" segments.
+func staticFilePath(targetDir string, rhTokens []string, reqPath string) (fileSpec string, ok bool) {
+	if decoded, err := url.PathUnescape(reqPath); err == nil {
+		reqPath = decoded // so encoded separators and dots can't slip past the check below
+	}
+	if strings.IndexByte(reqPath, 0) >= 0 {
+		return "", false
+	}
+
+	root := filepath.Join("/", targetDir, strings.Join(rhTokens, "/"))
+	fileSpec = filepath.Join(root, reqPath)
+	if fileSpec != root && !strings.HasPrefix(fileSpec, strings.TrimSuffix(root, "/")+"/") {
+		return "", false
+	}
+	return fileSpec, true
+}
+
+// staticForbidden refuses a static files request for a path outside the served directory
+func staticForbidden(ctx Context) error {
+	ctx.SetStatus(consts.StatusForbidden)
+	return ctx.WriteText(consts.StatusTextFromCode[consts.StatusForbidden])
+}
+
+// readStaticFile reads the named file for a static files request.
+// When the client accepts gzip and a pre-compressed "<name>.gz" exists alongside the file,
+// that is read instead, and the response marked with Content-Encoding: gzip.
+// The content type is still that of the original file.
+func readStaticFile(ctx Context, name string, readFile func(string) ([]byte, error)) ([]byte, error) {
+	if acceptsGzip(ctx.Request().Header(consts.HeaderAcceptEncoding)) {
+		if body, err := readFile(name + ".gz"); err == nil {
+			ctx.Response().SetHeader(consts.HeaderContentEncoding, "gzip")
+			addVary(ctx.Response(), consts.HeaderAcceptEncoding)
+			return body, nil
+		}
+	}
+	return readFile(name)
+}
+
+// staticRoute builds the wildcard route for a static files request dir,
+// and the request path tokens remaining after stripping the leftmost nbrOfTokensToStrip tokens.
+// ok is false if the request dir is unusable.
+func (s *Server) staticRoute(reqDir string, nbrOfTokensToStrip int) (route string, rhTokens []string, ok bool) {
+	if len(reqDir) < 2 {
+		fmt.Println("StaticFiles request dir is too short -- not handling")
+		return
+	}
+
+	// Build wildcard route
+	route = filepath.Join("/", reqDir, "*path")
+	if s.options.Debug {
+		fmt.Println("**-> static route:", route)
+	}
+
+	// Remove any leading "/" so we can properly split below
+	if reqDir[0] == '/' {
+		reqDir = reqDir[1:]
+	}
 
-		// Build the actual filepath now
-		wildcardPath := ctx.Request().Param("path")
-		fileSpec := filepath.Join("/", targetDir,
-			strings.Join(rhTokens, "/"), wildcardPath)
-		if s.options.Debug {
-			fmt.Println("**-> fileFullPath", fileSpec)
-		}
+	// Strip off the left -- keep the right side tokens here
+	// It is okay if we strip all
+	tokens := strings.Split(reqDir, "/")
+	if s.options.Debug {
+		fmt.Printf("**-> tokens: %q", tokens)
+	}
 
-		body, err := os.ReadFile("." + fileSpec)
-		if err != nil {
-			return err
-		}
+	// Remove unwanted tokens from the request path
+	if len(tokens) >= nbrOfTokensToStrip {
+		rhTokens = tokens[nbrOfTokensToStrip:]
+	}
+	if s.options.Debug {
+		fmt.Printf("**-> rhTokens: %q\n", rhTokens)
+	}
 
-		return File(ctx, filepath.Base(fileSpec), body)
-	})
+	return route, rhTokens, true
 }
 
 // Request performs a synthetic request and returns the response.
@@ -570,12 +1335,24 @@ func (s *Server) StaticFiles(reqDir string, targetDir string, nbrOfTokensToStrip
 // However it is very useful inside tests where you don't want to spin up a real web server.
 func (s *Server) Request(method string, url string, headers []Header, body io.Reader) Response {
 	ctx := s.newContext()
-	ctx.request.headers = headers
-	s.handleRequest(ctx, method, url, io.Discard)
+	ctx.request.headers = append(ctx.request.headers[:0], headers...) // copy, as handlers may modify them
+	s.handleRequest(ctx, method, url, syntheticWriter{})
 	return ctx.Response()
 }
 
+// syntheticWriter is where synthetic requests "send" their response - it discards everything.
+// Streamed bodies are instead collected into the response body, so they can be inspected.
+type syntheticWriter struct{}
+
+func (syntheticWriter) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
 func (s *Server) RunWithHttpsRedirect() error {
+	if err := checkListenAddrs(s.options.Address, s.options.TLS.TLSAddr); err != nil {
+		return err
+	}
+
 	// Start HTTPS server
 	go func() {
 		err := s.Run()
@@ -585,10 +1362,43 @@ func (s *Server) RunWithHttpsRedirect() error {
 	}()
 
 	// Start HTTP redirect server
-	return http.ListenAndServe(s.options.Address, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		httpsURL := "https://" + r.Host + r.RequestURI
 		http.Redirect(w, r, httpsURL, http.StatusMovedPermanently)
-	}))
+	})
+	if s.options.TLS.AutoCert.Enabled {
+		// Answer ACME HTTP-01 challenges, redirecting everything else
+		handler = s.certManager().HTTPHandler(handler)
+	}
+	return http.ListenAndServe(s.options.Address, handler)
+}
+
+// checkListenAddrs returns an error if the HTTP and TLS listen addresses would bind the same port.
+// An empty or zero port (chosen by the system) never clashes.
+func checkListenAddrs(httpAddr, tlsAddr string) error {
+	if httpAddr == "" {
+		httpAddr = ":80" // as used by http.ListenAndServe
+	}
+	httpHost, httpPort, err := net.SplitHostPort(httpAddr)
+	if err != nil {
+		return fmt.Errorf("invalid listen address %q: %w", httpAddr, err)
+	}
+	tlsHost, tlsPort, err := net.SplitHostPort(tlsAddr)
+	if err != nil {
+		return fmt.Errorf("invalid TLS listen address %q: %w", tlsAddr, err)
+	}
+
+	if httpPort == "" || httpPort == "0" || httpPort != tlsPort {
+		return nil
+	}
+	// Listening on all interfaces (no host) clashes with listening on any
+	anyHost := func(host string) bool {
+		return host == "" || host == "0.0.0.0" || host == "::"
+	}
+	if httpHost == tlsHost || anyHost(httpHost) || anyHost(tlsHost) {
+		return fmt.Errorf("the HTTP (%q) and TLS (%q) listen addresses use the same port", httpAddr, tlsAddr)
+	}
+	return nil
 }
 
 // Run starts the server on the given address.
@@ -596,14 +1406,32 @@ func (s *Server) Run() (err error) {
 	var listener net.Listener
 
 	if s.options.TLS.UseTLS {
-		cert, err := tls.LoadX509KeyPair(s.options.TLS.CertFile, s.options.TLS.KeyFile)
-		if err != nil {
-			return fmt.Errorf("failed to load TLS certificate: %v", err)
+		tlsConfig := &tls.Config{}
+		if err = configureProtocol(tlsConfig, s.options.TLS); err != nil {
+			return err
+		}
+
+		if s.options.TLS.AutoCert.Enabled {
+			mgr := s.certManager()
+			tlsConfig.GetCertificate = mgr.GetCertificate
+			// Allow the TLS-ALPN-01 challenge on the TLS listener itself
+			tlsConfig.NextProtos = []string{"http/1.1", acme.ALPNProto}
+		} else if s.options.TLS.CertReloadInterval > 0 {
+			reloader, err := newCertReloader(s.options.TLS.CertFile, s.options.TLS.KeyFile, s.options.TLS.CertReloadInterval)
+			if err != nil {
+				return fmt.Errorf("failed to load TLS certificate: %v", err)
+			}
+			tlsConfig.GetCertificate = reloader.GetCertificate
+		} else {
+			cert, err := tls.LoadX509KeyPair(s.options.TLS.CertFile, s.options.TLS.KeyFile)
+			if err != nil {
+				return fmt.Errorf("failed to load TLS certificate: %v", err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
 		}
 
-		tlsConfig := &tls.Config{
-			Certificates: []tls.Certificate{cert},
-			MinVersion:   tls.VersionTLS12, // Require TLS 1.2 or higher
+		if err = configureClientAuth(tlsConfig, s.options.TLS); err != nil {
+			return err
 		}
 
 		// Create TLS listener
@@ -621,6 +1449,9 @@ func (s *Server) Run() (err error) {
 	defer listener.Close()
 
 	s.listenAddr = listener.Addr().String()
+	if s.options.TLS.UseTLS {
+		s.tlsAddr = s.listenAddr
+	}
 
 	// Go accept and handle connections
 	go func() {
@@ -655,8 +1486,16 @@ func (s *Server) Run() (err error) {
 			}
 			// fmt.Printf("** Connection established: %s <-- %s\n", conn.LocalAddr(), conn.RemoteAddr())
 
+			if !s.acquireConnSlot() {
+				go refuseConnection(conn)
+				continue
+			}
+
 			// Each connection separately bc a copy is passed in
-			go s.handleConnection(conn)
+			go func() {
+				defer s.releaseConnSlot()
+				s.handleConnection(conn)
+			}()
 		}
 	}()
 
@@ -686,6 +1525,8 @@ func (s *Server) ListRoutes() {
 }
 
 // Use adds handlers to your handlers chain.
+// They are appended after any middleware already added, just before routing,
+// so middleware runs in the order it was added with Use - after any added with UsePrepend.
 func (s *Server) Use(handlers ...Handler) {
 	last := s.handlers[len(s.handlers)-1]
 	// Re-slice to exclude last and add append the incoming handlers
@@ -693,6 +1534,72 @@ func (s *Server) Use(handlers ...Handler) {
 	s.handlers = append(s.handlers, last) // add back the last
 }
 
+// UsePrepend adds handlers at the front of your handlers chain, so they run before all
+// middleware added so far, whether with Use or UsePrepend, and wrap everything after them.
+// This suits middleware that must see the whole request, such as panic recovery and request IDs,
+// regardless of where it's registered. The handlers of a single call keep their given order.
+// For example, after:
+//
+//	s.Use(logger)
+//	s.UsePrepend(requestID)
+//	s.UsePrepend(recovery)
+//	s.Use(auth)
+//
+// a request runs recovery, requestID, logger, auth and then the route handler.
+func (s *Server) UsePrepend(handlers ...Handler) {
+	s.handlers = slices.Insert(s.handlers, 0, handlers...)
+}
+
+// SetNotFoundHandler sets the handler that renders the response when no route matches.
+// The status is already set to 404 when h runs, and as it runs in place of a route handler,
+// server middleware (logging etc.) sees it like any other request.
+// Example:
+//
+//	s.SetNotFoundHandler(func(ctx rweb.Context) error {
+//		return ctx.WriteHTML("<h1>Page not found</h1>")
+//	})
+func (s *Server) SetNotFoundHandler(h Handler) {
+	s.notFound = h
+}
+
+// Fallback sets a handler for requests that match no route, e.g. to serve a single-page app's shell
+// for the paths of its client-side routing. Unlike the not found handler, it runs with the status at 200,
+// so it serves a regular response - though it may still set a 404 itself, e.g. for paths that look like files.
+// Routing precedence is: exact routes, then routes with params or wildcards, then
+// a 405 Method Not Allowed for paths registered for other methods, then the fallback, and finally the 404.
+// The fallback only serves GET and HEAD requests - other methods get the 404,
+// so e.g. a POST to a mistyped API path isn't answered with the app's shell.
+// Example:
+//
+//	s.StaticFiles("/assets/", "dist/assets", 1)
+//	s.Fallback(func(ctx rweb.Context) error {
+//		return rweb.ServeFile(ctx, "dist/index.html")
+//	})
+func (s *Server) Fallback(h Handler) {
+	s.fallback = h
+}
+
+// PreRoute adds hooks that run for every request, matched or not, before any middleware and routing.
+// The request is parsed at that point, but no handler has run.
+// Unlike middleware, hooks can't stop the request - they suit metrics and logging
+// that must see every request uniformly. Hooks run in the order they were added.
+func (s *Server) PreRoute(hooks ...func(ctx Context)) {
+	s.preRoute = append(s.preRoute, hooks...)
+}
+
+// PostResponse adds hooks that run for every request after its response has been written,
+// including 404s, error responses and responses from middleware that didn't call Next().
+// For SSE streams and WebSockets, they run once the stream or connection has finished.
+// The response (status, headers and body) can be inspected, but changes no longer reach the client.
+// Example:
+//
+//	s.PostResponse(func(ctx rweb.Context) {
+//	    metrics.Observe(ctx.Request().Path(), ctx.Response().Status())
+//	})
+func (s *Server) PostResponse(hooks ...func(ctx Context)) {
+	s.postResponse = append(s.postResponse, hooks...)
+}
+
 // Group creates a new route group with the given prefix and optional middleware.
 // Groups allow organizing routes under a common URL prefix and applying middleware
 // that only affects routes within the group.
@@ -707,13 +1614,52 @@ func (s *Server) Group(prefix string, handlers ...Handler) *Group {
 	}
 }
 
+// acquireConnSlot reserves a slot for serving a connection,
+// returning false if MaxConnections are already being served
+func (s *Server) acquireConnSlot() bool {
+	if s.connSlots != nil {
+		select {
+		case s.connSlots <- struct{}{}:
+		default:
+			return false
+		}
+	}
+	s.activeConns.Add(1)
+	return true
+}
+
+// releaseConnSlot frees the slot of a connection that has been served
+func (s *Server) releaseConnSlot() {
+	s.activeConns.Add(-1)
+	if s.connSlots != nil {
+		<-s.connSlots
+	}
+}
+
+// refuseConnection answers a connection beyond MaxConnections with a 503, and closes it.
+// The client gets little time to take the response, so it can't hold on to the connection.
+func refuseConnection(conn net.Conn) {
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(time.Second))
+	_, _ = io.WriteString(conn, consts.HTTPServiceUnavailable)
+}
+
+// ActiveConnections returns the number of connections currently being served, e.g. for monitoring.
+func (s *Server) ActiveConnections() int {
+	return int(s.activeConns.Load())
+}
+
 // handleConnection handles an accepted connection.
 func (s *Server) handleConnection(conn net.Conn) {
 	var method, url string
 	var ctx = s.contextPool.Get().(*context) // get a new context from the pool
+	captureRaw := s.options.CaptureRawRequest
+	maxHeaderBytes, maxHeaderCount := s.maxHeaderBytes(), s.maxHeaderCount()
 
-	ctx.reader.Reset(conn) // prepare to read from the accepted connection
-	ctx.conn = conn        // store connection for WebSocket upgrades
+	connReader := newConnReader(conn)
+	ctx.reader.Reset(connReader) // prepare to read from the accepted connection
+	ctx.conn = conn              // store connection for WebSocket upgrades
+	ctx.connReader = connReader
 
 	defer conn.Close()
 
@@ -723,15 +1669,33 @@ func (s *Server) handleConnection(conn net.Conn) {
 		s.contextPool.Put(ctx)
 	}()
 
-	for {
+	for keepAlive := false; ; keepAlive = true {
+		// Limit how long we wait for the request to start
+		s.setRequestStartDeadline(conn, keepAlive)
+
 		// Read a line from the connection
-		message, err := ctx.reader.ReadString(consts.RuneNewLine)
+		message, err := readHeadLine(ctx.reader, maxHeaderBytes)
+		if errors.Is(err, errHeaderTooLarge) {
+			_, _ = io.WriteString(conn, consts.HTTPRequestHeaderFieldsTooLarge)
+			return
+		}
 		if err != nil {
-			if s.options.Debug && err.Error() != consts.EOF {
+			// A timeout here is just an idle client going away
+			if s.options.Debug && err.Error() != consts.EOF && !errors.Is(err, os.ErrDeadlineExceeded) {
 				fmt.Println("Error reading connection:", err)
 			}
 			return
 		}
+		if captureRaw {
+			ctx.request.raw = append(ctx.request.raw, message...)
+		}
+
+		// The rest of the request must arrive within ReadTimeout
+		if s.options.ReadTimeout > 0 {
+			_ = conn.SetReadDeadline(time.Now().Add(s.options.ReadTimeout))
+		} else if s.options.IdleTimeout > 0 {
+			_ = conn.SetReadDeadline(time.Time{})
+		}
 
 		space := strings.IndexByte(message, consts.RuneSingleSpace)
 
@@ -753,21 +1717,35 @@ func (s *Server) handleConnection(conn net.Conn) {
 
 		lastSpace := strings.LastIndexByte(message, consts.RuneSingleSpace)
 
+		var proto string // e.g. "HTTP/1.1" (none, from some simple clients)
 		if lastSpace == space {
 			lastSpace = len(message) - len(consts.CRLF)
+		} else {
+			proto = strings.TrimSpace(message[lastSpace+1:])
 		}
 
 		url = message[space+1 : lastSpace]
 
 		var contentLen int64
 		var isChunked bool
+		var expectContinue bool
+		var connection string // the Connection header's options
 
-		// Read headers until we meet an empty line
+		// Read headers until we meet an empty line, within the limits
+		headBytes := len(message)
 		for {
-			message, err = ctx.reader.ReadString(consts.RuneNewLine) // read a line
+			message, err = readHeadLine(ctx.reader, maxHeaderBytes-headBytes) // read a line
+			if errors.Is(err, errHeaderTooLarge) {
+				_, _ = io.WriteString(conn, consts.HTTPRequestHeaderFieldsTooLarge)
+				return
+			}
 			if err != nil {
 				return
 			}
+			headBytes += len(message)
+			if captureRaw {
+				ctx.request.raw = append(ctx.request.raw, message...)
+			}
 
 			if message == consts.CRLF { // "empty" line // end of headers
 				break
@@ -786,6 +1764,10 @@ func (s *Server) handleConnection(conn net.Conn) {
 				Key:   key,
 				Value: value,
 			})
+			if len(ctx.request.headers) > maxHeaderCount {
+				_, _ = io.WriteString(conn, consts.HTTPRequestHeaderFieldsTooLarge)
+				return
+			}
 
 			// Check for Content-Length and Transfer-Encoding headers
 			if strings.EqualFold(key, consts.HeaderContentLength) {
@@ -799,20 +1781,53 @@ func (s *Server) handleConnection(conn net.Conn) {
 			} else if strings.EqualFold(key, consts.HeaderTransferEncoding) &&
 				strings.Contains(strings.ToLower(value), "chunked") {
 				isChunked = true
+			} else if strings.EqualFold(key, consts.HeaderExpect) &&
+				strings.EqualFold(value, b2s(consts.Byt100Continue)) {
+				expectContinue = true
+			} else if strings.EqualFold(key, consts.HeaderConnection) {
+				connection += "," + value
+			}
+		}
+		ctx.closeConn = !keepConnAlive(proto, connection)
+
+		// The client is waiting for our go-ahead before sending the body
+		if expectContinue && (contentLen > 0 || isChunked) {
+			if !s.handleExpectContinue(ctx, method, url, conn) {
+				return
+			}
+		}
+
+		// Report upload progress as the body streams in, if requested
+		var bodyReader io.Reader = ctx.reader
+		if s.options.UploadProgress != nil && (contentLen > 0 || isChunked) &&
+			bytes.HasPrefix(ctx.request.ContentType, consts.BytMultipartFormData) {
+			_, _, path, _ := parseURL(url, s.options.URLOptions)
+			if onProgress := s.options.UploadProgress(method, path, ctx.request.headers); onProgress != nil {
+				totalBytes := contentLen
+				if isChunked {
+					totalBytes = -1
+				}
+				bodyReader = &progressReader{reader: ctx.reader, totalBytes: totalBytes, onProgress: onProgress}
 			}
 		}
 
 		// Read the request body if present
-		if contentLen > 0 {
+		if s.streamsMultipart(method, contentLen, isChunked, ctx.request.ContentType) {
+			// A large multipart body is left on the connection, for the handler to read part by part
+			streamBody(ctx, bodyReader, contentLen, isChunked)
+		} else if contentLen > 0 {
 			// Fixed-length body
 			body := make([]byte, contentLen)
-			_, err = io.ReadFull(ctx.reader, body)
+			_, err = io.ReadFull(bodyReader, body)
 			if err != nil {
 				if s.options.Verbose {
 					fmt.Println("Error reading request body:", err)
 				}
 				return
 			}
+			if captureRaw {
+				ctx.request.raw = append(ctx.request.raw, body...)
+			}
 
 			if method != consts.MethodHead && method != consts.MethodTrace {
 				ctx.request.body = append(ctx.request.body, body...)
@@ -826,6 +1841,9 @@ func (s *Server) handleConnection(conn net.Conn) {
 				if err != nil {
 					return
 				}
+				if captureRaw {
+					ctx.request.raw = append(ctx.request.raw, chunkSize...)
+				}
 
 				// Parse chunk size (hex)
 				size, err := strconv.ParseInt(strings.TrimSpace(chunkSize), 16, 64)
@@ -837,26 +1855,33 @@ func (s *Server) handleConnection(conn net.Conn) {
 				// Zero size chunk means end of body
 				if size == 0 {
 					// Read final CRLF
-					_, err = ctx.reader.ReadString(consts.RuneNewLine)
+					message, err = ctx.reader.ReadString(consts.RuneNewLine)
 					if err != nil {
 						return
 					}
+					if captureRaw {
+						ctx.request.raw = append(ctx.request.raw, message...)
+					}
 					break
 				}
 
 				// Read chunk data
 				chunk := make([]byte, size)
-				_, err = io.ReadFull(ctx.reader, chunk)
+				_, err = io.ReadFull(bodyReader, chunk)
 				if err != nil {
 					return
 				}
 				ctx.request.body = append(ctx.request.body, chunk...)
 
 				// Read chunk LF
-				_, err = ctx.reader.ReadString(consts.RuneNewLine)
+				message, err = ctx.reader.ReadString(consts.RuneNewLine)
 				if err != nil {
 					return
 				}
+				if captureRaw {
+					ctx.request.raw = append(ctx.request.raw, chunk...)
+					ctx.request.raw = append(ctx.request.raw, message...)
+				}
 			}
 		}
 
@@ -864,25 +1889,131 @@ func (s *Server) handleConnection(conn net.Conn) {
 			fmt.Printf("** ctx.request.body: %q\n", string(ctx.request.body))
 		}
 
-		// Handle the request
+		// The request is read - handlers (e.g. WebSockets) are not bound by the read deadline
+		if s.options.ReadTimeout > 0 || s.options.IdleTimeout > 0 {
+			_ = conn.SetReadDeadline(time.Time{})
+		}
+		if s.options.WriteTimeout > 0 {
+			_ = conn.SetWriteDeadline(time.Now().Add(s.options.WriteTimeout))
+		}
+
+		// Handle the request, with a context canceled if the client goes away meanwhile
+		// (The connection is watched for that only once a handler asks for the context - see ctx.Context)
+		ctx.goCtx, ctx.cancelGoCtx = s.requestContext()
 		s.handleRequest(ctx, method, url, conn)
+		connReader.stopWatching()
+		ctx.cancelGoCtx()
+		if s.options.WriteTimeout > 0 {
+			_ = conn.SetWriteDeadline(time.Time{})
+		}
 		if s.options.DebugRequestContext {
 			fmt.Printf("** ctx -> %#v\n\n", ctx)
 		}
 
 		// If the connection was upgraded to WebSocket, exit the HTTP loop
 		if ctx.wsUpgraded {
+			// The handler returned, so the WebSocket session is over
+			s.wsConns.Add(-1)
+			s.removeWebSocket(ctx.wsConn)
+			ctx.wsConn.notifyClose(wsCloseAbnormalClosure, "")
 			// The WebSocket handler is responsible for managing the connection now
 			return
 		}
 
+		// An SSE stream has no Content-Length - its end is marked by closing the connection
+		if ctx.sseEventsChan != nil {
+			return
+		}
+
+		// Skip any of a streamed body the handler left, to reach the next request
+		if !s.finishBodyStream(ctx, isChunked) {
+			return
+		}
+
+		// The client, or a handler, asked for the connection to be closed after this response
+		if ctx.closeConn || headerHasToken(ctx.response.Header(consts.HeaderConnection), "close") {
+			return
+		}
+
 		// Clean up the context by zeroing some slices, etc
 		ctx.Clean()
+		ctx.conn = conn // still serving this connection
+		ctx.connReader = connReader
+	}
+}
+
+// requestContext returns the context.Context for handling a request, with the WriteTimeout, if set, as a deadline
+func (s *Server) requestContext() (gocontext.Context, gocontext.CancelFunc) {
+	if s.options.WriteTimeout > 0 {
+		return gocontext.WithTimeout(gocontext.Background(), s.options.WriteTimeout)
+	}
+	return gocontext.WithCancel(gocontext.Background())
+}
+
+// keepConnAlive reports whether the connection may be kept open for further requests,
+// per the request's protocol version and Connection header options.
+// HTTP/1.1 connections persist unless the client asks to "close".
+// HTTP/1.0 ones are closed, even if the client offers keep-alive, as we don't echo it.
+func keepConnAlive(proto, connection string) bool {
+	if headerHasToken(connection, "close") {
+		return false
+	}
+	return proto != "HTTP/1.0"
+}
+
+// headerHasToken reports whether a comma-separated header value, such as Connection's, includes token (case-insensitively)
+func headerHasToken(value, token string) bool {
+	for _, v := range strings.Split(value, ",") {
+		if strings.EqualFold(strings.TrimSpace(v), token) {
+			return true
+		}
+	}
+	return false
+}
+
+// setRequestStartDeadline sets the read deadline for the start of the next request on conn.
+// The first request gets ReadTimeout, while subsequent requests on a keep-alive
+// connection get IdleTimeout, falling back to ReadTimeout.
+func (s *Server) setRequestStartDeadline(conn net.Conn, keepAlive bool) {
+	timeout := s.options.ReadTimeout
+	if keepAlive && s.options.IdleTimeout > 0 {
+		timeout = s.options.IdleTimeout
+	}
+	if timeout > 0 {
+		_ = conn.SetReadDeadline(time.Now().Add(timeout))
 	}
 }
 
+// handleExpectContinue answers an "Expect: 100-continue" request before its body is read.
+// If an ExpectContinueHandler is configured, it decides from the headers whether the body is wanted.
+// A rejection is written as the final response and false is returned, so the caller
+// closes the connection rather than reading a body the client may or may not send.
+func (s *Server) handleExpectContinue(ctx *context, method string, url string, conn io.Writer) bool {
+	if s.options.ExpectContinueHandler != nil {
+		_, _, path, _ := parseURL(url, s.options.URLOptions)
+
+		status := s.options.ExpectContinueHandler(method, path, ctx.request.headers)
+		if status != 0 && status != consts.StatusContinue {
+			if s.options.Verbose {
+				fmt.Printf("Rejecting %s %q before body with status %d\n", method, path, status)
+			}
+			ctx.response.SetStatus(status)
+			ctx.response.SetHeader(consts.HeaderConnection, "close")
+			s.writeResponse(ctx, conn)
+			return false
+		}
+	}
+
+	_, err := conn.Write(consts.BytResponseContinue)
+	return err == nil
+}
+
 // handleRequest handles the given request.
 func (s *Server) handleRequest(ctx *context, method string, url string, respWriter io.Writer) {
+	var start time.Time
+	if s.options.Metrics != nil {
+		start = time.Now()
+	}
 	ctx.method = method
 	ctx.scheme, ctx.host, ctx.path, ctx.query = parseURL(url, s.options.URLOptions)
 	if s.options.Debug {
@@ -890,17 +2021,34 @@ func (s *Server) handleRequest(ctx *context, method string, url string, respWrit
 			method, string(ctx.ContentType), len(ctx.request.body), ctx.scheme, ctx.host, ctx.path, ctx.query)
 	}
 
+	// Decompress the body, if requested
+	var rejectStatus int // for a body we won't handle
+	if s.options.DecompressRequests && len(ctx.request.body) > 0 {
+		if err := ctx.request.decompressBody(s.maxDecompressedSize()); err != nil {
+			if s.options.Verbose {
+				fmt.Printf("Error decompressing request body: %v\n", err)
+			}
+			rejectStatus = consts.StatusBadRequest
+			if errors.Is(err, errBodyTooLarge) {
+				rejectStatus = consts.StatusRequestEntityTooLarge
+			}
+		}
+	}
+
 	// Parse Post Args or Multipart Form
-	if len(ctx.request.body) > 0 {
+	if len(ctx.request.body) > 0 && rejectStatus == 0 {
 		if bytes.HasPrefix(ctx.ContentType, consts.BytMultipartFormData) {
-			if err := ctx.request.ParseMultipartForm(); err != nil {
+			if err := ctx.request.parseMultipartForm(s.multipartLimits()); err != nil {
 				fmt.Printf("Error parsing multipart form: %v\n", err)
+				if errors.Is(err, errMultipartTooLarge) || errors.Is(err, multipart.ErrMessageTooLarge) {
+					rejectStatus = consts.StatusRequestEntityTooLarge
+				}
 			} else {
 				if s.options.Verbose {
 					fmt.Println("Parsed Multipart Form")
 				}
 			}
-		} else if bytes.EqualFold(ctx.ContentType, consts.BytFormData) {
+		} else if isFormURLEncoded(ctx.ContentType) {
 			ctx.request.parsePostArgs()
 			if s.options.Debug {
 				fmt.Println("** Post Args -->", ctx.request.postArgs.String())
@@ -908,15 +2056,66 @@ func (s *Server) handleRequest(ctx *context, method string, url string, respWrit
 		}
 	}
 
+	for _, hook := range s.preRoute {
+		hook(ctx)
+	}
+
 	// Call the first handler in the chain
 	// (which will call any subsequent handlers)
 	// Handlers populate the context, before the response is written
-	err := s.handlers[0](ctx)
-	if err != nil {
-		s.errorHandler(ctx, err)
+	var err error
+	if rejectStatus != 0 {
+		ctx.SetStatus(rejectStatus)
+		err = ctx.WriteText(consts.StatusTextFromCode[rejectStatus])
+	} else if location, ok := s.cleanPathRedirect(ctx, url); ok {
+		status := consts.StatusMovedPermanently
+		if method != consts.MethodGet && method != consts.MethodHead {
+			status = consts.StatusPermanentRedirect // keeps the method and body
+		}
+		err = ctx.Redirect(status, location)
+	} else {
+		err = s.callHandlers(ctx)
+	}
+	if err != nil && ctx.response.stream != nil {
+		// The response is already under way, so it's too late for an error response
+		s.abortFlushed(ctx, respWriter, err)
+	} else {
+		if err != nil {
+			s.errorHandler(ctx, err)
+		}
+		s.writeResponse(ctx, respWriter)
+	}
+
+	if s.options.Metrics != nil {
+		s.options.Metrics.ObserveRequest(method, ctx.route, ctx.response.Status(), time.Since(start))
+	}
+
+	for _, hook := range s.postResponse {
+		hook(ctx)
+	}
+}
+
+// maxDecompressedSize returns the most bytes a request body may decompress to, per the server options
+func (s *Server) maxDecompressedSize() int64 {
+	if s.options.MaxDecompressedSize > 0 {
+		return s.options.MaxDecompressedSize
 	}
+	return defaultMaxDecompressedSize
+}
 
-	s.writeResponse(ctx, respWriter)
+// multipartLimits returns the limits on multipart forms, per the server options
+func (s *Server) multipartLimits() multipartLimits {
+	limits := defaultMultipartLimits
+	if s.options.MaxMultipartMemory > 0 {
+		limits.maxMemory = s.options.MaxMultipartMemory
+	}
+	if s.options.MaxFormFields > 0 {
+		limits.maxFields = s.options.MaxFormFields
+	}
+	if s.options.MaxFormFiles > 0 {
+		limits.maxFiles = s.options.MaxFormFiles
+	}
+	return limits
 }
 
 // writeWebSocketUpgradeResponse writes the WebSocket upgrade response immediately
@@ -953,6 +2152,47 @@ func (s *Server) writeResponse(ctx *context, respWriter io.Writer) {
 		return
 	}
 
+	// Served through ServeHTTP
+	if w, ok := respWriter.(*netHTTPWriter); ok {
+		s.writeNetHTTPResponse(ctx, w)
+		return
+	}
+
+	// Flushed - the head and some of the body are already sent
+	if ctx.response.stream != nil {
+		s.finishFlushed(ctx, respWriter)
+		return
+	}
+
+	s.applyDefaultHeaders(ctx)
+	ctx.response.addServerTimingHeader()
+
+	// Write headers to the response writer
+	_, err := respWriter.Write(s.responseHead(ctx, ctx.streamFn != nil && ctx.streamLen == 0))
+	if err != nil {
+		fmt.Println("Error writing headers: ", err)
+	}
+
+	// Body
+	if ctx.request.method == consts.MethodHead && ctx.sseEventsChan == nil {
+		return // HEAD responses have no body (the headers are as for GET)
+	} else if ctx.streamFn != nil {
+		s.sendStream(ctx, respWriter)
+	} else if ctx.sseEventsChan == nil {
+		_, _ = respWriter.Write(ctx.response.body)
+	} else {
+		// fmt.Println("RWEB: SSE events channel is set -- sending events")
+		err = s.sendSSE(ctx, respWriter)
+		if err != nil {
+			fmt.Println("Error sending SSE events: ", err)
+		}
+	}
+}
+
+// responseHead returns the status line and headers of the response.
+// A chunked response (a streamed body of unknown length) gets Transfer-Encoding: chunked,
+// others a Content-Length (of the body, or the known length of a stream), except for SSE.
+func (s *Server) responseHead(ctx *context, chunked bool) []byte {
 	tmp := bytes.Buffer{}
 
 	// HTTP1.1 header and status
@@ -965,11 +2205,28 @@ func (s *Server) writeResponse(ctx *context, respWriter io.Writer) {
 	}
 	tmp.WriteString(consts.CRLF)
 
-	if ctx.sseEventsChan == nil { // For SSE -- don't set content-length
+	if chunked {
+		tmp.WriteString(consts.HeaderTransferEncoding)
+		tmp.WriteString(consts.ColonSpace)
+		tmp.WriteString("chunked")
+		tmp.WriteString(consts.CRLF)
+	} else if ctx.sseEventsChan == nil { // For SSE -- don't set content-length
 		// Content-Length
 		tmp.WriteString(consts.HeaderContentLength)
 		tmp.WriteString(consts.ColonSpace)
-		tmp.WriteString(strconv.Itoa(len(ctx.response.body)))
+		bodyLen := int64(len(ctx.response.body))
+		if ctx.streamFn != nil {
+			bodyLen = ctx.streamLen
+		}
+		tmp.WriteString(strconv.FormatInt(bodyLen, 10))
+		tmp.WriteString(consts.CRLF)
+	}
+
+	// Tell the client we'll close the connection after this response
+	if ctx.closeConn && ctx.response.Header(consts.HeaderConnection) == "" {
+		tmp.WriteString(consts.HeaderConnection)
+		tmp.WriteString(consts.ColonSpace)
+		tmp.WriteString("close")
 		tmp.WriteString(consts.CRLF)
 	}
 
@@ -982,25 +2239,29 @@ func (s *Server) writeResponse(ctx *context, respWriter io.Writer) {
 	}
 	tmp.WriteString(consts.CRLF)
 
-	// Write headers to the response writer
-	_, err := respWriter.Write(tmp.Bytes())
-	if err != nil {
-		fmt.Println("Error writing headers: ", err)
-	}
+	return tmp.Bytes()
+}
 
-	// Body
-	if ctx.sseEventsChan == nil {
-		_, _ = respWriter.Write(ctx.response.body)
-	} else {
-		// fmt.Println("RWEB: SSE events channel is set -- sending events")
-		err = s.sendSSE(ctx, respWriter)
-		if err != nil {
-			fmt.Println("Error sending SSE events: ", err)
+// applyDefaultHeaders adds the server's DefaultHeaders to the response,
+// skipping any the handler has already set (matched case-insensitively).
+func (s *Server) applyDefaultHeaders(ctx *context) {
+	for _, def := range s.options.DefaultHeaders {
+		if !slices.ContainsFunc(ctx.response.headers, func(h Header) bool {
+			return strings.EqualFold(h.Key, def.Key)
+		}) {
+			ctx.response.headers = append(ctx.response.headers, def)
 		}
 	}
 }
 
 func (s *Server) sendSSE(ctx *context, respWriter io.Writer) (err error) {
+	respWriter = countingWriter{w: respWriter, n: &ctx.response.sent}
+
+	// Streams are long-lived, so WriteTimeout doesn't apply
+	if s.options.WriteTimeout > 0 && ctx.conn != nil {
+		_ = ctx.conn.SetWriteDeadline(time.Time{})
+	}
+
 	// Run any registered cleanup when SSE streaming ends (e.g., SSEHub auto-unregister)
 	defer func() {
 		if ctx.sseCleanup != nil {
@@ -1012,17 +2273,39 @@ func (s *Server) sendSSE(ctx *context, respWriter io.Writer) (err error) {
 	// A read on a half-closed or fully-closed TCP connection returns immediately
 	// (EOF or error), giving us sub-second disconnect detection instead of waiting
 	// up to a full heartbeat interval (~25s) to discover a broken pipe on write.
+	// The read is the connection reader's watch, so it is the only one reading the connection,
+	// even if the handler had the connection watched already, by calling ctx.Context().
+	// The watch is ended by the connection loop once we return.
 	connGone := make(chan struct{})
-	if ctx.conn != nil {
+	if cr := ctx.connReader; cr != nil {
+		cancel := ctx.cancelGoCtx
+		cr.stopWatching() // a watch from ctx.Context() would only cancel the context
+		cr.watch(func() {
+			if cancel != nil {
+				cancel() // as ctx.Context() promises
+			}
+			close(connGone)
+		})
+	} else if ctx.httpReq != nil {
+		done := ctx.httpReq.Context().Done() // canceled by net/http when the client goes
 		go func() {
-			buf := make([]byte, 1)
-			// Read blocks until the client closes or the conn is closed.
-			// We don't expect any incoming data on an SSE connection.
-			_, _ = ctx.conn.Read(buf)
+			<-done
 			close(connGone)
 		}()
 	}
 
+	// Receive any server-wide notices
+	notices := s.addSSEStream()
+	defer s.removeSSEStream(notices)
+
+	// Tell the client how long to wait before reconnecting
+	if s.options.SSECfg.RetryMillis > 0 {
+		_, err = fmt.Fprintf(respWriter, "retry: %d\n\n", s.options.SSECfg.RetryMillis)
+		if err != nil {
+			fmt.Println("Error writing retry directive: ", err)
+		}
+	}
+
 	// Send a connect event -- not required per SSE standard, but may be helpful
 	if s.options.SSECfg.SendConnectedEvent {
 		_, err = fmt.Fprint(respWriter, "event: message\ndata: Connected\n\n")
@@ -1043,6 +2326,15 @@ func (s *Server) sendSSE(ctx *context, respWriter io.Writer) (err error) {
 			ctx.sseEventName, ctx.sseEventsChan, ctx.status)
 	}
 
+	// Keepalive ticks only fire after an interval with nothing sent (a nil channel never fires)
+	var keepAlive *time.Ticker
+	var keepAliveTick <-chan time.Time
+	if s.options.SSECfg.KeepAliveInterval > 0 {
+		keepAlive = time.NewTicker(s.options.SSECfg.KeepAliveInterval)
+		defer keepAlive.Stop()
+		keepAliveTick = keepAlive.C
+	}
+
 	// Event Loop - until the input channel is closed or we exit
 	for {
 		select {
@@ -1054,6 +2346,31 @@ func (s *Server) sendSSE(ctx *context, respWriter io.Writer) (err error) {
 			_ = rw.Flush()
 			return nil
 
+		case <-keepAliveTick:
+			// SSE comment — ignored by EventSource, but shows intermediaries the stream is alive
+			if _, err = fmt.Fprint(rw, ":keepalive\n\n"); err == nil {
+				err = rw.Flush()
+			}
+			if err != nil {
+				fmt.Printf("Error writing SSE keepalive for channel %v: %v\n", ctx.sseEventsChan, err)
+				return err
+			}
+
+		case notice := <-notices:
+			switch v := notice.(type) {
+			case SSEvent:
+				err = s.writeSSEEvent(rw, v.ID, v.Type, fmt.Sprintf("%s", v.Data))
+			default:
+				err = s.writeSSEEvent(rw, "", ctx.sseEventName, fmt.Sprintf("%s", v))
+			}
+			if err == nil {
+				err = rw.Flush()
+			}
+			if err != nil {
+				fmt.Printf("Error writing SSE notice for channel %v: %v\n", ctx.sseEventsChan, err)
+				return err
+			}
+
 		case event, ok := <-ctx.sseEventsChan:
 			if !ok {
 				fmt.Println("SSE Channel closed and drained, let's clean up and exit...")
@@ -1084,29 +2401,29 @@ func (s *Server) sendSSE(ctx *context, respWriter io.Writer) (err error) {
 				_, err = fmt.Fprint(rw, ":keepalive\n\n")
 				_ = v // use v to satisfy the compiler
 			case SSEvent: // get the eventName from the data (rweb.SSEvent) received
-				_, err = fmt.Fprintf(rw, "event: %s\ndata: %s\n\n", v.Type, v.Data)
+				err = s.writeSSEEvent(rw, v.ID, v.Type, fmt.Sprintf("%s", v.Data))
 			case string:
-				_, err = fmt.Fprintf(rw, "event: %s\ndata: %s\n\n", ctx.sseEventName, v)
+				err = s.writeSSEEvent(rw, "", ctx.sseEventName, v)
 			default:
-				_, err = fmt.Fprintf(rw, "event: %s\ndata: %+v\n\n", ctx.sseEventName, v)
+				err = s.writeSSEEvent(rw, "", ctx.sseEventName, fmt.Sprintf("%+v", v))
 			}
 
-			if err != nil {
-				fmt.Printf("Error writing SSE event from channel %v: %v\n", ctx.sseEventsChan, err)
-				rw.Reset(respWriter) // Reset the buffer for the next event
-				continue
+			if err == nil {
+				err = rw.Flush() // Flush the buffer to send data immediately
 			}
-
-			err = rw.Flush() // Flush the buffer to send data immediately
 			if err != nil {
-				fmt.Printf("Error flushing SSE output from channel %v: %v\n", ctx.sseEventsChan, err)
-				rw.Reset(respWriter) // Reset the buffer for the next event
+				// The client is gone (or the stream is broken, part way through an event) - stop serving it
+				fmt.Printf("Error writing SSE event from channel %v: %v\n", ctx.sseEventsChan, err)
 				return err
 			}
 
 			if s.options.Verbose {
 				fmt.Printf("RWEB Sent (from channel: %v) event: %s\n", ctx.sseEventsChan, event)
 			}
+
+			if keepAlive != nil { // postpone the next keepalive, as we've just sent something
+				keepAlive.Reset(s.options.SSECfg.KeepAliveInterval)
+			}
 		}
 	}
 
@@ -1123,14 +2440,21 @@ func (s *Server) newContext() *context {
 			params:  make([]rtr.Parameter, 0, 8),
 		},
 		response: response{
-			body:    make([]byte, 0, 1024),
-			headers: make([]Header, 0, 8),
-			status:  200,
+			body:        make([]byte, 0, 1024),
+			headers:     make([]Header, 0, 8),
+			status:      200,
+			jsonEncoder: s.options.JSONEncoder,
 		},
 		data: make(map[string]any),
 	}
 }
 
+// GetTLSListenAddr returns the actual address of the TLS listener, once the server is running with TLS,
+// e.g. with the port chosen by the system. Otherwise it returns "".
+func (s *Server) GetTLSListenAddr() string {
+	return s.tlsAddr
+}
+
 func (s *Server) GetListenAddr() string {
 	return s.listenAddr
 }
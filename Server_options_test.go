@@ -1,6 +1,7 @@
 package rweb_test
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -96,6 +97,84 @@ func TestWithKeepTrailingSlashes(t *testing.T) {
 	assert.Equal(t, resp.Status(), consts.StatusOK)
 }
 
+// TestWithStrictTrailingSlashes tests that routes with and without a trailing slash are distinct
+func TestWithStrictTrailingSlashes(t *testing.T) {
+	s := rweb.NewServerWithOptions(
+		rweb.WithStrictTrailingSlashes(),
+	)
+
+	s.Get("/users/", func(ctx rweb.Context) error {
+		return ctx.WriteString("collection")
+	})
+	s.Get("/users", func(ctx rweb.Context) error {
+		return ctx.Redirect(consts.StatusMovedPermanently, "/users/")
+	})
+	s.Get("/users/:id", func(ctx rweb.Context) error {
+		return ctx.WriteString("user " + ctx.Request().Param("id"))
+	})
+
+	resp := s.Request(consts.MethodGet, "/users/", nil, nil)
+	assert.Equal(t, resp.Status(), consts.StatusOK)
+	assert.Equal(t, string(resp.Body()), "collection")
+
+	resp = s.Request(consts.MethodGet, "/users", nil, nil)
+	assert.Equal(t, resp.Status(), consts.StatusMovedPermanently)
+
+	resp = s.Request(consts.MethodGet, "/users/42", nil, nil)
+	assert.Equal(t, string(resp.Body()), "user 42")
+
+	resp = s.Request(consts.MethodGet, "/users/42/", nil, nil)
+	assert.Equal(t, resp.Status(), consts.StatusNotFound)
+
+	// By default the trailing slash variant matches too
+	s = rweb.NewServer()
+	s.Get("/items/:id", func(ctx rweb.Context) error {
+		return ctx.WriteString("item " + ctx.Request().Param("id"))
+	})
+	resp = s.Request(consts.MethodGet, "/items/42/", nil, nil)
+	assert.Equal(t, string(resp.Body()), "item 42")
+}
+
+// TestWithDefaultHeaders tests that default headers are added to every response
+func TestWithDefaultHeaders(t *testing.T) {
+	s := rweb.NewServerWithOptions(
+		rweb.WithDefaultHeaders(
+			rweb.Header{Key: "X-Content-Type-Options", Value: "nosniff"},
+			rweb.Header{Key: "X-Version", Value: "1.2.3"},
+		),
+	)
+
+	s.Get("/", func(ctx rweb.Context) error {
+		return ctx.WriteString("ok")
+	})
+	s.Get("/override", func(ctx rweb.Context) error {
+		ctx.Response().SetHeader("x-version", "beta")
+		return ctx.WriteString("ok")
+	})
+	s.Get("/error", func(ctx rweb.Context) error {
+		return errors.New("boom")
+	})
+
+	resp := s.Request(consts.MethodGet, "/", nil, nil)
+	assert.Equal(t, resp.Header("X-Content-Type-Options"), "nosniff")
+	assert.Equal(t, resp.Header("X-Version"), "1.2.3")
+
+	// Handler-set headers take precedence
+	resp = s.Request(consts.MethodGet, "/override", nil, nil)
+	assert.Equal(t, resp.Header("x-version"), "beta")
+	assert.Equal(t, resp.Header("X-Version"), "")
+	assert.Equal(t, resp.Header("X-Content-Type-Options"), "nosniff")
+
+	// Error and not found responses carry them too
+	resp = s.Request(consts.MethodGet, "/error", nil, nil)
+	assert.Equal(t, resp.Status(), consts.StatusInternalServerError)
+	assert.Equal(t, resp.Header("X-Version"), "1.2.3")
+
+	resp = s.Request(consts.MethodGet, "/missing", nil, nil)
+	assert.Equal(t, resp.Status(), consts.StatusNotFound)
+	assert.Equal(t, resp.Header("X-Version"), "1.2.3")
+}
+
 // TestWithSSESendConnectedEvent tests the WithSSESendConnectedEvent functional option
 func TestWithSSESendConnectedEvent(t *testing.T) {
 	s := rweb.NewServerWithOptions(
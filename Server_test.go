@@ -1,13 +1,17 @@
 package rweb_test
 
 import (
+	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"strings"
 	"syscall"
 	"testing"
+	"time"
 
 	"github.com/rohanthewiz/assert"
 	"github.com/rohanthewiz/rweb"
@@ -22,18 +26,22 @@ func TestPanic(t *testing.T) {
 	s := rweb.NewServer()
 
 	s.Get("/panic", func(ctx rweb.Context) error {
+		ctx.Response().SetHeader("X-Partial", "true")
 		panic("Something unbelievable happened")
 	})
+	s.Get("/ok", func(ctx rweb.Context) error {
+		return ctx.WriteString("fine")
+	})
 
-	defer func() {
-		r := recover()
+	// The panic is recovered, and rendered by the error handler, as on a real connection
+	response := s.Request(consts.MethodGet, "/panic", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusInternalServerError)
+	assert.Equal(t, response.Header("X-Partial"), "")
+	assert.True(t, strings.Contains(string(response.Body()), "500 Internal Server Error"))
 
-		if r == nil {
-			t.Error("Didn't panic")
-		}
-	}()
-
-	s.Request(consts.MethodGet, "/panic", nil, nil)
+	// The server keeps working normally
+	response = s.Request(consts.MethodGet, "/ok", nil, nil)
+	assert.Equal(t, string(response.Body()), "fine")
 }
 
 func TestGet(t *testing.T) {
@@ -302,6 +310,121 @@ func TestBadRequestProtocol(t *testing.T) {
 	_ = s.Run()
 }
 
+func TestConnectionClose(t *testing.T) {
+	readyChan := make(chan struct{}, 1)
+	s := rweb.NewServer(rweb.ServerOptions{ReadyChan: readyChan, Address: "localhost:"})
+
+	s.Get("/", func(ctx rweb.Context) error {
+		return ctx.WriteString("Hello")
+	})
+	s.Get("/bye", func(ctx rweb.Context) error {
+		ctx.Response().SetHeader(consts.HeaderConnection, "close")
+		return ctx.WriteString("Bye")
+	})
+
+	go func() {
+		defer syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+		<-readyChan // wait for server
+
+		// send writes the requests on a new connection, returning whether each response said
+		// the connection would be closed (Connection: close), and whether the server then closed it
+		send := func(requests ...string) (closes []bool, closed bool) {
+			conn, err := net.Dial("tcp", "127.0.0.1:"+s.GetListenPort())
+			assert.Nil(t, err)
+			defer conn.Close()
+			_, err = io.WriteString(conn, strings.Join(requests, ""))
+			assert.Nil(t, err)
+
+			reader := bufio.NewReader(conn)
+			for range requests {
+				resp, err := http.ReadResponse(reader, nil)
+				assert.Nil(t, err)
+				_, _ = io.ReadAll(resp.Body)
+				_ = resp.Body.Close()
+				closes = append(closes, resp.Close)
+			}
+
+			_ = conn.SetReadDeadline(time.Now().Add(500 * time.Millisecond))
+			_, err = reader.ReadByte()
+			return closes, errors.Is(err, io.EOF)
+		}
+
+		// HTTP/1.1 connections are kept alive by default
+		closes, closed := send("GET / HTTP/1.1\r\nHost: x\r\n\r\n", "GET / HTTP/1.1\r\nHost: x\r\n\r\n")
+		assert.Equal(t, fmt.Sprint(closes), "[false false]")
+		assert.False(t, closed)
+
+		// The client asks for the connection to be closed
+		closes, closed = send("GET / HTTP/1.1\r\nHost: x\r\nConnection: Keep-Alive, Close\r\n\r\n")
+		assert.Equal(t, fmt.Sprint(closes), "[true]")
+		assert.True(t, closed)
+
+		// HTTP/1.0 connections are closed
+		closes, closed = send("GET / HTTP/1.0\r\nConnection: keep-alive\r\n\r\n")
+		assert.Equal(t, fmt.Sprint(closes), "[true]")
+		assert.True(t, closed)
+
+		// A handler asks for the connection to be closed
+		closes, closed = send("GET / HTTP/1.1\r\nHost: x\r\n\r\n", "GET /bye HTTP/1.1\r\nHost: x\r\n\r\n")
+		assert.Equal(t, fmt.Sprint(closes), "[false true]")
+		assert.True(t, closed)
+	}()
+
+	_ = s.Run()
+}
+
+func TestMaxConnections(t *testing.T) {
+	readyChan := make(chan struct{}, 1)
+	s := rweb.NewServer(rweb.ServerOptions{ReadyChan: readyChan, Address: "localhost:", MaxConnections: 1})
+
+	s.Get("/", func(ctx rweb.Context) error {
+		return ctx.WriteString("Hello")
+	})
+
+	go func() {
+		defer syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+		<-readyChan // wait for server
+		addr := "127.0.0.1:" + s.GetListenPort()
+		waitForConns := func(n int) {
+			deadline := time.Now().Add(2 * time.Second)
+			for s.ActiveConnections() != n && time.Now().Before(deadline) {
+				time.Sleep(5 * time.Millisecond)
+			}
+			assert.Equal(t, s.ActiveConnections(), n)
+		}
+		get := func() int {
+			conn, err := net.Dial("tcp", addr)
+			assert.Nil(t, err)
+			defer conn.Close()
+			_, err = io.WriteString(conn, "GET / HTTP/1.1\r\nHost: x\r\nConnection: close\r\n\r\n")
+			assert.Nil(t, err)
+			resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+			assert.Nil(t, err)
+			_ = resp.Body.Close()
+			return resp.StatusCode
+		}
+
+		// An idle connection takes the only slot
+		idle, err := net.Dial("tcp", addr)
+		assert.Nil(t, err)
+		waitForConns(1)
+
+		// So others are refused
+		assert.Equal(t, get(), consts.StatusServiceUnavailable)
+		assert.Equal(t, s.ActiveConnections(), 1)
+
+		// Until it's closed
+		_ = idle.Close()
+		waitForConns(0)
+		assert.Equal(t, get(), consts.StatusOK)
+		waitForConns(0)
+	}()
+
+	_ = s.Run()
+}
+
 func TestEarlyClose(t *testing.T) {
 	readyChan := make(chan struct{}, 1)
 	s := rweb.NewServer(rweb.ServerOptions{Verbose: true, ReadyChan: readyChan})
@@ -333,3 +456,646 @@ func TestUnavailablePort(t *testing.T) {
 	s := rweb.NewServer(rweb.ServerOptions{Verbose: true, Address: testPort})
 	_ = s.Run()
 }
+
+func TestExpectContinueHandler(t *testing.T) {
+	readyChan := make(chan struct{}, 1)
+	s := rweb.NewServerWithOptions(
+		rweb.WithAddress("localhost:"),
+		rweb.WithReadyChan(readyChan),
+		rweb.WithExpectContinueHandler(func(method, path string, headers []rweb.Header) int {
+			for _, hdr := range headers {
+				if hdr.Key == "X-Upload-Token" && hdr.Value == "secret" {
+					return consts.StatusContinue
+				}
+			}
+			return consts.StatusUnauthorized
+		}),
+	)
+
+	s.Post("/upload", func(ctx rweb.Context) error {
+		return ctx.WriteString(string(ctx.Request().Body()))
+	})
+
+	go func() {
+		defer syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+		<-readyChan // wait for server
+		addr := fmt.Sprintf(":%s", s.GetListenPort())
+
+		// Rejected before the body is sent
+		conn, err := net.Dial(consts.ProtocolTCP, addr)
+		assert.Nil(t, err)
+
+		_, err = io.WriteString(conn, "POST /upload HTTP/1.1\r\nContent-Length: 5\r\nExpect: 100-continue\r\n\r\n")
+		assert.Nil(t, err)
+
+		response, err := io.ReadAll(conn) // server closes the connection after rejecting
+		assert.Nil(t, err)
+		assert.True(t, strings.HasPrefix(string(response), "HTTP/1.1 401"))
+		_ = conn.Close()
+
+		// Accepted -- interim response, then the body is read and handled
+		conn, err = net.Dial(consts.ProtocolTCP, addr)
+		assert.Nil(t, err)
+		defer conn.Close()
+
+		_, err = io.WriteString(conn,
+			"POST /upload HTTP/1.1\r\nContent-Length: 5\r\nX-Upload-Token: secret\r\nExpect: 100-continue\r\n\r\n")
+		assert.Nil(t, err)
+
+		reader := bufio.NewReader(conn)
+		line, err := reader.ReadString('\n')
+		assert.Nil(t, err)
+		assert.Equal(t, line, "HTTP/1.1 100 Continue\r\n")
+		line, err = reader.ReadString('\n')
+		assert.Nil(t, err)
+		assert.Equal(t, line, consts.CRLF)
+
+		_, err = io.WriteString(conn, "hello")
+		assert.Nil(t, err)
+
+		line, err = reader.ReadString('\n')
+		assert.Nil(t, err)
+		assert.True(t, strings.HasPrefix(line, HTTP11OK))
+	}()
+
+	_ = s.Run()
+}
+
+func TestExpectContinue(t *testing.T) {
+	readyChan := make(chan struct{}, 1)
+	s := rweb.NewServerWithOptions(rweb.WithAddress("localhost:"), rweb.WithReadyChan(readyChan))
+
+	s.Post("/upload", func(ctx rweb.Context) error {
+		return ctx.WriteString(fmt.Sprintf("got %q", ctx.Request().Body()))
+	})
+
+	go func() {
+		defer syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+		<-readyChan // wait for server
+		conn, err := net.Dial(consts.ProtocolTCP, fmt.Sprintf(":%s", s.GetListenPort()))
+		assert.Nil(t, err)
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+
+		// expectContinue sends a request's head, waits for the go-ahead, then sends its body
+		expectContinue := func(head, body string) {
+			_, err := io.WriteString(conn, head)
+			assert.Nil(t, err)
+
+			_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second)) // we mustn't be left waiting
+			resp, err := http.ReadResponse(reader, nil)
+			assert.Nil(t, err)
+			assert.Equal(t, resp.StatusCode, consts.StatusContinue)
+			_ = conn.SetReadDeadline(time.Time{})
+
+			_, err = io.WriteString(conn, body)
+			assert.Nil(t, err)
+		}
+		readBody := func() string {
+			resp, err := http.ReadResponse(reader, nil)
+			assert.Nil(t, err)
+			assert.Equal(t, resp.StatusCode, consts.StatusOK)
+			body, _ := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			return string(body)
+		}
+
+		// Without an ExpectContinueHandler, every body is wanted
+		expectContinue("POST /upload HTTP/1.1\r\nHost: x\r\nContent-Length: 5\r\nExpect: 100-continue\r\n\r\n", "hello")
+		assert.Equal(t, readBody(), `got "hello"`)
+
+		// Chunked, on the same connection, with the expectation in other case
+		expectContinue("POST /upload HTTP/1.1\r\nHost: x\r\nTransfer-Encoding: chunked\r\nExpect: 100-Continue\r\n\r\n",
+			"5\r\nworld\r\n0\r\n\r\n")
+		assert.Equal(t, readBody(), `got "world"`)
+
+		// No body, no interim response
+		_, err = io.WriteString(conn, "POST /upload HTTP/1.1\r\nHost: x\r\nContent-Length: 0\r\nExpect: 100-continue\r\n\r\n")
+		assert.Nil(t, err)
+		assert.Equal(t, readBody(), `got ""`)
+
+		// And as Go's client does it
+		client := &http.Client{Transport: &http.Transport{ExpectContinueTimeout: 10 * time.Second}}
+		req, err := http.NewRequest(consts.MethodPost, fmt.Sprintf("http://127.0.0.1:%s/upload", s.GetListenPort()),
+			strings.NewReader("from go"))
+		assert.Nil(t, err)
+		req.Header.Set(consts.HeaderExpect, "100-continue")
+		start := time.Now()
+		resp, err := client.Do(req)
+		assert.Nil(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		assert.Equal(t, string(body), `got "from go"`)
+		assert.True(t, time.Since(start) < 5*time.Second) // didn't wait out the timeout
+	}()
+
+	_ = s.Run()
+}
+
+func TestConnectionTimeouts(t *testing.T) {
+	readyChan := make(chan struct{}, 1)
+	s := rweb.NewServerWithOptions(
+		rweb.WithAddress("localhost:"),
+		rweb.WithReadyChan(readyChan),
+		rweb.WithReadTimeout(100*time.Millisecond),
+		rweb.WithIdleTimeout(300*time.Millisecond),
+		rweb.WithWriteTimeout(time.Second),
+	)
+
+	s.Get("/hello", func(ctx rweb.Context) error {
+		return ctx.WriteString("hello")
+	})
+
+	go func() {
+		defer syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+		<-readyChan // wait for server
+		addr := fmt.Sprintf(":%s", s.GetListenPort())
+
+		// A client that stalls mid-request is disconnected after ReadTimeout
+		conn, err := net.Dial(consts.ProtocolTCP, addr)
+		assert.Nil(t, err)
+		start := time.Now()
+		_, err = io.WriteString(conn, "GET /hello HTTP/1.1\r\nHost: localhost\r\n")
+		assert.Nil(t, err)
+		response, err := io.ReadAll(conn) // returns once the server closes the connection
+		assert.Nil(t, err)
+		assert.Equal(t, len(response), 0)
+		elapsed := time.Since(start)
+		assert.True(t, elapsed >= 100*time.Millisecond && elapsed < time.Second)
+		_ = conn.Close()
+
+		// A keep-alive connection serves several requests, then is closed after IdleTimeout
+		conn, err = net.Dial(consts.ProtocolTCP, addr)
+		assert.Nil(t, err)
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+
+		for range 2 {
+			_, err = io.WriteString(conn, "GET /hello HTTP/1.1\r\nHost: localhost\r\n\r\n")
+			assert.Nil(t, err)
+			resp, err := http.ReadResponse(reader, nil)
+			assert.Nil(t, err)
+			body, _ := io.ReadAll(resp.Body)
+			assert.Equal(t, string(body), "hello")
+
+			// Idle for longer than ReadTimeout, but within IdleTimeout
+			time.Sleep(150 * time.Millisecond)
+		}
+
+		start = time.Now()
+		_, err = reader.ReadByte()
+		assert.Equal(t, err, io.EOF)
+		assert.True(t, time.Since(start) < time.Second)
+	}()
+
+	_ = s.Run()
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	s := rweb.NewServer()
+
+	s.Get("/users", func(ctx rweb.Context) error {
+		return ctx.WriteString("users")
+	})
+	s.Post("/users", func(ctx rweb.Context) error {
+		return ctx.WriteString("created")
+	})
+
+	response := s.Request(consts.MethodDelete, "/users", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusMethodNotAllowed)
+	assert.Equal(t, response.Header(consts.HeaderAllow), "GET, POST")
+
+	// The methods are listed in a stable order, across static and parameterized routes
+	for _, method := range []string{consts.MethodPut, consts.MethodPatch, consts.MethodDelete, consts.MethodPost} {
+		s.AddMethod(method, "/items/:id", func(ctx rweb.Context) error {
+			return ctx.WriteString("item")
+		})
+	}
+	s.Put("/items/new", func(ctx rweb.Context) error {
+		return ctx.WriteString("new item")
+	})
+	s.Get("/items/new", func(ctx rweb.Context) error {
+		return ctx.WriteString("new item form")
+	})
+	for range 10 {
+		response = s.Request(consts.MethodConnect, "/items/7", nil, nil)
+		assert.Equal(t, response.Header(consts.HeaderAllow), "POST, PUT, PATCH, DELETE")
+		response = s.Request(consts.MethodConnect, "/items/new", nil, nil)
+		assert.Equal(t, response.Header(consts.HeaderAllow), "GET, POST, PUT, PATCH, DELETE")
+		response = s.Request(consts.MethodOptions, "/items/new", nil, nil)
+		assert.Equal(t, response.Header(consts.HeaderAllow), "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+	}
+
+	// Unknown paths are still not found
+	response = s.Request(consts.MethodDelete, "/accounts", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusNotFound)
+	assert.Equal(t, response.Header(consts.HeaderAllow), "")
+}
+
+func TestNotFoundHandler(t *testing.T) {
+	s := rweb.NewServer()
+
+	var logged []string
+	s.Use(func(ctx rweb.Context) error {
+		err := ctx.Next()
+		logged = append(logged, fmt.Sprintf("%s %d", ctx.Request().Path(), ctx.Response().Status()))
+		return err
+	})
+
+	s.Get("/users", func(ctx rweb.Context) error {
+		return ctx.WriteString("users")
+	})
+
+	s.SetNotFoundHandler(func(ctx rweb.Context) error {
+		return ctx.WriteJSON(map[string]string{"error": "no such page: " + ctx.Request().Path()})
+	})
+
+	response := s.Request(consts.MethodGet, "/accounts", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusNotFound)
+	assert.Equal(t, response.Header(consts.HeaderContentType), consts.MIMEJSON)
+	assert.Equal(t, string(response.Body()), `{"error":"no such page: /accounts"}`)
+
+	// Matched routes and wrong methods are unaffected
+	response = s.Request(consts.MethodGet, "/users", nil, nil)
+	assert.Equal(t, string(response.Body()), "users")
+	response = s.Request(consts.MethodDelete, "/users", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusMethodNotAllowed)
+
+	// The middleware saw the 404
+	assert.Equal(t, strings.Join(logged, ","), "/accounts 404,/users 200,/users 405")
+}
+
+func TestFallback(t *testing.T) {
+	s := rweb.NewServer()
+
+	s.Get("/", func(ctx rweb.Context) error {
+		return ctx.WriteString("home")
+	})
+	s.Get("/users/:id", func(ctx rweb.Context) error {
+		return ctx.WriteString("user " + ctx.Request().Param("id"))
+	})
+	s.SetNotFoundHandler(func(ctx rweb.Context) error {
+		return ctx.WriteString("not found")
+	})
+	s.Fallback(func(ctx rweb.Context) error {
+		if strings.HasPrefix(ctx.Request().Path(), "/api/") {
+			ctx.SetStatus(consts.StatusNotFound)
+			return ctx.WriteString("no such endpoint")
+		}
+		return ctx.WriteHTML("<div id=app></div>")
+	})
+
+	// Routes come first
+	response := s.Request(consts.MethodGet, "/", nil, nil)
+	assert.Equal(t, string(response.Body()), "home")
+	response = s.Request(consts.MethodGet, "/users/3", nil, nil)
+	assert.Equal(t, string(response.Body()), "user 3")
+
+	// Then a 405 for a path of other methods
+	response = s.Request(consts.MethodPost, "/users/3", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusMethodNotAllowed)
+
+	// Then the fallback - a regular response, in place of the 404
+	response = s.Request(consts.MethodGet, "/settings/profile", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusOK)
+	assert.Equal(t, string(response.Body()), "<div id=app></div>")
+	assert.Equal(t, response.Header(consts.HeaderContentType), consts.MIMEHTML)
+
+	response = s.Request(consts.MethodGet, "/api/nothing", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusNotFound)
+	assert.Equal(t, string(response.Body()), "no such endpoint")
+
+	// The fallback is only for GET and HEAD
+	response = s.Request(consts.MethodHead, "/settings/profile", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusOK)
+	response = s.Request(consts.MethodPost, "/settings/profile", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusNotFound)
+	assert.Equal(t, string(response.Body()), "not found")
+
+	// The not found handler is for when there's no fallback
+	s.Fallback(nil)
+	response = s.Request(consts.MethodGet, "/settings/profile", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusNotFound)
+	assert.Equal(t, string(response.Body()), "not found")
+}
+
+func TestUsePrepend(t *testing.T) {
+	s := rweb.NewServer()
+
+	var order []string
+	mw := func(name string) rweb.Handler {
+		return func(ctx rweb.Context) error {
+			order = append(order, name)
+			return ctx.Next()
+		}
+	}
+
+	s.Use(mw("logger"))
+	s.UsePrepend(mw("requestID"))
+	s.UsePrepend(mw("recovery"), mw("metrics"))
+	s.Use(mw("auth"))
+
+	s.Get("/", func(ctx rweb.Context) error {
+		order = append(order, "handler")
+		return ctx.WriteString("ok")
+	})
+
+	response := s.Request(consts.MethodGet, "/", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusOK)
+	assert.Equal(t, strings.Join(order, ","), "recovery,metrics,requestID,logger,auth,handler")
+}
+
+func TestAutoOptions(t *testing.T) {
+	s := rweb.NewServer()
+
+	s.Get("/users", func(ctx rweb.Context) error {
+		return ctx.WriteString("users")
+	})
+	s.Put("/users", func(ctx rweb.Context) error {
+		return ctx.WriteString("updated")
+	})
+
+	response := s.Request(consts.MethodOptions, "/users", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusNoContent)
+	assert.Equal(t, response.Header(consts.HeaderAllow), "GET, PUT, OPTIONS")
+
+	// A user-defined OPTIONS handler takes precedence
+	s.Options("/users", func(ctx rweb.Context) error {
+		ctx.Response().SetHeader(consts.HeaderAllow, "GET")
+		return ctx.WriteString("custom")
+	})
+
+	response = s.Request(consts.MethodOptions, "/users", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusOK)
+	assert.Equal(t, response.Header(consts.HeaderAllow), "GET")
+	assert.Equal(t, string(response.Body()), "custom")
+}
+
+func TestMethodNotAllowedParameterized(t *testing.T) {
+	s := rweb.NewServer()
+
+	s.Get("/users/:id", func(ctx rweb.Context) error {
+		return ctx.WriteString("user " + ctx.Request().Param("id"))
+	})
+	s.Get("/users", func(ctx rweb.Context) error {
+		return ctx.WriteString("users")
+	})
+	s.Post("/users", func(ctx rweb.Context) error {
+		return ctx.WriteString("created")
+	})
+
+	response := s.Request(consts.MethodDelete, "/users/5", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusMethodNotAllowed)
+	assert.Equal(t, response.Header(consts.HeaderAllow), "GET")
+
+	response = s.Request(consts.MethodOptions, "/users/5", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusNoContent)
+	assert.Equal(t, response.Header(consts.HeaderAllow), "GET, OPTIONS")
+
+	// Static routes are unaffected by the parameterized one
+	response = s.Request(consts.MethodDelete, "/users", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusMethodNotAllowed)
+	assert.Equal(t, response.Header(consts.HeaderAllow), "GET, POST")
+}
+
+func TestAutoHead(t *testing.T) {
+	readyChan := make(chan struct{}, 1)
+	s := rweb.NewServerWithOptions(
+		rweb.WithAddress("localhost:"),
+		rweb.WithReadyChan(readyChan),
+		rweb.WithAutoHead(),
+	)
+
+	s.Get("/users", func(ctx rweb.Context) error {
+		return ctx.WriteText("all users")
+	})
+	s.Get("/users/:id", func(ctx rweb.Context) error {
+		ctx.Response().SetHeader("X-User", ctx.Request().Param("id"))
+		return ctx.WriteText("user " + ctx.Request().Param("id"))
+	})
+	s.Get("/custom", func(ctx rweb.Context) error {
+		return ctx.WriteText("from GET")
+	})
+	s.Head("/custom", func(ctx rweb.Context) error {
+		ctx.Response().SetHeader("X-Handler", "head")
+		return nil
+	})
+
+	// HEAD is advertised along with GET
+	response := s.Request(consts.MethodOptions, "/users", nil, nil)
+	assert.Equal(t, response.Header(consts.HeaderAllow), "GET, HEAD, OPTIONS")
+
+	go func() {
+		defer syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+		<-readyChan // wait for server
+		addr := fmt.Sprintf("localhost:%s", s.GetListenPort())
+
+		conn, err := net.Dial(consts.ProtocolTCP, addr)
+		assert.Nil(t, err)
+		defer conn.Close()
+		_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+		reader := bufio.NewReader(conn)
+
+		roundTrip := func(method, path string) *http.Response {
+			req, err := http.NewRequest(method, "http://"+addr+path, nil)
+			assert.Nil(t, err)
+			assert.Nil(t, req.Write(conn))
+			resp, err := http.ReadResponse(reader, req)
+			assert.Nil(t, err)
+			return resp
+		}
+
+		resp := roundTrip(consts.MethodHead, "/users")
+		assert.Equal(t, resp.StatusCode, consts.StatusOK)
+		assert.Equal(t, resp.ContentLength, int64(len("all users")))
+		assert.Equal(t, resp.Header.Get(consts.HeaderContentType), "text/plain")
+
+		resp = roundTrip(consts.MethodHead, "/users/7")
+		assert.Equal(t, resp.StatusCode, consts.StatusOK)
+		assert.Equal(t, resp.Header.Get("X-User"), "7")
+		assert.Equal(t, resp.ContentLength, int64(len("user 7")))
+
+		// An explicit HEAD handler takes precedence
+		resp = roundTrip(consts.MethodHead, "/custom")
+		assert.Equal(t, resp.Header.Get("X-Handler"), "head")
+
+		// No body was sent for the HEAD requests, so the connection is still in step
+		resp = roundTrip(consts.MethodGet, "/users/8")
+		body, err := io.ReadAll(resp.Body)
+		assert.Nil(t, err)
+		assert.Equal(t, string(body), "user 8")
+	}()
+
+	err := s.Run()
+	assert.Nil(t, err)
+
+	// Without AutoHead, HEAD isn't served by GET handlers
+	s2 := rweb.NewServer()
+	s2.Get("/users", func(ctx rweb.Context) error {
+		return ctx.WriteText("all users")
+	})
+	response = s2.Request(consts.MethodHead, "/users", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusMethodNotAllowed)
+}
+
+func TestPreRoutePostResponseHooks(t *testing.T) {
+	s := rweb.NewServer()
+
+	var trace []string
+	s.PreRoute(func(ctx rweb.Context) {
+		trace = append(trace, "pre:"+ctx.Request().Path())
+	})
+	s.PostResponse(func(ctx rweb.Context) {
+		trace = append(trace, fmt.Sprintf("post:%d", ctx.Response().Status()))
+	})
+
+	s.Use(func(ctx rweb.Context) error {
+		trace = append(trace, "middleware")
+		if ctx.Request().Path() == "/blocked" {
+			return ctx.SetStatus(403).WriteString("no")
+		}
+		return ctx.Next()
+	})
+
+	s.Get("/ok", func(ctx rweb.Context) error {
+		trace = append(trace, "handler")
+		return ctx.WriteString("ok")
+	})
+	s.Get("/fail", func(ctx rweb.Context) error {
+		return errors.New("boom")
+	})
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"/ok", "pre:/ok middleware handler post:200"},
+		{"/missing", "pre:/missing middleware post:404"},
+		{"/fail", "pre:/fail middleware post:500"},
+		{"/blocked", "pre:/blocked middleware post:403"},
+	}
+
+	for _, tt := range tests {
+		trace = nil
+		s.Request(consts.MethodGet, tt.path, nil, nil)
+		assert.Equal(t, strings.Join(trace, " "), tt.expected)
+	}
+}
+
+func TestFormBodyMethods(t *testing.T) {
+	readyChan := make(chan struct{}, 1)
+
+	s := rweb.NewServer(rweb.ServerOptions{ReadyChan: readyChan, Address: "localhost:"})
+
+	handler := func(ctx rweb.Context) error {
+		return ctx.WriteString(ctx.Request().Method() + " " + ctx.Request().GetPostValue("def") + " " + ctx.Request().FormValue("abc"))
+	}
+	s.Put("/", handler)
+	s.Patch("/", handler)
+	s.Delete("/", handler)
+
+	go func() {
+		defer func() {
+			_ = syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+		}()
+
+		<-readyChan // wait for server
+
+		send := func(method, contentType string) string {
+			req, err := http.NewRequest(method, fmt.Sprintf("http://127.0.0.1:%s", s.GetListenPort()),
+				strings.NewReader("abc=123&def=456"))
+			assert.Nil(t, err)
+			req.Header.Set(consts.HeaderContentType, contentType)
+
+			resp, err := http.DefaultClient.Do(req)
+			assert.Nil(t, err)
+			defer func() {
+				_ = resp.Body.Close()
+			}()
+			assert.Equal(t, resp.Status, consts.OK200)
+
+			body, _ := io.ReadAll(resp.Body)
+			return string(body)
+		}
+
+		assert.Equal(t, send(consts.MethodPut, string(consts.BytFormData)), "PUT 456 123")
+		assert.Equal(t, send(consts.MethodPatch, string(consts.BytFormData)), "PATCH 456 123")
+		assert.Equal(t, send(consts.MethodDelete, string(consts.BytFormData)), "DELETE 456 123")
+
+		// With a charset, as some clients send
+		assert.Equal(t, send(consts.MethodPatch, string(consts.BytFormData)+"; charset=UTF-8"), "PATCH 456 123")
+
+		// Not a form
+		assert.Equal(t, send(consts.MethodPut, "text/plain"), "PUT  ")
+	}()
+
+	_ = s.Run() // run with high-order port
+}
+
+func TestHeaderLimits(t *testing.T) {
+	readyChan := make(chan struct{}, 1)
+	s := rweb.NewServerWithOptions(
+		rweb.WithAddress("localhost:"),
+		rweb.WithReadyChan(readyChan),
+		rweb.WithHeaderLimits(8192, 50),
+	)
+
+	s.Get("/", func(ctx rweb.Context) error {
+		return ctx.WriteString(fmt.Sprintf("%d headers", len(ctx.Request().Headers())))
+	})
+
+	go func() {
+		defer syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+		<-readyChan // wait for server
+		addr := "127.0.0.1:" + s.GetListenPort()
+
+		send := func(head string) (int, string) {
+			conn, err := net.Dial("tcp", addr)
+			assert.Nil(t, err)
+			defer conn.Close()
+			go func() {
+				_, _ = io.WriteString(conn, head) // the server may stop reading part way
+			}()
+			resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+			assert.Nil(t, err)
+			body, _ := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			return resp.StatusCode, string(body)
+		}
+		headers := func(n int, size int) string {
+			var sb strings.Builder
+			sb.WriteString("GET / HTTP/1.1\r\n")
+			for i := range n {
+				fmt.Fprintf(&sb, "X-H%d: %s\r\n", i, strings.Repeat("v", size))
+			}
+			sb.WriteString("\r\n")
+			return sb.String()
+		}
+
+		// Within the limits
+		status, body := send(headers(50, 100))
+		assert.Equal(t, status, consts.StatusOK)
+		assert.Equal(t, body, "50 headers")
+
+		// Too many
+		status, _ = send(headers(5000, 1))
+		assert.Equal(t, status, consts.StatusRequestHeaderFieldsTooLarge)
+
+		// Too large in all
+		status, _ = send(headers(20, 500))
+		assert.Equal(t, status, consts.StatusRequestHeaderFieldsTooLarge)
+
+		// One huge header, or request line
+		status, _ = send(headers(1, 1<<20))
+		assert.Equal(t, status, consts.StatusRequestHeaderFieldsTooLarge)
+		status, _ = send("GET /" + strings.Repeat("a", 1<<20) + " HTTP/1.1\r\n\r\n")
+		assert.Equal(t, status, consts.StatusRequestHeaderFieldsTooLarge)
+	}()
+
+	_ = s.Run()
+}
@@ -0,0 +1,41 @@
+package rweb
+
+import (
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// WithAutoCert serves HTTPS on tlsAddr with certificates obtained and renewed automatically
+// from Let's Encrypt for the given hosts, cached in cacheDir.
+// Use with RunWithHttpsRedirect, so the HTTP listener (WithAddress, normally ":80") can answer
+// the CA's HTTP-01 challenges.
+// Example: WithAutoCert(":443", "/var/lib/myapp/certs", "example.com", "www.example.com")
+func WithAutoCert(tlsAddr, cacheDir string, hosts ...string) ServerOption {
+	return func(opts *ServerOptions) {
+		opts.TLS = TLSCfg{
+			UseTLS:  true,
+			TLSAddr: tlsAddr,
+			AutoCert: AutoCertCfg{
+				Enabled:  true,
+				Hosts:    hosts,
+				CacheDir: cacheDir,
+			},
+		}
+	}
+}
+
+// certManager returns the server's ACME certificate manager, creating it on first use.
+// The TLS and HTTP listeners share it, so challenges started by one can be answered by the other.
+func (s *Server) certManager() *autocert.Manager {
+	s.certMgrOnce.Do(func() {
+		cfg := s.options.TLS.AutoCert
+		s.certMgr = &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(cfg.Hosts...),
+			Email:      cfg.Email,
+		}
+		if cfg.CacheDir != "" {
+			s.certMgr.Cache = autocert.DirCache(cfg.CacheDir)
+		}
+	})
+	return s.certMgr
+}
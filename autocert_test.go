@@ -0,0 +1,41 @@
+package rweb
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rohanthewiz/assert"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+func TestAutoCert(t *testing.T) {
+	dir := t.TempDir()
+	s := NewServerWithOptions(WithAutoCert(":8443", dir, "example.com"))
+
+	assert.True(t, s.options.TLS.UseTLS)
+	assert.True(t, s.options.TLS.AutoCert.Enabled)
+	assert.Equal(t, s.options.TLS.TLSAddr, ":8443")
+
+	mgr := s.certManager()
+	assert.True(t, mgr == s.certManager()) // shared by both listeners
+	assert.Equal(t, string(mgr.Cache.(autocert.DirCache)), dir)
+
+	// Certificates are only requested for allowed hosts
+	_, err := mgr.GetCertificate(&tls.ClientHelloInfo{ServerName: "attacker.example.org"})
+	assert.NotNil(t, err)
+
+	// The HTTP listener answers challenges (refusing unknown hosts) and passes on everything else
+	handler := mgr.HTTPHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMovedPermanently)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://attacker.example.org/.well-known/acme-challenge/token", nil))
+	assert.Equal(t, rec.Code, http.StatusForbidden)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://example.com/page", nil))
+	assert.Equal(t, rec.Code, http.StatusMovedPermanently)
+}
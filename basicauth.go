@@ -0,0 +1,72 @@
+package rweb
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"strings"
+
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+// BasicAuthUserKey is the context data key under which BasicAuth stores the authenticated username
+const BasicAuthUserKey = "user"
+
+// BasicAuth returns a middleware requiring HTTP Basic authentication.
+// validate is called with the credentials from the Authorization header. Requests without valid
+// credentials get a 401 with a WWW-Authenticate challenge for realm, so browsers prompt for a login.
+// On success the username is stored under BasicAuthUserKey ("user"), e.g. ctx.Get("user").
+// validate should compare secrets in constant time - BasicAuthUsers does so for a fixed set of users.
+// Example:
+//
+//	admin := s.Group("/admin", rweb.BasicAuth(rweb.BasicAuthUsers(map[string]string{"ops": pw}), "Admin"))
+func BasicAuth(validate func(user, pass string) bool, realm string) Handler {
+	challenge := `Basic realm="` + strings.ReplaceAll(realm, `"`, `\"`) + `", charset="UTF-8"`
+
+	return func(ctx Context) error {
+		user, pass, ok := parseBasicAuth(ctx.Request().Header(consts.HeaderAuthorization))
+		if !ok || !validate(user, pass) {
+			ctx.Abort()
+			ctx.Response().SetHeader(consts.HeaderWWWAuthenticate, challenge)
+			ctx.SetStatus(consts.StatusUnauthorized)
+			return ctx.WriteText(consts.StatusTextFromCode[consts.StatusUnauthorized])
+		}
+
+		ctx.Set(BasicAuthUserKey, user)
+		return ctx.Next()
+	}
+}
+
+// BasicAuthUsers returns a BasicAuth validator accepting the given usernames and passwords.
+// Credentials are compared in constant time, so response timing doesn't reveal how much of them matched.
+func BasicAuthUsers(users map[string]string) func(user, pass string) bool {
+	// Compare fixed-length digests, so the time taken doesn't reveal the lengths either
+	type digests struct{ user, pass [sha256.Size]byte }
+	accounts := make([]digests, 0, len(users))
+	for user, pass := range users {
+		accounts = append(accounts, digests{sha256.Sum256([]byte(user)), sha256.Sum256([]byte(pass))})
+	}
+
+	return func(user, pass string) bool {
+		userSum, passSum := sha256.Sum256([]byte(user)), sha256.Sum256([]byte(pass))
+		match := 0
+		for _, acct := range accounts { // check every account, so timing doesn't reveal which user matched
+			match |= subtle.ConstantTimeCompare(userSum[:], acct.user[:]) & subtle.ConstantTimeCompare(passSum[:], acct.pass[:])
+		}
+		return match == 1
+	}
+}
+
+// parseBasicAuth extracts the credentials from a Basic Authorization header value
+func parseBasicAuth(auth string) (user, pass string, ok bool) {
+	const prefix = "Basic "
+	if len(auth) < len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+		return "", "", false
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(auth[len(prefix):]))
+	if err != nil {
+		return "", "", false
+	}
+	return strings.Cut(string(decoded), ":")
+}
@@ -0,0 +1,57 @@
+package rweb_test
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/rohanthewiz/assert"
+	"github.com/rohanthewiz/rweb"
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+func TestBasicAuth(t *testing.T) {
+	s := rweb.NewServer()
+
+	s.Get("/public", func(ctx rweb.Context) error {
+		return ctx.WriteString("public")
+	})
+
+	admin := s.Group("/admin", rweb.BasicAuth(rweb.BasicAuthUsers(map[string]string{
+		"ops":   "s3cret:with:colons",
+		"audit": "readonly",
+	}), `Admin "tools"`))
+	admin.Get("/dashboard", func(ctx rweb.Context) error {
+		return ctx.WriteString("hello " + ctx.Get(rweb.BasicAuthUserKey).(string))
+	})
+
+	basic := func(credentials string) []rweb.Header {
+		return []rweb.Header{{Key: consts.HeaderAuthorization, Value: "Basic " + base64.StdEncoding.EncodeToString([]byte(credentials))}}
+	}
+
+	// Valid credentials
+	response := s.Request(consts.MethodGet, "/admin/dashboard", basic("ops:s3cret:with:colons"), nil)
+	assert.Equal(t, response.Status(), 200)
+	assert.Equal(t, string(response.Body()), "hello ops")
+
+	response = s.Request(consts.MethodGet, "/admin/dashboard", basic("audit:readonly"), nil)
+	assert.Equal(t, string(response.Body()), "hello audit")
+
+	// Missing, wrong or malformed credentials are challenged
+	for _, headers := range [][]rweb.Header{
+		nil,
+		basic("ops:wrong"),
+		basic("audit:s3cret:with:colons"),
+		basic("nobody"),
+		{{Key: consts.HeaderAuthorization, Value: "Basic !!notbase64"}},
+		{{Key: consts.HeaderAuthorization, Value: "Bearer abc"}},
+	} {
+		response = s.Request(consts.MethodGet, "/admin/dashboard", headers, nil)
+		assert.Equal(t, response.Status(), consts.StatusUnauthorized)
+		assert.Equal(t, response.Header(consts.HeaderWWWAuthenticate), `Basic realm="Admin \"tools\"", charset="UTF-8"`)
+		assert.Equal(t, string(response.Body()), "Unauthorized")
+	}
+
+	// Routes outside the group are unaffected
+	response = s.Request(consts.MethodGet, "/public", nil, nil)
+	assert.Equal(t, string(response.Body()), "public")
+}
@@ -0,0 +1,213 @@
+package rweb
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrJSONBodyTooLarge is returned by DecodeJSONLimited for a body over the size limit (respond 413)
+	ErrJSONBodyTooLarge = errors.New("JSON body too large")
+	// ErrJSONMalformed is returned by DecodeJSONLimited for a body that isn't valid JSON for the target value
+	ErrJSONMalformed = errors.New("malformed JSON body")
+	// ErrJSONUnknownField is returned by DecodeJSONLimited, in strict mode, for a body with a field the target doesn't have
+	ErrJSONUnknownField = errors.New("unknown field in JSON body")
+)
+
+// JSONDecodeOptions configures DecodeJSONLimited
+type JSONDecodeOptions struct {
+	// DisallowUnknownFields rejects objects with fields not in the target struct, with ErrJSONUnknownField
+	DisallowUnknownFields bool
+}
+
+// BindForm fills the struct v points to from the request's form fields,
+// which may be url-encoded or multipart.
+// Fields are matched by their `form:"name"` tag, else by the field name; `form:"-"` skips a field.
+// Supported field types are strings, bools, ints, uints and floats, and slices of these
+// (filled from repeated fields). Fields tagged `validate:"required"` must be present and non-empty.
+// All problems are reported together in the returned error.
+// Example:
+//
+//	var signup struct {
+//		Email  string   `form:"email" validate:"required"`
+//		Age    int      `form:"age"`
+//		Topics []string `form:"topic"`
+//	}
+//	if err := ctx.BindForm(&signup); err != nil {
+//		return ctx.WriteError(err, consts.StatusBadRequest)
+//	}
+func (ctx *context) BindForm(v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("BindForm needs a pointer to a struct")
+	}
+
+	var errs []error
+	var missing []string
+	ctx.request.bindFormFields(rv.Elem(), &errs, &missing)
+
+	if len(missing) > 0 {
+		errs = append(errs, fmt.Errorf("missing required form fields: %s", strings.Join(missing, ", ")))
+	}
+	return errors.Join(errs...)
+}
+
+// DecodeJSONLimited decodes the request's JSON body into v, refusing bodies over maxBytes.
+// The errors returned wrap one of ErrJSONBodyTooLarge, ErrJSONMalformed or ErrJSONUnknownField
+// (with the details), so they can be told apart with errors.Is, e.g. to respond 413 or 400.
+// An empty body, or anything after the JSON value, is malformed.
+// Example:
+//
+//	var order Order
+//	err := ctx.DecodeJSONLimited(&order, 64<<10, rweb.JSONDecodeOptions{DisallowUnknownFields: true})
+//	if errors.Is(err, rweb.ErrJSONBodyTooLarge) {
+//		return ctx.WriteError(err, consts.StatusRequestEntityTooLarge)
+//	} else if err != nil {
+//		return ctx.WriteError(err, consts.StatusBadRequest)
+//	}
+func (ctx *context) DecodeJSONLimited(v any, maxBytes int64, opts ...JSONDecodeOptions) error {
+	body := ctx.request.body
+	if int64(len(body)) > maxBytes {
+		return fmt.Errorf("%w: %d bytes, the limit is %d", ErrJSONBodyTooLarge, len(body), maxBytes)
+	}
+
+	dec := json.NewDecoder(io.LimitReader(bytes.NewReader(body), maxBytes))
+	if len(opts) > 0 && opts[0].DisallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+
+	if err := dec.Decode(v); err != nil {
+		if errors.Is(err, io.EOF) {
+			return fmt.Errorf("%w: empty body", ErrJSONMalformed)
+		}
+		// The json package has no distinct error type for unknown fields
+		if strings.HasPrefix(err.Error(), "json: unknown field ") {
+			return fmt.Errorf("%w: %s", ErrJSONUnknownField, strings.TrimPrefix(err.Error(), "json: unknown field "))
+		}
+		return fmt.Errorf("%w: %w", ErrJSONMalformed, err)
+	}
+
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%w: unexpected data after the JSON value", ErrJSONMalformed)
+	}
+	return nil
+}
+
+// formValues returns all values of the named form field
+func (req *request) formValues(key string) []string {
+	if req.multipartForm != nil {
+		if values := req.multipartForm.Value[key]; len(values) > 0 {
+			return values
+		}
+	}
+
+	multi := req.PostArgs().PeekMulti(key)
+	if len(multi) == 0 {
+		return nil
+	}
+	values := make([]string, len(multi))
+	for i, val := range multi {
+		values[i] = string(val)
+	}
+	return values
+}
+
+func (req *request) bindFormFields(sv reflect.Value, errs *[]error, missing *[]string) {
+	st := sv.Type()
+	for i := range st.NumField() {
+		field := st.Field(i)
+		fv := sv.Field(i)
+
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			req.bindFormFields(fv, errs, missing)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		name := field.Tag.Get("form")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		values := req.formValues(name)
+		if len(values) == 0 || (len(values) == 1 && values[0] == "") {
+			if field.Tag.Get("validate") == "required" {
+				*missing = append(*missing, name)
+			}
+			continue
+		}
+
+		if err := setFormField(fv, values); err != nil {
+			*errs = append(*errs, fmt.Errorf("form field %q: %w", name, err))
+		}
+	}
+}
+
+// setFormField converts values to fv's type and sets it
+func setFormField(fv reflect.Value, values []string) error {
+	if fv.Kind() == reflect.Slice {
+		slice := reflect.MakeSlice(fv.Type(), len(values), len(values))
+		for i, val := range values {
+			if err := setFormValue(slice.Index(i), val); err != nil {
+				return err
+			}
+		}
+		fv.Set(slice)
+		return nil
+	}
+	return setFormValue(fv, values[0])
+}
+
+func setFormValue(fv reflect.Value, val string) error {
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(val)
+
+	case reflect.Bool:
+		if val == "on" { // an HTML checkbox without a value attribute
+			fv.SetBool(true)
+			return nil
+		}
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(val, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(val, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(n)
+
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(val, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+
+	default:
+		return fmt.Errorf("unsupported field type %s", fv.Type())
+	}
+	return nil
+}
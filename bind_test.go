@@ -0,0 +1,171 @@
+package rweb_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/rohanthewiz/assert"
+	"github.com/rohanthewiz/rweb"
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+type Audit struct {
+	Source string `form:"source"`
+}
+
+type signupForm struct {
+	Audit
+	Email    string   `form:"email" validate:"required"`
+	Name     string   `form:"name" validate:"required"`
+	Age      int      `form:"age"`
+	Score    float64  `form:"score"`
+	Verified bool     `form:"verified"`
+	Topics   []string `form:"topic"`
+	Ids      []uint16 `form:"id"`
+	Internal string   `form:"-"`
+	Nickname string
+}
+
+func TestBindForm(t *testing.T) {
+	readyChan := make(chan struct{}, 1)
+	s := rweb.NewServer(rweb.ServerOptions{ReadyChan: readyChan, Address: "localhost:"})
+
+	s.Post("/signup", func(ctx rweb.Context) error {
+		var form signupForm
+		if err := ctx.BindForm(&form); err != nil {
+			return ctx.WriteError(err, consts.StatusBadRequest)
+		}
+		return ctx.WriteString(fmt.Sprintf("%+v", form))
+	})
+
+	go func() {
+		defer syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+		<-readyChan // wait for server
+		url := fmt.Sprintf("http://127.0.0.1:%s/signup", s.GetListenPort())
+
+		post := func(contentType string, body io.Reader) (int, string) {
+			resp, err := http.Post(url, contentType, body)
+			assert.Nil(t, err)
+			defer resp.Body.Close()
+			respBody, _ := io.ReadAll(resp.Body)
+			return resp.StatusCode, string(respBody)
+		}
+
+		const expected = "{Audit:{Source:web} Email:a@b.com Name:Ann Age:42 Score:9.5 Verified:true " +
+			"Topics:[go web] Ids:[1 2] Internal: Nickname:annie}"
+
+		// URL-encoded
+		status, body := post(string(consts.BytFormData), strings.NewReader(
+			"email=a%40b.com&name=Ann&age=42&score=9.5&verified=on&topic=go&topic=web&id=1&id=2"+
+				"&source=web&-=x&Internal=x&Nickname=annie"))
+		assert.Equal(t, status, 200)
+		assert.Equal(t, body, expected)
+
+		// Multipart
+		buf := &bytes.Buffer{}
+		writer := multipart.NewWriter(buf)
+		for _, kv := range [][2]string{{"email", "a@b.com"}, {"name", "Ann"}, {"age", "42"}, {"score", "9.5"},
+			{"verified", "true"}, {"topic", "go"}, {"topic", "web"}, {"id", "1"}, {"id", "2"},
+			{"source", "web"}, {"Nickname", "annie"}} {
+			assert.Nil(t, writer.WriteField(kv[0], kv[1]))
+		}
+		assert.Nil(t, writer.Close())
+		status, body = post(writer.FormDataContentType(), buf)
+		assert.Equal(t, status, 200)
+		assert.Equal(t, body, expected)
+
+		// All problems are reported
+		status, body = post(string(consts.BytFormData), strings.NewReader("name=&age=old&id=70000"))
+		assert.Equal(t, status, consts.StatusBadRequest)
+		assert.True(t, strings.Contains(body, `form field "age"`))
+		assert.True(t, strings.Contains(body, `form field "id"`))
+		assert.True(t, strings.Contains(body, "missing required form fields: email, name"))
+	}()
+
+	_ = s.Run()
+
+	// Only structs can be bound
+	s2 := rweb.NewServer()
+	s2.Post("/", func(ctx rweb.Context) error {
+		var notStruct string
+		assert.NotNil(t, ctx.BindForm(&notStruct))
+		assert.NotNil(t, ctx.BindForm(signupForm{}))
+		return nil
+	})
+	s2.Request(consts.MethodPost, "/", nil, nil)
+}
+
+func TestDecodeJSONLimited(t *testing.T) {
+	readyChan := make(chan struct{}, 1)
+	s := rweb.NewServer(rweb.ServerOptions{ReadyChan: readyChan, Address: "localhost:"})
+
+	type order struct {
+		Item string `json:"item"`
+		Qty  int    `json:"qty"`
+	}
+	decode := func(opts ...rweb.JSONDecodeOptions) rweb.Handler {
+		return func(ctx rweb.Context) error {
+			var o order
+			err := ctx.DecodeJSONLimited(&o, 32, opts...)
+			switch {
+			case errors.Is(err, rweb.ErrJSONBodyTooLarge):
+				return ctx.WriteError(err, consts.StatusRequestEntityTooLarge)
+			case errors.Is(err, rweb.ErrJSONUnknownField):
+				return ctx.WriteError(err, http.StatusUnprocessableEntity)
+			case errors.Is(err, rweb.ErrJSONMalformed):
+				return ctx.WriteError(err, consts.StatusBadRequest)
+			case err != nil:
+				return err
+			}
+			return ctx.WriteString(fmt.Sprintf("%s x%d", o.Item, o.Qty))
+		}
+	}
+	s.Post("/orders", decode())
+	s.Post("/orders/strict", decode(rweb.JSONDecodeOptions{DisallowUnknownFields: true}))
+
+	go func() {
+		defer syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+		<-readyChan // wait for server
+
+		tests := []struct {
+			path   string
+			body   string
+			status int
+			resp   string
+		}{
+			{"/orders", `{"item":"pen","qty":2}`, consts.StatusOK, "pen x2"},
+			{"/orders", `{"item":"pen","note":"x"}`, consts.StatusOK, "pen x0"},
+			{"/orders/strict", `{"item":"pen","qty":2}`, consts.StatusOK, "pen x2"},
+			{"/orders/strict", `{"item":"pen","note":"x"}`, http.StatusUnprocessableEntity, `unknown field in JSON body: "note"`},
+			{"/orders", `{"item":"a very long item name indeed","qty":2}`, consts.StatusRequestEntityTooLarge,
+				"JSON body too large: 47 bytes, the limit is 32"},
+			{"/orders", `{"item":"pen",`, consts.StatusBadRequest, "malformed JSON body: unexpected EOF"},
+			{"/orders", `{"item":"pen","qty":"2"}`, consts.StatusBadRequest, ""},
+			{"/orders", `{"item":"pen"} {}`, consts.StatusBadRequest, "malformed JSON body: unexpected data after the JSON value"},
+			{"/orders", ``, consts.StatusBadRequest, "malformed JSON body: empty body"},
+		}
+
+		for _, tt := range tests {
+			resp, err := http.Post("http://localhost:"+s.GetListenPort()+tt.path, consts.MIMEJSON, strings.NewReader(tt.body))
+			assert.Nil(t, err)
+			body, _ := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			assert.Equal(t, resp.StatusCode, tt.status)
+			if tt.resp != "" {
+				assert.Equal(t, string(body), tt.resp)
+			}
+		}
+	}()
+
+	err := s.Run()
+	assert.Nil(t, err)
+}
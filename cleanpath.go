@@ -0,0 +1,109 @@
+package rweb
+
+import (
+	"path"
+	"strings"
+
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+// CleanPathOptions configures the CleanPath middleware
+type CleanPathOptions struct {
+	// Redirect sends the client to the canonical path (301 for GET and HEAD, 308 otherwise,
+	// so the method and body are kept), rather than serving the request under the cleaned path.
+	Redirect bool
+}
+
+// CleanPath returns a middleware that normalizes the request path before routing:
+// repeated slashes are collapsed, "." and ".." segments are resolved (never above the root),
+// including their percent-encoded forms, and trailing dots are trimmed from segments.
+// A trailing slash is kept. This keeps routing consistent,
+// and stops paths like "/public/../admin" slipping past path-prefix based checks.
+// It must run before the route lookup, so register it with Server.Use.
+// URLOptions.CleanPath does the same for every request, without the middleware.
+// Example:
+//
+//	s.Use(rweb.CleanPath())
+//	// or, to have clients use the canonical URL:
+//	s.Use(rweb.CleanPath(rweb.CleanPathOptions{Redirect: true}))
+func CleanPath(opts ...CleanPathOptions) Handler {
+	var opt CleanPathOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	return func(ctx Context) error {
+		base := baseContext(ctx)
+		if base == nil {
+			return ctx.Next()
+		}
+
+		cleaned := cleanPath(base.request.path)
+		if cleaned == base.request.path {
+			return ctx.Next()
+		}
+
+		if opt.Redirect {
+			location := cleaned
+			if base.request.query != "" {
+				location += "?" + base.request.query
+			}
+			status := consts.StatusMovedPermanently
+			if method := base.request.method; method != consts.MethodGet && method != consts.MethodHead {
+				status = consts.StatusPermanentRedirect
+			}
+			return ctx.Redirect(status, location)
+		}
+
+		base.request.path = cleaned
+		return ctx.Next()
+	}
+}
+
+// cleanPathRedirect returns where to redirect a request to, for the canonical form of its path,
+// if the URLOptions ask for such redirects and the path (already cleaned, in ctx) isn't canonical as sent
+func (s *Server) cleanPathRedirect(ctx *context, url string) (location string, ok bool) {
+	if !s.options.URLOptions.CleanPath || !s.options.URLOptions.RedirectCleanPath {
+		return "", false
+	}
+
+	rawOpts := s.options.URLOptions
+	rawOpts.CleanPath = false
+	if _, _, raw, _ := parseURL(url, rawOpts); raw == ctx.path {
+		return "", false
+	}
+
+	location = ctx.path
+	if ctx.query != "" {
+		location += "?" + ctx.query
+	}
+	return location, true
+}
+
+// cleanPath returns the canonical form of the request path p
+func cleanPath(p string) string {
+	// Encoded dots are equivalent to plain ones, so must be resolved too
+	if strings.Contains(p, "%") {
+		p = strings.NewReplacer("%2e", ".", "%2E", ".").Replace(p)
+	}
+
+	trailingSlash := len(p) > 1 && strings.HasSuffix(p, "/")
+
+	cleaned := path.Clean("/" + p) // rooting the path means ".." can't climb above it
+
+	if strings.Contains(cleaned, "./") || strings.HasSuffix(cleaned, ".") {
+		segments := strings.Split(cleaned, "/")
+		kept := segments[:0]
+		for _, segment := range segments {
+			if segment = strings.TrimRight(segment, "."); segment != "" {
+				kept = append(kept, segment)
+			}
+		}
+		cleaned = "/" + strings.Join(kept, "/")
+	}
+
+	if trailingSlash && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned
+}
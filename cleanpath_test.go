@@ -0,0 +1,127 @@
+package rweb_test
+
+import (
+	"testing"
+
+	"github.com/rohanthewiz/assert"
+	"github.com/rohanthewiz/rweb"
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+func TestCleanPath(t *testing.T) {
+	s := rweb.NewServer(rweb.ServerOptions{URLOptions: rweb.URLOptions{KeepTrailingSlashes: true}})
+	s.Use(rweb.CleanPath())
+
+	handler := func(ctx rweb.Context) error {
+		return ctx.WriteString(ctx.Request().Path())
+	}
+	s.Get("/", handler)
+	s.Get("/admin/users", handler)
+	s.Get("/admin/users/", handler)
+	s.Get("/files/*path", handler)
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"/admin/users", "/admin/users"},
+		{"//admin///users", "/admin/users"},
+		{"/admin/./users", "/admin/users"},
+		{"/public/../admin/users", "/admin/users"},
+		{"/../../admin/users", "/admin/users"}, // can't climb above the root
+		{"/public/%2e%2E/admin/users", "/admin/users"},
+		{"/admin/users./", "/admin/users/"},
+		{"/admin/users.", "/admin/users"},
+		{"/admin//users/", "/admin/users/"},
+		{"/..", "/"},
+	}
+
+	for _, tt := range tests {
+		response := s.Request(consts.MethodGet, tt.path, nil, nil)
+		assert.Equal(t, response.Status(), 200)
+		assert.Equal(t, string(response.Body()), tt.expected)
+	}
+
+	// Resolved before routing, so this no longer matches the wildcard route
+	response := s.Request(consts.MethodGet, "/files/a/../../../etc/passwd", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusNotFound)
+	response = s.Request(consts.MethodGet, "/files/a/../b", nil, nil)
+	assert.Equal(t, string(response.Body()), "/files/b")
+}
+
+func TestCleanPathRedirect(t *testing.T) {
+	s := rweb.NewServer()
+	s.Use(rweb.CleanPath(rweb.CleanPathOptions{Redirect: true}))
+
+	s.Get("/admin/users", func(ctx rweb.Context) error {
+		return ctx.WriteString("users")
+	})
+	s.Post("/admin/users", func(ctx rweb.Context) error {
+		return ctx.WriteString("created")
+	})
+
+	response := s.Request(consts.MethodGet, "/admin/users", nil, nil)
+	assert.Equal(t, response.Status(), 200)
+	assert.Equal(t, string(response.Body()), "users")
+
+	response = s.Request(consts.MethodGet, "/public/..//admin/./users?page=2", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusMovedPermanently)
+	assert.Equal(t, response.Header(consts.HeaderLocation), "/admin/users?page=2")
+
+	response = s.Request(consts.MethodPost, "//admin/users", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusPermanentRedirect)
+	assert.Equal(t, response.Header(consts.HeaderLocation), "/admin/users")
+}
+
+func TestCleanPathOption(t *testing.T) {
+	s := rweb.NewServer(rweb.ServerOptions{URLOptions: rweb.URLOptions{CleanPath: true}})
+
+	var seen string
+	s.PreRoute(func(ctx rweb.Context) {
+		seen = ctx.Request().Path()
+	})
+	s.Get("/api/users", func(ctx rweb.Context) error {
+		return ctx.WriteString(ctx.Request().Path())
+	})
+
+	for _, path := range []string{"/api/users", "/api//users", "/api/./users", "/api/x/../users/", "//api/users//"} {
+		response := s.Request(consts.MethodGet, path, nil, nil)
+		assert.Equal(t, response.Status(), consts.StatusOK)
+		assert.Equal(t, string(response.Body()), "/api/users")
+		assert.Equal(t, seen, "/api/users") // cleaned before anything else sees it
+	}
+
+	// Off by default
+	s2 := rweb.NewServer()
+	s2.Get("/api/users", func(ctx rweb.Context) error {
+		return nil
+	})
+	response := s2.Request(consts.MethodGet, "/api//users", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusNotFound)
+}
+
+func TestCleanPathOptionRedirect(t *testing.T) {
+	s := rweb.NewServerWithOptions(rweb.WithCleanPath(true))
+
+	s.Get("/api/users", func(ctx rweb.Context) error {
+		return ctx.WriteString("users")
+	})
+	s.Post("/api/users", func(ctx rweb.Context) error {
+		return ctx.WriteString("created")
+	})
+
+	response := s.Request(consts.MethodGet, "/api/./users?page=2", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusMovedPermanently)
+	assert.Equal(t, response.Header("Location"), "/api/users?page=2")
+	assert.Equal(t, len(response.Body()), 0)
+
+	response = s.Request(consts.MethodPost, "/api//users", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusPermanentRedirect)
+	assert.Equal(t, response.Header("Location"), "/api/users")
+
+	// Canonical paths, and those only differing by a trailing slash that's removed anyway, are served
+	response = s.Request(consts.MethodGet, "/api/users", nil, nil)
+	assert.Equal(t, string(response.Body()), "users")
+	response = s.Request(consts.MethodGet, "/api/users/", nil, nil)
+	assert.Equal(t, string(response.Body()), "users")
+}
@@ -0,0 +1,100 @@
+package rweb
+
+import (
+	"log"
+	"net"
+	"net/netip"
+	"strings"
+
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+// parseTrustedProxies parses the TrustedProxies option into networks, skipping invalid entries
+func parseTrustedProxies(proxies []string) (nets []netip.Prefix) {
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if prefix, err := netip.ParsePrefix(proxy); err == nil {
+			nets = append(nets, prefix.Masked())
+		} else if addr, err := netip.ParseAddr(proxy); err == nil {
+			nets = append(nets, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+		} else {
+			log.Printf("[rweb] ignoring invalid trusted proxy %q\n", proxy)
+		}
+	}
+	return nets
+}
+
+// isTrustedProxy reports whether ip is the address of one of the server's trusted proxies
+func (s *Server) isTrustedProxy(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range s.trustedNets {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the IP address of the client.
+// This is the connection's remote address unless the connection comes from one of
+// ServerOptions.TrustedProxies, in which case the proxy headers are used:
+// X-Forwarded-For is read from the right (nearest) hop, skipping trusted proxies,
+// so the result is the first address not vouched for by a trusted proxy - entries further left
+// could have been sent by the client, so are ignored. Failing that, X-Real-IP is used.
+// Returns "" if there is no connection (synthetic requests).
+func (ctx *context) ClientIP() string {
+	remote := connRemoteIP(ctx.conn)
+	if ctx.conn == nil && ctx.httpReq != nil { // served through ServeHTTP
+		remote = addrHost(ctx.httpReq.RemoteAddr)
+	}
+	if remote == "" || len(ctx.server.trustedNets) == 0 || !ctx.server.isTrustedProxy(remote) {
+		return remote
+	}
+
+	// Gather the hops of all X-Forwarded-For headers, in order
+	var hops []string
+	var realIP string
+	for _, hdr := range ctx.request.headers {
+		if strings.EqualFold(hdr.Key, consts.HeaderXForwardedFor) {
+			for _, hop := range strings.Split(hdr.Value, ",") {
+				hops = append(hops, strings.TrimSpace(hop))
+			}
+		} else if strings.EqualFold(hdr.Key, consts.HeaderXRealIP) {
+			realIP = strings.TrimSpace(hdr.Value)
+		}
+	}
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		if _, err := netip.ParseAddr(hops[i]); err != nil {
+			break // garbage - can't trust anything beyond it
+		}
+		if i == 0 || !ctx.server.isTrustedProxy(hops[i]) {
+			return hops[i]
+		}
+	}
+
+	if _, err := netip.ParseAddr(realIP); err == nil {
+		return realIP
+	}
+	return remote
+}
+
+// connRemoteIP returns the IP address of the connection's peer, or "" if there is no connection
+func connRemoteIP(conn net.Conn) string {
+	if conn == nil {
+		return ""
+	}
+	return addrHost(conn.RemoteAddr().String())
+}
+
+// addrHost returns the host of a "host:port" address
+func addrHost(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
@@ -0,0 +1,101 @@
+package rweb_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"syscall"
+	"testing"
+
+	"github.com/rohanthewiz/assert"
+	"github.com/rohanthewiz/rweb"
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+func TestClientIP(t *testing.T) {
+	// A server behind proxies (the test client on localhost plays the nearest one)
+	readyChan := make(chan struct{}, 1)
+	s := rweb.NewServerWithOptions(
+		rweb.WithAddress("127.0.0.1:"),
+		rweb.WithReadyChan(readyChan),
+		rweb.WithTrustedProxies("127.0.0.1", "10.0.0.0/8", "not-an-ip"),
+	)
+	s.Get("/ip", func(ctx rweb.Context) error {
+		return ctx.WriteString(ctx.ClientIP())
+	})
+
+	// And one trusting no proxies
+	directReadyChan := make(chan struct{}, 1)
+	direct := rweb.NewServerWithOptions(rweb.WithAddress("127.0.0.1:"), rweb.WithReadyChan(directReadyChan))
+	direct.Get("/ip", func(ctx rweb.Context) error {
+		return ctx.WriteString(ctx.ClientIP())
+	})
+	go func() { _ = direct.Run() }()
+	<-directReadyChan
+
+	getIP := func(port string, headers map[string][]string) string {
+		req, err := http.NewRequest(consts.MethodGet, fmt.Sprintf("http://127.0.0.1:%s/ip", port), nil)
+		assert.Nil(t, err)
+		for key, values := range headers {
+			for _, value := range values {
+				req.Header.Add(key, value)
+			}
+		}
+		resp, err := http.DefaultClient.Do(req)
+		assert.Nil(t, err)
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	go func() {
+		defer syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+		<-readyChan // wait for server
+		port := s.GetListenPort()
+
+		// No proxy headers
+		assert.Equal(t, getIP(port, nil), "127.0.0.1")
+
+		// The nearest untrusted hop is the client; anything left of it could be spoofed
+		assert.Equal(t, getIP(port, map[string][]string{
+			"X-Forwarded-For": {"6.6.6.6, 203.0.113.7, 10.1.2.3"},
+		}), "203.0.113.7")
+
+		// Hops can be spread over several headers
+		assert.Equal(t, getIP(port, map[string][]string{
+			"X-Forwarded-For": {"6.6.6.6", "2001:db8::1,10.1.2.3"},
+		}), "2001:db8::1")
+
+		// All hops trusted - the leftmost is the client
+		assert.Equal(t, getIP(port, map[string][]string{
+			"X-Forwarded-For": {"10.9.9.9, 10.1.2.3"},
+		}), "10.9.9.9")
+
+		// Garbage isn't returned
+		assert.Equal(t, getIP(port, map[string][]string{
+			"X-Forwarded-For": {"203.0.113.7, <script>"},
+		}), "127.0.0.1")
+
+		// X-Real-IP is used when there's no X-Forwarded-For
+		assert.Equal(t, getIP(port, map[string][]string{
+			"X-Real-Ip": {"198.51.100.4"},
+		}), "198.51.100.4")
+
+		// Without trusted proxies, the headers are ignored
+		assert.Equal(t, getIP(direct.GetListenPort(), map[string][]string{
+			"X-Forwarded-For": {"203.0.113.7"},
+			"X-Real-Ip":       {"198.51.100.4"},
+		}), "127.0.0.1")
+	}()
+
+	err := s.Run()
+	assert.Nil(t, err)
+
+	// No connection for synthetic requests
+	s2 := rweb.NewServer()
+	s2.Get("/ip", func(ctx rweb.Context) error {
+		return ctx.WriteString(ctx.ClientIP())
+	})
+	assert.Equal(t, string(s2.Request(consts.MethodGet, "/ip", nil, nil).Body()), "")
+}
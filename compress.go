@@ -0,0 +1,177 @@
+package rweb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+// compressibleTypes are the content types (or type prefixes) worth compressing, by default.
+// Already compressed formats like images, video and archives are left alone.
+var compressibleTypes = []string{
+	"text/*",
+	consts.MIMEJSON,
+	consts.MIMEXML,
+	"application/javascript",
+	"image/svg+xml",
+}
+
+// CompressConfig configures the Compress middleware
+type CompressConfig struct {
+	// Types lists the content types to compress - exact types (e.g. "application/json"),
+	// or whole families (e.g. "text/*"). Defaults to text/*, JSON, XML, JavaScript and SVG.
+	// Event streams (SSE) are never compressed, as they must reach the client event by event.
+	Types []string
+	// MinLength is the smallest body compressed (e.g. 1024). Compressing tiny bodies costs more than it saves.
+	MinLength int
+	// Level is the gzip compression level, from gzip.BestSpeed (1) to gzip.BestCompression (9).
+	// Defaults (0) to gzip.DefaultCompression.
+	Level int
+}
+
+// gzipWriterPools holds a pool of gzip writers per compression level
+var gzipWriterPools sync.Map // level -> *sync.Pool
+
+// gzipWriterPool returns the pool of gzip writers for the compression level
+func gzipWriterPool(level int) *sync.Pool {
+	if pool, ok := gzipWriterPools.Load(level); ok {
+		return pool.(*sync.Pool)
+	}
+	pool, _ := gzipWriterPools.LoadOrStore(level, &sync.Pool{
+		New: func() any {
+			zw, _ := gzip.NewWriterLevel(nil, level) // the level is checked up front
+			return zw
+		},
+	})
+	return pool.(*sync.Pool)
+}
+
+// Compress returns a middleware that gzips response bodies of at least minLength bytes
+// for clients that accept gzip, using the default content types and compression level.
+// See CompressWithConfig.
+// Example:
+//
+//	s.Use(rweb.Compress(1024))
+func Compress(minLength int) Handler {
+	return CompressWithConfig(CompressConfig{MinLength: minLength})
+}
+
+// CompressWithConfig returns a middleware that gzips response bodies of the configured content types,
+// of at least MinLength bytes, for clients that accept gzip.
+// The size of the body is only known once the handler has produced it,
+// so the decision is made after ctx.Next(), looking at the buffered body and its content type.
+// Streamed responses (SSE, StreamJSON, Flush), WebSocket upgrades,
+// and responses that already have a Content-Encoding are passed through untouched.
+// Example:
+//
+//	s.Use(rweb.CompressWithConfig(rweb.CompressConfig{
+//		Types:     []string{"application/json", "text/*"},
+//		MinLength: 512,
+//		Level:     gzip.BestSpeed,
+//	}))
+func CompressWithConfig(cfg CompressConfig) Handler {
+	types := cfg.Types
+	if len(types) == 0 {
+		types = compressibleTypes
+	}
+	level := cfg.Level
+	if level == 0 || level < gzip.HuffmanOnly || level > gzip.BestCompression {
+		level = gzip.DefaultCompression
+	}
+	pool := gzipWriterPool(level)
+
+	return func(ctx Context) error {
+		if err := ctx.Next(); err != nil {
+			return err
+		}
+
+		if !acceptsGzip(ctx.Request().Header(consts.HeaderAcceptEncoding)) {
+			return nil
+		}
+
+		if base := baseContext(ctx); base != nil {
+			if base.wsUpgraded || base.sseEventsChan != nil || base.streamFn != nil || base.response.stream != nil {
+				return nil
+			}
+		}
+
+		res := ctx.Response()
+		body := res.Body()
+		if len(body) < cfg.MinLength || res.Header(consts.HeaderContentEncoding) != "" ||
+			!isCompressibleType(res.Header(consts.HeaderContentType), types) {
+			return nil
+		}
+
+		var buf bytes.Buffer
+		zw := pool.Get().(*gzip.Writer)
+		zw.Reset(&buf)
+		_, err := zw.Write(body)
+		if err == nil {
+			err = zw.Close()
+		}
+		pool.Put(zw)
+		if err != nil || buf.Len() >= len(body) {
+			return nil // not worth it - send as is
+		}
+
+		res.SetBody(buf.Bytes())
+		res.SetHeader(consts.HeaderContentEncoding, "gzip")
+		addVary(res, consts.HeaderAcceptEncoding)
+		// Content-Length is written from the final body, so drop any length the handler set
+		res.DelHeader(consts.HeaderContentLength)
+		return nil
+	}
+}
+
+// addVary adds token to the response's Vary header, keeping whatever else the response varies by
+func addVary(res Response, token string) {
+	for _, value := range res.Headers(consts.HeaderVary) {
+		if headerHasToken(value, token) || headerHasToken(value, "*") {
+			return
+		}
+	}
+	if vary := res.Header(consts.HeaderVary); vary != "" {
+		token = vary + ", " + token
+	}
+	res.SetHeader(consts.HeaderVary, token)
+}
+
+// acceptsGzip reports whether an Accept-Encoding header value allows gzip
+func acceptsGzip(acceptEncoding string) bool {
+	for _, enc := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(enc, ";")
+		name = strings.TrimSpace(name)
+		if name != "gzip" && name != "*" {
+			continue
+		}
+		// gzip;q=0 means "not acceptable"
+		if qv, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if q, err := strconv.ParseFloat(qv, 64); err == nil && q == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// isCompressibleType reports whether the content type is one of types, to be compressed.
+// Types ending in "/*" (or just "/") match the whole family. Event streams never match.
+func isCompressibleType(contentType string, types []string) bool {
+	mimeType, _, _ := strings.Cut(contentType, ";")
+	mimeType = strings.ToLower(strings.TrimSpace(mimeType))
+	if mimeType == consts.MIMETextEventStream {
+		return false
+	}
+	for _, t := range types {
+		t = strings.ToLower(strings.TrimSuffix(t, "*"))
+		if strings.HasSuffix(t, "/") && strings.HasPrefix(mimeType, t) || mimeType == t {
+			return true
+		}
+	}
+	return false
+}
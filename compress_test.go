@@ -0,0 +1,164 @@
+package rweb_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strconv"
+	"testing"
+
+	"github.com/rohanthewiz/assert"
+	"github.com/rohanthewiz/rweb"
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+func TestCompress(t *testing.T) {
+	s := rweb.NewServer()
+	s.Use(rweb.Compress(256))
+
+	small := []byte(`{"ok":true}`)
+	large := bytes.Repeat([]byte(`{"name":"compress me","ok":true},`), 50)
+
+	s.Get("/small", func(ctx rweb.Context) error {
+		ctx.Response().SetHeader(consts.HeaderContentType, consts.MIMEJSON)
+		return ctx.Bytes(small)
+	})
+
+	s.Get("/large", func(ctx rweb.Context) error {
+		ctx.Response().SetHeader(consts.HeaderContentType, consts.MIMEJSON)
+		// A length set up front is stale once the body is compressed
+		ctx.Response().SetHeader(consts.HeaderContentLength, strconv.Itoa(len(large)))
+		return ctx.Bytes(large)
+	})
+
+	s.Get("/origin", func(ctx rweb.Context) error {
+		ctx.Response().SetHeader(consts.HeaderContentType, consts.MIMEJSON)
+		ctx.Response().SetHeader(consts.HeaderVary, "Origin")
+		return ctx.Bytes(large)
+	})
+
+	s.Get("/varied", func(ctx rweb.Context) error {
+		ctx.Response().SetHeader(consts.HeaderContentType, consts.MIMEJSON)
+		ctx.Response().SetHeader(consts.HeaderVary, "Origin, accept-encoding")
+		return ctx.Bytes(large)
+	})
+
+	s.Get("/image", func(ctx rweb.Context) error {
+		ctx.Response().SetHeader(consts.HeaderContentType, "image/png")
+		return ctx.Bytes(large)
+	})
+
+	acceptGzip := []rweb.Header{{Key: consts.HeaderAcceptEncoding, Value: "gzip, deflate, br"}}
+
+	t.Run("below threshold is not compressed", func(t *testing.T) {
+		response := s.Request(consts.MethodGet, "/small", acceptGzip, nil)
+		assert.Equal(t, response.Status(), 200)
+		assert.Equal(t, response.Header(consts.HeaderContentEncoding), "")
+		assert.DeepEqual(t, response.Body(), small)
+	})
+
+	t.Run("above threshold is compressed", func(t *testing.T) {
+		response := s.Request(consts.MethodGet, "/large", acceptGzip, nil)
+		assert.Equal(t, response.Status(), 200)
+		assert.Equal(t, response.Header(consts.HeaderContentEncoding), "gzip")
+		assert.Equal(t, response.Header(consts.HeaderVary), consts.HeaderAcceptEncoding)
+		assert.Equal(t, response.Header(consts.HeaderContentLength), "")
+		assert.True(t, len(response.Body()) < len(large))
+
+		reader, err := gzip.NewReader(bytes.NewReader(response.Body()))
+		assert.Nil(t, err)
+		decompressed, err := io.ReadAll(reader)
+		assert.Nil(t, err)
+		assert.DeepEqual(t, decompressed, large)
+	})
+
+	t.Run("existing Vary is kept", func(t *testing.T) {
+		response := s.Request(consts.MethodGet, "/origin", acceptGzip, nil)
+		assert.Equal(t, response.Header(consts.HeaderContentEncoding), "gzip")
+		assert.Equal(t, response.Header(consts.HeaderVary), "Origin, Accept-Encoding")
+
+		response = s.Request(consts.MethodGet, "/varied", acceptGzip, nil)
+		assert.Equal(t, response.Header(consts.HeaderContentEncoding), "gzip")
+		assert.Equal(t, response.Header(consts.HeaderVary), "Origin, accept-encoding")
+	})
+
+	t.Run("client without gzip support", func(t *testing.T) {
+		response := s.Request(consts.MethodGet, "/large", nil, nil)
+		assert.Equal(t, response.Header(consts.HeaderContentEncoding), "")
+		assert.DeepEqual(t, response.Body(), large)
+
+		response = s.Request(consts.MethodGet, "/large",
+			[]rweb.Header{{Key: consts.HeaderAcceptEncoding, Value: "br, gzip;q=0"}}, nil)
+		assert.Equal(t, response.Header(consts.HeaderContentEncoding), "")
+	})
+
+	t.Run("incompressible content type", func(t *testing.T) {
+		response := s.Request(consts.MethodGet, "/image", acceptGzip, nil)
+		assert.Equal(t, response.Header(consts.HeaderContentEncoding), "")
+		assert.DeepEqual(t, response.Body(), large)
+	})
+}
+
+func TestCompressWithConfig(t *testing.T) {
+	large := bytes.Repeat([]byte(`{"name":"compress me","ok":true,"n":12345},`), 100)
+
+	newServer := func(cfg rweb.CompressConfig) *rweb.Server {
+		s := rweb.NewServer()
+		s.Use(rweb.CompressWithConfig(cfg))
+		for path, contentType := range map[string]string{
+			"/json": consts.MIMEJSON, "/html": "text/html; charset=utf-8",
+			"/events": consts.MIMETextEventStream, "/font": "font/woff2",
+		} {
+			s.Get(path, func(ctx rweb.Context) error {
+				ctx.Response().SetHeader(consts.HeaderContentType, contentType)
+				return ctx.Bytes(large)
+			})
+		}
+		s.Get("/encoded", func(ctx rweb.Context) error {
+			ctx.Response().SetHeader(consts.HeaderContentType, consts.MIMEJSON)
+			ctx.Response().SetHeader(consts.HeaderContentEncoding, "br")
+			return ctx.Bytes(large)
+		})
+		return s
+	}
+	acceptGzip := []rweb.Header{{Key: consts.HeaderAcceptEncoding, Value: "gzip"}}
+	encoding := func(s *rweb.Server, path string) string {
+		return s.Request(consts.MethodGet, path, acceptGzip, nil).Header(consts.HeaderContentEncoding)
+	}
+
+	// Defaults - text/*, JSON and XML, but never event streams
+	s := newServer(rweb.CompressConfig{})
+	assert.Equal(t, encoding(s, "/json"), "gzip")
+	assert.Equal(t, encoding(s, "/html"), "gzip")
+	assert.Equal(t, encoding(s, "/events"), "")
+	assert.Equal(t, encoding(s, "/font"), "")
+	assert.Equal(t, encoding(s, "/encoded"), "br") // already encoded
+
+	// Only the types configured
+	s = newServer(rweb.CompressConfig{Types: []string{consts.MIMEJSON, "font/*"}})
+	assert.Equal(t, encoding(s, "/json"), "gzip")
+	assert.Equal(t, encoding(s, "/html"), "")
+	assert.Equal(t, encoding(s, "/font"), "gzip")
+
+	s = newServer(rweb.CompressConfig{Types: []string{"text/*"}})
+	assert.Equal(t, encoding(s, "/events"), "")
+
+	// Bodies below MinLength are left alone
+	s = newServer(rweb.CompressConfig{MinLength: len(large) + 1})
+	assert.Equal(t, encoding(s, "/json"), "")
+
+	// The level is applied
+	sizes := map[int]int{}
+	for _, level := range []int{gzip.HuffmanOnly, gzip.BestCompression} {
+		s = newServer(rweb.CompressConfig{Level: level})
+		response := s.Request(consts.MethodGet, "/json", acceptGzip, nil)
+		sizes[level] = len(response.Body())
+
+		reader, err := gzip.NewReader(bytes.NewReader(response.Body()))
+		assert.Nil(t, err)
+		decompressed, err := io.ReadAll(reader)
+		assert.Nil(t, err)
+		assert.DeepEqual(t, decompressed, large)
+	}
+	assert.True(t, sizes[gzip.BestCompression] < sizes[gzip.HuffmanOnly])
+}
@@ -0,0 +1,100 @@
+package rweb
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// aLongTimeAgo is a read deadline in the past, which unblocks a pending read straight away
+var aLongTimeAgo = time.Unix(1, 0)
+
+// connReader reads a connection for the request parser.
+// While a request is handled, it watches the connection in the background,
+// so the request's context can be canceled as soon as the client goes away.
+type connReader struct {
+	conn net.Conn
+
+	mu       sync.Mutex
+	cond     *sync.Cond
+	watching bool // a background read is in progress
+	aborting bool // the background read is being ended by us, rather than the client
+	hasByte  bool // the background read got a byte (e.g. of a pipelined request), still to be read
+	byteBuf  [1]byte
+}
+
+// newConnReader returns a reader for conn
+func newConnReader(conn net.Conn) *connReader {
+	cr := &connReader{conn: conn}
+	cr.cond = sync.NewCond(&cr.mu)
+	return cr
+}
+
+// Read reads from the connection, ending any background read first
+func (cr *connReader) Read(p []byte) (int, error) {
+	cr.stopWatching()
+
+	cr.mu.Lock()
+	if cr.hasByte && len(p) > 0 {
+		p[0] = cr.byteBuf[0]
+		cr.hasByte = false
+		cr.mu.Unlock()
+		return 1, nil
+	}
+	cr.mu.Unlock()
+
+	return cr.conn.Read(p)
+}
+
+// watch starts reading the connection in the background, calling onGone if the client closes it.
+// Any byte read meanwhile (the client may send its next request already) is kept for the next Read.
+// Safe to call on a nil connReader (e.g. for synthetic requests).
+func (cr *connReader) watch(onGone func()) {
+	if cr == nil {
+		return
+	}
+
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	if cr.watching || cr.hasByte {
+		return // there's already something to read, so the client is still there
+	}
+	cr.watching = true
+
+	go func() {
+		n, err := cr.conn.Read(cr.byteBuf[:])
+
+		cr.mu.Lock()
+		defer cr.mu.Unlock()
+		if n == 1 {
+			cr.hasByte = true
+		}
+		if err != nil && !cr.aborting {
+			onGone()
+		}
+		cr.watching, cr.aborting = false, false
+		cr.cond.Broadcast()
+	}()
+}
+
+// stopWatching ends any background read, waiting for it to finish.
+// Safe to call on a nil connReader (e.g. for synthetic requests).
+func (cr *connReader) stopWatching() {
+	if cr == nil {
+		return
+	}
+
+	cr.mu.Lock()
+	if !cr.watching {
+		cr.mu.Unlock()
+		return
+	}
+	cr.aborting = true
+	_ = cr.conn.SetReadDeadline(aLongTimeAgo)
+	for cr.watching {
+		cr.cond.Wait()
+	}
+	cr.mu.Unlock()
+
+	_ = cr.conn.SetReadDeadline(time.Time{})
+}
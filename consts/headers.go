@@ -85,6 +85,7 @@ const (
 	HeaderXForwardedFor   = "X-Forwarded-For"
 	HeaderXForwardedHost  = "X-Forwarded-Host"
 	HeaderXForwardedProto = "X-Forwarded-Proto"
+	HeaderXRealIP         = "X-Real-IP"
 
 	// Redirects.
 	HeaderLocation = "Location"
@@ -157,9 +158,11 @@ const (
 	HeaderSignedHeaders       = "Signed-Headers"
 	HeaderSourceMap           = "SourceMap"
 	HeaderUpgrade             = "Upgrade"
+	HeaderXCSRFToken          = "X-CSRF-Token"
 	HeaderXDNSPrefetchControl = "X-DNS-Prefetch-Control"
 	HeaderXPingback           = "X-Pingback"
 	HeaderXRequestedWith      = "X-Requested-With"
+	HeaderXRequestID          = "X-Request-ID"
 	HeaderXRobotsTag          = "X-Robots-Tag"
 	HeaderXUACompatible       = "X-UA-Compatible"
 	HeaderXAccelBuffering     = "X-Accel-Buffering"
@@ -26,6 +26,10 @@ const (
 
 	HTTPBadRequest = "HTTP/1.1 400 Bad Request\r\n\r\n"
 	HTTPBadMethod  = "BAD-METHOD / HTTP/1.1\r\n\r\n"
+	// HTTPServiceUnavailable is sent to connections refused when at the server's connection limit
+	HTTPServiceUnavailable = "HTTP/1.1 503 Service Unavailable\r\nConnection: close\r\nContent-Length: 0\r\n\r\n"
+	// HTTPRequestHeaderFieldsTooLarge is sent to requests whose headers are over the server's limits
+	HTTPRequestHeaderFieldsTooLarge = "HTTP/1.1 431 Request Header Fields Too Large\r\nConnection: close\r\nContent-Length: 0\r\n\r\n"
 )
 
 var ( // HTTP messages
@@ -2,6 +2,7 @@ package consts
 
 const ( // HTTP status codes
 	// 1xx Informational
+	StatusContinue           = 100
 	StatusSwitchingProtocols = 101
 
 	// 2xx Success
@@ -36,6 +37,11 @@ const ( // HTTP status codes
 	StatusConflict          = 409
 	StatusGone              = 410
 
+	StatusPreconditionFailed    = 412
+	StatusRequestEntityTooLarge = 413
+
+	StatusRequestHeaderFieldsTooLarge = 431
+
 	StatusInternalServerError     = 500
 	StatusNotImplemented          = 501
 	StatusBadGateway              = 502
@@ -46,6 +52,7 @@ const ( // HTTP status codes
 
 var StatusTextFromCode = map[int]string{
 	// 1xx Informational
+	StatusContinue:           "Continue",
 	StatusSwitchingProtocols: "Switching Protocols",
 
 	// 2xx Success
@@ -79,6 +86,11 @@ var StatusTextFromCode = map[int]string{
 	StatusConflict:          "Conflict",
 	StatusGone:              "Gone",
 
+	StatusPreconditionFailed:    "Precondition Failed",
+	StatusRequestEntityTooLarge: "Request Entity Too Large",
+
+	StatusRequestHeaderFieldsTooLarge: "Request Header Fields Too Large",
+
 	StatusInternalServerError:     "Internal Server Error",
 	StatusNotImplemented:          "Not Implemented",
 	StatusBadGateway:              "Bad Gateway",
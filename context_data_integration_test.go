@@ -87,3 +87,40 @@ func TestContextDataCleanup(t *testing.T) {
 
 	assert.Equal(t, requestCount, 3)
 }
+
+func TestContextDataTyped(t *testing.T) {
+	type user struct{ Name string }
+
+	s := rweb.NewServer()
+
+	s.Use(func(ctx rweb.Context) error {
+		rweb.SetValue(ctx, "user", &user{Name: "ann"})
+		rweb.SetValue(ctx, "visits", 3)
+		return ctx.Next()
+	})
+
+	s.Get("/", func(ctx rweb.Context) error {
+		u, ok := rweb.GetValue[*user](ctx, "user")
+		assert.True(t, ok)
+		assert.Equal(t, u.Name, "ann")
+
+		visits, ok := rweb.GetValue[int](ctx, "visits")
+		assert.True(t, ok)
+		assert.Equal(t, visits, 3)
+
+		// A type mismatch gives the zero value rather than a panic
+		name, ok := rweb.GetValue[string](ctx, "visits")
+		assert.False(t, ok)
+		assert.Equal(t, name, "")
+
+		// As does a missing key
+		missing, ok := rweb.GetValue[*user](ctx, "nobody")
+		assert.False(t, ok)
+		assert.Nil(t, missing)
+
+		return ctx.WriteString("OK")
+	})
+
+	response := s.Request(consts.MethodGet, "/", nil, nil)
+	assert.Equal(t, string(response.Body()), "OK")
+}
@@ -104,6 +104,19 @@ func (hr *HashRouter[T]) ListRoutes() (routes []RouteList) {
 	return
 }
 
+// AllowedMethods returns the HTTP methods having a handler registered for the exact path.
+// Methods are returned in a stable order (see allMethods), so the result can be used
+// directly for an Allow header - SortMethods keeps that order when adding other methods.
+// Returns nil if the path is not registered for any method.
+func (hr *HashRouter[T]) AllowedMethods(path string) (methods []string) {
+	for _, method := range allMethods {
+		if _, ok := hr.selectMethodMap(method)[path]; ok {
+			methods = append(methods, method)
+		}
+	}
+	return
+}
+
 // Lookup finds the handler for the given route.
 // Returns the zero value of T if no handler is found.
 //
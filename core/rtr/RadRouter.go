@@ -1,6 +1,8 @@
 package rtr
 
 import (
+	"reflect"
+
 	"github.com/rohanthewiz/rweb/consts"
 )
 
@@ -27,6 +29,10 @@ type RadixRouter[T any] struct {
 	connect Tree[T]
 	trace   Tree[T]
 	options Tree[T]
+
+	// StrictTrailingSlash, when set before adding routes, keeps /users and /users/ as distinct routes.
+	// By default a route also matches its trailing slash variant.
+	StrictTrailingSlash bool
 }
 
 // New creates a new router containing trees for every HTTP method.
@@ -45,6 +51,7 @@ func New[T any]() *RadixRouter[T] {
 // Routes are automatically optimized during insertion for fastest possible lookup.
 func (router *RadixRouter[T]) Add(method string, path string, handler T) {
 	tree := router.selectTree(method)
+	tree.StrictTrailingSlash = router.StrictTrailingSlash
 	tree.Add(path, handler)
 }
 
@@ -82,6 +89,22 @@ func (router *RadixRouter[T]) LookupNoAlloc(method string, path string, addParam
 	return tree.LookupNoAlloc(path, addParameter)
 }
 
+// AllowedMethods returns the HTTP methods whose tree has a handler matching the given path,
+// taking parameters and wildcards into account (e.g. /users/5 matches /users/:id).
+// This is used to answer 405 Method Not Allowed and OPTIONS requests with an accurate Allow header.
+//
+// Performance note:
+// - Performs a lookup in every method tree, so it is intended for the not-found path only
+func (router *RadixRouter[T]) AllowedMethods(path string) (methods []string) {
+	for _, method := range allMethods {
+		data := router.selectTree(method).LookupNoAlloc(path, func(string, string) {})
+		if !isZero(data) {
+			methods = append(methods, method)
+		}
+	}
+	return
+}
+
 // Map traverses all trees and calls the given function on every node.
 // This allows bulk transformation of all handlers in the router.
 //
@@ -134,3 +157,9 @@ func (router *RadixRouter[T]) selectTree(method string) *Tree[T] {
 		return nil
 	}
 }
+
+// isZero reports whether data is the zero value of T (i.e. no handler is stored).
+// Reflection is needed as T is unconstrained; a nil func is not == nil once boxed in an interface.
+func isZero[T any](data T) bool {
+	return reflect.ValueOf(&data).Elem().IsZero()
+}
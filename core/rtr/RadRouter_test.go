@@ -73,6 +73,52 @@ func TestParameter(t *testing.T) {
 	assert.Equal(t, data, "Comment")
 }
 
+func TestOptionalParameter(t *testing.T) {
+	r := rtr.New[string]()
+	r.Add(consts.MethodGet, "/files/:name?", "File")
+	r.Add(consts.MethodGet, "/files/recent", "Recent")
+	r.Add(consts.MethodGet, "/:lang?", "Home")
+
+	data, params := r.Lookup(consts.MethodGet, "/files/annual.pdf")
+	assert.Equal(t, data, "File")
+	assert.Equal(t, len(params), 1)
+	assert.Equal(t, params[0].Key, "name")
+	assert.Equal(t, params[0].Value, "annual.pdf")
+
+	// The bare path goes to the same handler, without the parameter
+	data, params = r.Lookup(consts.MethodGet, "/files")
+	assert.Equal(t, data, "File")
+	assert.Equal(t, len(params), 0)
+
+	data, params = r.Lookup(consts.MethodGet, "/files/")
+	assert.Equal(t, data, "File")
+	assert.Equal(t, len(params), 0)
+
+	// Static segments take precedence over the parameter
+	data, _ = r.Lookup(consts.MethodGet, "/files/recent")
+	assert.Equal(t, data, "Recent")
+
+	// At the root
+	data, params = r.Lookup(consts.MethodGet, "/")
+	assert.Equal(t, data, "Home")
+	assert.Equal(t, len(params), 0)
+	data, params = r.Lookup(consts.MethodGet, "/de")
+	assert.Equal(t, data, "Home")
+	assert.Equal(t, params[0].Value, "de")
+
+	// Registering the bare path explicitly replaces it
+	r.Add(consts.MethodGet, "/files", "Index")
+	data, _ = r.Lookup(consts.MethodGet, "/files")
+	assert.Equal(t, data, "Index")
+	data, _ = r.Lookup(consts.MethodGet, "/files/a.txt")
+	assert.Equal(t, data, "File")
+
+	// ? only marks a trailing parameter optional
+	r.Add(consts.MethodGet, "/docs?", "Docs")
+	data, _ = r.Lookup(consts.MethodGet, "/docs")
+	assert.Equal(t, data, "")
+}
+
 func TestWildcard(t *testing.T) {
 	r := rtr.New[string]()
 	r.Add(consts.MethodGet, "/", "Front page")
@@ -247,6 +293,47 @@ func TestTrailingSlashOverwrite(t *testing.T) {
 	assert.Equal(t, data, "route 5")
 }
 
+func TestStrictTrailingSlash(t *testing.T) {
+	r := rtr.New[string]()
+	r.StrictTrailingSlash = true
+	r.Add(consts.MethodGet, "/users", "Users")
+	r.Add(consts.MethodGet, "/users/:id", "User")
+	r.Add(consts.MethodGet, "/posts/", "Posts collection")
+	r.Add(consts.MethodGet, "/posts/:id/", "Post collection")
+	r.Add(consts.MethodGet, "/posts/:id/comments", "Comments")
+
+	data, _ := r.Lookup(consts.MethodGet, "/users")
+	assert.Equal(t, data, "Users")
+
+	data, _ = r.Lookup(consts.MethodGet, "/users/")
+	assert.Equal(t, data, "")
+
+	data, params := r.Lookup(consts.MethodGet, "/users/42")
+	assert.Equal(t, data, "User")
+	assert.Equal(t, params[0].Value, "42")
+
+	data, _ = r.Lookup(consts.MethodGet, "/users/42/")
+	assert.Equal(t, data, "")
+
+	data, _ = r.Lookup(consts.MethodGet, "/users/42/more")
+	assert.Equal(t, data, "")
+
+	data, _ = r.Lookup(consts.MethodGet, "/posts/")
+	assert.Equal(t, data, "Posts collection")
+
+	data, _ = r.Lookup(consts.MethodGet, "/posts")
+	assert.Equal(t, data, "")
+
+	data, _ = r.Lookup(consts.MethodGet, "/posts/7/")
+	assert.Equal(t, data, "Post collection")
+
+	data, _ = r.Lookup(consts.MethodGet, "/posts/7")
+	assert.Equal(t, data, "")
+
+	data, _ = r.Lookup(consts.MethodGet, "/posts/7/comments")
+	assert.Equal(t, data, "Comments")
+}
+
 func TestOverwrite(t *testing.T) {
 	r := rtr.New[string]()
 	r.Add(consts.MethodGet, "/", "1")
@@ -281,3 +368,23 @@ func TestMemoryUsage(t *testing.T) {
 
 	t.Logf("%d bytes", result.MemBytes)
 }
+
+func TestAllowedMethods(t *testing.T) {
+	r := rtr.New[string]()
+	r.Add(consts.MethodGet, "/users/:id", "Get user")
+	r.Add(consts.MethodPut, "/users/:id", "Update user")
+	r.Add(consts.MethodGet, "/users/:id/posts", "Posts")
+	r.Add(consts.MethodPost, "/files/*path", "Upload")
+
+	assert.DeepEqual(t, r.AllowedMethods("/users/5"), []string{consts.MethodGet, consts.MethodPut})
+	assert.DeepEqual(t, r.AllowedMethods("/users/5/posts"), []string{consts.MethodGet})
+	assert.DeepEqual(t, r.AllowedMethods("/files/a/b.txt"), []string{consts.MethodPost})
+	assert.Equal(t, len(r.AllowedMethods("/users")), 0)
+	assert.Equal(t, len(r.AllowedMethods("/unknown")), 0)
+}
+
+func TestSortMethods(t *testing.T) {
+	methods := []string{"PROPFIND", consts.MethodTrace, consts.MethodOptions, consts.MethodPut, consts.MethodGet}
+	rtr.SortMethods(methods)
+	assert.DeepEqual(t, methods, []string{consts.MethodGet, consts.MethodPut, consts.MethodOptions, consts.MethodTrace, "PROPFIND"})
+}
@@ -1,6 +1,10 @@
 package rtr
 
-import "github.com/rohanthewiz/rweb/consts"
+import (
+	"strings"
+
+	"github.com/rohanthewiz/rweb/consts"
+)
 
 // Tree represents a radix tree (compressed trie) for efficient route storage and lookup.
 // The tree compresses common prefixes to minimize memory usage and traversal time.
@@ -15,6 +19,10 @@ import "github.com/rohanthewiz/rweb/consts"
 // Zero value is ready to use - the root node is embedded, not a pointer.
 type Tree[T any] struct {
 	root treeNode[T]
+
+	// StrictTrailingSlash disables the automatic trailing slash variants of added routes,
+	// so /users and /users/ are distinct routes. Set it before adding routes.
+	StrictTrailingSlash bool
 }
 
 // Add adds a new element to the tree.
@@ -27,8 +35,17 @@ type Tree[T any] struct {
 // 3. Create new nodes for remaining path segments
 // 4. Handle special nodes (parameters and wildcards)
 //
+// A final parameter can be made optional with a trailing ?, e.g. /files/:name?,
+// which adds both /files/:name and the bare /files, so /files matches with no parameter.
+// Adding the bare path again explicitly replaces its data, whichever comes last.
+//
 // The implementation modifies the tree in-place for efficiency.
 func (tree *Tree[T]) Add(path string, data T) {
+	if base, param, ok := optionalParam(path); ok {
+		tree.Add(base, data)
+		path = param
+	}
+
 	// Search tree for equal parts until we can no longer proceed
 	i := 0      // Current position in the path string
 	offset := 0 // Start of the current node's prefix in the path
@@ -52,7 +69,7 @@ func (tree *Tree[T]) Add(path string, data T) {
 			// the next child node to continue traversal.
 			// Example: /user/:id/posts where we're at the / after :id
 			if path[i] == consts.RuneFwdSlash {
-				node, offset, _ = node.end(path, data, i, offset)
+				node, offset, _ = node.end(path, data, i, offset, tree.StrictTrailingSlash)
 				goto next
 			}
 
@@ -72,7 +89,7 @@ func (tree *Tree[T]) Add(path string, data T) {
 				//   node: /blog|feed
 				//   path: /blog|
 				// Result: /blog| -> feed
-				node.split(i-offset, "", data)
+				node.split(i-offset, "", data, tree.StrictTrailingSlash)
 				return
 			}
 
@@ -82,7 +99,7 @@ func (tree *Tree[T]) Add(path string, data T) {
 			//   path: /|blog
 			if i-offset == len(node.prefix) {
 				var control flow
-				node, offset, control = node.end(path, data, i, offset)
+				node, offset, control = node.end(path, data, i, offset, tree.StrictTrailingSlash)
 
 				switch control {
 				case flowStop:
@@ -100,7 +117,7 @@ func (tree *Tree[T]) Add(path string, data T) {
 			//   path: /b|riefcase
 			// Result: /b| -> ag, riefcase
 			if path[i] != node.prefix[i-offset] {
-				node.split(i-offset, path[i:], data)
+				node.split(i-offset, path[i:], data, tree.StrictTrailingSlash)
 				return
 			}
 		}
@@ -110,6 +127,24 @@ func (tree *Tree[T]) Add(path string, data T) {
 	}
 }
 
+// optionalParam splits a path ending in an optional parameter (/files/:name?)
+// into the bare path (/files) and the parameterized one (/files/:name)
+func optionalParam(path string) (base, param string, ok bool) {
+	if !strings.HasSuffix(path, "?") {
+		return "", "", false
+	}
+	slash := strings.LastIndexByte(path, consts.RuneFwdSlash)
+	if slash < 0 || slash+1 >= len(path) || path[slash+1] != consts.RuneColon {
+		return "", "", false
+	}
+
+	base = path[:slash]
+	if base == "" {
+		base = "/"
+	}
+	return base, path[:len(path)-1], true
+}
+
 // Lookup finds the data for the given path.
 // This is a convenience wrapper around LookupNoAlloc that collects parameters into a slice.
 //
@@ -197,6 +232,11 @@ begin:
 					//   node: /:id|/posts
 					//   path: /123|/posts
 					if path[i] == consts.RuneFwdSlash {
+						// With strict trailing slashes the parameter may have no "/" child
+						if consts.RuneFwdSlash < node.startIndex || consts.RuneFwdSlash >= node.endIndex ||
+							node.indices[consts.RuneFwdSlash-node.startIndex] == 0 {
+							goto notFound
+						}
 						addParameter(node.prefix, path[:i])
 						index := node.indices[consts.RuneFwdSlash-node.startIndex]
 						node = node.children[index]
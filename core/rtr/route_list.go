@@ -1,5 +1,41 @@
 package rtr
 
+import (
+	"slices"
+
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+// allMethods lists the HTTP methods supported by the routers,
+// in the order they are reported by AllowedMethods.
+var allMethods = []string{
+	consts.MethodGet,
+	consts.MethodHead,
+	consts.MethodPost,
+	consts.MethodPut,
+	consts.MethodPatch,
+	consts.MethodDelete,
+	consts.MethodConnect,
+	consts.MethodOptions,
+	consts.MethodTrace,
+}
+
+// SortMethods sorts methods into the order of AllowedMethods, e.g. to combine the methods
+// of several routers for an Allow header. Methods the routers don't support go last.
+func SortMethods(methods []string) {
+	slices.SortStableFunc(methods, func(a, b string) int {
+		return methodIndex(a) - methodIndex(b)
+	})
+}
+
+// methodIndex returns the position of method in allMethods, or len(allMethods) if it isn't there.
+func methodIndex(method string) int {
+	if i := slices.Index(allMethods, method); i >= 0 {
+		return i
+	}
+	return len(allMethods)
+}
+
 // RouteList represents a registered route for debugging and inspection purposes.
 // This struct is used by router implementations to expose their route tables
 // in a human-readable format.
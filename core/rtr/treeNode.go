@@ -57,7 +57,7 @@ type treeNode[T any] struct {
 // 2. Reset current node to common prefix
 // 3. Add cloned node as child
 // 4. Add new branch if path is not empty
-func (node *treeNode[T]) split(index int, path string, data T) {
+func (node *treeNode[T]) split(index int, path string, data T, strictSlash bool) {
 	// Create split node with the remaining string
 	splitNode := node.clone(node.prefix[index:])
 
@@ -75,7 +75,7 @@ func (node *treeNode[T]) split(index int, path string, data T) {
 	node.addChild(splitNode)
 
 	// Create new nodes with the remaining path
-	node.append(path, data)
+	node.append(path, data, strictSlash)
 }
 
 // clone clones the node with a new prefix.
@@ -214,7 +214,9 @@ func (node *treeNode[T]) addTrailingSlash(data T) {
 //   - *param segments: Added as wildcard nodes (match everything)
 //
 // The method processes the path iteratively, creating nodes as needed.
-func (node *treeNode[T]) append(path string, data T) {
+// When strictSlash is set, no trailing slash variants are created,
+// so /users and /users/ must be registered separately.
+func (node *treeNode[T]) append(path string, data T, strictSlash bool) {
 	// Process the path iteratively until fully consumed
 	for {
 		if path == "" {
@@ -235,7 +237,9 @@ func (node *treeNode[T]) append(path string, data T) {
 			if node.prefix == "" {
 				node.prefix = path
 				node.data = data
-				node.addTrailingSlash(data)
+				if !strictSlash {
+					node.addTrailingSlash(data)
+				}
 				return
 			}
 
@@ -246,7 +250,9 @@ func (node *treeNode[T]) append(path string, data T) {
 			}
 
 			node.addChild(child)
-			child.addTrailingSlash(data)
+			if !strictSlash {
+				child.addTrailingSlash(data)
+			}
 			return
 		}
 
@@ -269,7 +275,9 @@ func (node *treeNode[T]) append(path string, data T) {
 			switch child.kind {
 			case consts.RuneColon:
 				// Parameter node - can have children
-				child.addTrailingSlash(data)
+				if !strictSlash {
+					child.addTrailingSlash(data)
+				}
 				node.parameter = child
 				node = child
 				path = path[paramEnd:]
@@ -300,7 +308,7 @@ func (node *treeNode[T]) append(path string, data T) {
 
 		// Special handling: "/" nodes inherit parent data
 		// This enables /users and /users/ to work identically
-		if child.prefix == "/" {
+		if child.prefix == "/" && !strictSlash {
 			child.data = node.data
 		}
 
@@ -320,7 +328,7 @@ func (node *treeNode[T]) append(path string, data T) {
 //   3. Handle parameter node transitions
 //
 // Returns: (next node, new offset, control flow directive)
-func (node *treeNode[T]) end(path string, data T, i int, offset int) (*treeNode[T], int, flow) {
+func (node *treeNode[T]) end(path string, data T, i int, offset int, strictSlash bool) (*treeNode[T], int, flow) {
 	char := path[i]
 
 	// Try to find a matching child for the next character
@@ -339,7 +347,7 @@ func (node *treeNode[T]) end(path string, data T, i int, offset int) (*treeNode[
 	
 	// Special case: Empty prefix means this is the root node
 	if node.prefix == "" {
-		node.append(path[i:], data)
+		node.append(path[i:], data, strictSlash)
 		return node, offset, flowStop
 	}
 
@@ -354,7 +362,7 @@ func (node *treeNode[T]) end(path string, data T, i int, offset int) (*treeNode[
 	}
 
 	// No suitable child - append remaining path as new nodes
-	node.append(path[i:], data)
+	node.append(path[i:], data, strictSlash)
 	return node, offset, flowStop
 }
 
@@ -0,0 +1,139 @@
+package rweb
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"time"
+
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+// CSRFTokenKey is the context data key under which the CSRF middleware stores the request's token
+const CSRFTokenKey = "rweb.csrfToken"
+
+// csrfTokenLen is the number of random bytes in a CSRF token
+const csrfTokenLen = 32
+
+// CSRFOptions configures the CSRF middleware
+type CSRFOptions struct {
+	// CookieName is the name of the cookie holding the token. Defaults to "_csrf".
+	CookieName string
+	// FieldName is the form field checked for the token. Defaults to "csrf_token".
+	FieldName string
+	// HeaderName is the request header checked for the token (e.g. for fetch/XHR requests). Defaults to "X-CSRF-Token".
+	HeaderName string
+	// MaxAge is how long the token cookie lasts. Defaults to 0, a browser session cookie.
+	MaxAge time.Duration
+}
+
+// CSRF returns a middleware protecting against cross-site request forgery, with the signed double submit cookie pattern.
+// Each client gets a random token in a cookie. Requests with unsafe methods (POST, PUT, PATCH and DELETE)
+// must send the same token back, in the FieldName form field or the HeaderName header, or are rejected with a 403.
+// A forged request from another site can carry the cookie, but can't read it to send the token too.
+// The token is signed with the server's CookieConfig.Secret (ErrCookieSecretNotSet is returned without one),
+// and bound to the client's session, if it has one, so a token planted by another (sub)domain isn't accepted.
+// Handlers get the token for forms and pages via ctx.CSRFToken().
+// Example:
+//
+//	s.Use(rweb.CSRF(rweb.CSRFOptions{}))
+//	s.Get("/profile", func(ctx rweb.Context) error {
+//		return ctx.WriteHTML(`<form method="post"><input type="hidden" name="csrf_token" value="` + ctx.CSRFToken() + `">...`)
+//	})
+func CSRF(opts CSRFOptions) Handler {
+	if opts.CookieName == "" {
+		opts.CookieName = "_csrf"
+	}
+	if opts.FieldName == "" {
+		opts.FieldName = "csrf_token"
+	}
+	if opts.HeaderName == "" {
+		opts.HeaderName = consts.HeaderXCSRFToken
+	}
+
+	return func(ctx Context) error {
+		base := baseContext(ctx)
+		var secret []byte
+		if base != nil {
+			secret = base.cookieSecret()
+		}
+		if len(secret) == 0 {
+			return ErrCookieSecretNotSet
+		}
+		binding := csrfBinding(base, opts.CookieName)
+
+		token, _ := ctx.GetCookie(opts.CookieName)
+		hadToken := validCSRFToken(secret, binding, token)
+		if !hadToken {
+			var err error
+			if token, err = newCSRFToken(secret, binding); err != nil {
+				return err
+			}
+			err = ctx.SetCookieWithOptions(&Cookie{
+				Name:     opts.CookieName,
+				Value:    token,
+				MaxAge:   int(opts.MaxAge / time.Second),
+				HttpOnly: true,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		ctx.Set(CSRFTokenKey, token)
+
+		if csrfSafeMethod(ctx.Request().Method()) {
+			return ctx.Next()
+		}
+
+		sent := ctx.Request().Header(opts.HeaderName)
+		if sent == "" {
+			sent = ctx.Request().FormValue(opts.FieldName)
+		}
+		if !hadToken || subtle.ConstantTimeCompare([]byte(sent), []byte(token)) != 1 {
+			ctx.Abort()
+			ctx.SetStatus(consts.StatusForbidden)
+			return ctx.WriteText("invalid CSRF token")
+		}
+		return ctx.Next()
+	}
+}
+
+// CSRFToken returns the request's CSRF token, to embed in forms (or pages, for scripts to send in a header),
+// or "" if the CSRF middleware isn't in use.
+func (ctx *context) CSRFToken() string {
+	token, _ := ctx.Get(CSRFTokenKey).(string)
+	return token
+}
+
+// csrfSafeMethod reports whether requests with method don't need a CSRF token, as they shouldn't change anything
+func csrfSafeMethod(method string) bool {
+	switch method {
+	case consts.MethodGet, consts.MethodHead, consts.MethodOptions, consts.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// csrfBinding returns what CSRF tokens for the request are signed for: the token cookie, and the client's session.
+// The session ID goes into the signature only, so pages showing the token don't reveal it.
+// CookieSessionStore sessions have no ID (their token is the data, changing with every save), so tokens aren't bound to them.
+func csrfBinding(ctx *context, cookieName string) string {
+	if _, ok := ctx.server.sessionStore.(CookieSessionStore); ok {
+		return cookieName
+	}
+	return cookieName + "|" + ctx.Session().token
+}
+
+// newCSRFToken returns a random CSRF token, signed for binding
+func newCSRFToken(secret []byte, binding string) (string, error) {
+	b := make([]byte, csrfTokenLen)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return signCookieValue(secret, binding, string(b)), nil
+}
+
+// validCSRFToken reports whether a token from the client's cookie is one we issued for binding
+func validCSRFToken(secret []byte, binding, token string) bool {
+	value, err := verifyCookieValue(secret, binding, token)
+	return err == nil && len(value) == csrfTokenLen
+}
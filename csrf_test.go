@@ -0,0 +1,186 @@
+package rweb_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/rohanthewiz/assert"
+	"github.com/rohanthewiz/rweb"
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+// csrfSecret signs the CSRF tokens of the test servers
+var csrfSecret = []byte("0123456789abcdef0123456789abcdef")
+
+func TestCSRF(t *testing.T) {
+	s := rweb.NewServer(rweb.ServerOptions{Cookie: rweb.CookieConfig{Secret: csrfSecret}})
+	s.Use(rweb.CSRF(rweb.CSRFOptions{}))
+
+	s.Get("/form", func(ctx rweb.Context) error {
+		return ctx.WriteString(ctx.CSRFToken())
+	})
+	s.Post("/save", func(ctx rweb.Context) error {
+		return ctx.WriteString("saved")
+	})
+
+	// Safe requests get a token, and the cookie holding it
+	response := s.Request(consts.MethodGet, "/form", nil, nil)
+	token := string(response.Body())
+	assert.Equal(t, len(token), 87) // 32 bytes and their signature, base64url
+	cookie := response.Cookies()[0]
+	assert.Equal(t, cookie.Name, "_csrf")
+	assert.Equal(t, cookie.Value, token)
+	assert.True(t, cookie.HttpOnly)
+
+	// The client's token is kept
+	withCookie := []rweb.Header{{Key: "Cookie", Value: "_csrf=" + token}}
+	response = s.Request(consts.MethodGet, "/form", withCookie, nil)
+	assert.Equal(t, string(response.Body()), token)
+	assert.Equal(t, len(response.Cookies()), 0)
+
+	// Unsafe requests need the token back
+	response = s.Request(consts.MethodPost, "/save", withCookie, nil)
+	assert.Equal(t, response.Status(), consts.StatusForbidden)
+
+	response = s.Request(consts.MethodPost, "/save",
+		append(withCookie, rweb.Header{Key: consts.HeaderXCSRFToken, Value: token}), nil)
+	assert.Equal(t, response.Status(), consts.StatusOK)
+	assert.Equal(t, string(response.Body()), "saved")
+
+	// ...matching the cookie
+	other := strings.Repeat("A", 87)
+	response = s.Request(consts.MethodPost, "/save",
+		append(withCookie, rweb.Header{Key: consts.HeaderXCSRFToken, Value: other}), nil)
+	assert.Equal(t, response.Status(), consts.StatusForbidden)
+
+	// A token without a cookie isn't enough
+	response = s.Request(consts.MethodPost, "/save",
+		[]rweb.Header{{Key: consts.HeaderXCSRFToken, Value: token}}, nil)
+	assert.Equal(t, response.Status(), consts.StatusForbidden)
+
+	// Nor is a made up cookie
+	response = s.Request(consts.MethodPost, "/save", []rweb.Header{
+		{Key: "Cookie", Value: "_csrf=x"}, {Key: consts.HeaderXCSRFToken, Value: "x"}}, nil)
+	assert.Equal(t, response.Status(), consts.StatusForbidden)
+
+	// Nor one planted along with a matching token (e.g. by a sibling subdomain) without our signature
+	planted := "QUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUE." + strings.Repeat("A", 43)
+	response = s.Request(consts.MethodPost, "/save", []rweb.Header{
+		{Key: "Cookie", Value: "_csrf=" + planted}, {Key: consts.HeaderXCSRFToken, Value: planted}}, nil)
+	assert.Equal(t, response.Status(), consts.StatusForbidden)
+}
+
+func TestCSRFSession(t *testing.T) {
+	s := rweb.NewServer(rweb.ServerOptions{Cookie: rweb.CookieConfig{Secret: csrfSecret}})
+	s.Use(rweb.CSRF(rweb.CSRFOptions{}))
+
+	s.Get("/form", func(ctx rweb.Context) error {
+		return ctx.WriteString(ctx.CSRFToken())
+	})
+	s.Post("/login", func(ctx rweb.Context) error {
+		ctx.Session().Set("user", "ann")
+		return ctx.Session().Save()
+	})
+	s.Post("/save", func(ctx rweb.Context) error {
+		return ctx.WriteString("saved")
+	})
+
+	// A token from before the client had a session...
+	response := s.Request(consts.MethodGet, "/form", nil, nil)
+	token := string(response.Body())
+	csrfCookie := "_csrf=" + token
+	response = s.Request(consts.MethodPost, "/login",
+		[]rweb.Header{{Key: "Cookie", Value: csrfCookie}, {Key: consts.HeaderXCSRFToken, Value: token}}, nil)
+	assert.Equal(t, response.Status(), consts.StatusOK)
+	var sessionCookie string
+	for _, cookie := range response.Cookies() {
+		if cookie.Name == "session" {
+			sessionCookie = "session=" + cookie.Value
+		}
+	}
+
+	// ...isn't accepted with the session
+	withSession := []rweb.Header{{Key: "Cookie", Value: csrfCookie + "; " + sessionCookie}}
+	response = s.Request(consts.MethodPost, "/save",
+		append(withSession, rweb.Header{Key: consts.HeaderXCSRFToken, Value: token}), nil)
+	assert.Equal(t, response.Status(), consts.StatusForbidden)
+
+	// The client gets a new token, bound to the session
+	response = s.Request(consts.MethodGet, "/form", withSession, nil)
+	token = string(response.Body())
+	assert.Equal(t, response.Cookies()[0].Value, token)
+	withSession = []rweb.Header{{Key: "Cookie", Value: "_csrf=" + token + "; " + sessionCookie}}
+	response = s.Request(consts.MethodPost, "/save",
+		append(withSession, rweb.Header{Key: consts.HeaderXCSRFToken, Value: token}), nil)
+	assert.Equal(t, response.Status(), consts.StatusOK)
+
+	// ...which is no good without it
+	response = s.Request(consts.MethodPost, "/save",
+		[]rweb.Header{{Key: "Cookie", Value: "_csrf=" + token}, {Key: consts.HeaderXCSRFToken, Value: token}}, nil)
+	assert.Equal(t, response.Status(), consts.StatusForbidden)
+}
+
+func TestCSRFNeedsSecret(t *testing.T) {
+	s := rweb.NewServer()
+	s.Use(rweb.CSRF(rweb.CSRFOptions{}))
+	s.Get("/form", func(ctx rweb.Context) error {
+		return ctx.WriteString(ctx.CSRFToken())
+	})
+
+	response := s.Request(consts.MethodGet, "/form", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusInternalServerError)
+	assert.Equal(t, len(response.Cookies()), 0)
+}
+
+func TestCSRFFormField(t *testing.T) {
+	readyChan := make(chan struct{}, 1)
+	s := rweb.NewServer(rweb.ServerOptions{ReadyChan: readyChan, Address: "localhost:", Cookie: rweb.CookieConfig{Secret: csrfSecret}})
+	s.Use(rweb.CSRF(rweb.CSRFOptions{CookieName: "xsrf", FieldName: "token"}))
+
+	s.Get("/form", func(ctx rweb.Context) error {
+		return ctx.WriteString(ctx.CSRFToken())
+	})
+	s.Post("/save", func(ctx rweb.Context) error {
+		return ctx.WriteString("saved " + ctx.Request().FormValue("name"))
+	})
+
+	go func() {
+		defer syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+		<-readyChan // wait for server
+		base := fmt.Sprintf("http://127.0.0.1:%s", s.GetListenPort())
+
+		resp, err := http.Get(base + "/form")
+		assert.Nil(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		token := string(body)
+		assert.Equal(t, resp.Cookies()[0].Name, "xsrf")
+
+		post := func(form url.Values) (int, string) {
+			req, err := http.NewRequest(consts.MethodPost, base+"/save", strings.NewReader(form.Encode()))
+			assert.Nil(t, err)
+			req.Header.Set(consts.HeaderContentType, string(consts.BytFormData))
+			req.AddCookie(&http.Cookie{Name: "xsrf", Value: token})
+			resp, err := http.DefaultClient.Do(req)
+			assert.Nil(t, err)
+			defer resp.Body.Close()
+			respBody, _ := io.ReadAll(resp.Body)
+			return resp.StatusCode, string(respBody)
+		}
+
+		status, body2 := post(url.Values{"name": {"ann"}, "token": {token}})
+		assert.Equal(t, status, consts.StatusOK)
+		assert.Equal(t, body2, "saved ann")
+
+		status, _ = post(url.Values{"name": {"ann"}})
+		assert.Equal(t, status, consts.StatusForbidden)
+	}()
+
+	_ = s.Run()
+}
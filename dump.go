@@ -0,0 +1,128 @@
+package rweb
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"strings"
+)
+
+// DumpConfig configures the Dump middleware.
+type DumpConfig struct {
+	// Match selects which requests are dumped. If nil, all requests are dumped.
+	Match func(ctx Context) bool
+	// PathPrefixes limits dumping to requests whose path starts with one of the prefixes.
+	// Empty means all paths.
+	PathPrefixes []string
+	// RedactHeaders lists headers whose values are replaced with "[REDACTED]" (case-insensitive).
+	// Defaults to Authorization, Cookie and Set-Cookie.
+	RedactHeaders []string
+	// MaxBodySize caps the number of body bytes logged for each of the request and response.
+	// Defaults to 4KB. Set to -1 to omit bodies entirely.
+	MaxBodySize int
+	// Output receives the dump. Defaults to the standard logger's output.
+	Output io.Writer
+}
+
+const dumpDefaultMaxBodySize = 4 * 1024
+
+var dumpDefaultRedactHeaders = []string{"Authorization", "Cookie", "Set-Cookie"}
+
+// Dump returns a debugging middleware that logs the full request
+// (method, path, headers, body) and response (status, headers, body).
+// Sensitive headers are redacted and bodies are truncated to MaxBodySize.
+// It is intended for development - register it only when needed.
+// Example:
+//
+//	if debug {
+//		s.Use(rweb.Dump(rweb.DumpConfig{PathPrefixes: []string{"/webhooks"}}))
+//	}
+func Dump(cfg DumpConfig) Handler {
+	if cfg.RedactHeaders == nil {
+		cfg.RedactHeaders = dumpDefaultRedactHeaders
+	}
+	if cfg.MaxBodySize == 0 {
+		cfg.MaxBodySize = dumpDefaultMaxBodySize
+	}
+
+	return func(ctx Context) error {
+		if !cfg.matches(ctx) {
+			return ctx.Next()
+		}
+
+		err := ctx.Next()
+
+		out := cfg.Output
+		if out == nil {
+			out = log.Writer()
+		}
+
+		var sb strings.Builder
+		req := ctx.Request()
+		reqLine := req.Path()
+		if qry := req.Query(); qry != "" {
+			reqLine += "?" + qry
+		}
+		sb.WriteString(fmt.Sprintf("---- [DUMP] request: %s %s\n", req.Method(), reqLine))
+		cfg.writeHeaders(&sb, req.Headers())
+		cfg.writeBody(&sb, req.Body())
+
+		sb.WriteString(fmt.Sprintf("---- [DUMP] response: %d\n", ctx.Response().Status()))
+		if c := baseContext(ctx); c != nil {
+			cfg.writeHeaders(&sb, c.response.headers)
+		}
+		cfg.writeBody(&sb, ctx.Response().Body())
+		if err != nil {
+			sb.WriteString(fmt.Sprintf("error: %s\n", err))
+		}
+		sb.WriteString("---- [DUMP] end\n")
+
+		_, _ = io.WriteString(out, sb.String())
+		return err
+	}
+}
+
+// matches reports whether the request should be dumped
+func (cfg *DumpConfig) matches(ctx Context) bool {
+	if len(cfg.PathPrefixes) > 0 {
+		path := ctx.Request().Path()
+		found := false
+		for _, prefix := range cfg.PathPrefixes {
+			if strings.HasPrefix(path, prefix) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return cfg.Match == nil || cfg.Match(ctx)
+}
+
+func (cfg *DumpConfig) writeHeaders(sb *strings.Builder, headers []Header) {
+	for _, hdr := range headers {
+		value := hdr.Value
+		for _, redact := range cfg.RedactHeaders {
+			if strings.EqualFold(hdr.Key, redact) {
+				value = "[REDACTED]"
+				break
+			}
+		}
+		sb.WriteString(hdr.Key + ": " + value + "\n")
+	}
+}
+
+func (cfg *DumpConfig) writeBody(sb *strings.Builder, body []byte) {
+	if cfg.MaxBodySize < 0 || len(body) == 0 {
+		return
+	}
+	sb.WriteString("\n")
+	if len(body) > cfg.MaxBodySize {
+		sb.Write(body[:cfg.MaxBodySize])
+		sb.WriteString(fmt.Sprintf("\n... (%d more bytes)\n", len(body)-cfg.MaxBodySize))
+		return
+	}
+	sb.Write(body)
+	sb.WriteString("\n")
+}
@@ -0,0 +1,77 @@
+package rweb_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/rohanthewiz/assert"
+	"github.com/rohanthewiz/rweb"
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+func TestDump(t *testing.T) {
+	var buf bytes.Buffer
+
+	s := rweb.NewServer()
+	s.Use(rweb.Dump(rweb.DumpConfig{
+		PathPrefixes: []string{"/api"},
+		MaxBodySize:  10,
+		Output:       &buf,
+	}))
+
+	s.Get("/api/users", func(ctx rweb.Context) error {
+		ctx.Response().SetHeader("X-Total", "2")
+		return ctx.WriteString("0123456789abcdef")
+	})
+	s.Get("/health", func(ctx rweb.Context) error {
+		return ctx.WriteString("ok")
+	})
+
+	response := s.Request(consts.MethodGet, "/api/users?page=2", []rweb.Header{
+		{Key: "Authorization", Value: "Bearer secret-token"},
+		{Key: "Accept", Value: "text/plain"},
+	}, nil)
+	assert.Equal(t, response.Status(), consts.StatusOK)
+	assert.Equal(t, string(response.Body()), "0123456789abcdef") // the response itself is untouched
+
+	dump := buf.String()
+	assert.True(t, strings.Contains(dump, "request: GET /api/users?page=2"))
+	assert.True(t, strings.Contains(dump, "Accept: text/plain"))
+	assert.True(t, strings.Contains(dump, "Authorization: [REDACTED]"))
+	assert.False(t, strings.Contains(dump, "secret-token"))
+	assert.True(t, strings.Contains(dump, "response: 200"))
+	assert.True(t, strings.Contains(dump, "X-Total: 2"))
+	assert.True(t, strings.Contains(dump, "0123456789\n... (6 more bytes)"))
+	assert.False(t, strings.Contains(dump, "abcdef"))
+
+	// Non-matching paths are not dumped
+	buf.Reset()
+	response = s.Request(consts.MethodGet, "/health", nil, nil)
+	assert.Equal(t, string(response.Body()), "ok")
+	assert.Equal(t, buf.Len(), 0)
+}
+
+func TestDumpMatch(t *testing.T) {
+	var buf bytes.Buffer
+
+	s := rweb.NewServer()
+	s.Use(rweb.Dump(rweb.DumpConfig{
+		Match: func(ctx rweb.Context) bool {
+			return ctx.Request().Header("X-Debug") == "1"
+		},
+		MaxBodySize: -1,
+		Output:      &buf,
+	}))
+
+	s.Get("/", func(ctx rweb.Context) error {
+		return ctx.WriteString("hello")
+	})
+
+	s.Request(consts.MethodGet, "/", nil, nil)
+	assert.Equal(t, buf.Len(), 0)
+
+	s.Request(consts.MethodGet, "/", []rweb.Header{{Key: "X-Debug", Value: "1"}}, nil)
+	assert.True(t, strings.Contains(buf.String(), "request: GET /"))
+	assert.False(t, strings.Contains(buf.String(), "hello")) // bodies omitted
+}
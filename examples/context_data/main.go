@@ -43,7 +43,7 @@ func main() {
 	// Session middleware
 	s.Use(func(ctx rweb.Context) error {
 		// Add session data if user is logged in
-		if ctx.Has("isLoggedIn") && ctx.Get("isLoggedIn").(bool) {
+		if loggedIn, _ := rweb.GetValue[bool](ctx, "isLoggedIn"); loggedIn {
 			ctx.Set("session", map[string]any{
 				"theme": "dark",
 				"lang":  "en",
@@ -59,12 +59,13 @@ func main() {
 
 	// Protected route
 	s.Get("/profile", func(ctx rweb.Context) error {
-		if !ctx.Has("isLoggedIn") || !ctx.Get("isLoggedIn").(bool) {
+		if loggedIn, _ := rweb.GetValue[bool](ctx, "isLoggedIn"); !loggedIn {
 			return ctx.SetStatus(401).WriteString("Unauthorized")
 		}
 
-		username := ctx.Get("username").(string)
-		userId := ctx.Get("userId").(string)
+		// Typed retrieval - no panic if a value is missing or of another type
+		username, _ := rweb.GetValue[string](ctx, "username")
+		userId, _ := rweb.GetValue[string](ctx, "userId")
 
 		return ctx.WriteJSON(map[string]any{
 			"userId":   userId,
@@ -75,7 +76,7 @@ func main() {
 
 	// Admin-only route
 	s.Get("/admin", func(ctx rweb.Context) error {
-		if !ctx.Has("isAdmin") || !ctx.Get("isAdmin").(bool) {
+		if isAdmin, _ := rweb.GetValue[bool](ctx, "isAdmin"); !isAdmin {
 			return ctx.SetStatus(403).WriteString("Forbidden")
 		}
 
@@ -84,12 +85,12 @@ func main() {
 
 	// Route that modifies context data
 	s.Post("/settings", func(ctx rweb.Context) error {
-		if !ctx.Has("isLoggedIn") || !ctx.Get("isLoggedIn").(bool) {
+		if loggedIn, _ := rweb.GetValue[bool](ctx, "isLoggedIn"); !loggedIn {
 			return ctx.SetStatus(401).WriteString("Unauthorized")
 		}
 
 		// Update session data
-		if session, ok := ctx.Get("session").(map[string]any); ok {
+		if session, ok := rweb.GetValue[map[string]any](ctx, "session"); ok {
 			session["theme"] = "light" // Update theme
 			ctx.Set("session", session)
 		}
@@ -1,8 +1,9 @@
-// Package main demonstrates cookie usage in rweb including sessions,
-// flash messages, and remember me functionality.
+// Package main demonstrates cookie usage in rweb including sessions
+// and flash messages.
 package main
 
 import (
+	"crypto/rand"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -13,9 +14,6 @@ import (
 	"github.com/rohanthewiz/rweb"
 )
 
-// simple in-memory session store (use Redis or similar in production)
-var sessions = make(map[string]map[string]interface{})
-
 // pageLayout is a reusable component for page structure
 type pageLayout struct {
 	Title string
@@ -57,6 +55,13 @@ func (f flashMessage) Render(b *element.Builder) any {
 }
 
 func main() {
+	// the secret signs the session cookie
+	// (load a stable one from your config in production, so sessions survive restarts)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		log.Fatal(err)
+	}
+
 	s := rweb.NewServerWithOptions(
 		rweb.WithAddress(":8080"),
 		rweb.WithVerbose(),
@@ -65,7 +70,10 @@ func main() {
 			HttpOnly: true,                 // prevent JavaScript access by default
 			SameSite: rweb.SameSiteLaxMode, // CSRF protection
 			Path:     "/",                  // cookies available site-wide
+			Secret:   secret,
 		}),
+		// sessions are kept in memory by default (use a SessionStore backed by Redis or similar to scale out)
+		rweb.WithSessions(rweb.SessionCfg{MaxAge: 7 * 24 * time.Hour}),
 	)
 
 	// session middleware - runs for all routes
@@ -98,15 +106,10 @@ func main() {
 	}
 }
 
-// sessionMiddleware loads session data from cookie
+// sessionMiddleware makes the logged in user, if any, available in the context
 func sessionMiddleware(ctx rweb.Context) error {
-	if ctx.HasCookie("session_id") {
-		sessionID, _ := ctx.GetCookie("session_id")
-		if session, exists := sessions[sessionID]; exists {
-			// store session data in context
-			ctx.Set("session", session)
-			ctx.Set("user_id", session["user_id"])
-		}
+	if userID, ok := ctx.Session().Get("user_id").(string); ok {
+		ctx.Set("user_id", userID)
 	}
 	return ctx.Next()
 }
@@ -162,11 +165,6 @@ func (h homePage) Render(b *element.Builder) any {
 				b.Input("type", "password", "name", "password", "required", "required"),
 			),
 			b.Br(),
-			b.Label().R(
-				b.Input("type", "checkbox", "name", "remember", "value", "1"),
-				b.T(" Remember me"),
-			),
-			b.Br(),
 			b.Button("type", "submit").T("Login"),
 		)
 		b.P().T("Hint: Use any username and password \"secret\"")
@@ -216,7 +214,6 @@ func homeHandler(ctx rweb.Context) error {
 func loginHandler(ctx rweb.Context) error {
 	username := ctx.Request().GetPostValue("username")
 	password := ctx.Request().GetPostValue("password")
-	remember := ctx.Request().GetPostValue("remember")
 
 	// simple auth check (use proper auth in production)
 	if password != "secret" {
@@ -224,26 +221,12 @@ func loginHandler(ctx rweb.Context) error {
 		return ctx.Redirect(302, "/")
 	}
 
-	// create session
-	sessionID := generateSessionID()
-	sessions[sessionID] = map[string]interface{}{
-		"user_id":  username,
-		"login_at": time.Now(),
-	}
-
-	// set session cookie
-	if remember == "1" {
-		// persistent cookie for "remember me"
-		cookie := &rweb.Cookie{
-			Name:    "session_id",
-			Value:   sessionID,
-			Expires: time.Now().Add(30 * 24 * time.Hour), // 30 days
-			MaxAge:  30 * 24 * 60 * 60,                   // 30 days in seconds
-		}
-		ctx.SetCookieWithOptions(cookie)
-	} else {
-		// session cookie (expires on browser close)
-		ctx.SetCookie("session_id", sessionID)
+	// start the session - saving it sends the (signed) session cookie
+	sess := ctx.Session()
+	sess.Set("user_id", username)
+	sess.Set("login_at", time.Now())
+	if err := sess.Save(); err != nil {
+		return err
 	}
 
 	setFlashMessage(ctx, "success", fmt.Sprintf("Welcome back, %s!", username))
@@ -252,10 +235,8 @@ func loginHandler(ctx rweb.Context) error {
 
 // logoutHandler clears the session
 func logoutHandler(ctx rweb.Context) error {
-	if ctx.HasCookie("session_id") {
-		sessionID, _ := ctx.GetCookie("session_id")
-		delete(sessions, sessionID)
-		ctx.DeleteCookie("session_id")
+	if err := ctx.Session().Destroy(); err != nil {
+		return err
 	}
 
 	setFlashMessage(ctx, "success", "You have been logged out")
@@ -294,8 +275,7 @@ func (d dashboardPage) Render(b *element.Builder) any {
 // dashboardHandler shows the protected dashboard
 func dashboardHandler(ctx rweb.Context) error {
 	userID := ctx.Get("user_id").(string)
-	session := ctx.Get("session").(map[string]interface{})
-	loginTime := session["login_at"].(time.Time)
+	loginTime := ctx.Session().Get("login_at").(time.Time)
 
 	b := element.NewBuilder()
 
@@ -360,8 +340,3 @@ func getFlashMessage(ctx rweb.Context) map[string]string {
 
 	return flash
 }
-
-// generateSessionID creates a simple session ID
-func generateSessionID() string {
-	return fmt.Sprintf("sess_%d", time.Now().UnixNano())
-}
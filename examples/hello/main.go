@@ -5,7 +5,6 @@ package main
 
 import (
 	"fmt"
-	"io"
 	"log"
 	"os"
 	"time"
@@ -175,29 +174,13 @@ func main() {
 		name := req.FormValue("vehicle")
 		fmt.Println("vehicle:", name)
 
-		// Get the uploaded file
-		// GetFormFile returns: file handle, file header (with metadata), error
-		// We're ignoring the file header (second return value) here
-		file, _, err := req.GetFormFile("file")
-		if err != nil {
-			return err
-		}
-		// Always close the file when done
-		defer file.Close()
-
-		// Read the entire file content into memory
-		// For large files, consider streaming to disk instead
-		data, err := io.ReadAll(file)
-		if err != nil {
-			return err
-		}
-
-		// Save the uploaded file to disk
-		// 0666 permissions: read/write for owner, group, and others
-		err = os.WriteFile("uploaded_file.txt", data, 0666)
+		// Stream the uploaded file straight to disk
+		// SaveFormFile copies in chunks, so large uploads are not read into memory
+		written, err := req.SaveFormFile("file", "uploaded_file.txt")
 		if err != nil {
 			return err
 		}
+		fmt.Println("bytes saved:", written)
 
 		// Return nil indicates successful handling
 		return nil
@@ -9,6 +9,9 @@ replace github.com/rohanthewiz/rweb => ../..
 require github.com/rohanthewiz/rweb v0.1.19-0.20250724033211-0709f777d0de
 
 require (
-	github.com/rohanthewiz/element v0.5.5-0.20260204132123-bceae1a2e28b // indirect
-	github.com/rohanthewiz/serr v1.2.21-0.20260210012051-ba62e01024d8 // indirect
+	github.com/rohanthewiz/element v0.5.6 // indirect
+	github.com/rohanthewiz/serr v1.3.0 // indirect
+	golang.org/x/crypto v0.36.0 // indirect
+	golang.org/x/net v0.21.0 // indirect
+	golang.org/x/text v0.23.0 // indirect
 )
@@ -11,4 +11,9 @@ require (
 	github.com/rohanthewiz/rweb v0.0.0-00010101000000-000000000000
 )
 
-require github.com/rohanthewiz/serr v1.3.0 // indirect
+require (
+	github.com/rohanthewiz/serr v1.3.0 // indirect
+	golang.org/x/crypto v0.36.0 // indirect
+	golang.org/x/net v0.21.0 // indirect
+	golang.org/x/text v0.23.0 // indirect
+)
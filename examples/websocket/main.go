@@ -7,7 +7,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
-	"sync"
 	"time"
 
 	"github.com/rohanthewiz/element"
@@ -28,102 +27,22 @@ type Message struct {
 	Timestamp time.Time `json:"timestamp"` // Message timestamp
 }
 
-// Hub manages WebSocket connections and message broadcasting.
-// It uses a channel-based event loop to serialize access to the client map,
-// with a mutex for the broadcast path where concurrent reads are safe.
-type Hub struct {
-	clients    map[*rweb.WSConn]bool
-	broadcast  chan Message
-	register   chan *rweb.WSConn
-	unregister chan *rweb.WSConn
-	mu         sync.RWMutex
-}
-
-func NewHub() *Hub {
-	return &Hub{
-		clients:    make(map[*rweb.WSConn]bool),
-		broadcast:  make(chan Message, 100),
-		register:   make(chan *rweb.WSConn),
-		unregister: make(chan *rweb.WSConn),
-	}
-}
-
-// Run starts the hub's event loop for managing clients and broadcasting messages
-func (h *Hub) Run() {
-	for {
-		select {
-		case client := <-h.register:
-			h.mu.Lock()
-			h.clients[client] = true
-			h.mu.Unlock()
-
-			// Send welcome message to the new client
-			welcome := Message{
-				Type:      "system",
-				Content:   "Welcome to the WebSocket chat!",
-				Sender:    "Server",
-				Timestamp: time.Now(),
-			}
-			data, _ := json.Marshal(welcome)
-			client.WriteMessage(rweb.TextMessage, data)
-
-			// Notify other clients about the new connection
-			h.broadcast <- Message{
-				Type:      "system",
-				Content:   fmt.Sprintf("A new user joined. Total users: %d", len(h.clients)),
-				Sender:    "Server",
-				Timestamp: time.Now(),
-			}
-
-		case client := <-h.unregister:
-			h.mu.Lock()
-			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				client.Close(1000, "Client disconnected")
-				h.mu.Unlock()
-
-				h.broadcast <- Message{
-					Type:      "system",
-					Content:   fmt.Sprintf("A user left. Total users: %d", len(h.clients)),
-					Sender:    "Server",
-					Timestamp: time.Now(),
-				}
-			} else {
-				h.mu.Unlock()
-			}
-
-		case message := <-h.broadcast:
-			// Fan out the message to all connected clients
-			h.mu.RLock()
-			data, _ := json.Marshal(message)
-			for client := range h.clients {
-				err := client.WriteMessage(rweb.TextMessage, data)
-				if err != nil {
-					// Schedule removal of failed client outside the read lock
-					go func(c *rweb.WSConn) {
-						h.unregister <- c
-					}(client)
-				}
-			}
-			h.mu.RUnlock()
-		}
+// broadcastMessage sends a Message to every client of the hub
+func broadcastMessage(hub *rweb.WSHub, msg Message) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return
 	}
-}
-
-// ClientCount returns the number of connected clients (thread-safe)
-func (h *Hub) ClientCount() int {
-	h.mu.RLock()
-	defer h.mu.RUnlock()
-	return len(h.clients)
+	hub.Broadcast(rweb.TextMessage, data)
 }
 
 func main() {
-	s := rweb.NewServer(
+	s := rweb.NewServerWithOptions(
 		rweb.WithAddress(":8080"),
 		rweb.WithVerbose(),
 	)
 
-	hub := NewHub()
+	hub := rweb.NewWSHub()
 	go hub.Run()
 
 	// Serve the HTML client page built with the element builder
@@ -133,7 +52,7 @@ func main() {
 
 	// Simple echo WebSocket endpoint — demonstrates basic bidirectional messaging
 	s.WebSocket("/ws/echo", func(ws *rweb.WSConn) error {
-		defer ws.Close(1000, "Server closing connection")
+		defer ws.Close(rweb.CloseNormalClosure, "Server closing connection")
 
 		fmt.Printf("Echo WebSocket connected from %s\n", ws.RemoteAddr())
 
@@ -171,34 +90,45 @@ func main() {
 		return nil
 	})
 
-	// Chat WebSocket endpoint — multi-client broadcasting via Hub
+	// Chat WebSocket endpoint — multi-client broadcasting via WSHub
 	s.WebSocket("/ws/chat", func(ws *rweb.WSConn) error {
-		hub.register <- ws
+		hub.Register(ws)
 		defer func() {
-			hub.unregister <- ws
+			hub.Unregister(ws)
+			broadcastMessage(hub, Message{
+				Type:      "system",
+				Content:   "A user left",
+				Sender:    "Server",
+				Timestamp: time.Now(),
+			})
 		}()
 
+		// Send welcome message to the new client, and notify the others
+		err := ws.WriteJSON(Message{
+			Type:      "system",
+			Content:   "Welcome to the WebSocket chat!",
+			Sender:    "Server",
+			Timestamp: time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+		broadcastMessage(hub, Message{
+			Type:      "system",
+			Content:   "A new user joined",
+			Sender:    "Server",
+			Timestamp: time.Now(),
+		})
+
 		fmt.Printf("Chat WebSocket connected from %s\n", ws.RemoteAddr())
 
 		// Ping/pong keepalive so idle connections aren't dropped by proxies.
-		// Done() ensures the ticker goroutine exits cleanly when the connection closes.
+		// Clients that stop answering pings are disconnected, ending the read loop below.
 		ws.SetPongHandler(func(data []byte) error {
 			fmt.Printf("Received pong from %s\n", ws.RemoteAddr())
 			return nil
 		})
-
-		go func() {
-			ticker := time.NewTicker(20 * time.Second)
-			defer ticker.Stop()
-			for {
-				select {
-				case <-ws.Done():
-					return
-				case <-ticker.C:
-					ws.WritePing([]byte("ping"))
-				}
-			}
-		}()
+		ws.EnableAutoPing(20 * time.Second)
 
 		// Read loop — broadcast incoming messages to all clients
 		for {
@@ -219,7 +149,7 @@ func main() {
 						Timestamp: time.Now(),
 					}
 				}
-				hub.broadcast <- incomingMsg
+				broadcastMessage(hub, incomingMsg)
 			}
 		}
 
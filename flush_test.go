@@ -0,0 +1,114 @@
+package rweb_test
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/rohanthewiz/assert"
+	"github.com/rohanthewiz/rweb"
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+func TestFlush(t *testing.T) {
+	readyChan := make(chan struct{}, 1)
+	s := rweb.NewServerWithOptions(
+		rweb.WithAddress("localhost:"),
+		rweb.WithReadyChan(readyChan),
+		rweb.WithDefaultHeaders(rweb.Header{Key: "X-Server", Value: "rweb"}),
+	)
+
+	proceed := make(chan struct{})
+	s.Get("/progress", func(ctx rweb.Context) error {
+		ctx.Response().SetHeader("X-Job", "42")
+		_ = ctx.WriteText("started\n")
+		if err := ctx.Flush(); err != nil {
+			return err
+		}
+		<-proceed // the client has the first part before the handler finishes
+		if err := ctx.WriteString("step 1\n"); err != nil {
+			return err
+		}
+		_ = ctx.Flush()
+		return ctx.WriteString("done\n")
+	})
+	s.Get("/fail", func(ctx rweb.Context) error {
+		_ = ctx.WriteString("partial")
+		_ = ctx.Flush()
+		return errors.New("boom")
+	})
+	s.Get("/plain", func(ctx rweb.Context) error {
+		return ctx.WriteString("plain")
+	})
+
+	go func() {
+		defer syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+		<-readyChan // wait for server
+		addr := fmt.Sprintf("localhost:%s", s.GetListenPort())
+
+		conn, err := net.Dial(consts.ProtocolTCP, addr)
+		assert.Nil(t, err)
+		defer conn.Close()
+		_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+		reader := bufio.NewReader(conn)
+
+		_, err = fmt.Fprintf(conn, "GET /progress HTTP/1.1\r\nHost: %s\r\n\r\n", addr)
+		assert.Nil(t, err)
+		resp, err := http.ReadResponse(reader, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, resp.StatusCode, consts.StatusOK)
+		assert.Equal(t, strings.Join(resp.TransferEncoding, ","), "chunked")
+		assert.Equal(t, resp.Header.Get("X-Job"), "42")
+		assert.Equal(t, resp.Header.Get("X-Server"), "rweb")
+		assert.Equal(t, resp.Header.Get(consts.HeaderContentType), "text/plain")
+
+		first := make([]byte, len("started\n"))
+		_, err = io.ReadFull(resp.Body, first)
+		assert.Nil(t, err)
+		assert.Equal(t, string(first), "started\n")
+
+		close(proceed)
+		rest, err := io.ReadAll(resp.Body)
+		assert.Nil(t, err)
+		assert.Equal(t, string(rest), "step 1\ndone\n")
+
+		// The chunked body was properly terminated, so the connection can be reused
+		_, err = fmt.Fprintf(conn, "GET /plain HTTP/1.1\r\nHost: %s\r\n\r\n", addr)
+		assert.Nil(t, err)
+		resp, err = http.ReadResponse(reader, nil)
+		assert.Nil(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, string(body), "plain")
+		assert.Equal(t, resp.ContentLength, int64(5))
+
+		// An error after flushing leaves the body incomplete
+		resp, err = http.Get(fmt.Sprintf("http://%s/fail", addr))
+		assert.Nil(t, err)
+		assert.Equal(t, resp.StatusCode, consts.StatusOK)
+		body, err = io.ReadAll(resp.Body)
+		assert.Equal(t, string(body), "partial")
+		assert.Equal(t, errors.Is(err, io.ErrUnexpectedEOF), true)
+		_ = resp.Body.Close()
+	}()
+
+	err := s.Run()
+	assert.Nil(t, err)
+
+	// Synthetic requests buffer the whole body
+	s2 := rweb.NewServer()
+	s2.Get("/progress", func(ctx rweb.Context) error {
+		_ = ctx.WriteString("one ")
+		_ = ctx.Flush()
+		return ctx.WriteString("two")
+	})
+	resp := s2.Request(consts.MethodGet, "/progress", nil, nil)
+	assert.Equal(t, string(resp.Body()), "one two")
+}
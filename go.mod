@@ -7,6 +7,11 @@ toolchain go1.23.4
 require (
 	github.com/rohanthewiz/assert v0.1.2
 	github.com/rohanthewiz/element v0.5.6
+	golang.org/x/crypto v0.36.0
 )
 
-require github.com/rohanthewiz/serr v1.3.0 // indirect
+require (
+	github.com/rohanthewiz/serr v1.3.0 // indirect
+	golang.org/x/net v0.21.0 // indirect
+	golang.org/x/text v0.23.0 // indirect
+)
@@ -0,0 +1,59 @@
+package rweb
+
+import (
+	"bufio"
+	"errors"
+
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+const (
+	// defaultMaxHeaderBytes is the most bytes of a request's head (request line and headers), unless the server options say otherwise
+	defaultMaxHeaderBytes = 1 << 20
+	// defaultMaxHeaderCount is the most headers a request may have, unless the server options say otherwise
+	defaultMaxHeaderCount = 1000
+)
+
+// errHeaderTooLarge is returned when reading a request head over the limits
+var errHeaderTooLarge = errors.New("request header too large")
+
+// readHeadLine reads a line of a request's head, of at most limit bytes (line ending included).
+// A longer line isn't buffered in full - errHeaderTooLarge is returned as soon as it is over the limit.
+func readHeadLine(reader *bufio.Reader, limit int) (string, error) {
+	line, err := reader.ReadSlice(consts.RuneNewLine)
+	if len(line) > limit {
+		return "", errHeaderTooLarge
+	}
+	if err == nil {
+		return string(line), nil
+	}
+
+	// Longer than the reader's buffer - gather the pieces
+	buf := append([]byte(nil), line...)
+	for err == bufio.ErrBufferFull {
+		line, err = reader.ReadSlice(consts.RuneNewLine)
+		if buf = append(buf, line...); len(buf) > limit {
+			return "", errHeaderTooLarge
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+// maxHeaderBytes returns the most bytes of a request's head, per the server options
+func (s *Server) maxHeaderBytes() int {
+	if s.options.MaxHeaderBytes > 0 {
+		return s.options.MaxHeaderBytes
+	}
+	return defaultMaxHeaderBytes
+}
+
+// maxHeaderCount returns the most headers a request may have, per the server options
+func (s *Server) maxHeaderCount() int {
+	if s.options.MaxHeaderCount > 0 {
+		return s.options.MaxHeaderCount
+	}
+	return defaultMaxHeaderCount
+}
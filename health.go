@@ -0,0 +1,54 @@
+package rweb
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+// healthResponse is the JSON body of a health check response
+type healthResponse struct {
+	Status string   `json:"status"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+// HealthCheck registers a GET (and HEAD) health endpoint at path, for load balancers and orchestrators.
+// The checks run concurrently on each request. If they all pass (or there are none - a liveness check),
+// it responds 200 with {"status":"ok"}, otherwise 503 with {"status":"unavailable","errors":[...]},
+// listing the failing checks by their position, e.g. "check 2: connection refused".
+// Register liveness and readiness separately:
+//
+//	s.HealthCheck("/healthz")
+//	s.HealthCheck("/readyz", db.Ping, cache.Ping)
+func (s *Server) HealthCheck(path string, checks ...func() error) {
+	handler := func(ctx Context) error {
+		errs := make([]error, len(checks))
+		var wg sync.WaitGroup
+		for i, check := range checks {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs[i] = check()
+			}()
+		}
+		wg.Wait()
+
+		health := healthResponse{Status: "ok"}
+		for i, err := range errs {
+			if err != nil {
+				health.Errors = append(health.Errors, fmt.Sprintf("check %d: %v", i+1, err))
+			}
+		}
+
+		ctx.Response().SetHeader(consts.HeaderCacheControl, "no-store") // always the current state
+		if len(health.Errors) > 0 {
+			health.Status = "unavailable"
+			ctx.SetStatus(consts.StatusServiceUnavailable)
+		}
+		return ctx.WriteJSON(health)
+	}
+
+	s.Get(path, handler)
+	s.Head(path, handler)
+}
@@ -0,0 +1,48 @@
+package rweb_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/rohanthewiz/assert"
+	"github.com/rohanthewiz/rweb"
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+func TestHealthCheck(t *testing.T) {
+	s := rweb.NewServer()
+
+	dbErr := error(nil)
+	s.HealthCheck("/healthz")
+	s.HealthCheck("/readyz",
+		func() error { return nil },
+		func() error { return dbErr },
+		func() error { return errors.New("cache unreachable") },
+	)
+	s.HealthCheck("/ready-db", func() error { return dbErr })
+
+	// Liveness - no checks
+	response := s.Request(consts.MethodGet, "/healthz", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusOK)
+	assert.Equal(t, string(response.Body()), `{"status":"ok"}`)
+	assert.Equal(t, response.Header(consts.HeaderContentType), consts.MIMEJSON)
+	assert.Equal(t, response.Header(consts.HeaderCacheControl), "no-store")
+
+	response = s.Request(consts.MethodHead, "/healthz", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusOK)
+
+	// Readiness - all checks must pass
+	response = s.Request(consts.MethodGet, "/ready-db", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusOK)
+
+	dbErr = errors.New("connection refused")
+	response = s.Request(consts.MethodGet, "/ready-db", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusServiceUnavailable)
+	assert.Equal(t, string(response.Body()), `{"status":"unavailable","errors":["check 1: connection refused"]}`)
+
+	// Failures are listed in check order
+	response = s.Request(consts.MethodGet, "/readyz", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusServiceUnavailable)
+	assert.Equal(t, string(response.Body()),
+		`{"status":"unavailable","errors":["check 2: connection refused","check 3: cache unreachable"]}`)
+}
@@ -43,10 +43,14 @@ func parseURL(url string, urlOpts URLOptions) (scheme string, host string, path
 
 	// FIXUPS
 
+	if urlOpts.CleanPath && path != "" {
+		path = cleanPath(path)
+	}
+
 	if lnPath := len(path); lnPath == 0 {
 		path = "/"
 	} else { // Trailing slash removal
-		if !urlOpts.KeepTrailingSlashes && lnPath > 1 && strings.HasSuffix(path, "/") {
+		if !urlOpts.KeepTrailingSlashes && !urlOpts.StrictTrailingSlashes && lnPath > 1 && strings.HasSuffix(path, "/") {
 			path = path[:lnPath-1]
 		}
 	}
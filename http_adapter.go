@@ -0,0 +1,137 @@
+package rweb
+
+import (
+	"bytes"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+// WrapHTTP adapts a net/http handler (or standard library middleware wrapping one) to an rweb Handler.
+// The handler gets an *http.Request built from the context - method, URL, headers, body,
+// the request's context.Context, and the route's params as path values (r.PathValue("id")).
+// What it writes to its http.ResponseWriter - status, headers and body - becomes the rweb response.
+// As with net/http, headers set after the status is written are ignored, and the Content-Type,
+// if not set, is detected from the body. Flushing (http.Flusher) sends the response so far, as ctx.Flush does.
+// Example:
+//
+//	s.Get("/debug/vars", rweb.WrapHTTP(expvar.Handler()))
+func WrapHTTP(h http.Handler) Handler {
+	return func(ctx Context) error {
+		req, err := newHTTPRequest(ctx)
+		if err != nil {
+			return err
+		}
+
+		w := &httpResponseWriter{ctx: ctx, header: make(http.Header)}
+		h.ServeHTTP(w, req)
+		w.writeHeader(http.StatusOK) // if the handler never wrote
+		return nil
+	}
+}
+
+// HTTPHandler registers a net/http handler for all methods of path, via WrapHTTP.
+// The path may have params and a wildcard, e.g. "/legacy/*path".
+func (s *Server) HTTPHandler(path string, h http.Handler) {
+	s.setMethodProxyHandler(path, WrapHTTP(h))
+}
+
+// newHTTPRequest builds the net/http request for a context
+func newHTTPRequest(ctx Context) (*http.Request, error) {
+	rq := ctx.Request()
+	uri := rq.Path()
+	if rq.Query() != "" {
+		uri += "?" + rq.Query()
+	}
+
+	var body io.Reader = bytes.NewReader(rq.Body())
+	base := baseContext(ctx)
+	if base != nil && base.request.bodyStream != nil {
+		body = base.request.bodyStream // not buffered - see NextPart
+	}
+
+	req, err := http.NewRequestWithContext(ctx.Context(), rq.Method(), uri, body)
+	if err != nil {
+		return nil, err
+	}
+	req.RequestURI = uri
+	req.Header = rq.HeaderMap()
+	req.Host = rq.Header(consts.HeaderHost)
+	if req.Host == "" {
+		req.Host = rq.Host()
+	}
+	req.URL.Host = req.Host
+	req.URL.Scheme = rq.Scheme()
+	req.ContentLength = int64(len(rq.Body()))
+	if base != nil && base.request.bodyStream != nil {
+		req.ContentLength = -1
+	}
+	if conn := ctx.GetConn(); conn != nil {
+		req.RemoteAddr = conn.RemoteAddr().String()
+	}
+	if base != nil {
+		for _, param := range base.request.params {
+			req.SetPathValue(param.Key, param.Value)
+		}
+	}
+	return req, nil
+}
+
+// httpResponseWriter is the http.ResponseWriter of a net/http handler, writing into the rweb response
+type httpResponseWriter struct {
+	ctx         Context
+	header      http.Header
+	wroteHeader bool
+}
+
+// Header returns the headers to send, which take effect when the status is written
+func (w *httpResponseWriter) Header() http.Header {
+	return w.header
+}
+
+// WriteHeader sets the status, and the headers as they stand
+func (w *httpResponseWriter) WriteHeader(status int) {
+	w.writeHeader(status)
+}
+
+// Write writes to the body, first writing a 200 status if none has been
+func (w *httpResponseWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		if w.header.Get(consts.HeaderContentType) == "" && w.header.Get(consts.HeaderTransferEncoding) == "" && len(p) > 0 {
+			w.header.Set(consts.HeaderContentType, http.DetectContentType(p))
+		}
+		w.writeHeader(http.StatusOK)
+	}
+	return w.ctx.Response().Write(p)
+}
+
+// Flush sends the response so far to the client
+func (w *httpResponseWriter) Flush() {
+	w.writeHeader(http.StatusOK)
+	_ = w.ctx.Flush()
+}
+
+// writeHeader copies the status and headers to the rweb response, once
+func (w *httpResponseWriter) writeHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+
+	resp := w.ctx.Response()
+	for _, key := range slices.Sorted(maps.Keys(w.header)) {
+		// We set the body's framing ourselves - don't set it twice
+		if strings.EqualFold(key, consts.HeaderContentLength) || strings.EqualFold(key, consts.HeaderTransferEncoding) {
+			continue
+		}
+		resp.DelHeader(key)
+		for _, value := range w.header[key] {
+			resp.AddHeader(key, value)
+		}
+	}
+	w.ctx.SetStatus(status)
+}
@@ -0,0 +1,95 @@
+package rweb_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/rohanthewiz/assert"
+	"github.com/rohanthewiz/rweb"
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+func TestWrapHTTP(t *testing.T) {
+	s := rweb.NewServer()
+
+	s.Get("/users/:id", rweb.WrapHTTP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("X-Tag", "a")
+		w.Header().Add("X-Tag", "b")
+		w.Header().Set(consts.HeaderContentType, "text/plain")
+		w.WriteHeader(http.StatusAccepted)
+		w.Header().Set("X-Late", "ignored") // as with net/http
+		_, _ = fmt.Fprintf(w, "%s %s %s %s %s", r.Method, r.PathValue("id"), r.URL.Path, r.URL.Query().Get("q"), r.Header.Get("X-Req"))
+	})))
+	s.Get("/sniff", rweb.WrapHTTP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "<html><body>hi</body></html>")
+	})))
+	s.Get("/empty", rweb.WrapHTTP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Only", "headers")
+	})))
+
+	// Standard library middleware
+	s.HTTPHandler("/legacy/*path", http.StripPrefix("/legacy", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.Method+" "+r.URL.Path)
+	})))
+
+	response := s.Request(consts.MethodGet, "/users/42?q=find", []rweb.Header{{Key: "X-Req", Value: "r1"}}, nil)
+	assert.Equal(t, response.Status(), consts.StatusAccepted)
+	assert.Equal(t, string(response.Body()), "GET 42 /users/42 find r1")
+	assert.Equal(t, response.Header(consts.HeaderContentType), "text/plain")
+	assert.Equal(t, strings.Join(response.Headers("X-Tag"), ","), "a,b")
+	assert.Equal(t, response.Header("X-Late"), "")
+
+	response = s.Request(consts.MethodGet, "/sniff", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusOK)
+	assert.Equal(t, response.Header(consts.HeaderContentType), "text/html; charset=utf-8")
+
+	response = s.Request(consts.MethodGet, "/empty", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusOK)
+	assert.Equal(t, response.Header("X-Only"), "headers")
+
+	response = s.Request(consts.MethodDelete, "/legacy/items/3", nil, nil)
+	assert.Equal(t, string(response.Body()), "DELETE /items/3")
+}
+
+func TestWrapHTTPServer(t *testing.T) {
+	readyChan := make(chan struct{}, 1)
+	s := rweb.NewServer(rweb.ServerOptions{ReadyChan: readyChan, Address: "localhost:"})
+
+	s.HTTPHandler("/echo", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set(consts.HeaderContentLength, fmt.Sprint(len(body)+len(r.RemoteAddr)+1)) // ours is used
+		_, _ = fmt.Fprintf(w, "%s %d %s", body, r.ContentLength, r.Host)
+	}))
+	s.HTTPHandler("/progress", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "step 1;")
+		w.(http.Flusher).Flush()
+		_, _ = io.WriteString(w, "step 2")
+	}))
+
+	go func() {
+		defer syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+		<-readyChan // wait for server
+		host := "127.0.0.1:" + s.GetListenPort()
+
+		resp, err := http.Post("http://"+host+"/echo", "text/plain", strings.NewReader("ping"))
+		assert.Nil(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		assert.Equal(t, string(body), "ping 4 "+host)
+		assert.Equal(t, resp.ContentLength, int64(len(body)))
+
+		resp, err = http.Get("http://" + host + "/progress")
+		assert.Nil(t, err)
+		body, _ = io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		assert.Equal(t, string(body), "step 1;step 2")
+		assert.Equal(t, resp.TransferEncoding[0], "chunked")
+	}()
+
+	_ = s.Run()
+}
@@ -0,0 +1,69 @@
+package rweb
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+// JSONEncoderCfg configures how JSON responses are encoded (ctx.WriteJSON, rweb.JSON, StreamJSON, JSONError and Render),
+// as well as SSEHub broadcasts and NotifyStreamingClients notices.
+// The zero value is encoding/json's defaults - compact, with HTML characters escaped.
+type JSONEncoderCfg struct {
+	// DisableHTMLEscape leaves <, > and & in strings as they are, rather than escaped as \u003c, \u003e and \u0026
+	DisableHTMLEscape bool
+	// Indent, when set, pretty-prints the JSON with it as the indentation (e.g. "  ")
+	Indent string
+}
+
+// newEncoder returns a JSON encoder writing to w, as configured
+func (cfg JSONEncoderCfg) newEncoder(w io.Writer) *json.Encoder {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(!cfg.DisableHTMLEscape)
+	if cfg.Indent != "" {
+		enc.SetIndent("", cfg.Indent)
+	}
+	return enc
+}
+
+// marshal returns the JSON encoding of v, as configured
+func (cfg JSONEncoderCfg) marshal(v any) ([]byte, error) {
+	if cfg == (JSONEncoderCfg{}) {
+		return json.Marshal(v)
+	}
+
+	var buf bytes.Buffer
+	if err := cfg.newEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte{'\n'}), nil // as json.Marshal has no newline
+}
+
+// jsonEncoder returns the server's JSON encoder config
+func (ctx *context) jsonEncoder() JSONEncoderCfg {
+	if ctx.server == nil {
+		return JSONEncoderCfg{}
+	}
+	return ctx.server.options.JSONEncoder
+}
+
+// WriteJSONIndent writes the given value to the response as pretty-printed JSON, indented with indent,
+// e.g. for debug output. The server's JSONEncoder config applies otherwise.
+func (ctx *context) WriteJSONIndent(body any, indent string) error {
+	cfg := ctx.jsonEncoder()
+	cfg.Indent = indent
+	return ctx.writeJSON(body, cfg)
+}
+
+// writeJSON writes the given value to the response as JSON, encoded per cfg
+func (ctx *context) writeJSON(body any, cfg JSONEncoderCfg) error {
+	byts, err := cfg.marshal(body)
+	if err != nil {
+		return err
+	}
+	ctx.response.SetHeader(consts.HeaderContentType, consts.MIMEJSON)
+	_, err = ctx.response.Write(byts)
+	return err
+}
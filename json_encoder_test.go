@@ -0,0 +1,68 @@
+package rweb_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/rohanthewiz/assert"
+	"github.com/rohanthewiz/rweb"
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+func TestJSONEncoder(t *testing.T) {
+	data := map[string]any{"html": "<b>Tom & Jerry</b>", "n": 1}
+
+	routes := func(s *rweb.Server) {
+		s.Get("/write", func(ctx rweb.Context) error {
+			return ctx.WriteJSON(data)
+		})
+		s.Get("/json", func(ctx rweb.Context) error {
+			return rweb.JSON(ctx, data)
+		})
+		s.Get("/stream", func(ctx rweb.Context) error {
+			return ctx.StreamJSON(func(enc *json.Encoder) error {
+				return enc.Encode(data)
+			})
+		})
+		s.Get("/indent", func(ctx rweb.Context) error {
+			return ctx.WriteJSONIndent(data, "  ")
+		})
+	}
+
+	// Defaults - compact, with HTML escaped
+	s := rweb.NewServer()
+	routes(s)
+	response := s.Request(consts.MethodGet, "/write", nil, nil)
+	assert.Equal(t, string(response.Body()), `{"html":"\u003cb\u003eTom \u0026 Jerry\u003c/b\u003e","n":1}`)
+	assert.Equal(t, response.Header(consts.HeaderContentType), consts.MIMEJSON)
+	response = s.Request(consts.MethodGet, "/indent", nil, nil)
+	assert.Equal(t, string(response.Body()), "{\n  \"html\": \"\\u003cb\\u003eTom \\u0026 Jerry\\u003c/b\\u003e\",\n  \"n\": 1\n}")
+
+	// HTML left as is
+	s = rweb.NewServerWithOptions(rweb.WithJSONEncoder(rweb.JSONEncoderCfg{DisableHTMLEscape: true}))
+	routes(s)
+	for _, path := range []string{"/write", "/json", "/stream"} {
+		response = s.Request(consts.MethodGet, path, nil, nil)
+		assert.Contains(t, string(response.Body()), `{"html":"<b>Tom & Jerry</b>","n":1}`)
+	}
+	response = s.Request(consts.MethodGet, "/indent", nil, nil)
+	assert.Equal(t, string(response.Body()), "{\n  \"html\": \"<b>Tom & Jerry</b>\",\n  \"n\": 1\n}")
+
+	// SSE hub broadcasts too, once the hub is served
+	hub := rweb.NewSSEHub()
+	defer hub.Close()
+	s.Get("/events", hub.Handler(s))
+	client := make(chan any, 1)
+	hub.Register(client)
+	hub.Broadcast(rweb.SSEvent{Type: "update", Data: data})
+	evt := (<-client).(rweb.SSEvent)
+	assert.Equal(t, evt.Data, `{"data":{"html":"<b>Tom & Jerry</b>","n":1},"type":"update"}`)
+
+	// Pretty-printed throughout
+	s = rweb.NewServer(rweb.ServerOptions{JSONEncoder: rweb.JSONEncoderCfg{Indent: "\t"}})
+	routes(s)
+	response = s.Request(consts.MethodGet, "/write", nil, nil)
+	assert.Equal(t, string(response.Body()), "{\n\t\"html\": \"\\u003cb\\u003eTom \\u0026 Jerry\\u003c/b\\u003e\",\n\t\"n\": 1\n}")
+	response = s.Request(consts.MethodGet, "/json", nil, nil)
+	assert.Equal(t, string(response.Body()), "{\n\t\"html\": \"\\u003cb\\u003eTom \\u0026 Jerry\\u003c/b\\u003e\",\n\t\"n\": 1\n}\n")
+}
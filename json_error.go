@@ -0,0 +1,42 @@
+package rweb
+
+// JSONErrorFormatter builds the body of the JSON error responses of ctx.JSONError, for APIs with their own error shape.
+// details is nil when none are given, the value when one is, or a []any of them all.
+type JSONErrorFormatter func(status int, message string, details any) any
+
+// JSONErrorBody is the error of the default JSON error response: {"error": {"code": ..., "message": ..., "details": ...}}
+type JSONErrorBody struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Details any    `json:"details,omitempty"`
+}
+
+// jsonErrorResponse is the default JSON error response
+type jsonErrorResponse struct {
+	Error JSONErrorBody `json:"error"`
+}
+
+// JSONError sets the status and writes a JSON error response, so API errors share one shape.
+// By default it is {"error": {"code": status, "message": message, "details": ...}}, with details omitted if none are given.
+// The shape can be changed for the whole server with the JSONErrorFormat option.
+// Example:
+//
+//	return ctx.JSONError(consts.StatusBadRequest, "invalid order", map[string]string{"qty": "must be positive"})
+func (ctx *context) JSONError(status int, message string, details ...any) error {
+	var detail any
+	switch len(details) {
+	case 0:
+	case 1:
+		detail = details[0]
+	default:
+		detail = details
+	}
+
+	var body any = jsonErrorResponse{Error: JSONErrorBody{Code: status, Message: message, Details: detail}}
+	if ctx.server != nil && ctx.server.options.JSONErrorFormat != nil {
+		body = ctx.server.options.JSONErrorFormat(status, message, detail)
+	}
+
+	ctx.SetStatus(status)
+	return ctx.WriteJSON(body)
+}
@@ -0,0 +1,46 @@
+package rweb_test
+
+import (
+	"testing"
+
+	"github.com/rohanthewiz/assert"
+	"github.com/rohanthewiz/rweb"
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+func TestJSONError(t *testing.T) {
+	s := rweb.NewServer()
+	s.Get("/plain", func(ctx rweb.Context) error {
+		return ctx.JSONError(consts.StatusNotFound, "no such order")
+	})
+	s.Get("/detailed", func(ctx rweb.Context) error {
+		return ctx.JSONError(consts.StatusBadRequest, "invalid order", map[string]string{"qty": "must be positive"})
+	})
+	s.Get("/several", func(ctx rweb.Context) error {
+		return ctx.JSONError(consts.StatusConflict, "conflict", "a", 2)
+	})
+
+	response := s.Request(consts.MethodGet, "/plain", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusNotFound)
+	assert.Equal(t, response.Header(consts.HeaderContentType), "application/json")
+	assert.Equal(t, string(response.Body()), `{"error":{"code":404,"message":"no such order"}}`)
+
+	response = s.Request(consts.MethodGet, "/detailed", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusBadRequest)
+	assert.Equal(t, string(response.Body()),
+		`{"error":{"code":400,"message":"invalid order","details":{"qty":"must be positive"}}}`)
+
+	response = s.Request(consts.MethodGet, "/several", nil, nil)
+	assert.Equal(t, string(response.Body()), `{"error":{"code":409,"message":"conflict","details":["a",2]}}`)
+
+	// A custom shape
+	s2 := rweb.NewServerWithOptions(rweb.WithJSONErrorFormat(func(status int, message string, details any) any {
+		return map[string]any{"status": status, "title": message}
+	}))
+	s2.Get("/plain", func(ctx rweb.Context) error {
+		return ctx.JSONError(consts.StatusNotFound, "no such order")
+	})
+	response = s2.Request(consts.MethodGet, "/plain", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusNotFound)
+	assert.Equal(t, string(response.Body()), `{"status":404,"title":"no such order"}`)
+}
@@ -0,0 +1,93 @@
+package rweb
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// LoggerOptions configures the Logger middleware
+type LoggerOptions struct {
+	// Output is where log lines are written. Defaults to os.Stdout.
+	Output io.Writer
+	// JSON writes each entry as a JSON object, for log aggregators, instead of a formatted line
+	JSON bool
+	// SkipPaths are request paths not to log, e.g. health checks
+	SkipPaths []string
+}
+
+// accessLogEntry is a Logger entry in JSON form
+type accessLogEntry struct {
+	Time       string  `json:"time"`
+	RequestID  string  `json:"request_id,omitempty"`
+	Method     string  `json:"method"`
+	Path       string  `json:"path"`
+	Status     int     `json:"status"`
+	DurationMs float64 `json:"duration_ms"`
+	Bytes      int     `json:"bytes"`
+	ClientIP   string  `json:"client_ip"`
+}
+
+// Logger returns a middleware that writes an access log entry for each request once it is handled,
+// with the method, path, status, duration, response size and client IP.
+// The request's ID is included when the RequestID middleware is in use.
+// Example:
+//
+//	s.Use(rweb.Logger(rweb.LoggerOptions{JSON: true, SkipPaths: []string{"/healthz"}}))
+func Logger(opts LoggerOptions) Handler {
+	out := opts.Output
+	if out == nil {
+		out = os.Stdout
+	}
+	var mu sync.Mutex // keep concurrent entries from interleaving
+
+	return func(ctx Context) error {
+		if slices.Contains(opts.SkipPaths, ctx.Request().Path()) {
+			return ctx.Next()
+		}
+
+		start := time.Now()
+		err := ctx.Next()
+
+		entry := accessLogEntry{
+			Time:       start.UTC().Format(time.RFC3339),
+			RequestID:  ctx.RequestID(),
+			Method:     ctx.Request().Method(),
+			Path:       ctx.Request().Path(),
+			Status:     ctx.Response().Status(),
+			DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+			Bytes:      ctx.Response().BytesWritten(),
+			ClientIP:   ctx.ClientIP(),
+		}
+		if err != nil && (entry.Status == 0 || entry.Status == 200) {
+			entry.Status = 500 // as the server's error handler will respond
+		}
+
+		var line []byte
+		if opts.JSON {
+			line, _ = json.Marshal(entry)
+			line = append(line, '\n')
+		} else {
+			var reqID string
+			if entry.RequestID != "" {
+				reqID = " " + entry.RequestID
+			}
+			clientIP := entry.ClientIP
+			if clientIP == "" {
+				clientIP = "-"
+			}
+			line = fmt.Appendf(nil, "%s%s %s %s %q -> %d [%.3fms] %dB\n", entry.Time, reqID,
+				clientIP, entry.Method, entry.Path, entry.Status, entry.DurationMs, entry.Bytes)
+		}
+
+		mu.Lock()
+		_, _ = out.Write(line)
+		mu.Unlock()
+
+		return err
+	}
+}
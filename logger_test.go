@@ -0,0 +1,73 @@
+package rweb_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/rohanthewiz/assert"
+	"github.com/rohanthewiz/rweb"
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+func TestLogger(t *testing.T) {
+	out := &bytes.Buffer{}
+	s := rweb.NewServer()
+	s.Use(rweb.RequestID())
+	s.Use(rweb.Logger(rweb.LoggerOptions{Output: out, SkipPaths: []string{"/healthz"}}))
+
+	s.Get("/users/:id", func(ctx rweb.Context) error {
+		return ctx.WriteString("user " + ctx.Request().Param("id"))
+	})
+	s.Get("/healthz", func(ctx rweb.Context) error {
+		return ctx.WriteString("ok")
+	})
+	s.Get("/fail", func(ctx rweb.Context) error {
+		return errors.New("boom")
+	})
+
+	s.Request(consts.MethodGet, "/users/42", []rweb.Header{{Key: consts.HeaderXRequestID, Value: "req-1"}}, nil)
+	s.Request(consts.MethodGet, "/healthz", nil, nil)
+	s.Request(consts.MethodGet, "/fail", nil, nil)
+	s.Request(consts.MethodPost, "/missing", nil, nil)
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	assert.Equal(t, len(lines), 3)
+	assert.True(t, regexp.MustCompile(`^\S+Z req-1 - GET "/users/42" -> 200 \[\d+\.\d{3}ms\] 7B$`).MatchString(lines[0]))
+	assert.True(t, regexp.MustCompile(`^\S+Z \S{19} - GET "/fail" -> 500 `).MatchString(lines[1]))
+	assert.True(t, regexp.MustCompile(`^\S+Z \S{19} - POST "/missing" -> 404 `).MatchString(lines[2]))
+}
+
+func TestLoggerJSON(t *testing.T) {
+	out := &bytes.Buffer{}
+	s := rweb.NewServer()
+	s.Use(rweb.Logger(rweb.LoggerOptions{Output: out, JSON: true}))
+
+	s.Post("/items", func(ctx rweb.Context) error {
+		ctx.SetStatus(consts.StatusCreated)
+		return ctx.WriteJSON(map[string]int{"id": 1})
+	})
+
+	s.Request(consts.MethodPost, "/items", nil, nil)
+
+	var entry struct {
+		Method     string   `json:"method"`
+		Path       string   `json:"path"`
+		Status     int      `json:"status"`
+		Bytes      int      `json:"bytes"`
+		ClientIP   string   `json:"client_ip"`
+		RequestID  *string  `json:"request_id"`
+		DurationMs *float64 `json:"duration_ms"`
+	}
+	assert.Nil(t, json.Unmarshal(out.Bytes(), &entry))
+	assert.Equal(t, entry.Method, "POST")
+	assert.Equal(t, entry.Path, "/items")
+	assert.Equal(t, entry.Status, consts.StatusCreated)
+	assert.Equal(t, entry.Bytes, 8)
+	assert.Equal(t, entry.ClientIP, "") // no connection for synthetic requests
+	assert.True(t, entry.RequestID == nil)
+	assert.True(t, entry.DurationMs != nil)
+}
@@ -0,0 +1,109 @@
+package rweb
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Metrics receives an observation of every request, after its response is written,
+// e.g. to feed Prometheus counters and histograms, or a MemoryMetrics.
+// It is called from the connections' goroutines, so it must be safe for concurrent use.
+type Metrics interface {
+	// ObserveRequest records a request. path is the route pattern it matched (e.g. "/users/:id"),
+	// so a route's metrics aren't split by its params, or "" if it matched no route (e.g. a 404).
+	// dur is the time taken to handle the request and write the response.
+	ObserveRequest(method, path string, status int, dur time.Duration)
+}
+
+// RouteMetrics are the metrics for the requests to a route with a given response status
+type RouteMetrics struct {
+	Method        string        `json:"method"`
+	Path          string        `json:"path"`
+	Status        int           `json:"status"`
+	Count         int64         `json:"count"`
+	TotalDuration time.Duration `json:"total_duration_ns"`
+	MaxDuration   time.Duration `json:"max_duration_ns"`
+}
+
+// routeMetricsKey identifies the metrics of a route and status
+type routeMetricsKey struct {
+	method string
+	path   string
+	status int
+}
+
+// MemoryMetrics is a simple Metrics, keeping the count and durations of requests per route and status in memory.
+// Serve them for scraping with its Handler:
+//
+//	metrics := rweb.NewMemoryMetrics()
+//	s := rweb.NewServerWithOptions(rweb.WithMetrics(metrics))
+//	s.Get("/metrics", metrics.Handler())
+type MemoryMetrics struct {
+	mu     sync.Mutex
+	routes map[routeMetricsKey]*RouteMetrics
+}
+
+// NewMemoryMetrics returns an empty MemoryMetrics
+func NewMemoryMetrics() *MemoryMetrics {
+	return &MemoryMetrics{routes: make(map[routeMetricsKey]*RouteMetrics)}
+}
+
+// ObserveRequest records a request
+func (m *MemoryMetrics) ObserveRequest(method, path string, status int, dur time.Duration) {
+	key := routeMetricsKey{method: method, path: path, status: status}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rm := m.routes[key]
+	if rm == nil {
+		rm = &RouteMetrics{Method: method, Path: path, Status: status}
+		m.routes[key] = rm
+	}
+	rm.Count++
+	rm.TotalDuration += dur
+	rm.MaxDuration = max(rm.MaxDuration, dur)
+}
+
+// Snapshot returns the metrics so far, sorted by path, method and status
+func (m *MemoryMetrics) Snapshot() []RouteMetrics {
+	m.mu.Lock()
+	snapshot := make([]RouteMetrics, 0, len(m.routes))
+	for _, rm := range m.routes {
+		snapshot = append(snapshot, *rm)
+	}
+	m.mu.Unlock()
+
+	slices.SortFunc(snapshot, func(a, b RouteMetrics) int {
+		return cmp.Or(cmp.Compare(a.Path, b.Path), cmp.Compare(a.Method, b.Method), cmp.Compare(a.Status, b.Status))
+	})
+	return snapshot
+}
+
+// Reset clears the metrics
+func (m *MemoryMetrics) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	clear(m.routes)
+}
+
+// Handler returns a handler responding with a JSON snapshot of the metrics
+func (m *MemoryMetrics) Handler() Handler {
+	return func(ctx Context) error {
+		return ctx.WriteJSON(m.Snapshot())
+	}
+}
+
+// routeObserved wraps a route's handler to note its pattern for the Metrics, when in use
+func (s *Server) routeObserved(path string, handler Handler) Handler {
+	if s.options.Metrics == nil {
+		return handler
+	}
+	return func(ctx Context) error {
+		if c := baseContext(ctx); c != nil {
+			c.route = path
+		}
+		return handler(ctx)
+	}
+}
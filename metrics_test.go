@@ -0,0 +1,80 @@
+package rweb_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rohanthewiz/assert"
+	"github.com/rohanthewiz/rweb"
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+func TestMemoryMetrics(t *testing.T) {
+	metrics := rweb.NewMemoryMetrics()
+	s := rweb.NewServerWithOptions(rweb.WithMetrics(metrics))
+
+	s.Get("/users/:id", func(ctx rweb.Context) error {
+		time.Sleep(2 * time.Millisecond)
+		return ctx.WriteString("user " + ctx.Request().Param("id"))
+	})
+	api := s.Group("/api")
+	api.Post("/orders", func(ctx rweb.Context) error {
+		return errors.New("out of stock")
+	})
+	s.Get("/metrics", metrics.Handler())
+
+	s.Request(consts.MethodGet, "/users/1", nil, nil)
+	s.Request(consts.MethodGet, "/users/2", nil, nil)
+	s.Request(consts.MethodPost, "/api/orders", nil, nil)
+	s.Request(consts.MethodGet, "/nowhere", nil, nil)
+
+	snapshot := metrics.Snapshot()
+	assert.Equal(t, len(snapshot), 3)
+
+	// Unmatched requests have no route
+	assert.Equal(t, snapshot[0].Path, "")
+	assert.Equal(t, snapshot[0].Status, consts.StatusNotFound)
+
+	assert.Equal(t, snapshot[1].Path, "/api/orders")
+	assert.Equal(t, snapshot[1].Method, consts.MethodPost)
+	assert.Equal(t, snapshot[1].Status, consts.StatusInternalServerError)
+	assert.Equal(t, snapshot[1].Count, int64(1))
+
+	// Requests for a route are counted together, whatever their params
+	users := snapshot[2]
+	assert.Equal(t, users.Path, "/users/:id")
+	assert.Equal(t, users.Status, consts.StatusOK)
+	assert.Equal(t, users.Count, int64(2))
+	assert.True(t, users.MaxDuration >= 2*time.Millisecond)
+	assert.True(t, users.TotalDuration >= 4*time.Millisecond)
+
+	// Scraped
+	response := s.Request(consts.MethodGet, "/metrics", nil, nil)
+	var scraped []rweb.RouteMetrics
+	assert.Nil(t, json.Unmarshal(response.Body(), &scraped))
+	assert.Equal(t, len(scraped), 3)
+	assert.Equal(t, scraped[2].Count, int64(2))
+
+	metrics.Reset()
+	assert.Equal(t, len(metrics.Snapshot()), 0)
+}
+
+func TestMemoryMetricsTimeout(t *testing.T) {
+	metrics := rweb.NewMemoryMetrics()
+	s := rweb.NewServerWithOptions(rweb.WithMetrics(metrics))
+	s.Use(rweb.Timeout(time.Second))
+
+	s.Get("/users/:id", func(ctx rweb.Context) error {
+		return ctx.WriteString("user " + ctx.Request().Param("id"))
+	})
+
+	s.Request(consts.MethodGet, "/users/1", nil, nil)
+
+	// The route is noted by the handler, run by Timeout on a copy of the context
+	snapshot := metrics.Snapshot()
+	assert.Equal(t, len(snapshot), 1)
+	assert.Equal(t, snapshot[0].Path, "/users/:id")
+	assert.Equal(t, snapshot[0].Count, int64(1))
+}
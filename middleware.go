@@ -6,12 +6,17 @@ import (
 )
 
 // RequestInfo is a middleware giving basic request / response stats
+// The request's ID is included when the RequestID middleware is in use
 func RequestInfo(ctx Context) error {
 	start := time.Now()
 
 	defer func() {
-		fmt.Printf("%sZ %s %q -> %d [%s]\n",
-			time.Now().UTC().Format("20060102T150405"),
+		var reqID string
+		if id := ctx.RequestID(); id != "" {
+			reqID = " " + id
+		}
+		fmt.Printf("%sZ%s %s %q -> %d [%s]\n",
+			time.Now().UTC().Format("20060102T150405"), reqID,
 			ctx.Request().Method(), ctx.Request().Path(), ctx.Response().Status(), time.Since(start))
 	}()
 
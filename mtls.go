@@ -0,0 +1,55 @@
+package rweb
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// configureClientAuth sets up verification of client certificates (mutual TLS) per the TLS options
+func configureClientAuth(tlsConfig *tls.Config, cfg TLSCfg) error {
+	if cfg.ClientCAFile == "" {
+		if cfg.RequireClientCert {
+			return errors.New("RequireClientCert needs a ClientCAFile to verify client certificates against")
+		}
+		return nil
+	}
+
+	pemCerts, err := os.ReadFile(cfg.ClientCAFile)
+	if err != nil {
+		return fmt.Errorf("failed to read client CA file: %v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pemCerts) {
+		return fmt.Errorf("no certificates found in client CA file %q", cfg.ClientCAFile)
+	}
+	tlsConfig.ClientCAs = pool
+
+	if cfg.RequireClientCert {
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	} else {
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return nil
+}
+
+// ClientCert returns the verified certificate the client presented over mutual TLS,
+// or nil if there is none (plain HTTP, no client cert, or a synthetic request).
+// Handlers can authorize on it, e.g. by ctx.ClientCert().Subject.CommonName
+func (ctx *context) ClientCert() *x509.Certificate {
+	var state tls.ConnectionState
+	if tlsConn, ok := ctx.conn.(*tls.Conn); ok {
+		state = tlsConn.ConnectionState()
+	} else if ctx.httpReq != nil && ctx.httpReq.TLS != nil { // served through ServeHTTP
+		state = *ctx.httpReq.TLS
+	} else {
+		return nil
+	}
+	// Only report certs that verified against our client CAs
+	if len(state.VerifiedChains) == 0 || len(state.PeerCertificates) == 0 {
+		return nil
+	}
+	return state.PeerCertificates[0]
+}
@@ -0,0 +1,148 @@
+package rweb
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/rohanthewiz/assert"
+)
+
+// issueTestCert creates a certificate for commonName, signed by parent (self-signed if nil)
+func issueTestCert(t *testing.T, commonName string, isCA bool, parent *tls.Certificate) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if isCA {
+		tmpl.KeyUsage = x509.KeyUsageCertSign
+	}
+
+	signer, signerKey := tmpl, any(key)
+	if parent != nil {
+		signer, signerKey = parent.Leaf, parent.PrivateKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	assert.Nil(t, err)
+	leaf, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	caFile := filepath.Join(dir, "ca.pem")
+
+	writeTestCert(t, certFile, keyFile, "localhost", time.Now())
+
+	ca := issueTestCert(t, "Test CA", true, nil)
+	assert.Nil(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Certificate[0]}), 0600))
+	clientCert := issueTestCert(t, "billing-service", false, &ca)
+	strangerCert := issueTestCert(t, "stranger", false, nil)
+
+	readyChan := make(chan struct{}, 1)
+	s := NewServer(ServerOptions{
+		ReadyChan: readyChan,
+		TLS: TLSCfg{
+			UseTLS:            true,
+			TLSAddr:           "localhost:",
+			CertFile:          certFile,
+			KeyFile:           keyFile,
+			ClientCAFile:      caFile,
+			RequireClientCert: true,
+		},
+	})
+
+	s.Get("/whoami", func(ctx Context) error {
+		cert := ctx.ClientCert()
+		if cert == nil {
+			return ctx.WriteString("anonymous")
+		}
+		return ctx.WriteString(cert.Subject.CommonName)
+	})
+
+	get := func(certs ...tls.Certificate) (string, error) {
+		client := &http.Client{Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true, Certificates: certs},
+		}}
+		defer client.CloseIdleConnections()
+
+		resp, err := client.Get(fmt.Sprintf("https://localhost:%s/whoami", s.GetListenPort()))
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		return string(body), err
+	}
+
+	go func() {
+		defer syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+		<-readyChan // wait for server
+
+		// A cert from our CA is accepted, and its subject is available to handlers
+		name, err := get(clientCert)
+		assert.Nil(t, err)
+		assert.Equal(t, name, "billing-service")
+
+		// No cert, or one from another CA, is refused
+		_, err = get()
+		assert.NotNil(t, err)
+		_, err = get(strangerCert)
+		assert.NotNil(t, err)
+	}()
+
+	err := s.Run()
+	assert.Nil(t, err)
+
+	// Not over TLS
+	s2 := NewServer()
+	s2.Get("/whoami", func(ctx Context) error {
+		assert.Nil(t, ctx.ClientCert())
+		return nil
+	})
+	s2.Request("GET", "/whoami", nil, nil)
+}
+
+func TestConfigureClientAuth(t *testing.T) {
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+
+	// Optional client certs
+	ca := issueTestCert(t, "Test CA", true, nil)
+	assert.Nil(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Certificate[0]}), 0600))
+	cfg := &tls.Config{}
+	assert.Nil(t, configureClientAuth(cfg, TLSCfg{ClientCAFile: caFile}))
+	assert.Equal(t, cfg.ClientAuth, tls.VerifyClientCertIfGiven)
+
+	// Nothing to verify against
+	assert.NotNil(t, configureClientAuth(&tls.Config{}, TLSCfg{RequireClientCert: true}))
+
+	// Not a CA bundle
+	assert.Nil(t, os.WriteFile(caFile, []byte("garbage"), 0600))
+	assert.NotNil(t, configureClientAuth(&tls.Config{}, TLSCfg{ClientCAFile: caFile}))
+}
@@ -0,0 +1,125 @@
+package rweb
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http/httputil"
+	"time"
+
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+// ErrNotMultipart is returned by NextPart for a request without a multipart body
+var ErrNotMultipart = errors.New("request is not multipart")
+
+// maxStreamDrain is the most of a streamed request body left unread by its handler that is skipped,
+// to keep the connection for the next request. Beyond it, the connection is closed instead.
+const maxStreamDrain = 256 << 10
+
+// NextPart returns the next part of a multipart request body, or io.EOF after the last part.
+// For bodies over the server's MultipartStreamThreshold, the parts are read straight from the connection,
+// so an upload of any size can be processed (e.g. saved to disk, or passed on) without buffering it in memory.
+// The body isn't available otherwise then - Body() is empty, and FormValue and GetFormFile find nothing.
+// A part must be read before the next is requested. Smaller bodies are buffered and parsed as usual,
+// but can still be read part by part.
+// Example:
+//
+//	for {
+//		part, err := ctx.Request().NextPart()
+//		if err == io.EOF {
+//			break
+//		}
+//		if err != nil {
+//			return err
+//		}
+//		n, err := io.Copy(dest, part)
+//		...
+//	}
+func (req *request) NextPart() (*multipart.Part, error) {
+	if req.partReader == nil {
+		if !bytes.HasPrefix(req.ContentType, consts.BytMultipartFormData) {
+			return nil, ErrNotMultipart
+		}
+		_, params, err := mime.ParseMediaType(b2s(req.ContentType))
+		if err != nil {
+			return nil, err
+		}
+		boundary := params["boundary"]
+		if boundary == "" {
+			return nil, ErrNotMultipart
+		}
+
+		var body io.Reader = req.bodyStream
+		if body == nil {
+			body = bytes.NewReader(req.body)
+		}
+		req.partReader = multipart.NewReader(body, boundary)
+	}
+	return req.partReader.NextPart()
+}
+
+// streamsMultipart reports whether a request body is to be left on the connection for its handler
+// to read with NextPart, rather than buffered - a multipart body over the MultipartStreamThreshold.
+// Chunked bodies, of unknown size, are streamed whenever the threshold is set.
+func (s *Server) streamsMultipart(method string, contentLen int64, isChunked bool, contentType []byte) bool {
+	threshold := s.options.MultipartStreamThreshold
+	if threshold <= 0 || method == consts.MethodHead || method == consts.MethodTrace ||
+		!bytes.HasPrefix(contentType, consts.BytMultipartFormData) {
+		return false
+	}
+	return isChunked || contentLen > threshold
+}
+
+// streamBody sets up the request body to be read from the connection by the handler.
+// bodyReader is the connection's reader, possibly wrapped to report upload progress.
+func streamBody(ctx *context, bodyReader io.Reader, contentLen int64, isChunked bool) {
+	if !isChunked {
+		ctx.request.bodyStream = io.LimitReader(bodyReader, contentLen)
+		return
+	}
+
+	// The chunk framing is read directly, and progress reported on the data within
+	var stream io.Reader = httputil.NewChunkedReader(ctx.reader)
+	if pr, ok := bodyReader.(*progressReader); ok {
+		pr.reader = stream
+		stream = pr
+	}
+	ctx.request.bodyStream = stream
+}
+
+// finishBodyStream skips whatever of a streamed request body its handler didn't read,
+// so the next request on the connection can be read.
+// It returns false if the connection can't be kept - the rest of the body is too large to skip, or broken.
+func (s *Server) finishBodyStream(ctx *context, isChunked bool) bool {
+	if ctx.request.bodyStream == nil {
+		return true
+	}
+	if s.options.ReadTimeout > 0 {
+		_ = ctx.conn.SetReadDeadline(time.Now().Add(s.options.ReadTimeout))
+	}
+
+	if _, err := io.CopyN(io.Discard, ctx.request.bodyStream, maxStreamDrain+1); err != io.EOF {
+		return false // too much left, or a read error
+	}
+	if isChunked {
+		return skipTrailer(ctx.reader)
+	}
+	return true
+}
+
+// skipTrailer reads past the trailer of a chunked body - any trailer fields, up to the empty line ending it
+func skipTrailer(reader *bufio.Reader) bool {
+	for {
+		line, err := reader.ReadString(consts.RuneNewLine)
+		if err != nil {
+			return false
+		}
+		if line == consts.CRLF {
+			return true
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package rweb
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+// AddMethodNamed registers a handler like AddMethod and records the route's path under name,
+// so URLs for it can be built with URL. Registering a name again replaces the earlier path.
+func (s *Server) AddMethodNamed(name string, method string, path string, handler Handler) {
+	if s.namedRoutes == nil {
+		s.namedRoutes = make(map[string]string)
+	}
+	s.namedRoutes[name] = path
+	s.AddMethod(method, path, handler)
+}
+
+// GetNamed registers a named GET route.
+// Example: s.GetNamed("user", "/users/:id", userHandler)
+func (s *Server) GetNamed(name string, path string, handler Handler) {
+	s.AddMethodNamed(name, consts.MethodGet, path, handler)
+}
+
+// PostNamed registers a named POST route.
+func (s *Server) PostNamed(name string, path string, handler Handler) {
+	s.AddMethodNamed(name, consts.MethodPost, path, handler)
+}
+
+// PutNamed registers a named PUT route.
+func (s *Server) PutNamed(name string, path string, handler Handler) {
+	s.AddMethodNamed(name, consts.MethodPut, path, handler)
+}
+
+// PatchNamed registers a named PATCH route.
+func (s *Server) PatchNamed(name string, path string, handler Handler) {
+	s.AddMethodNamed(name, consts.MethodPatch, path, handler)
+}
+
+// DeleteNamed registers a named DELETE route.
+func (s *Server) DeleteNamed(name string, path string, handler Handler) {
+	s.AddMethodNamed(name, consts.MethodDelete, path, handler)
+}
+
+// URL builds the path of a named route, substituting params into its :param and *wildcard segments.
+// Values are path-escaped; a wildcard value may contain slashes.
+// An optional trailing parameter (:name?) is left off when not given.
+// Returns an error if the name is unknown or a parameter is missing.
+// Example:
+//
+//	link, err := s.URL("user", map[string]string{"id": "42"}) // "/users/42"
+func (s *Server) URL(name string, params map[string]string) (string, error) {
+	pattern, ok := s.namedRoutes[name]
+	if !ok {
+		return "", fmt.Errorf("no route named %q", name)
+	}
+
+	segments := strings.Split(pattern, "/")
+	for i, segment := range segments {
+		if segment == "" || (segment[0] != consts.RuneColon && segment[0] != consts.RuneAsterisk) {
+			continue
+		}
+
+		key, optional := strings.CutSuffix(segment[1:], "?")
+		value, ok := params[key]
+		if !ok || value == "" {
+			if optional && i == len(segments)-1 { // a trailing optional parameter is simply left off
+				segments = segments[:i]
+				break
+			}
+			return "", fmt.Errorf("route %q: missing parameter %q", name, key)
+		}
+
+		if segment[0] == consts.RuneAsterisk {
+			// Keep the slashes of a wildcard value, escaping each of its parts
+			parts := strings.Split(strings.TrimPrefix(value, "/"), "/")
+			for j := range parts {
+				parts[j] = url.PathEscape(parts[j])
+			}
+			segments[i] = strings.Join(parts, "/")
+		} else {
+			segments[i] = url.PathEscape(value)
+		}
+	}
+
+	if len(segments) == 1 && segments[0] == "" { // an optional parameter was all there was
+		return "/", nil
+	}
+	return strings.Join(segments, "/"), nil
+}
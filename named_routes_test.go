@@ -0,0 +1,103 @@
+package rweb_test
+
+import (
+	"testing"
+
+	"github.com/rohanthewiz/assert"
+	"github.com/rohanthewiz/rweb"
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+func TestNamedRoutes(t *testing.T) {
+	s := rweb.NewServer()
+
+	s.GetNamed("home", "/", func(ctx rweb.Context) error {
+		return ctx.WriteString("home")
+	})
+	s.GetNamed("user", "/users/:id", func(ctx rweb.Context) error {
+		return ctx.WriteString("user " + ctx.Request().Param("id"))
+	})
+	s.PostNamed("user-post", "/users/:id/posts/:postId", func(ctx rweb.Context) error {
+		return ctx.WriteString("post " + ctx.Request().Param("postId"))
+	})
+	s.GetNamed("files", "/files/*path", func(ctx rweb.Context) error {
+		return ctx.WriteString(ctx.Request().Param("path"))
+	})
+
+	link, err := s.URL("home", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, link, "/")
+
+	link, err = s.URL("user", map[string]string{"id": "42"})
+	assert.Nil(t, err)
+	assert.Equal(t, link, "/users/42")
+
+	// Named routes are routed like any other
+	response := s.Request(consts.MethodGet, link, nil, nil)
+	assert.Equal(t, string(response.Body()), "user 42")
+
+	link, err = s.URL("user-post", map[string]string{"id": "42", "postId": "7"})
+	assert.Nil(t, err)
+	assert.Equal(t, link, "/users/42/posts/7")
+
+	// Values are escaped
+	link, err = s.URL("user", map[string]string{"id": "a b/c"})
+	assert.Nil(t, err)
+	assert.Equal(t, link, "/users/a%20b%2Fc")
+
+	// Wildcards keep their slashes
+	link, err = s.URL("files", map[string]string{"path": "docs/read me.txt"})
+	assert.Nil(t, err)
+	assert.Equal(t, link, "/files/docs/read%20me.txt")
+
+	// Missing parameter
+	_, err = s.URL("user-post", map[string]string{"id": "42"})
+	assert.Equal(t, err.Error(), `route "user-post": missing parameter "postId"`)
+
+	// Unknown route
+	_, err = s.URL("nope", nil)
+	assert.Equal(t, err.Error(), `no route named "nope"`)
+}
+
+func TestOptionalParamRoutes(t *testing.T) {
+	s := rweb.NewServer()
+
+	s.GetNamed("docs", "/docs/:page?", func(ctx rweb.Context) error {
+		return ctx.WriteString("docs [" + ctx.Request().Param("page") + "]")
+	})
+	s.GetNamed("lang", "/:lang?", func(ctx rweb.Context) error {
+		return ctx.WriteString("home [" + ctx.Request().Param("lang") + "]")
+	})
+	// A static route for the bare path takes precedence, whatever the order of registration
+	s.Get("/articles", func(ctx rweb.Context) error {
+		return ctx.WriteString("all articles")
+	})
+	s.Get("/articles/:slug?", func(ctx rweb.Context) error {
+		return ctx.WriteString("article [" + ctx.Request().Param("slug") + "]")
+	})
+
+	for path, expected := range map[string]string{
+		"/docs/intro":   "docs [intro]",
+		"/docs":         "docs []",
+		"/docs/":        "docs []",
+		"/":             "home []",
+		"/fr":           "home [fr]",
+		"/articles":     "all articles",
+		"/articles/go1": "article [go1]",
+	} {
+		response := s.Request(consts.MethodGet, path, nil, nil)
+		assert.Equal(t, response.Status(), 200)
+		assert.Equal(t, string(response.Body()), expected)
+	}
+
+	// URLs leave the parameter off when not given
+	link, err := s.URL("docs", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, link, "/docs")
+	link, err = s.URL("docs", map[string]string{"page": "intro"})
+	assert.Nil(t, err)
+	assert.Equal(t, link, "/docs/intro")
+	link, err = s.URL("lang", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, link, "/")
+}
@@ -0,0 +1,114 @@
+package rweb
+
+import (
+	"encoding/xml"
+	"strconv"
+	"strings"
+
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+// mimeTextXML is the alternative XML media type some clients ask for
+const mimeTextXML = "text/xml"
+
+// acceptRange is a media range from an Accept header, e.g. "text/*;q=0.5"
+type acceptRange struct {
+	typ, subtype string
+	q            float64
+}
+
+// matches reports whether the range covers mimeType, and how specifically:
+// 2 for an exact match, 1 for "type/*", 0 for "*/*", -1 for no match.
+func (ar acceptRange) matches(mimeType string) int {
+	typ, subtype, _ := strings.Cut(mimeType, "/")
+	switch {
+	case ar.typ == "*" && ar.subtype == "*":
+		return 0
+	case !strings.EqualFold(ar.typ, typ):
+		return -1
+	case ar.subtype == "*":
+		return 1
+	case strings.EqualFold(ar.subtype, subtype):
+		return 2
+	}
+	return -1
+}
+
+// parseAccept parses an Accept header into its media ranges.
+// Malformed ranges are skipped, and a malformed quality counts as 1.
+func parseAccept(accept string) (ranges []acceptRange) {
+	for _, part := range strings.Split(accept, ",") {
+		mediaRange, params, _ := strings.Cut(part, ";")
+		typ, subtype, ok := strings.Cut(strings.TrimSpace(mediaRange), "/")
+		if !ok || typ == "" || subtype == "" {
+			continue
+		}
+
+		ar := acceptRange{typ: typ, subtype: subtype, q: 1}
+		for _, param := range strings.Split(params, ";") {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(name, "q") {
+				if q, err := strconv.ParseFloat(value, 64); err == nil && q >= 0 && q <= 1 {
+					ar.q = q
+				}
+			}
+		}
+		ranges = append(ranges, ar)
+	}
+	return
+}
+
+// Negotiate returns the offered content type that best suits the request's Accept header.
+// Quality values are respected, the most specific matching range deciding an offer's quality
+// (so "text/*;q=0.5, text/html" prefers text/html over text/plain).
+// Between equally acceptable offers, the earlier offer wins.
+// The first offer is returned when there is no Accept header, or when nothing offered is acceptable.
+// Example:
+//
+//	switch ctx.Negotiate(consts.MIMEJSON, consts.MIMEHTML) {
+//	case consts.MIMEHTML:
+//		return ctx.WriteHTML(page)
+//	default:
+//		return ctx.WriteJSON(data)
+//	}
+func (ctx *context) Negotiate(offers ...string) string {
+	if len(offers) == 0 {
+		return ""
+	}
+
+	ranges := parseAccept(ctx.request.Header(consts.HeaderAccept))
+	if len(ranges) == 0 {
+		return offers[0]
+	}
+
+	best, bestQ := offers[0], 0.0
+	for _, offer := range offers {
+		q, specificity := 0.0, -1
+		for _, ar := range ranges {
+			if s := ar.matches(offer); s > specificity {
+				q, specificity = ar.q, s
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// Render writes data as JSON or XML, whichever the client's Accept header prefers.
+// JSON is used when the client has no preference.
+func (ctx *context) Render(data any) error {
+	switch contentType := ctx.Negotiate(consts.MIMEJSON, consts.MIMEXML, mimeTextXML); contentType {
+	case consts.MIMEXML, mimeTextXML:
+		byts, err := xml.Marshal(data)
+		if err != nil {
+			return err
+		}
+		ctx.response.SetHeader(consts.HeaderContentType, contentType)
+		_, err = ctx.response.Write(byts)
+		return err
+	default:
+		return ctx.WriteJSON(data)
+	}
+}
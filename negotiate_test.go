@@ -0,0 +1,66 @@
+package rweb_test
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/rohanthewiz/assert"
+	"github.com/rohanthewiz/rweb"
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+func TestNegotiate(t *testing.T) {
+	s := rweb.NewServer()
+
+	s.Get("/", func(ctx rweb.Context) error {
+		return ctx.WriteString(ctx.Negotiate(consts.MIMEJSON, consts.MIMEXML, consts.MIMEHTML))
+	})
+
+	tests := []struct {
+		accept   string
+		expected string
+	}{
+		{"", consts.MIMEJSON},
+		{"*/*", consts.MIMEJSON},
+		{"application/xml", consts.MIMEXML},
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", consts.MIMEHTML},
+		{"application/json;q=0.5, application/xml", consts.MIMEXML},
+		{"application/*;q=0.2, application/xml;q=0.1", consts.MIMEJSON},
+		{"text/*", consts.MIMEHTML},
+		{"*/*;q=0.1, application/json;q=0", consts.MIMEXML},
+		{"image/png", consts.MIMEJSON}, // nothing acceptable - the first offer
+		{"garbage, application/xml;q=oops", consts.MIMEXML},
+	}
+
+	for _, tt := range tests {
+		response := s.Request(consts.MethodGet, "/", []rweb.Header{{Key: consts.HeaderAccept, Value: tt.accept}}, nil)
+		assert.Equal(t, string(response.Body()), tt.expected)
+	}
+}
+
+type renderItem struct {
+	XMLName xml.Name `json:"-" xml:"item"`
+	ID      int      `json:"id" xml:"id"`
+	Name    string   `json:"name" xml:"name"`
+}
+
+func TestRender(t *testing.T) {
+	s := rweb.NewServer()
+
+	s.Get("/item", func(ctx rweb.Context) error {
+		return ctx.Render(renderItem{ID: 7, Name: "widget"})
+	})
+
+	response := s.Request(consts.MethodGet, "/item", nil, nil)
+	assert.Equal(t, response.Header(consts.HeaderContentType), consts.MIMEJSON)
+	assert.Equal(t, string(response.Body()), `{"id":7,"name":"widget"}`)
+
+	response = s.Request(consts.MethodGet, "/item",
+		[]rweb.Header{{Key: consts.HeaderAccept, Value: "application/json;q=0.8, application/xml"}}, nil)
+	assert.Equal(t, response.Header(consts.HeaderContentType), consts.MIMEXML)
+	assert.Equal(t, string(response.Body()), `<item><id>7</id><name>widget</name></item>`)
+
+	response = s.Request(consts.MethodGet, "/item",
+		[]rweb.Header{{Key: consts.HeaderAccept, Value: "text/xml"}}, nil)
+	assert.Equal(t, response.Header(consts.HeaderContentType), "text/xml")
+}
@@ -0,0 +1,89 @@
+package rweb
+
+import "fmt"
+
+// streamingClients tracks the server's active SSE streams and WebSocket connections,
+// so they can be sent server-wide notices
+type streamingClients struct {
+	sseStreams map[chan any]struct{} // each stream's channel for notices
+	webSockets map[*WSConn]struct{}
+}
+
+// addSSEStream registers an SSE stream, returning the channel on which it receives notices
+func (s *Server) addSSEStream() chan any {
+	notices := make(chan any, 1)
+	s.streamingMu.Lock()
+	if s.streaming.sseStreams == nil {
+		s.streaming.sseStreams = make(map[chan any]struct{})
+	}
+	s.streaming.sseStreams[notices] = struct{}{}
+	s.streamingMu.Unlock()
+	return notices
+}
+
+func (s *Server) removeSSEStream(notices chan any) {
+	s.streamingMu.Lock()
+	delete(s.streaming.sseStreams, notices)
+	s.streamingMu.Unlock()
+}
+
+func (s *Server) addWebSocket(ws *WSConn) {
+	s.streamingMu.Lock()
+	if s.streaming.webSockets == nil {
+		s.streaming.webSockets = make(map[*WSConn]struct{})
+	}
+	s.streaming.webSockets[ws] = struct{}{}
+	s.streamingMu.Unlock()
+}
+
+func (s *Server) removeWebSocket(ws *WSConn) {
+	s.streamingMu.Lock()
+	delete(s.streaming.webSockets, ws)
+	s.streamingMu.Unlock()
+}
+
+// NotifyStreamingClients sends event to every active SSE stream and WebSocket connection,
+// e.g. to tell clients the server is going down, so they can reconnect to a healthy instance.
+// SSE streams receive an SSEvent or string as usual; other values are sent as JSON data
+// under the stream's event name. WebSocket clients receive a text message: a string as is,
+// an SSEvent's Data, or anything else as JSON.
+// Call it before shutting down, giving clients a moment to receive the notice:
+//
+//	s.NotifyStreamingClients(map[string]string{"type": "server_shutting_down"})
+//	time.Sleep(time.Second)
+//	// ... stop the server
+func (s *Server) NotifyStreamingClients(event any) {
+	// Render non-string events once, as JSON
+	notice := event
+	switch v := event.(type) {
+	case string, SSEvent:
+	default:
+		byts, err := s.options.JSONEncoder.marshal(v)
+		if err != nil {
+			fmt.Printf("RWEB unable to encode streaming clients notice: %v\n", err)
+			return
+		}
+		notice = string(byts)
+	}
+
+	wsMessage := fmt.Sprintf("%s", notice)
+	if evt, ok := notice.(SSEvent); ok {
+		wsMessage = fmt.Sprintf("%s", evt.Data)
+	}
+
+	s.streamingMu.Lock()
+	defer s.streamingMu.Unlock()
+
+	for notices := range s.streaming.sseStreams {
+		select {
+		case notices <- notice:
+		default: // the stream hasn't taken the previous notice yet - don't block on it
+		}
+	}
+
+	for ws := range s.streaming.webSockets {
+		go func() {
+			_ = ws.WriteMessage(TextMessage, []byte(wsMessage))
+		}()
+	}
+}
@@ -0,0 +1,96 @@
+package rweb_test
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/rohanthewiz/assert"
+	"github.com/rohanthewiz/rweb"
+)
+
+func TestNotifyStreamingClients(t *testing.T) {
+	readyChan := make(chan struct{}, 1)
+	s := rweb.NewServerWithOptions(
+		rweb.WithAddress("localhost:"),
+		rweb.WithReadyChan(readyChan),
+		rweb.WithSSEConfig(rweb.SSECfg{RetryMillis: 1000}),
+	)
+
+	s.Get("/events", func(ctx rweb.Context) error {
+		return ctx.SetSSE(make(chan any), "update") // no events of its own
+	})
+
+	s.WebSocket("/ws", func(ws *rweb.WSConn) error {
+		if err := ws.WriteMessage(rweb.TextMessage, []byte("welcome")); err != nil {
+			return err
+		}
+		for {
+			if _, err := ws.ReadMessage(); err != nil {
+				return nil
+			}
+		}
+	})
+
+	go func() {
+		defer syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+		<-readyChan // wait for server
+		addr := fmt.Sprintf("localhost:%s", s.GetListenPort())
+
+		// An SSE client, streaming once the retry directive arrives
+		sseConn, err := net.Dial("tcp", addr)
+		assert.Nil(t, err)
+		defer sseConn.Close()
+		_, err = fmt.Fprintf(sseConn, "GET /events HTTP/1.1\r\nHost: %s\r\nAccept: text/event-stream\r\n\r\n", addr)
+		assert.Nil(t, err)
+		_ = sseConn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		sseReader := bufio.NewReader(sseConn)
+		for {
+			line, err := sseReader.ReadString('\n')
+			assert.Nil(t, err)
+			if err != nil || strings.HasPrefix(line, "retry:") {
+				break
+			}
+		}
+
+		// A WebSocket client, connected once welcomed
+		wsConn, resp := dialWebSocket(t, addr, "/ws")
+		defer wsConn.Close()
+		assert.Equal(t, resp.StatusCode, 101)
+		ws := rweb.NewWSConn(wsConn, false)
+		msg, err := ws.ReadMessage()
+		assert.Nil(t, err)
+		assert.Equal(t, string(msg.Data), "welcome")
+
+		s.NotifyStreamingClients(map[string]string{"type": "server_shutting_down"})
+
+		// SSE gets the notice under the stream's event name
+		var lines []string
+		for len(lines) < 2 {
+			line, err := sseReader.ReadString('\n')
+			assert.Nil(t, err)
+			if err != nil {
+				break
+			}
+			if line = strings.TrimSuffix(line, "\n"); line != "" {
+				lines = append(lines, line)
+			}
+		}
+		assert.Equal(t, strings.Join(lines, "|"), `event: update|data: {"type":"server_shutting_down"}`)
+
+		// WebSocket gets it as a text message
+		_ = wsConn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		msg, err = ws.ReadMessage()
+		assert.Nil(t, err)
+		assert.Equal(t, msg.Type, rweb.TextMessage)
+		assert.Equal(t, string(msg.Data), `{"type":"server_shutting_down"}`)
+	}()
+
+	err := s.Run()
+	assert.Nil(t, err)
+}
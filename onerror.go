@@ -0,0 +1,26 @@
+package rweb
+
+// ErrorMiddlewareHandler handles an error returned by a downstream handler.
+// It may render a response, record metrics, or transform the error.
+// Returning nil marks the error as handled, so the server's error handler is skipped.
+type ErrorMiddlewareHandler func(ctx Context, err error) error
+
+// OnError returns a middleware that calls fn only when a downstream handler returns an error.
+// The error fn returns is passed up the chain and, if it reaches the top,
+// on to the server's error handler.
+// It can be used at server level or scoped to a group.
+// Example:
+//
+//	api := s.Group("/api", rweb.OnError(func(ctx rweb.Context, err error) error {
+//		ctx.SetStatus(consts.StatusBadRequest)
+//		return ctx.WriteJSON(map[string]string{"error": err.Error()})
+//	}))
+func OnError(fn ErrorMiddlewareHandler) Handler {
+	return func(ctx Context) error {
+		err := ctx.Next()
+		if err == nil {
+			return nil
+		}
+		return fn(ctx, err)
+	}
+}
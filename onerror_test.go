@@ -0,0 +1,93 @@
+package rweb_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/rohanthewiz/assert"
+	"github.com/rohanthewiz/rweb"
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+var errNotAllowed = errors.New("not allowed")
+
+func TestOnErrorHandled(t *testing.T) {
+	s := rweb.NewServer()
+
+	var called int
+	s.Use(rweb.OnError(func(ctx rweb.Context, err error) error {
+		called++
+		return ctx.SetStatus(consts.StatusForbidden).WriteString("handled: " + err.Error())
+	}))
+
+	s.Get("/fail", func(ctx rweb.Context) error {
+		return errNotAllowed
+	})
+	s.Get("/ok", func(ctx rweb.Context) error {
+		return ctx.WriteString("fine")
+	})
+
+	// The error is rendered by the middleware, not the server's error handler
+	response := s.Request(consts.MethodGet, "/fail", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusForbidden)
+	assert.Equal(t, string(response.Body()), "handled: not allowed")
+	assert.Equal(t, called, 1)
+
+	// Successful requests don't invoke the error middleware
+	response = s.Request(consts.MethodGet, "/ok", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusOK)
+	assert.Equal(t, string(response.Body()), "fine")
+	assert.Equal(t, called, 1)
+}
+
+func TestOnErrorTransform(t *testing.T) {
+	s := rweb.NewServer()
+
+	var outerErr error
+	s.Use(func(ctx rweb.Context) error {
+		outerErr = ctx.Next()
+		return outerErr
+	})
+
+	// Enrich the error and pass it on to the server's error handler
+	s.Use(rweb.OnError(func(ctx rweb.Context, err error) error {
+		return fmt.Errorf("%s %s: %w", ctx.Request().Method(), ctx.Request().Path(), err)
+	}))
+
+	s.Get("/fail", func(ctx rweb.Context) error {
+		return errNotAllowed
+	})
+
+	response := s.Request(consts.MethodGet, "/fail", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusInternalServerError)
+	assert.True(t, strings.Contains(string(response.Body()), "500 Internal Server Error"))
+	assert.True(t, errors.Is(outerErr, errNotAllowed))
+	assert.Equal(t, outerErr.Error(), "GET /fail: not allowed")
+}
+
+func TestOnErrorGroup(t *testing.T) {
+	s := rweb.NewServer()
+
+	api := s.Group("/api", rweb.OnError(func(ctx rweb.Context, err error) error {
+		ctx.SetStatus(consts.StatusBadRequest)
+		return ctx.WriteJSON(map[string]string{"error": err.Error()})
+	}))
+
+	api.Get("/fail", func(ctx rweb.Context) error {
+		return errNotAllowed
+	})
+	s.Get("/fail", func(ctx rweb.Context) error {
+		return errNotAllowed
+	})
+
+	// Scoped to the group
+	response := s.Request(consts.MethodGet, "/api/fail", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusBadRequest)
+	assert.Equal(t, strings.TrimSpace(string(response.Body())), `{"error":"not allowed"}`)
+
+	// Routes outside the group fall through to the server's error handler
+	response = s.Request(consts.MethodGet, "/fail", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusInternalServerError)
+}
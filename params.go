@@ -0,0 +1,109 @@
+package rweb
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrParamMissing is returned by the typed param accessors (ParamInt etc.) when the route has no such param, or it is empty
+	ErrParamMissing = errors.New("missing path param")
+	// ErrParamInvalid is returned by the typed param accessors when the param's value can't be converted
+	ErrParamInvalid = errors.New("invalid path param")
+)
+
+// ParamInt returns a Path parameter's value as an int.
+// Example:
+//
+//	id, err := ctx.Request().ParamInt("id")
+//	if err != nil {
+//		return ctx.WriteError(err, consts.StatusBadRequest)
+//	}
+func (req *request) ParamInt(name string) (int, error) {
+	value, err := req.requiredParam(name)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, invalidParam(name, value, "an integer")
+	}
+	return n, nil
+}
+
+// ParamInt64 returns a Path parameter's value as an int64.
+func (req *request) ParamInt64(name string) (int64, error) {
+	value, err := req.requiredParam(name)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, invalidParam(name, value, "a 64-bit integer")
+	}
+	return n, nil
+}
+
+// ParamBool returns a Path parameter's value as a bool.
+// It accepts the values strconv.ParseBool does: 1, t, true, 0, f, false etc.
+func (req *request) ParamBool(name string) (bool, error) {
+	value, err := req.requiredParam(name)
+	if err != nil {
+		return false, err
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, invalidParam(name, value, "a boolean")
+	}
+	return b, nil
+}
+
+// ParamUUID returns a Path parameter's value, checked to be a UUID in the standard
+// 8-4-4-4-12 hex digit form (e.g. "123e4567-e89b-12d3-a456-426614174000"), and lowercased.
+func (req *request) ParamUUID(name string) (string, error) {
+	value, err := req.requiredParam(name)
+	if err != nil {
+		return "", err
+	}
+	if !isUUID(value) {
+		return "", invalidParam(name, value, "a UUID")
+	}
+	return strings.ToLower(value), nil
+}
+
+// requiredParam returns a Path parameter's value, or ErrParamMissing if it's absent or empty
+func (req *request) requiredParam(name string) (string, error) {
+	value := req.Param(name)
+	if value == "" {
+		return "", fmt.Errorf("%w: %q", ErrParamMissing, name)
+	}
+	return value, nil
+}
+
+// invalidParam returns the ErrParamInvalid error for a param value that isn't the expected kind
+func invalidParam(name, value, want string) error {
+	return fmt.Errorf("%w: %q is %q, want %s", ErrParamInvalid, name, value, want)
+}
+
+// isUUID reports whether s is a UUID in the standard textual form
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i := range len(s) {
+		switch i {
+		case 8, 13, 18, 23:
+			if s[i] != '-' {
+				return false
+			}
+		default:
+			c := s[i]
+			if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,138 @@
+package rweb
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+// CheckPrecondition evaluates the request's conditional headers (If-Match, If-Unmodified-Since,
+// If-None-Match and If-Modified-Since) against the current ETag and modification time of the resource,
+// in the order RFC 7232 lays down. It returns true if the request should proceed.
+// When a precondition fails, it sets the status - 412 Precondition Failed, or 304 Not Modified
+// for a GET or HEAD of an unchanged resource - and the handler should return without doing anything more.
+// etag is the resource's entity tag, quoted or not (e.g. `"v42"`, `W/"v42"` or v42), or "" if it has none.
+// modTime is when the resource last changed, or the zero time if unknown.
+// Use it for optimistic concurrency on writes, so a client doesn't overwrite changes it hasn't seen:
+//
+//	s.Put("/docs/:id", func(ctx rweb.Context) error {
+//		doc := loadDoc(ctx.Request().Param("id"))
+//		if !ctx.CheckPrecondition(doc.ETag(), doc.Updated) {
+//			return nil // 412 - the client's copy is stale
+//		}
+//		// update the doc...
+//	})
+func (ctx *context) CheckPrecondition(etag string, modTime time.Time) bool {
+	if etag != "" && !strings.HasPrefix(etag, `"`) && !strings.HasPrefix(etag, `W/"`) {
+		etag = `"` + etag + `"`
+	}
+	exists := etag != "" || !modTime.IsZero() // there's a current representation for "*" to match
+	modTime = modTime.Truncate(time.Second)   // HTTP dates have a resolution of seconds
+	isGetOrHead := ctx.method == consts.MethodGet || ctx.method == consts.MethodHead
+
+	// 1. If-Match, else 2. If-Unmodified-Since
+	if ifMatch := ctx.request.Header(consts.HeaderIfMatch); ifMatch != "" {
+		if !etagListMatches(ifMatch, etag, exists, true) {
+			return ctx.preconditionFailed()
+		}
+	} else if since, ok := parseHTTPDate(ctx.request.Header(consts.HeaderIfUnmodifiedSince)); ok && !modTime.IsZero() {
+		if modTime.After(since) {
+			return ctx.preconditionFailed()
+		}
+	}
+
+	// 3. If-None-Match, else 4. If-Modified-Since (for GET and HEAD only)
+	if ifNoneMatch := ctx.request.Header(consts.HeaderIfNoneMatch); ifNoneMatch != "" {
+		if etagListMatches(ifNoneMatch, etag, exists, false) {
+			if isGetOrHead {
+				return ctx.notModified(etag, modTime)
+			}
+			return ctx.preconditionFailed()
+		}
+	} else if isGetOrHead && !modTime.IsZero() {
+		if since, ok := parseHTTPDate(ctx.request.Header(consts.HeaderIfModifiedSince)); ok && !modTime.After(since) {
+			return ctx.notModified(etag, modTime)
+		}
+	}
+
+	return true
+}
+
+// preconditionFailed sets a 412 status, returning false for CheckPrecondition
+func (ctx *context) preconditionFailed() bool {
+	ctx.SetStatus(consts.StatusPreconditionFailed)
+	return false
+}
+
+// notModified sets a 304 status, with the validators a cache needs to keep using its copy,
+// returning false for CheckPrecondition
+func (ctx *context) notModified(etag string, modTime time.Time) bool {
+	if etag != "" {
+		ctx.response.SetHeader(consts.HeaderETag, etag)
+	}
+	if !modTime.IsZero() {
+		ctx.response.SetHeader(consts.HeaderLastModified, modTime.UTC().Format(http.TimeFormat))
+	}
+	ctx.SetStatus(consts.StatusNotModified)
+	return false
+}
+
+// parseHTTPDate parses a date from a request header, reporting whether it is valid
+func parseHTTPDate(value string) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+	t, err := http.ParseTime(value)
+	return t, err == nil
+}
+
+// etagListMatches reports whether an If-Match or If-None-Match header value matches etag.
+// "*" matches any existing resource. The strong comparison (for If-Match) fails for weak tags,
+// while the weak comparison (for If-None-Match) ignores the W/ prefixes.
+func etagListMatches(list, etag string, exists, strong bool) bool {
+	if strings.TrimSpace(list) == "*" {
+		return exists
+	}
+	if etag == "" {
+		return false
+	}
+
+	weak := strings.HasPrefix(etag, "W/")
+	opaque := strings.TrimPrefix(etag, "W/")
+	for {
+		list = strings.TrimLeft(list, " \t,")
+		if list == "" {
+			return false
+		}
+		tag, rest, ok := scanETag(list)
+		if !ok {
+			return false // malformed - stop rather than guess
+		}
+		list = rest
+
+		tagWeak := strings.HasPrefix(tag, "W/")
+		if strings.TrimPrefix(tag, "W/") == opaque && (!strong || !weak && !tagWeak) {
+			return true
+		}
+	}
+}
+
+// scanETag reads the entity tag at the start of s (e.g. `"v1"` or `W/"v1"`), returning it and the rest of s.
+// Tags are scanned for their closing quote, as they may contain commas.
+func scanETag(s string) (tag, rest string, ok bool) {
+	start := 0
+	if strings.HasPrefix(s, "W/") {
+		start = 2
+	}
+	if len(s) <= start || s[start] != '"' {
+		return "", "", false
+	}
+	end := strings.IndexByte(s[start+1:], '"')
+	if end < 0 {
+		return "", "", false
+	}
+	end += start + 2 // past the closing quote
+	return s[:end], s[end:], true
+}
@@ -0,0 +1,106 @@
+package rweb_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/rohanthewiz/assert"
+	"github.com/rohanthewiz/rweb"
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+func TestCheckPrecondition(t *testing.T) {
+	modTime := time.Date(2025, 3, 1, 12, 0, 0, 500, time.UTC)
+	before := modTime.Add(-time.Hour).Format(http.TimeFormat)
+	at := modTime.Format(http.TimeFormat)
+
+	s := rweb.NewServer()
+	handler := func(ctx rweb.Context) error {
+		if !ctx.CheckPrecondition("v2", modTime) {
+			return nil
+		}
+		return ctx.WriteString("done")
+	}
+	s.Get("/doc", handler)
+	s.Put("/doc", handler)
+
+	check := func(method string, key, value string) rweb.Response {
+		return s.Request(method, "/doc", []rweb.Header{{Key: key, Value: value}}, nil)
+	}
+
+	tests := []struct {
+		method, key, value string
+		status             int
+	}{
+		// Unconditional
+		{consts.MethodPut, "X-None", "", consts.StatusOK},
+
+		// If-Match - strong comparison, any of a list, or any resource for *
+		{consts.MethodPut, consts.HeaderIfMatch, `"v2"`, consts.StatusOK},
+		{consts.MethodPut, consts.HeaderIfMatch, `"v1", "v2"`, consts.StatusOK},
+		{consts.MethodPut, consts.HeaderIfMatch, `*`, consts.StatusOK},
+		{consts.MethodPut, consts.HeaderIfMatch, `"v1"`, consts.StatusPreconditionFailed},
+		{consts.MethodPut, consts.HeaderIfMatch, `W/"v2"`, consts.StatusPreconditionFailed},
+		{consts.MethodPut, consts.HeaderIfMatch, `"v,2"`, consts.StatusPreconditionFailed},
+
+		// If-Unmodified-Since
+		{consts.MethodPut, consts.HeaderIfUnmodifiedSince, at, consts.StatusOK},
+		{consts.MethodPut, consts.HeaderIfUnmodifiedSince, before, consts.StatusPreconditionFailed},
+		{consts.MethodPut, consts.HeaderIfUnmodifiedSince, "not a date", consts.StatusOK},
+
+		// If-None-Match - weak comparison, 304 for GET, 412 otherwise
+		{consts.MethodGet, consts.HeaderIfNoneMatch, `"v1"`, consts.StatusOK},
+		{consts.MethodGet, consts.HeaderIfNoneMatch, `W/"v2"`, consts.StatusNotModified},
+		{consts.MethodGet, consts.HeaderIfNoneMatch, `*`, consts.StatusNotModified},
+		{consts.MethodPut, consts.HeaderIfNoneMatch, `*`, consts.StatusPreconditionFailed},
+		{consts.MethodPut, consts.HeaderIfNoneMatch, `"v1"`, consts.StatusOK},
+
+		// If-Modified-Since - GET only
+		{consts.MethodGet, consts.HeaderIfModifiedSince, at, consts.StatusNotModified},
+		{consts.MethodGet, consts.HeaderIfModifiedSince, before, consts.StatusOK},
+		{consts.MethodPut, consts.HeaderIfModifiedSince, at, consts.StatusOK},
+	}
+	for _, tt := range tests {
+		response := check(tt.method, tt.key, tt.value)
+		if response.Status() != tt.status {
+			t.Errorf("%s with %s: %s - expected %d, got %d", tt.method, tt.key, tt.value, tt.status, response.Status())
+		}
+		if tt.status == consts.StatusOK {
+			assert.Equal(t, string(response.Body()), "done")
+		} else {
+			assert.Equal(t, len(response.Body()), 0)
+		}
+	}
+
+	// A 304 carries the validators
+	response := check(consts.MethodGet, consts.HeaderIfNoneMatch, `"v2"`)
+	assert.Equal(t, response.Header(consts.HeaderETag), `"v2"`)
+	assert.Equal(t, response.Header(consts.HeaderLastModified), at)
+
+	// If-Match takes precedence over If-Unmodified-Since
+	response = s.Request(consts.MethodPut, "/doc", []rweb.Header{
+		{Key: consts.HeaderIfMatch, Value: `"v2"`},
+		{Key: consts.HeaderIfUnmodifiedSince, Value: before},
+	}, nil)
+	assert.Equal(t, response.Status(), consts.StatusOK)
+
+	// If-None-Match takes precedence over If-Modified-Since
+	response = s.Request(consts.MethodGet, "/doc", []rweb.Header{
+		{Key: consts.HeaderIfNoneMatch, Value: `"v1"`},
+		{Key: consts.HeaderIfModifiedSince, Value: at},
+	}, nil)
+	assert.Equal(t, response.Status(), consts.StatusOK)
+
+	// Nothing matches * when there's no resource
+	s.Put("/new", func(ctx rweb.Context) error {
+		if !ctx.CheckPrecondition("", time.Time{}) {
+			return nil
+		}
+		return ctx.SetStatus(consts.StatusCreated).WriteString("created")
+	})
+	response = s.Request(consts.MethodPut, "/new", []rweb.Header{{Key: consts.HeaderIfNoneMatch, Value: "*"}}, nil)
+	assert.Equal(t, response.Status(), consts.StatusCreated)
+	response = s.Request(consts.MethodPut, "/new", []rweb.Header{{Key: consts.HeaderIfMatch, Value: "*"}}, nil)
+	assert.Equal(t, response.Status(), consts.StatusPreconditionFailed)
+}
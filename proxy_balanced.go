@@ -0,0 +1,134 @@
+package rweb
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+// proxyBackendCooldown is how long a backend that failed to connect is passed over
+const proxyBackendCooldown = 10 * time.Second
+
+// proxyBackend is a target of a balanced proxy, with its health
+type proxyBackend struct {
+	url      *url.URL
+	failedAt time.Time // when the backend last had a connection error, zero if healthy
+}
+
+// proxyBalancer round-robins requests across backends, passing over any in cooldown
+type proxyBalancer struct {
+	mu       sync.Mutex
+	backends []*proxyBackend
+	next     int // round-robin cursor
+	cooldown time.Duration
+}
+
+// order returns the backends in the order to try them for the next request:
+// healthy backends, starting with the next in the rotation, then any in cooldown as a last resort.
+func (b *proxyBalancer) order() []*proxyBackend {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	ordered := make([]*proxyBackend, 0, len(b.backends))
+	var cooling []*proxyBackend
+
+	first := b.next
+	for i := range b.backends {
+		idx := (b.next + i) % len(b.backends)
+		backend := b.backends[idx]
+		if !backend.failedAt.IsZero() && now.Sub(backend.failedAt) < b.cooldown {
+			cooling = append(cooling, backend)
+			continue
+		}
+		if len(ordered) == 0 {
+			first = idx
+		}
+		ordered = append(ordered, backend)
+	}
+	// Continue the rotation after the backend we lead with, so skipped ones don't skew the load
+	b.next = (first + 1) % len(b.backends)
+
+	return append(ordered, cooling...)
+}
+
+// markFailed puts a backend into cooldown
+func (b *proxyBalancer) markFailed(backend *proxyBackend) {
+	b.mu.Lock()
+	backend.failedAt = time.Now()
+	b.mu.Unlock()
+}
+
+// markHealthy takes a backend out of cooldown
+func (b *proxyBalancer) markHealthy(backend *proxyBackend) {
+	b.mu.Lock()
+	backend.failedAt = time.Time{}
+	b.mu.Unlock()
+}
+
+// ProxyBalanced sets up a reverse proxy like Proxy, but spreading requests round-robin across several target URLs.
+// A backend that can't be reached is passed over for a short cooldown,
+// and a request whose backend can't be dialed is retried on the next one.
+// Other failures are not retried, as the backend may have acted on the request.
+// If no backend can be reached, the client gets a 502.
+// Example:
+//
+//	s.ProxyBalanced("/api", []string{"http://10.0.0.1:8080", "http://10.0.0.2:8080"}, 0)
+func (s *Server) ProxyBalanced(pathPrefix string, targets []string, prefixTokensToRemove int) (err error) {
+	if len(targets) == 0 {
+		return errors.New("no proxy targets provided")
+	}
+
+	balancer := &proxyBalancer{cooldown: proxyBackendCooldown}
+	for _, target := range targets {
+		tURL, err := url.Parse(target)
+		if err != nil {
+			return err
+		}
+		balancer.backends = append(balancer.backends, &proxyBackend{url: tURL})
+	}
+
+	pathPrefix, strippedPrefix := proxyPrefixes(pathPrefix, prefixTokensToRemove)
+
+	hdlr := func(ctx Context) (err error) {
+		for _, backend := range balancer.order() {
+			proxyURL := proxyTargetURL(ctx.Request(), backend.url, pathPrefix, strippedPrefix)
+
+			if s.options.Verbose {
+				fmt.Printf("PROXY %q -> %q\n", ctx.Request().Path(), proxyURL)
+			}
+
+			resp, reqErr := s.forwardProxyRequest(ctx, proxyURL)
+			if reqErr == nil {
+				balancer.markHealthy(backend)
+				return writeProxyResponse(ctx, resp)
+			}
+
+			balancer.markFailed(backend)
+			err = reqErr
+			if !isDialError(reqErr) {
+				break // the request may have reached the backend, so it isn't safe to retry
+			}
+		}
+
+		ctx.Response().SetStatus(consts.StatusBadGateway)
+		return err
+	}
+
+	s.setMethodProxyHandler(filepath.Join("/", pathPrefix, "*path"), hdlr)
+	// The wildcard route does not handle the root of the prefix, so have to handle that separately
+	s.setMethodProxyHandler(filepath.Join("/", pathPrefix), hdlr)
+	return nil
+}
+
+// isDialError reports whether err is a failure to connect, so the request was never sent
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
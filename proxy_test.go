@@ -6,9 +6,14 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"syscall"
 	"testing"
+	"time"
 
 	"github.com/rohanthewiz/assert"
 	"github.com/rohanthewiz/rweb"
@@ -166,6 +171,57 @@ func TestProxy(t *testing.T) {
 	_ = pxy.Run()
 }
 
+// TestProxyRewrittenHeaders verifies that request headers modified by middleware are what gets forwarded
+func TestProxyRewrittenHeaders(t *testing.T) {
+	tgtReadyChan := make(chan struct{}, 1)
+	tgt := rweb.NewServer(rweb.ServerOptions{ReadyChan: tgtReadyChan, Address: "localhost:"})
+	tgt.Get("/echo", func(ctx rweb.Context) error {
+		req := ctx.Request()
+		return ctx.WriteString(req.Header("X-Api-Version") + "|" + req.Header("X-Api-Ver") + "|" + req.Header("X-Internal"))
+	})
+
+	go func() {
+		_ = tgt.Run()
+	}()
+	<-tgtReadyChan
+
+	pxyReadyChan := make(chan struct{}, 1)
+	pxy := rweb.NewServer(rweb.ServerOptions{ReadyChan: pxyReadyChan, Address: "localhost:"})
+
+	// Map a legacy header to its new name and strip an internal one
+	pxy.Use(func(ctx rweb.Context) error {
+		req := ctx.Request()
+		if ver := req.Header("X-Api-Ver"); ver != "" {
+			req.SetHeader("X-Api-Version", ver)
+			req.DelHeader("X-Api-Ver")
+		}
+		req.DelHeader("X-Internal")
+		return ctx.Next()
+	})
+
+	err := pxy.Proxy("/api", fmt.Sprintf("http://localhost:%s", tgt.GetListenPort()), 1)
+	assert.Nil(t, err)
+
+	go func() {
+		defer syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+		<-pxyReadyChan // wait for proxy
+
+		req, err := http.NewRequest(consts.MethodGet, fmt.Sprintf("http://127.0.0.1:%s/api/echo", pxy.GetListenPort()), nil)
+		assert.Nil(t, err)
+		req.Header.Set("X-Api-Ver", "2")
+		req.Header.Set("X-Internal", "secret")
+
+		resp, err := http.DefaultClient.Do(req)
+		assert.Nil(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		assert.Equal(t, string(body), "2||")
+	}()
+
+	_ = pxy.Run()
+}
+
 /*// TestProxySkewed tests proxying to one server by default and another server based on a prefix
 func TestProxySkewed(t *testing.T) {
 	// Init US Server
@@ -300,3 +356,228 @@ func TestProxySkewed(t *testing.T) {
 	_ = pxy.Run()
 }
 */
+
+func TestProxyBalanced(t *testing.T) {
+	// Two live backends, identifying themselves
+	var backendPorts []string
+	for _, name := range []string{"alpha", "beta"} {
+		readyChan := make(chan struct{}, 1)
+		backend := rweb.NewServer(rweb.ServerOptions{ReadyChan: readyChan, Address: "localhost:"})
+		backend.Get("/items/:id", func(ctx rweb.Context) error {
+			return ctx.WriteString(name + ":" + ctx.Request().Param("id"))
+		})
+		go func() { _ = backend.Run() }()
+		<-readyChan
+		backendPorts = append(backendPorts, backend.GetListenPort())
+	}
+
+	// And one that is down
+	ln, err := net.Listen(consts.ProtocolTCP, "localhost:0")
+	assert.Nil(t, err)
+	deadAddr := ln.Addr().String()
+	_ = ln.Close()
+
+	pxy := rweb.NewServer()
+	err = pxy.ProxyBalanced("/api", []string{
+		"http://localhost:" + backendPorts[0],
+		"http://" + deadAddr,
+		"http://localhost:" + backendPorts[1],
+	}, 1)
+	assert.Nil(t, err)
+
+	// Every request is served, by the live backends in turn
+	counts := map[string]int{}
+	for i := range 6 {
+		response := pxy.Request(consts.MethodGet, fmt.Sprintf("/api/items/%d", i), nil, nil)
+		assert.Equal(t, response.Status(), 200)
+		name, id, _ := strings.Cut(string(response.Body()), ":")
+		assert.Equal(t, id, fmt.Sprintf("%d", i))
+		counts[name]++
+	}
+	assert.Equal(t, counts["alpha"], 3)
+	assert.Equal(t, counts["beta"], 3)
+
+	// With nothing reachable, the client gets a Bad Gateway
+	pxy = rweb.NewServer()
+	err = pxy.ProxyBalanced("/api", []string{"http://" + deadAddr}, 1)
+	assert.Nil(t, err)
+	response := pxy.Request(consts.MethodGet, "/api/items/1", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusBadGateway)
+
+	assert.NotNil(t, pxy.ProxyBalanced("/none", nil, 0))
+}
+
+// countingTransport counts the requests it sends on to the upstream
+type countingTransport struct {
+	count int
+}
+
+func (ct *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ct.count++
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestProxyStreamedBody(t *testing.T) {
+	// A large upstream body
+	large := strings.Repeat("0123456789abcdef", 128*1024) // 2MB
+
+	tgtReadyChan := make(chan struct{}, 1)
+	tgt := rweb.NewServer(rweb.ServerOptions{ReadyChan: tgtReadyChan, Address: "localhost:"})
+	tgt.Get("/large", func(ctx rweb.Context) error {
+		return ctx.WriteString(large)
+	})
+	tgt.Get("/empty", func(ctx rweb.Context) error {
+		ctx.SetStatus(consts.StatusNoContent)
+		return nil
+	})
+
+	go func() {
+		_ = tgt.Run()
+	}()
+	<-tgtReadyChan
+
+	transport := &countingTransport{}
+	pxyReadyChan := make(chan struct{}, 1)
+	pxy := rweb.NewServerWithOptions(
+		rweb.WithAddress("localhost:"),
+		rweb.WithReadyChan(pxyReadyChan),
+		rweb.WithProxyClient(&http.Client{Transport: transport}),
+	)
+	err := pxy.Proxy("/api", fmt.Sprintf("http://localhost:%s", tgt.GetListenPort()), 1)
+	assert.Nil(t, err)
+
+	// Synthetic requests still see the whole body
+	response := pxy.Request(consts.MethodGet, "/api/large", nil, nil)
+	assert.Equal(t, response.Status(), 200)
+	assert.Equal(t, len(response.Body()), len(large))
+
+	go func() {
+		defer syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+		<-pxyReadyChan // wait for proxy
+
+		// The body is streamed through, with its Content-Length, and arrives intact
+		resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%s/api/large", pxy.GetListenPort()))
+		assert.Nil(t, err)
+		body, err := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		assert.Nil(t, err)
+		assert.Equal(t, resp.StatusCode, 200)
+		assert.Equal(t, resp.ContentLength, int64(len(large)))
+		assert.True(t, string(body) == large)
+
+		// Bodiless responses are passed on as is
+		resp, err = http.Get(fmt.Sprintf("http://127.0.0.1:%s/api/empty", pxy.GetListenPort()))
+		assert.Nil(t, err)
+		body, _ = io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		assert.Equal(t, resp.StatusCode, consts.StatusNoContent)
+		assert.Equal(t, len(body), 0)
+
+		// All went through the configured client
+		assert.Equal(t, transport.count, 3)
+	}()
+
+	_ = pxy.Run()
+}
+
+// stubTransport answers every request with body, of known length
+type stubTransport struct {
+	body   string
+	bodies []*trackedBody
+}
+
+func (st *stubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body := &trackedBody{Reader: strings.NewReader(st.body)}
+	st.bodies = append(st.bodies, body)
+	return &http.Response{StatusCode: 200, Header: http.Header{}, ContentLength: int64(len(st.body)), Body: body, Request: req}, nil
+}
+
+// trackedBody notes whether it was closed
+type trackedBody struct {
+	io.Reader
+	closed atomic.Bool
+}
+
+func (b *trackedBody) Close() error {
+	b.closed.Store(true)
+	return nil
+}
+
+func TestProxyResponseBody(t *testing.T) {
+	transport := &stubTransport{body: "hello"}
+	pxyReadyChan := make(chan struct{}, 1)
+	pxy := rweb.NewServerWithOptions(
+		rweb.WithAddress("localhost:"),
+		rweb.WithReadyChan(pxyReadyChan),
+		rweb.WithProxyClient(&http.Client{Transport: transport}),
+	)
+	pxy.Use(func(ctx rweb.Context) error {
+		err := ctx.Next()
+		if strings.HasSuffix(ctx.Request().Path(), "/panic") {
+			panic("after the proxy")
+		}
+		return err
+	})
+	err := pxy.Proxy("/api", "http://upstream.test", 1)
+	assert.Nil(t, err)
+
+	// The body is closed even though the response carrying it is replaced
+	response := pxy.Request(consts.MethodGet, "/api/panic", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusInternalServerError)
+	assert.Equal(t, len(transport.bodies), 1)
+	assert.True(t, transport.bodies[0].closed.Load())
+
+	go func() {
+		defer syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+		<-pxyReadyChan // wait for proxy
+
+		// A body of known length is passed on with its Content-Length, rather than chunked
+		resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%s/api/hello", pxy.GetListenPort()))
+		assert.Nil(t, err)
+		body, err := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		assert.Nil(t, err)
+		assert.Equal(t, string(body), "hello")
+		assert.Equal(t, resp.ContentLength, int64(5))
+		assert.Equal(t, len(resp.TransferEncoding), 0)
+		assert.True(t, transport.bodies[1].closed.Load())
+	}()
+
+	_ = pxy.Run()
+}
+
+func TestProxyTimeout(t *testing.T) {
+	// The upstream body takes a while to arrive
+	tgt := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "first,")
+		w.(http.Flusher).Flush()
+		time.Sleep(50 * time.Millisecond)
+		_, _ = io.WriteString(w, "second")
+	}))
+	defer tgt.Close()
+
+	pxyReadyChan := make(chan struct{}, 1)
+	pxy := rweb.NewServer(rweb.ServerOptions{ReadyChan: pxyReadyChan, Address: "localhost:"})
+	pxy.Use(rweb.Timeout(time.Second))
+	err := pxy.Proxy("/api", tgt.URL, 1)
+	assert.Nil(t, err)
+
+	go func() {
+		defer syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+		<-pxyReadyChan // wait for proxy
+
+		// The body is streamed after the Timeout middleware is done, and still arrives whole
+		resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%s/api/slow", pxy.GetListenPort()))
+		assert.Nil(t, err)
+		body, err := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		assert.Nil(t, err)
+		assert.Equal(t, resp.StatusCode, 200)
+		assert.Equal(t, string(body), "first,second")
+	}()
+
+	_ = pxy.Run()
+}
@@ -0,0 +1,99 @@
+package rweb
+
+import (
+	"fmt"
+	"log"
+	"runtime/debug"
+
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+// RecoverOptions configures the Recover middleware.
+// The zero value logs the stack trace of any panic.
+type RecoverOptions struct {
+	// DisableStackTrace skips logging the stack trace of a recovered panic
+	DisableStackTrace bool
+	// OnPanic, if set, is called with the recovered value and stack trace (e.g. for alerting)
+	OnPanic func(ctx Context, recovered any, stack []byte)
+}
+
+// PanicError is the error returned by the Recover middleware when a handler panics.
+// It is passed on to the server's error handler, and can be inspected
+// by outer middleware with errors.As.
+type PanicError struct {
+	Value any    // the value passed to panic()
+	Stack []byte // stack trace captured at the time of recovery
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Recover returns a middleware that recovers from panics in subsequent handlers,
+// so a panicking handler results in a clean 500 response instead of a crashed connection.
+// Anything the handler wrote before panicking (status, headers, body) is discarded,
+// and a *PanicError is returned so the server's error handler renders the 500 response.
+// The server recovers panics the same way without it, as a last resort. Use Recover for its options,
+// or to let middleware registered before it see panics as errors.
+// Register it first so it wraps all other middleware.
+// Example:
+//
+//	s.Use(rweb.Recover())
+func Recover(options ...RecoverOptions) Handler {
+	var opts RecoverOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+
+	return func(ctx Context) (err error) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+
+			stack := debug.Stack()
+			if opts.DisableStackTrace {
+				log.Printf("[PANIC] %s %q: %v\n", ctx.Request().Method(), ctx.Request().Path(), rec)
+			} else {
+				log.Printf("[PANIC] %s %q: %v\n%s", ctx.Request().Method(), ctx.Request().Path(), rec, stack)
+			}
+
+			if opts.OnPanic != nil {
+				opts.OnPanic(ctx, rec, stack)
+			}
+
+			// Drop any partial response so the error handler starts from a clean slate
+			if c := baseContext(ctx); c != nil {
+				c.resetResponse()
+			}
+			ctx.SetStatus(consts.StatusInternalServerError)
+
+			err = &PanicError{Value: rec, Stack: stack}
+		}()
+
+		return ctx.Next()
+	}
+}
+
+// callHandlers runs the handler chain for a request, recovering from any panic the way Recover does,
+// so a panicking handler never takes the server (or a test calling Server.Request) down with it
+func (s *Server) callHandlers(ctx *context) (err error) {
+	defer func() {
+		rec := recover()
+		if rec == nil {
+			return
+		}
+
+		stack := debug.Stack()
+		log.Printf("[PANIC] %s %q: %v\n%s", ctx.method, ctx.path, rec, stack)
+
+		// Drop any partial response so the error handler starts from a clean slate
+		ctx.resetResponse()
+		ctx.SetStatus(consts.StatusInternalServerError)
+
+		err = &PanicError{Value: rec, Stack: stack}
+	}()
+
+	return s.handlers[0](ctx)
+}
@@ -0,0 +1,76 @@
+package rweb_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/rohanthewiz/assert"
+	"github.com/rohanthewiz/rweb"
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+func TestRecover(t *testing.T) {
+	s := rweb.NewServer()
+	s.Use(rweb.Recover(rweb.RecoverOptions{DisableStackTrace: true}))
+
+	s.Get("/panic", func(ctx rweb.Context) error {
+		// Partial response that must not leak into the error response
+		ctx.Response().SetHeader("X-Partial", "true")
+		_ = ctx.SetStatus(consts.StatusAccepted).WriteString("partial")
+		panic("Something unbelievable happened")
+	})
+
+	s.Get("/ok", func(ctx rweb.Context) error {
+		return ctx.WriteString("fine")
+	})
+
+	response := s.Request(consts.MethodGet, "/panic", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusInternalServerError)
+	assert.Equal(t, response.Header("X-Partial"), "")
+	assert.False(t, strings.Contains(string(response.Body()), "partial"))
+	assert.True(t, strings.Contains(string(response.Body()), "500 Internal Server Error"))
+
+	// The server keeps working normally
+	response = s.Request(consts.MethodGet, "/ok", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusOK)
+	assert.Equal(t, string(response.Body()), "fine")
+}
+
+func TestRecoverWithOtherMiddleware(t *testing.T) {
+	s := rweb.NewServer()
+
+	var outerErr error
+	var panicValue any
+
+	// Outer middleware sees the panic as an error
+	s.Use(func(ctx rweb.Context) error {
+		outerErr = ctx.Next()
+		return outerErr
+	})
+	s.Use(rweb.Recover(rweb.RecoverOptions{
+		DisableStackTrace: true,
+		OnPanic: func(ctx rweb.Context, recovered any, stack []byte) {
+			panicValue = recovered
+		},
+	}))
+
+	// Panics inside group middleware are recovered too
+	api := s.Group("/api", func(ctx rweb.Context) error {
+		ctx.Response().SetHeader("X-API", "true")
+		return ctx.Next()
+	})
+	api.Get("/panic", func(ctx rweb.Context) error {
+		panic(errors.New("boom"))
+	})
+
+	response := s.Request(consts.MethodGet, "/api/panic", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusInternalServerError)
+	assert.Equal(t, response.Header("X-API"), "")
+
+	var panicErr *rweb.PanicError
+	assert.True(t, errors.As(outerErr, &panicErr))
+	assert.Equal(t, panicErr.Error(), "panic: boom")
+	assert.True(t, len(panicErr.Stack) > 0)
+	assert.Equal(t, panicValue.(error).Error(), "boom")
+}
@@ -0,0 +1,63 @@
+package rweb
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+// defaultMaxDecompressedSize is the most a request body may decompress to, unless the server options say otherwise
+const defaultMaxDecompressedSize = 32 << 20
+
+var (
+	// errBodyTooLarge is returned when a compressed request body decompresses to over the limit
+	errBodyTooLarge = errors.New("decompressed request body too large")
+	// errBodyEncoding is returned for a request body that isn't valid for its Content-Encoding
+	errBodyEncoding = errors.New("malformed compressed request body")
+)
+
+// decompressBody replaces a gzip or deflate encoded body with its decompressed content, up to maxSize bytes,
+// so handlers (and the form parsing) see the body as sent. The Content-Encoding header is removed,
+// and Content-Length updated to match. Other encodings are left alone.
+// It returns an error wrapping errBodyTooLarge if the body decompresses to over maxSize
+// (guarding against "zip bombs"), or errBodyEncoding if it can't be decompressed.
+func (req *request) decompressBody(maxSize int64) error {
+	var reader io.ReadCloser
+	var err error
+	switch strings.ToLower(strings.TrimSpace(req.Header(consts.HeaderContentEncoding))) {
+	case "gzip", "x-gzip":
+		reader, err = gzip.NewReader(bytes.NewReader(req.body))
+	case "deflate":
+		// Properly zlib wrapped, though some clients send raw deflate
+		if reader, err = zlib.NewReader(bytes.NewReader(req.body)); err != nil {
+			reader, err = flate.NewReader(bytes.NewReader(req.body)), nil
+		}
+	default:
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", errBodyEncoding, err)
+	}
+	defer reader.Close()
+
+	body, err := io.ReadAll(io.LimitReader(reader, maxSize+1))
+	if err != nil {
+		return fmt.Errorf("%w: %v", errBodyEncoding, err)
+	}
+	if int64(len(body)) > maxSize {
+		return fmt.Errorf("%w: over %d bytes", errBodyTooLarge, maxSize)
+	}
+
+	req.body = body
+	req.DelHeader(consts.HeaderContentEncoding)
+	req.SetHeader(consts.HeaderContentLength, strconv.Itoa(len(body)))
+	return nil
+}
@@ -0,0 +1,97 @@
+package rweb_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"syscall"
+	"testing"
+
+	"github.com/rohanthewiz/assert"
+	"github.com/rohanthewiz/rweb"
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+func TestRequestDecompression(t *testing.T) {
+	readyChan := make(chan struct{}, 1)
+	s := rweb.NewServerWithOptions(
+		rweb.WithAddress("localhost:"),
+		rweb.WithReadyChan(readyChan),
+		rweb.WithRequestDecompression(64*1024),
+	)
+
+	s.Post("/form", func(ctx rweb.Context) error {
+		return ctx.WriteString(ctx.Request().GetPostValue("name") + " " + ctx.Request().Header(consts.HeaderContentEncoding))
+	})
+	s.Post("/json", func(ctx rweb.Context) error {
+		var order struct {
+			Qty int `json:"qty"`
+		}
+		if err := ctx.DecodeJSONLimited(&order, 1024); err != nil {
+			return err
+		}
+		return ctx.WriteString(fmt.Sprintf("qty %d, length %s", order.Qty, ctx.Request().Header(consts.HeaderContentLength)))
+	})
+
+	gzipped := func(data []byte) []byte {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		_, _ = zw.Write(data)
+		_ = zw.Close()
+		return buf.Bytes()
+	}
+	deflated := func(data []byte) []byte {
+		var buf bytes.Buffer
+		zw := zlib.NewWriter(&buf)
+		_, _ = zw.Write(data)
+		_ = zw.Close()
+		return buf.Bytes()
+	}
+
+	go func() {
+		defer syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+		<-readyChan // wait for server
+		base := fmt.Sprintf("http://127.0.0.1:%s", s.GetListenPort())
+
+		post := func(path, contentType, encoding string, body []byte) (int, string) {
+			req, err := http.NewRequest(consts.MethodPost, base+path, bytes.NewReader(body))
+			assert.Nil(t, err)
+			req.Header.Set(consts.HeaderContentType, contentType)
+			req.Header.Set(consts.HeaderContentEncoding, encoding)
+			resp, err := http.DefaultClient.Do(req)
+			assert.Nil(t, err)
+			defer resp.Body.Close()
+			respBody, _ := io.ReadAll(resp.Body)
+			return resp.StatusCode, string(respBody)
+		}
+
+		status, body := post("/form", string(consts.BytFormData), "gzip", gzipped([]byte("name=ann&x=1")))
+		assert.Equal(t, status, consts.StatusOK)
+		assert.Equal(t, body, "ann ")
+
+		status, body = post("/json", "application/json", "deflate", deflated([]byte(`{"qty":3}`)))
+		assert.Equal(t, status, consts.StatusOK)
+		assert.Equal(t, body, "qty 3, length 9")
+
+		// Uncompressed bodies are untouched
+		status, body = post("/form", string(consts.BytFormData), "identity", []byte("name=bob"))
+		assert.Equal(t, status, consts.StatusOK)
+		assert.Equal(t, body, "bob identity")
+
+		// A bomb - a small body decompressing to over the limit
+		bomb := gzipped(bytes.Repeat([]byte("a"), 1<<20))
+		assert.True(t, len(bomb) < 4096)
+		status, _ = post("/form", string(consts.BytFormData), "gzip", bomb)
+		assert.Equal(t, status, consts.StatusRequestEntityTooLarge)
+
+		// Not gzip at all
+		status, _ = post("/form", string(consts.BytFormData), "gzip", []byte("name=eve"))
+		assert.Equal(t, status, consts.StatusBadRequest)
+	}()
+
+	_ = s.Run()
+}
@@ -0,0 +1,54 @@
+package rweb
+
+import (
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+// RequestIDKey is the context data key under which the RequestID middleware stores the request's ID
+const RequestIDKey = "rweb.requestID"
+
+// maxRequestIDLen caps the length of an incoming request ID we are willing to reuse
+const maxRequestIDLen = 128
+
+// RequestID returns a middleware that tags each request with an ID, for tracing requests across logs.
+// An incoming X-Request-ID header (e.g. from a load balancer or upstream service) is reused,
+// otherwise a new ID is generated. The ID is available to handlers via ctx.RequestID(),
+// and echoed back in the X-Request-ID response header.
+// RequestInfo includes the ID in its log lines. Register RequestID early, so any other middleware can log it too.
+// Example:
+//
+//	s.Use(rweb.RequestID())
+//	s.Use(rweb.RequestInfo)
+func RequestID() Handler {
+	return func(ctx Context) error {
+		id := ctx.Request().Header(consts.HeaderXRequestID)
+		if !validRequestID(id) {
+			id = GenRandString(16, true)
+		}
+
+		ctx.Set(RequestIDKey, id)
+		ctx.Response().SetHeader(consts.HeaderXRequestID, id)
+		return ctx.Next()
+	}
+}
+
+// RequestID returns the ID assigned to the request by the RequestID middleware,
+// or "" if the middleware isn't in use.
+func (ctx *context) RequestID() string {
+	id, _ := ctx.Get(RequestIDKey).(string)
+	return id
+}
+
+// validRequestID reports whether a client-supplied request ID is safe to reuse -
+// not empty or overly long, and printable ASCII so it can't inject into logs or headers
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
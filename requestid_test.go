@@ -0,0 +1,53 @@
+package rweb_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rohanthewiz/assert"
+	"github.com/rohanthewiz/rweb"
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+func TestRequestID(t *testing.T) {
+	s := rweb.NewServer()
+	s.Use(rweb.RequestID())
+
+	s.Get("/", func(ctx rweb.Context) error {
+		return ctx.WriteString(ctx.RequestID())
+	})
+
+	// A new ID is generated for each request
+	response := s.Request(consts.MethodGet, "/", nil, nil)
+	id := string(response.Body())
+	assert.Equal(t, len(id), 19) // e.g. "ABCD-EFGH-IJKL-MNOP"
+	assert.Equal(t, response.Header(consts.HeaderXRequestID), id)
+
+	response = s.Request(consts.MethodGet, "/", nil, nil)
+	assert.NotEqual(t, string(response.Body()), id)
+
+	// An incoming ID is propagated
+	response = s.Request(consts.MethodGet, "/",
+		[]rweb.Header{{Key: consts.HeaderXRequestID, Value: "upstream-1234"}}, nil)
+	assert.Equal(t, string(response.Body()), "upstream-1234")
+	assert.Equal(t, response.Header(consts.HeaderXRequestID), "upstream-1234")
+
+	// Unsafe or oversized incoming IDs are replaced
+	for _, bad := range []string{"has space", "new\nline", strings.Repeat("x", 129)} {
+		response = s.Request(consts.MethodGet, "/",
+			[]rweb.Header{{Key: consts.HeaderXRequestID, Value: bad}}, nil)
+		assert.Equal(t, len(response.Body()), 19)
+	}
+}
+
+func TestRequestIDWithoutMiddleware(t *testing.T) {
+	s := rweb.NewServer()
+
+	s.Get("/", func(ctx rweb.Context) error {
+		return ctx.WriteString(ctx.RequestID())
+	})
+
+	response := s.Request(consts.MethodGet, "/", nil, nil)
+	assert.Equal(t, string(response.Body()), "")
+	assert.Equal(t, response.Header(consts.HeaderXRequestID), "")
+}
@@ -0,0 +1,36 @@
+package rweb
+
+import "github.com/rohanthewiz/rweb/consts"
+
+// AddMethodWith registers a handler like AddMethod, behind middleware for this route alone.
+// The middleware runs in order ahead of the handler, as a group's would,
+// so there's no need for a group just to protect a single route.
+func (s *Server) AddMethodWith(method string, path string, handler Handler, middleware ...Handler) {
+	s.addRoute(method, path, chainHandlers(handler, middleware), len(middleware) > 0)
+}
+
+// GetWith registers a GET route with its own middleware.
+// Example: s.GetWith("/admin", adminHandler, rweb.BasicAuth(rweb.BasicAuthUsers(admins), "admin"))
+func (s *Server) GetWith(path string, handler Handler, middleware ...Handler) {
+	s.AddMethodWith(consts.MethodGet, path, handler, middleware...)
+}
+
+// PostWith registers a POST route with its own middleware.
+func (s *Server) PostWith(path string, handler Handler, middleware ...Handler) {
+	s.AddMethodWith(consts.MethodPost, path, handler, middleware...)
+}
+
+// PutWith registers a PUT route with its own middleware.
+func (s *Server) PutWith(path string, handler Handler, middleware ...Handler) {
+	s.AddMethodWith(consts.MethodPut, path, handler, middleware...)
+}
+
+// PatchWith registers a PATCH route with its own middleware.
+func (s *Server) PatchWith(path string, handler Handler, middleware ...Handler) {
+	s.AddMethodWith(consts.MethodPatch, path, handler, middleware...)
+}
+
+// DeleteWith registers a DELETE route with its own middleware.
+func (s *Server) DeleteWith(path string, handler Handler, middleware ...Handler) {
+	s.AddMethodWith(consts.MethodDelete, path, handler, middleware...)
+}
@@ -0,0 +1,68 @@
+package rweb_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rohanthewiz/assert"
+	"github.com/rohanthewiz/rweb"
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+func TestRouteMiddleware(t *testing.T) {
+	s := rweb.NewServer()
+
+	var order []string
+	s.Use(func(ctx rweb.Context) error {
+		order = append(order, "server")
+		return ctx.Next()
+	})
+
+	tag := func(name string) rweb.Handler {
+		return func(ctx rweb.Context) error {
+			order = append(order, name)
+			return ctx.Next()
+		}
+	}
+	requireToken := func(ctx rweb.Context) error {
+		if ctx.Request().Header("X-Token") != "secret" {
+			ctx.Abort()
+			return ctx.SetStatus(consts.StatusUnauthorized).WriteText("no")
+		}
+		return nil // carries on without calling Next, as in a group
+	}
+
+	s.GetWith("/admin", func(ctx rweb.Context) error {
+		order = append(order, "handler")
+		return ctx.WriteText("admin")
+	}, tag("first"), tag("second"), requireToken)
+	s.PostWith("/items/:id", func(ctx rweb.Context) error {
+		return ctx.WriteText("saved " + ctx.Request().Param("id"))
+	}, requireToken)
+	s.Get("/open", func(ctx rweb.Context) error {
+		order = append(order, "handler")
+		return ctx.WriteText("open")
+	})
+
+	withToken := []rweb.Header{{Key: "X-Token", Value: "secret"}}
+
+	response := s.Request(consts.MethodGet, "/admin", withToken, nil)
+	assert.Equal(t, string(response.Body()), "admin")
+	assert.Equal(t, strings.Join(order, ","), "server,first,second,handler")
+
+	order = nil
+	response = s.Request(consts.MethodGet, "/admin", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusUnauthorized)
+	assert.Equal(t, strings.Join(order, ","), "server,first,second")
+
+	response = s.Request(consts.MethodPost, "/items/7", withToken, nil)
+	assert.Equal(t, string(response.Body()), "saved 7")
+	response = s.Request(consts.MethodPost, "/items/7", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusUnauthorized)
+
+	// Other routes are unaffected
+	order = nil
+	response = s.Request(consts.MethodGet, "/open", nil, nil)
+	assert.Equal(t, string(response.Body()), "open")
+	assert.Equal(t, strings.Join(order, ","), "server,handler")
+}
@@ -1,8 +1,11 @@
 package rweb
 
 import (
-	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
 	"net/url"
+	"os"
 	"path/filepath"
 	"strings"
 	"time"
@@ -223,6 +226,56 @@ func FileWithModTime(ctx Context, filename string, body []byte, modTime time.Tim
 	return ctx.Bytes(body)
 }
 
+// ServeFile streams the file at path to the client, with headers per its extension (as for File)
+// and a Last-Modified of its modification time.
+// The file is copied to the connection as the response is written, rather than read into memory,
+// so it suits large files. A missing file (or a directory) gets a 404.
+// Example Usage:
+//
+// // Stream a download straight from disk
+// return rweb.ServeFile(ctx, "exports/report.pdf")
+func ServeFile(ctx Context, path string) error {
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && info.IsDir()) {
+		ctx.SetStatus(consts.StatusNotFound)
+		return ctx.WriteText(consts.StatusTextFromCode[consts.StatusNotFound])
+	} else if err != nil {
+		return err
+	}
+
+	setFileHeaders(ctx, filepath.Base(path), info.ModTime())
+
+	base := baseContext(ctx)
+	if base == nil || info.Size() == 0 {
+		// Nothing to stream (or no way to) - buffer it
+		body, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return ctx.Bytes(body)
+	}
+
+	// The file is opened only once the body is due, so there's nothing to release
+	// if it never is (e.g. for HEAD requests, or if a later handler replaces the response)
+	size := info.Size()
+	base.streamLen = size
+	base.streamFn = func(w io.Writer) error {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		// Send no more than the Content-Length announced, even if the file has grown since
+		n, err := io.Copy(w, io.LimitReader(f, size))
+		if err == nil && n < size {
+			err = io.ErrUnexpectedEOF // the file shrank
+		}
+		return err
+	}
+	return nil
+}
+
 // JS sends the body with the content type set to `text/javascript`.
 func JS(ctx Context, body string) error {
 	ctx.Response().SetHeader("Content-Type", "text/javascript")
@@ -230,9 +283,14 @@ func JS(ctx Context, body string) error {
 }
 
 // JSON encodes the object in JSON format and sends it with the content type set to `application/json`.
+// The encoding is per the server's JSONEncoder config.
 func JSON(ctx Context, object any) error {
 	ctx.Response().SetHeader("Content-Type", "application/json")
-	return json.NewEncoder(ctx.Response()).Encode(object)
+	var cfg JSONEncoderCfg
+	if base := baseContext(ctx); base != nil {
+		cfg = base.jsonEncoder()
+	}
+	return cfg.newEncoder(ctx.Response()).Encode(object)
 }
 
 // Text sends the body with the content type set to `text/plain`.
@@ -1,6 +1,12 @@
 package rweb_test
 
 import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
 	"testing"
 	"time"
 
@@ -342,3 +348,71 @@ func TestFileWithModTime(t *testing.T) {
 		assert.NotEqual(t, response.Header("Date"), "")
 	})
 }
+
+func TestServeFile(t *testing.T) {
+	dir := t.TempDir()
+	content := strings.Repeat("rweb streams files from disk\n", 4096)
+	filePath := filepath.Join(dir, "notes.txt")
+	assert.Nil(t, os.WriteFile(filePath, []byte(content), 0o644))
+	modTime := time.Date(2024, 10, 14, 12, 30, 0, 0, time.UTC)
+	assert.Nil(t, os.Chtimes(filePath, modTime, modTime))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "empty.txt"), nil, 0o644))
+
+	readyChan := make(chan struct{}, 1)
+	s := rweb.NewServer(rweb.ServerOptions{ReadyChan: readyChan, Address: "localhost:"})
+	s.Get("/files/:name", func(ctx rweb.Context) error {
+		return rweb.ServeFile(ctx, filepath.Join(dir, ctx.Request().Param("name")))
+	})
+	s.Head("/files/:name", func(ctx rweb.Context) error {
+		return rweb.ServeFile(ctx, filepath.Join(dir, ctx.Request().Param("name")))
+	})
+
+	go func() {
+		defer syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+		<-readyChan // wait for server
+		url := "http://localhost:" + s.GetListenPort() + "/files/"
+
+		// Streamed with a Content-Length, not chunked
+		resp, err := http.Get(url + "notes.txt")
+		assert.Nil(t, err)
+		body, err := io.ReadAll(resp.Body)
+		assert.Nil(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, resp.StatusCode, consts.StatusOK)
+		assert.Equal(t, string(body), content)
+		assert.Equal(t, resp.ContentLength, int64(len(content)))
+		assert.Equal(t, len(resp.TransferEncoding), 0)
+		assert.Equal(t, resp.Header.Get("Content-Type"), "text/plain; charset=utf-8")
+		assert.Equal(t, resp.Header.Get("Last-Modified"), modTime.Format(time.RFC1123))
+
+		// HEAD has the headers, but no body
+		resp, err = http.Head(url + "notes.txt")
+		assert.Nil(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, resp.StatusCode, consts.StatusOK)
+		assert.Equal(t, resp.ContentLength, int64(len(content)))
+
+		// Empty file
+		resp, err = http.Get(url + "empty.txt")
+		assert.Nil(t, err)
+		body, _ = io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		assert.Equal(t, resp.StatusCode, consts.StatusOK)
+		assert.Equal(t, len(body), 0)
+
+		// Missing file
+		resp, err = http.Get(url + "missing.txt")
+		assert.Nil(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, resp.StatusCode, consts.StatusNotFound)
+	}()
+
+	err := s.Run()
+	assert.Nil(t, err)
+
+	// Synthetic requests get the whole file in the response body
+	resp := s.Request(consts.MethodGet, "/files/notes.txt", nil, nil)
+	assert.Equal(t, resp.Status(), consts.StatusOK)
+	assert.Equal(t, string(resp.Body()), content)
+}
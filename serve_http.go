@@ -0,0 +1,195 @@
+package rweb
+
+import (
+	"bufio"
+	gocontext "context"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+// ServeHTTP handles a request from a net/http server, making the Server an http.Handler.
+// So an rweb app can be mounted in an existing net/http server, composed with other routers,
+// or tested with httptest:
+//
+//	mux.Handle("/app/", http.StripPrefix("/app", s))
+//	ts := httptest.NewServer(s)
+//
+// Routing, middleware, hooks and the response (including streams, Flush, SSE and WebSockets)
+// work as when rweb serves the connection itself. Connection-level options, such as timeouts,
+// header limits and TLS, are then those of the net/http server.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := s.contextPool.Get().(*context)
+	defer func() {
+		ctx.Clean()
+		s.contextPool.Put(ctx)
+	}()
+
+	// Headers, in a stable order. net/http keeps the Host apart.
+	if r.Host != "" {
+		ctx.request.headers = append(ctx.request.headers, Header{Key: consts.HeaderHost, Value: r.Host})
+	}
+	for _, key := range slices.Sorted(maps.Keys(r.Header)) {
+		for _, value := range r.Header[key] {
+			ctx.request.headers = append(ctx.request.headers, Header{Key: key, Value: value})
+		}
+	}
+	ctx.request.ContentType = []byte(r.Header.Get(consts.HeaderContentType))
+
+	// Body - read, unless a large multipart one is left for NextPart
+	if r.Body != nil && r.Body != http.NoBody && r.Method != consts.MethodHead && r.Method != consts.MethodTrace {
+		if s.streamsMultipart(r.Method, r.ContentLength, r.ContentLength < 0, ctx.request.ContentType) {
+			ctx.request.bodyStream = r.Body
+		} else {
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				if s.options.Verbose {
+					fmt.Println("Error reading request body:", err)
+				}
+				http.Error(w, consts.StatusTextFromCode[consts.StatusBadRequest], consts.StatusBadRequest)
+				return
+			}
+			ctx.request.body = append(ctx.request.body, body...)
+		}
+	}
+
+	respWriter := &netHTTPWriter{ResponseWriter: w}
+	ctx.httpReq = r
+	ctx.httpWriter = respWriter
+	ctx.goCtx, ctx.cancelGoCtx = gocontext.WithCancel(r.Context())
+
+	s.handleRequest(ctx, r.Method, r.URL.RequestURI(), respWriter)
+	ctx.cancelGoCtx()
+
+	// The handler returned, so the WebSocket session is over
+	if ctx.wsUpgraded {
+		s.wsConns.Add(-1)
+		s.removeWebSocket(ctx.wsConn)
+		ctx.wsConn.notifyClose(wsCloseAbnormalClosure, "")
+		_ = ctx.conn.Close()
+		return
+	}
+
+	// Have net/http break off the response, so the client knows it's incomplete
+	if respWriter.aborted {
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// netHTTPWriter is where requests served through ServeHTTP send their response - to net/http's ResponseWriter
+type netHTTPWriter struct {
+	http.ResponseWriter
+	flush   bool // send each write straight to the client (streamed responses)
+	aborted bool // the response failed part way through (see Close)
+}
+
+func (w *netHTTPWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	if err == nil && w.flush {
+		_ = http.NewResponseController(w.ResponseWriter).Flush()
+	}
+	return n, err
+}
+
+// Close marks the response as failed part way through - where rweb closes its own connections,
+// so the client sees an incomplete body, ServeHTTP has net/http abort the response instead.
+func (w *netHTTPWriter) Close() error {
+	w.aborted = true
+	return nil
+}
+
+// writeHead sends the status and headers of the response. contentLen is the length of the body, or -1 if not known.
+func (w *netHTTPWriter) writeHead(ctx *context, contentLen int64) {
+	header := w.Header()
+	for _, hdr := range ctx.response.headers {
+		header.Add(hdr.Key, hdr.Value)
+	}
+	if contentLen >= 0 {
+		header.Set(consts.HeaderContentLength, strconv.FormatInt(contentLen, 10))
+	}
+	if _, ok := header[consts.HeaderContentType]; !ok {
+		header[consts.HeaderContentType] = nil // as rweb sends it - don't have net/http sniff one
+	}
+	w.WriteHeader(int(ctx.status))
+}
+
+// hijack takes the connection over from net/http, for a WebSocket
+func (w *netHTTPWriter) hijack() (net.Conn, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err != nil {
+		return nil, err
+	}
+	if rw.Reader.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, reader: rw.Reader}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn is a connection some of whose input has already been read into reader
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
+
+// writeNetHTTPResponse is writeResponse for requests served through ServeHTTP
+func (s *Server) writeNetHTTPResponse(ctx *context, w *netHTTPWriter) {
+	// Flushed - the head and some of the body are already sent
+	if ctx.response.stream != nil {
+		_, _ = w.Write(ctx.response.body)
+		return
+	}
+
+	s.applyDefaultHeaders(ctx)
+	ctx.response.addServerTimingHeader()
+
+	switch {
+	case ctx.sseEventsChan != nil:
+		w.writeHead(ctx, -1)
+		w.flush = true
+		if err := s.sendSSE(ctx, w); err != nil {
+			fmt.Println("Error sending SSE events: ", err)
+		}
+	case ctx.streamFn != nil:
+		if ctx.streamLen > 0 {
+			w.writeHead(ctx, ctx.streamLen)
+		} else {
+			w.writeHead(ctx, -1)
+			w.flush = true
+		}
+		if ctx.request.method == consts.MethodHead {
+			return
+		}
+		if err := ctx.streamFn(countingWriter{w: w, n: &ctx.response.sent}); err != nil {
+			fmt.Printf("Error streaming response for %q: %v\n", ctx.request.path, err)
+			_ = w.Close()
+		}
+	default:
+		w.writeHead(ctx, int64(len(ctx.response.body)))
+		if ctx.request.method != consts.MethodHead {
+			_, _ = w.Write(ctx.response.body)
+		}
+	}
+}
+
+// flushNetHTTP is Flush for requests served through ServeHTTP
+func (ctx *context) flushNetHTTP() error {
+	w := ctx.httpWriter
+	w.writeHead(ctx, -1)
+	w.flush = true
+	ctx.response.stream = w
+
+	body := ctx.response.body
+	ctx.response.body = ctx.response.body[:0]
+	_, err := ctx.response.Write(body)
+	return err
+}
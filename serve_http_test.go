@@ -0,0 +1,144 @@
+package rweb_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rohanthewiz/assert"
+	"github.com/rohanthewiz/rweb"
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+func TestServeHTTP(t *testing.T) {
+	s := rweb.NewServer(rweb.ServerOptions{DefaultHeaders: []rweb.Header{{Key: "X-Frame-Options", Value: "DENY"}}})
+	s.Use(func(ctx rweb.Context) error {
+		ctx.Response().SetHeader("X-Middleware", "ran")
+		return ctx.Next()
+	})
+
+	s.Get("/users/:id", func(ctx rweb.Context) error {
+		req := ctx.Request()
+		return ctx.WriteText(req.Param("id") + " " + req.QueryParam("sort") + " " + req.Header("X-Req") + " " + ctx.ClientIP())
+	})
+	s.Post("/form", func(ctx rweb.Context) error {
+		ctx.SetStatus(http.StatusCreated)
+		_ = ctx.SetCookie("seen", "yes")
+		return ctx.WriteText("hello " + ctx.Request().FormValue("name"))
+	})
+	s.Get("/plain", func(ctx rweb.Context) error {
+		return ctx.Bytes([]byte("<p>no type</p>"))
+	})
+
+	// httptest's recorder
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(consts.MethodGet, "/users/7?sort=asc", nil)
+	r.Header.Set("X-Req", "r1")
+	s.ServeHTTP(w, r)
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.Equal(t, w.Body.String(), "7 asc r1 192.0.2.1")
+	assert.Equal(t, w.Header().Get("X-Middleware"), "ran")
+	assert.Equal(t, w.Header().Get("X-Frame-Options"), "DENY")
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(consts.MethodPost, "/form", strings.NewReader("name=rweb"))
+	r.Header.Set(consts.HeaderContentType, "application/x-www-form-urlencoded")
+	s.ServeHTTP(w, r)
+	assert.Equal(t, w.Code, http.StatusCreated)
+	assert.Equal(t, w.Body.String(), "hello rweb")
+	assert.Equal(t, w.Result().Cookies()[0].Value, "yes")
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(consts.MethodGet, "/plain", nil))
+	assert.Equal(t, w.Header().Get(consts.HeaderContentType), "") // not sniffed, as rweb sends it
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(consts.MethodGet, "/nowhere", nil))
+	assert.Equal(t, w.Code, http.StatusNotFound)
+
+	// Mounted under a prefix of a net/http mux
+	mux := http.NewServeMux()
+	mux.Handle("/app/", http.StripPrefix("/app", s))
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(consts.MethodGet, "/app/users/9", nil))
+	assert.Equal(t, w.Body.String(), "9   192.0.2.1")
+}
+
+func TestServeHTTPServer(t *testing.T) {
+	s := rweb.NewServer()
+
+	s.Post("/echo", func(ctx rweb.Context) error {
+		return ctx.WriteText(string(ctx.Request().Body()) + " from " + ctx.Request().Header("Host"))
+	})
+	s.Get("/stream", func(ctx rweb.Context) error {
+		return ctx.StreamJSON(func(enc *json.Encoder) error {
+			for i := range 3 {
+				if err := enc.Encode(map[string]int{"n": i}); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+	flushed := make(chan struct{})
+	s.Get("/progress", func(ctx rweb.Context) error {
+		_ = ctx.WriteString("step 1;")
+		if err := ctx.Flush(); err != nil {
+			return err
+		}
+		<-flushed // the client has step 1 before we go on
+		return ctx.WriteString("step 2")
+	})
+	s.WebSocket("/ws", func(ws *rweb.WSConn) error {
+		msg, err := ws.ReadMessage()
+		if err != nil {
+			return err
+		}
+		return ws.WriteMessage(rweb.TextMessage, append([]byte("echo: "), msg.Data...))
+	})
+
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	host := strings.TrimPrefix(ts.URL, "http://")
+
+	resp, err := http.Post(ts.URL+"/echo", "text/plain", strings.NewReader("ping"))
+	assert.Nil(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	assert.Equal(t, string(body), "ping from "+host)
+	assert.Equal(t, resp.ContentLength, int64(len(body)))
+
+	resp, err = http.Get(ts.URL + "/stream")
+	assert.Nil(t, err)
+	body, _ = io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	assert.Equal(t, string(body), "{\"n\":0}\n{\"n\":1}\n{\"n\":2}\n")
+	assert.Equal(t, resp.Header.Get(consts.HeaderContentType), consts.MIMEJSON)
+	assert.Equal(t, resp.ContentLength, int64(-1))
+
+	resp, err = http.Get(ts.URL + "/progress")
+	assert.Nil(t, err)
+	reader := bufio.NewReader(resp.Body)
+	first := make([]byte, len("step 1;"))
+	_, err = io.ReadFull(reader, first)
+	assert.Nil(t, err)
+	assert.Equal(t, string(first), "step 1;")
+	close(flushed)
+	rest, _ := io.ReadAll(reader)
+	_ = resp.Body.Close()
+	assert.Equal(t, string(rest), "step 2")
+
+	// WebSockets take the connection over from net/http
+	conn, resp := dialWebSocket(t, host, "/ws")
+	defer conn.Close()
+	assert.Equal(t, resp.StatusCode, http.StatusSwitchingProtocols)
+	ws := rweb.NewWSConn(conn, false)
+	assert.Nil(t, ws.WriteMessage(rweb.TextMessage, []byte("hi")))
+	msg, err := ws.ReadMessage()
+	assert.Nil(t, err)
+	assert.Equal(t, string(msg.Data), "echo: hi")
+}
@@ -0,0 +1,67 @@
+package rweb
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+// serverTiming is a metric for the Server-Timing response header
+type serverTiming struct {
+	name string
+	dur  time.Duration
+	desc string
+}
+
+// AddServerTiming records a timing metric for the response's Server-Timing header,
+// which browser devtools show alongside the request's own timings.
+// name must be a token (e.g. "db", "render"). A zero dur or empty desc is left out of the metric.
+// Example:
+//
+//	start := time.Now()
+//	rows, err := db.QueryContext(ctx.Context(), query)
+//	ctx.AddServerTiming("db", time.Since(start), "Database")
+func (ctx *context) AddServerTiming(name string, dur time.Duration, desc string) {
+	ctx.response.timings = append(ctx.response.timings, serverTiming{name: name, dur: dur, desc: desc})
+}
+
+// addServerTimingHeader adds the Server-Timing header for the metrics recorded, if any
+func (res *response) addServerTimingHeader() {
+	if len(res.timings) == 0 {
+		return
+	}
+
+	var sb strings.Builder
+	for i, timing := range res.timings {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(timing.name)
+		if timing.dur != 0 {
+			sb.WriteString(";dur=")
+			// Milliseconds, to the microsecond
+			sb.WriteString(strconv.FormatFloat(float64(timing.dur.Microseconds())/1000, 'f', -1, 64))
+		}
+		if timing.desc != "" {
+			sb.WriteString(";desc=")
+			sb.WriteString(quoteHeaderValue(timing.desc))
+		}
+	}
+	res.AddHeader(consts.HeaderServerTiming, sb.String())
+}
+
+// quoteHeaderValue returns s as an HTTP quoted-string
+func quoteHeaderValue(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		if s[i] == '"' || s[i] == '\\' {
+			sb.WriteByte('\\')
+		}
+		sb.WriteByte(s[i])
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
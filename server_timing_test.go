@@ -0,0 +1,65 @@
+package rweb_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rohanthewiz/assert"
+	"github.com/rohanthewiz/rweb"
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+func TestServerTiming(t *testing.T) {
+	s := rweb.NewServer()
+	s.Use(func(ctx rweb.Context) error {
+		start := time.Now()
+		err := ctx.Next()
+		ctx.AddServerTiming("total", time.Since(start), "")
+		return err
+	})
+
+	s.Get("/report", func(ctx rweb.Context) error {
+		ctx.AddServerTiming("db", 53200*time.Microsecond, "Database")
+		ctx.AddServerTiming("cache", 0, `Hit "warm"`)
+		return ctx.WriteText("report")
+	})
+	s.Get("/plain", func(ctx rweb.Context) error {
+		return ctx.WriteText("plain")
+	})
+	s.Get("/fail", func(ctx rweb.Context) error {
+		ctx.AddServerTiming("db", 2*time.Millisecond, "")
+		return ctx.WriteError(http.ErrNoLocation, consts.StatusInternalServerError)
+	})
+
+	response := s.Request(consts.MethodGet, "/report", nil, nil)
+	timing := response.Header(consts.HeaderServerTiming)
+	assert.Equal(t, timing[:len(`db;dur=53.2;desc="Database", cache;desc="Hit \"warm\"", total;dur=`)],
+		`db;dur=53.2;desc="Database", cache;desc="Hit \"warm\"", total;dur=`)
+
+	// None recorded by the handler - just the middleware's
+	response = s.Request(consts.MethodGet, "/plain", nil, nil)
+	assert.Equal(t, len(response.Headers(consts.HeaderServerTiming)), 1)
+
+	// Kept for error responses
+	response = s.Request(consts.MethodGet, "/fail", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusInternalServerError)
+	assert.Equal(t, response.Header(consts.HeaderServerTiming)[:len("db;dur=2, total;dur=")], "db;dur=2, total;dur=")
+
+	// Served through net/http, and without any timing
+	s = rweb.NewServer()
+	s.Get("/", func(ctx rweb.Context) error {
+		ctx.AddServerTiming("render", 1500*time.Microsecond, "")
+		return ctx.WriteText("home")
+	})
+	s.Get("/none", func(ctx rweb.Context) error {
+		return ctx.WriteText("none")
+	})
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(consts.MethodGet, "/", nil))
+	assert.Equal(t, w.Header().Get(consts.HeaderServerTiming), "render;dur=1.5")
+
+	response = s.Request(consts.MethodGet, "/none", nil, nil)
+	assert.Equal(t, response.Header(consts.HeaderServerTiming), "")
+}
@@ -0,0 +1,280 @@
+package rweb
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"maps"
+	"sync"
+	"time"
+)
+
+// ErrSessionTooLarge is returned by Session.Save when the session cookie won't fit in a cookie,
+// as with a CookieSessionStore session holding too much data
+var ErrSessionTooLarge = errors.New("session too large for a cookie")
+
+// SessionStore keeps the data of sessions.
+// The session cookie carries a token from the store: a session ID for server-side stores,
+// or the data itself for CookieSessionStore. The session cookie is always signed
+// (with the server's CookieConfig.Secret), so the store only sees tokens it issued.
+type SessionStore interface {
+	// Load returns the data of the session for token, or nil if there's no such session (e.g. it has expired).
+	Load(token string) (map[string]any, error)
+	// Save stores the data of the session for token ("" for a new session), to be kept for maxAge,
+	// and returns the token for the session cookie.
+	Save(token string, data map[string]any, maxAge time.Duration) (string, error)
+	// Delete removes the session for token.
+	Delete(token string) error
+}
+
+// SessionCfg configures the sessions of ctx.Session()
+type SessionCfg struct {
+	// Store keeps the session data. Defaults to a MemorySessionStore.
+	Store SessionStore
+	// CookieName is the name of the session cookie. Defaults to "session".
+	CookieName string
+	// MaxAge is how long a session lasts after it was last saved. Defaults to 24 hours.
+	MaxAge time.Duration
+}
+
+const (
+	defaultSessionCookieName = "session"
+	defaultSessionMaxAge     = 24 * time.Hour
+)
+
+// Session is the session of a request's client, from ctx.Session().
+// Changes are kept only once saved with Save, which also sends the session cookie.
+// Example:
+//
+//	sess := ctx.Session()
+//	sess.Set("user_id", user.ID)
+//	if err := sess.Save(); err != nil {
+//		return err
+//	}
+type Session struct {
+	ctx   *context
+	token string // the store's token for the session, "" until saved
+	data  map[string]any
+}
+
+// Get returns the session value for key, or nil if there's none.
+func (sess *Session) Get(key string) any {
+	return sess.data[key]
+}
+
+// Set sets a session value.
+func (sess *Session) Set(key string, value any) {
+	sess.data[key] = value
+}
+
+// Delete removes a session value.
+func (sess *Session) Delete(key string) {
+	delete(sess.data, key)
+}
+
+// IsNew reports whether the session has yet to be saved, i.e. the client brought no valid session.
+func (sess *Session) IsNew() bool {
+	return sess.token == ""
+}
+
+// Save stores the session and sends the client the (signed) session cookie, extending the session by MaxAge.
+// Returns ErrCookieSecretNotSet if the server has no CookieConfig.Secret to sign the cookie with.
+func (sess *Session) Save() error {
+	secret := sess.ctx.cookieSecret()
+	if len(secret) == 0 {
+		return ErrCookieSecretNotSet
+	}
+
+	cfg := sess.ctx.server.sessionCfg()
+	token, err := cfg.Store.Save(sess.token, sess.data, cfg.MaxAge)
+	if err != nil {
+		return err
+	}
+	sess.token = token
+
+	value := signCookieValue(secret, cfg.CookieName, token)
+	if len(value) > maxSessionCookieSize {
+		return ErrSessionTooLarge
+	}
+	return sess.ctx.SetCookieWithOptions(&Cookie{
+		Name:     cfg.CookieName,
+		Value:    value,
+		MaxAge:   int(cfg.MaxAge / time.Second),
+		HttpOnly: true,
+	})
+}
+
+// Destroy ends the session (e.g. on logout), deleting it from the store and the client.
+// The session is left empty, so a later Save starts a new one.
+func (sess *Session) Destroy() error {
+	cfg := sess.ctx.server.sessionCfg()
+	if sess.token != "" {
+		if err := cfg.Store.Delete(sess.token); err != nil {
+			return err
+		}
+	}
+	sess.token = ""
+	clear(sess.data)
+	return sess.ctx.DeleteCookie(cfg.CookieName)
+}
+
+// Session returns the session of the request's client, loaded from the session cookie per the server's SessionCfg.
+// Without a valid session cookie (none, tampered with, or expired), the session is new and empty.
+// The same Session is returned for the rest of the request.
+func (ctx *context) Session() *Session {
+	if ctx.session != nil {
+		return ctx.session
+	}
+
+	ctx.session = &Session{ctx: ctx}
+	if ctx.server != nil {
+		cfg := ctx.server.sessionCfg()
+		if token, err := ctx.GetSignedCookie(cfg.CookieName); err == nil && token != "" {
+			if data, err := cfg.Store.Load(token); err == nil && data != nil {
+				ctx.session.token, ctx.session.data = token, data
+			}
+		}
+	}
+	if ctx.session.data == nil {
+		ctx.session.data = make(map[string]any)
+	}
+	return ctx.session
+}
+
+// sessionCfg returns the server's session configuration, with defaults applied
+func (s *Server) sessionCfg() SessionCfg {
+	cfg := s.options.Session
+	cfg.Store = s.sessionStore
+	if cfg.CookieName == "" {
+		cfg.CookieName = defaultSessionCookieName
+	}
+	if cfg.MaxAge <= 0 {
+		cfg.MaxAge = defaultSessionMaxAge
+	}
+	return cfg
+}
+
+// MemorySessionStore keeps sessions in memory, under cryptographically random IDs.
+// Sessions are lost on restart, and not shared between server instances,
+// so use a store backed by a database (e.g. Redis) to scale out.
+type MemorySessionStore struct {
+	mu        sync.Mutex
+	sessions  map[string]memorySession
+	lastSweep time.Time
+}
+
+// memorySession is a session held by a MemorySessionStore
+type memorySession struct {
+	data    map[string]any
+	expires time.Time
+}
+
+// NewMemorySessionStore returns an empty in-memory session store
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{sessions: make(map[string]memorySession), lastSweep: time.Now()}
+}
+
+// Load returns a copy of the session's data, or nil if there's no such session, or it has expired
+func (m *MemorySessionStore) Load(id string) (map[string]any, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sess, ok := m.sessions[id]
+	if !ok || time.Now().After(sess.expires) {
+		return nil, nil
+	}
+	return maps.Clone(sess.data), nil
+}
+
+// Save stores a copy of the session's data, under a new random ID if id is ""
+func (m *MemorySessionStore) Save(id string, data map[string]any, maxAge time.Duration) (string, error) {
+	if id == "" {
+		var err error
+		if id, err = newSessionID(); err != nil {
+			return "", err
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.sessions[id] = memorySession{data: maps.Clone(data), expires: now.Add(maxAge)}
+
+	// Drop expired sessions now and then, so abandoned ones don't accumulate
+	if now.Sub(m.lastSweep) > time.Minute {
+		for sid, sess := range m.sessions {
+			if now.After(sess.expires) {
+				delete(m.sessions, sid)
+			}
+		}
+		m.lastSweep = now
+	}
+	return id, nil
+}
+
+// Delete removes the session
+func (m *MemorySessionStore) Delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, id)
+	return nil
+}
+
+// newSessionID returns a cryptographically random session ID
+func newSessionID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// CookieSessionStore keeps the session data in the session cookie itself, JSON encoded,
+// so no server-side storage is needed. The cookie is signed, so the client can't alter the data,
+// but it can read it - don't store secrets. Values come back as their JSON types
+// (e.g. numbers as float64), and the encoded data is limited to what fits in a cookie.
+// The expiry goes in the signed data too, so a session can't outlive its MaxAge by the client keeping the cookie.
+type CookieSessionStore struct{}
+
+// maxSessionCookieSize keeps the signed session cookie value within the ~4KB browsers allow for a cookie
+const maxSessionCookieSize = 3800
+
+// cookieSession is the token of a CookieSessionStore session
+type cookieSession struct {
+	Expires int64          `json:"exp"` // Unix time
+	Data    map[string]any `json:"data"`
+}
+
+// NewCookieSessionStore returns a store keeping sessions in their cookies
+func NewCookieSessionStore() CookieSessionStore {
+	return CookieSessionStore{}
+}
+
+// Load decodes the session data from the cookie's token, or returns nil if the session has expired
+func (CookieSessionStore) Load(token string) (map[string]any, error) {
+	var sess cookieSession
+	if err := json.Unmarshal([]byte(token), &sess); err != nil {
+		return nil, err
+	}
+	if time.Now().Unix() >= sess.Expires {
+		return nil, nil
+	}
+	return sess.Data, nil
+}
+
+// Save encodes the session data, and its expiry after maxAge, as the cookie's token.
+// (Session.Save checks that the signed cookie fits.)
+func (CookieSessionStore) Save(_ string, data map[string]any, maxAge time.Duration) (string, error) {
+	token, err := json.Marshal(cookieSession{Expires: time.Now().Add(maxAge).Unix(), Data: data})
+	if err != nil {
+		return "", err
+	}
+	return string(token), nil
+}
+
+// Delete does nothing, as the session goes with its cookie
+func (CookieSessionStore) Delete(string) error {
+	return nil
+}
@@ -0,0 +1,137 @@
+package rweb_test
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rohanthewiz/assert"
+	"github.com/rohanthewiz/rweb"
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+// sessionServer returns a server with routes to log in, show the session and log out
+func sessionServer(opts rweb.ServerOptions) *rweb.Server {
+	s := rweb.NewServer(opts)
+
+	s.Post("/login/:user", func(ctx rweb.Context) error {
+		sess := ctx.Session()
+		sess.Set("user", ctx.Request().Param("user"))
+		sess.Set("visits", 1)
+		if err := sess.Save(); err != nil {
+			return ctx.WriteError(err, consts.StatusInternalServerError)
+		}
+		return ctx.WriteString("welcome")
+	})
+	s.Get("/me", func(ctx rweb.Context) error {
+		sess := ctx.Session()
+		return ctx.WriteString(fmt.Sprintf("new:%t user:%v visits:%v", sess.IsNew(), sess.Get("user"), sess.Get("visits")))
+	})
+	s.Post("/logout", func(ctx rweb.Context) error {
+		return ctx.Session().Destroy()
+	})
+	return s
+}
+
+// sessionCookie returns the session cookie a response sets, as a request header
+func sessionCookie(t *testing.T, response rweb.Response) []rweb.Header {
+	for _, cookie := range response.Cookies() {
+		if cookie.Name == "session" {
+			return []rweb.Header{{Key: "Cookie", Value: "session=" + cookie.Value}}
+		}
+	}
+	t.Fatal("no session cookie set")
+	return nil
+}
+
+func TestSessionMemoryStore(t *testing.T) {
+	s := sessionServer(rweb.ServerOptions{Cookie: rweb.CookieConfig{Secret: []byte("0123456789abcdef0123456789abcdef")}})
+
+	// No cookie, no session
+	response := s.Request(consts.MethodGet, "/me", nil, nil)
+	assert.Equal(t, string(response.Body()), "new:true user:<nil> visits:<nil>")
+
+	response = s.Request(consts.MethodPost, "/login/ann", nil, nil)
+	assert.Equal(t, string(response.Body()), "welcome")
+	cookie := response.Cookies()[0]
+	assert.True(t, cookie.HttpOnly)
+	assert.Equal(t, cookie.MaxAge, int((24 * time.Hour).Seconds()))
+	annSession := sessionCookie(t, response)
+
+	// The data stays on the server - values keep their types
+	response = s.Request(consts.MethodGet, "/me", annSession, nil)
+	assert.Equal(t, string(response.Body()), "new:false user:ann visits:1")
+
+	// Session IDs are random
+	bobSession := sessionCookie(t, s.Request(consts.MethodPost, "/login/bob", nil, nil))
+	assert.NotEqual(t, bobSession[0].Value, annSession[0].Value)
+
+	// A tampered cookie gets a new session
+	tampered := []rweb.Header{{Key: "Cookie", Value: strings.Replace(annSession[0].Value, "=", "=x", 1)}}
+	response = s.Request(consts.MethodGet, "/me", tampered, nil)
+	assert.Equal(t, string(response.Body()), "new:true user:<nil> visits:<nil>")
+
+	// Logging out ends the session, on the server as well as the client
+	response = s.Request(consts.MethodPost, "/logout", annSession, nil)
+	assert.Equal(t, response.Cookies()[0].MaxAge, -1)
+	response = s.Request(consts.MethodGet, "/me", annSession, nil)
+	assert.Equal(t, string(response.Body()), "new:true user:<nil> visits:<nil>")
+	response = s.Request(consts.MethodGet, "/me", bobSession, nil)
+	assert.Equal(t, string(response.Body()), "new:false user:bob visits:1")
+}
+
+func TestSessionCookieStore(t *testing.T) {
+	s := sessionServer(rweb.ServerOptions{
+		Cookie:  rweb.CookieConfig{Secret: []byte("0123456789abcdef0123456789abcdef")},
+		Session: rweb.SessionCfg{Store: rweb.NewCookieSessionStore(), CookieName: "session", MaxAge: time.Hour},
+	})
+
+	response := s.Request(consts.MethodPost, "/login/ann", nil, nil)
+	assert.Equal(t, response.Cookies()[0].MaxAge, 3600)
+	annSession := sessionCookie(t, response)
+
+	// The data comes back from the cookie - numbers as JSON numbers
+	response = s.Request(consts.MethodGet, "/me", annSession, nil)
+	assert.Equal(t, string(response.Body()), "new:false user:ann visits:1")
+
+	// Sessions too large for a cookie can't be saved - the limit is on the signed cookie value
+	s.Get("/blob/:size", func(ctx rweb.Context) error {
+		size, _ := strconv.Atoi(ctx.Request().Param("size"))
+		ctx.Session().Set("blob", strings.Repeat("x", size))
+		return ctx.Session().Save()
+	})
+	response = s.Request(consts.MethodGet, "/blob/2700", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusOK)
+	assert.True(t, len(response.Cookies()[0].Value) <= 3800)
+	response = s.Request(consts.MethodGet, "/blob/3000", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusInternalServerError)
+	assert.Equal(t, len(response.Cookies()), 0)
+}
+
+func TestSessionCookieStoreExpiry(t *testing.T) {
+	store := rweb.NewCookieSessionStore()
+
+	token, err := store.Save("", map[string]any{"user": "ann"}, time.Hour)
+	assert.Nil(t, err)
+	data, err := store.Load(token)
+	assert.Nil(t, err)
+	assert.Equal(t, data["user"], "ann")
+
+	// Past its MaxAge, the session is gone, even if the client kept the cookie
+	token, err = store.Save("", map[string]any{"user": "ann"}, -time.Second)
+	assert.Nil(t, err)
+	data, err = store.Load(token)
+	assert.Nil(t, err)
+	assert.Equal(t, len(data), 0)
+}
+
+func TestSessionNeedsSecret(t *testing.T) {
+	s := sessionServer(rweb.ServerOptions{})
+
+	response := s.Request(consts.MethodPost, "/login/ann", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusInternalServerError)
+	assert.Equal(t, string(response.Body()), rweb.ErrCookieSecretNotSet.Error())
+	assert.Equal(t, len(response.Cookies()), 0)
+}
@@ -0,0 +1,100 @@
+package rweb
+
+// SSEBroadcaster fans the events from a single source channel out to every connected SSE client.
+// Each client gets its own buffered channel, registered when it connects (via Server.SSEBroadcastHandler)
+// and removed when it disconnects. Delivery never blocks on a slow client: events that don't fit
+// in its buffer are dropped, and a client that keeps falling behind is evicted (per the SSEHubOptions).
+// When the source is closed, the broadcaster ends all client streams.
+// Example:
+//
+//	events := make(chan any, 16)
+//	b := rweb.NewSSEBroadcaster(events)
+//	s.Get("/events", s.SSEBroadcastHandler(b, "update"))
+//	events <- rweb.SSEvent{Type: "price", Data: "42.50"} // to all clients
+type SSEBroadcaster struct {
+	hub  *SSEHub
+	done chan struct{} // closed once the source is closed and drained
+}
+
+// NewSSEBroadcaster creates a broadcaster for the events on source, and starts delivering them.
+// Values are sent to clients as they are, as with SSEHandler: SSEvents carry their own type,
+// other values go out under the handler's event type.
+// The optional SSEHubOptions configure the per-client buffer (ChannelSize), how many consecutive
+// drops evict a client (MaxDropped), heartbeats and an OnDisconnect callback, as for an SSEHub.
+func NewSSEBroadcaster(source <-chan any, options ...SSEHubOptions) *SSEBroadcaster {
+	b := &SSEBroadcaster{
+		hub:  NewSSEHub(options...),
+		done: make(chan struct{}),
+	}
+	go b.run(source)
+	return b
+}
+
+// run delivers each event from the source to all clients, then ends their streams once the source is closed
+func (b *SSEBroadcaster) run(source <-chan any) {
+	for event := range source {
+		b.hub.broadcastToClients(event)
+	}
+
+	b.hub.Close() // stop any heartbeat
+
+	b.hub.mu.Lock()
+	defer b.hub.mu.Unlock()
+	close(b.done)
+	for client := range b.hub.clients {
+		b.hub.unregisterLocked(client) // closing the client's channel ends its stream
+	}
+}
+
+// register adds a client, unless the source is already closed
+func (b *SSEBroadcaster) register(client chan any) bool {
+	b.hub.mu.Lock()
+	defer b.hub.mu.Unlock()
+	select {
+	case <-b.done:
+		return false
+	default:
+		b.hub.clients[client] = &hubClient{}
+		return true
+	}
+}
+
+// Broadcast sends an event to all connected clients, in addition to those from the source.
+// Like events from the source, it is dropped for clients whose buffer is full.
+func (b *SSEBroadcaster) Broadcast(event any) {
+	b.hub.broadcastToClients(event)
+}
+
+// ClientCount returns the number of connected clients.
+func (b *SSEBroadcaster) ClientCount() int {
+	return b.hub.ClientCount()
+}
+
+// Done returns a channel that is closed once the source is closed and all client streams ended.
+func (b *SSEBroadcaster) Done() <-chan struct{} {
+	return b.done
+}
+
+// SSEBroadcastHandler returns a handler that streams the broadcaster's events to each client as Server-Sent Events.
+// Clients are registered with the broadcaster on connect, and removed when they disconnect.
+// The optional eventType is the event type for values that aren't SSEvents (defaults to "message").
+// Usage: s.Get("/events", s.SSEBroadcastHandler(b))
+func (s *Server) SSEBroadcastHandler(b *SSEBroadcaster, eventType ...string) Handler {
+	name := "message" // default event name
+	if len(eventType) > 0 && eventType[0] != "" {
+		name = eventType[0]
+	}
+
+	return func(ctx Context) error {
+		clientChan := make(chan any, b.hub.opts.ChannelSize)
+		if !b.register(clientChan) {
+			close(clientChan) // nothing more to come - the stream ends straight away
+		} else if base := baseContext(ctx); base != nil {
+			// Remove the client once its stream ends
+			base.sseCleanup = func() {
+				b.hub.Unregister(clientChan)
+			}
+		}
+		return s.SetupSSE(ctx, clientChan, name)
+	}
+}
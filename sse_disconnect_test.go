@@ -0,0 +1,50 @@
+package rweb
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rohanthewiz/assert"
+)
+
+// failingWriter is a connection to a client that has gone away
+type failingWriter struct{ writes int }
+
+func (w *failingWriter) Write([]byte) (int, error) {
+	w.writes++
+	return 0, errors.New("broken pipe")
+}
+
+// TestSSEWriteErrorEndsStream verifies that a failed write ends the stream, releasing it,
+// rather than the stream carrying on with the source channel.
+func TestSSEWriteErrorEndsStream(t *testing.T) {
+	s := NewServer()
+	ctx := s.newContext()
+
+	events := make(chan any, 4) // never closed
+	cleanedUp := false
+	assert.Nil(t, ctx.SetSSE(events, "update"))
+	ctx.sseCleanup = func() { cleanedUp = true }
+
+	// Events larger than the write buffer fail as they're written, others as they're flushed
+	events <- strings.Repeat("x", 10000)
+	events <- "small"
+
+	for range 2 { // a stream for each event
+		w := &failingWriter{}
+		done := make(chan error, 1)
+		go func() { done <- s.sendSSE(ctx, w) }()
+
+		select {
+		case err := <-done:
+			assert.NotNil(t, err)
+		case <-time.After(2 * time.Second):
+			t.Fatal("sendSSE carried on after a write error")
+		}
+		assert.Equal(t, w.writes, 1) // no more writes once one has failed
+		assert.True(t, cleanedUp)
+		cleanedUp = false
+	}
+}
@@ -0,0 +1,64 @@
+package rweb
+
+import (
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// writeSSEEvent writes an event to the SSE stream, applying the configured size limit to its data.
+// Only the first of any split events carries the ID.
+func (s *Server) writeSSEEvent(w io.Writer, id, eventName, data string) (err error) {
+	maxSize := s.options.SSECfg.MaxEventSize
+	if maxSize > 0 && len(data) > maxSize {
+		switch s.options.SSECfg.OversizePolicy {
+		case SSEOversizeDrop:
+			fmt.Printf("RWEB dropping %q SSE event of %d bytes - over the max event size of %d bytes\n",
+				eventName, len(data), maxSize)
+			return nil
+
+		case SSEOversizeSplit:
+			for len(data) > 0 {
+				cut := sseCutPoint(data, maxSize)
+				if err = writeSSEFields(w, id, eventName, data[:cut]); err != nil {
+					return err
+				}
+				data, id = data[cut:], ""
+			}
+			return nil
+
+		default: // SSEOversizeTruncate
+			data = data[:sseCutPoint(data, maxSize)]
+		}
+	}
+
+	return writeSSEFields(w, id, eventName, data)
+}
+
+// writeSSEFields writes a single SSE event
+func writeSSEFields(w io.Writer, id, eventName, data string) (err error) {
+	if id != "" {
+		if _, err = fmt.Fprintf(w, "id: %s\n", id); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", eventName, data)
+	return err
+}
+
+// sseCutPoint returns where to cut data so the first part is at most maxSize bytes,
+// without splitting a UTF-8 encoded character.
+func sseCutPoint(data string, maxSize int) int {
+	if len(data) <= maxSize {
+		return len(data)
+	}
+	cut := maxSize
+	for cut > 0 && !utf8.RuneStart(data[cut]) {
+		cut--
+	}
+	if cut == 0 { // maxSize is smaller than the character - take it whole rather than make no progress
+		_, size := utf8.DecodeRuneInString(data)
+		return size
+	}
+	return cut
+}
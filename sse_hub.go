@@ -1,9 +1,9 @@
 package rweb
 
 import (
-	"encoding/json"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -36,7 +36,9 @@ type SSEHubOptions struct {
 // hubClient tracks per-client state within the hub.
 // Currently used for drop-counting; extensible for future per-client metadata.
 type hubClient struct {
-	dropped int // consecutive broadcast sends that fell into the default (channel full) branch
+	// consecutive broadcast sends that fell into the default (channel full) branch
+	// (atomic, as broadcasts may run concurrently under the read lock)
+	dropped atomic.Int32
 }
 
 // SSEHub manages multiple SSE client connections with fan-out broadcast capability.
@@ -52,7 +54,8 @@ type SSEHub struct {
 	mu      sync.RWMutex
 	clients map[chan any]*hubClient
 	opts    SSEHubOptions
-	done    chan struct{} // signals heartbeat goroutine to stop
+	done    chan struct{}  // signals heartbeat goroutine to stop
+	json    JSONEncoderCfg // the JSONEncoder config of the server the hub is served by (see Handler)
 }
 
 // NewSSEHub creates a new SSEHub ready to accept client registrations.
@@ -170,11 +173,11 @@ func (h *SSEHub) broadcastToClients(value any) {
 		select {
 		case client <- value:
 			// Successful send — reset the drop counter
-			hc.dropped = 0
+			hc.dropped.Store(0)
 		default:
-			hc.dropped++
+			dropped := hc.dropped.Add(1)
 			// Check if this client has exceeded the eviction threshold
-			if h.opts.MaxDropped > 0 && hc.dropped >= h.opts.MaxDropped {
+			if h.opts.MaxDropped > 0 && int(dropped) >= h.opts.MaxDropped {
 				stale = append(stale, client)
 			}
 		}
@@ -196,6 +199,7 @@ func (h *SSEHub) broadcastToClients(value any) {
 // and sends it as a standard "message" SSE event to all clients.
 // This design lets JS EventSource clients use a single `onmessage` handler
 // and JSON.parse() the data to extract the event type and payload.
+// The JSON is encoded per the JSONEncoder config of the server the hub is served by.
 // Non-blocking: slow or full client channels are skipped (and may be evicted).
 func (h *SSEHub) Broadcast(event SSEvent) {
 	// Build a JSON payload wrapping type + data for easy client-side parsing
@@ -204,7 +208,10 @@ func (h *SSEHub) Broadcast(event SSEvent) {
 		"data": event.Data,
 	}
 
-	bytPayload, err := json.Marshal(payload)
+	h.mu.RLock()
+	cfg := h.json
+	h.mu.RUnlock()
+	bytPayload, err := cfg.marshal(payload)
 	if err != nil {
 		fmt.Printf("SSEHub: failed to marshal broadcast event: %v\n", err)
 		return
@@ -245,12 +252,17 @@ func (h *SSEHub) BroadcastAny(eventType string, data any) {
 // The optional eventName parameter sets the default SSE event type (defaults to "message").
 // Since SSEHub.Broadcast sends SSEvent structs with their own Type field, the eventName
 // here is mainly used as a fallback for non-SSEvent data on the channel.
+// Broadcasts are JSON encoded per the server's JSONEncoder config from then on.
 func (h *SSEHub) Handler(server *Server, eventName ...string) Handler {
 	evtName := "message"
 	if len(eventName) > 0 && eventName[0] != "" {
 		evtName = eventName[0]
 	}
 
+	h.mu.Lock()
+	h.json = server.options.JSONEncoder
+	h.mu.Unlock()
+
 	return func(c Context) error {
 		// Create a per-client buffered channel; size from options (default 8)
 		clientChan := make(chan any, h.opts.ChannelSize)
@@ -375,3 +375,28 @@ func TestSSEHubOnDisconnectCalledOnUnregister(t *testing.T) {
 	hub.Unregister(ch)
 	assert.Equal(t, int32(1), called.Load())
 }
+
+// TestSSEBroadcasterEvictsSlowClients verifies that the broadcaster never blocks on a client
+// whose buffer is full, and evicts it after MaxDropped consecutive drops.
+func TestSSEBroadcasterEvictsSlowClients(t *testing.T) {
+	source := make(chan any)
+	b := NewSSEBroadcaster(source, SSEHubOptions{ChannelSize: 1, MaxDropped: 2})
+
+	slow := make(chan any, 1)
+	healthy := make(chan any, 1)
+	assert.True(t, b.register(slow))
+	assert.True(t, b.register(healthy))
+
+	for i := range 3 {
+		source <- i // unbuffered, so each send waits for the previous event's delivery
+		assert.Equal(t, <-healthy, any(i))
+	}
+	close(source)
+	<-b.Done()
+
+	// The slow client got the first event, then was evicted, closing its channel
+	assert.Equal(t, <-slow, any(0))
+	_, open := <-slow
+	assert.False(t, open)
+	assert.False(t, b.register(make(chan any, 1)))
+}
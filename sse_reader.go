@@ -0,0 +1,74 @@
+package rweb
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// SSEFromReader streams r to the client as Server-Sent Events, one "message" event per line.
+// It suits line-oriented producers such as log files being tailed or a subprocess's stdout.
+// Lines are sent (and flushed) as soon as they are read, and a final line without a newline is sent at EOF.
+// Blank lines are skipped, as SSE can't carry an empty event.
+// Lines are read only as fast as the client consumes them, so a slow client slows the reader
+// rather than building up a backlog in memory.
+// The stream ends at EOF (or a read error), or when the client disconnects.
+// If r is also an io.Closer, it is closed when the stream ends,
+// which unblocks a pending read, e.g. on a pipe, after a client disconnect.
+// Example:
+//
+//	cmd := exec.Command("tail", "-f", "/var/log/app.log")
+//	stdout, _ := cmd.StdoutPipe()
+//	_ = cmd.Start()
+//	return ctx.SSEFromReader(stdout)
+func (ctx *context) SSEFromReader(r io.Reader) error {
+	if r == nil {
+		return errors.New("SSEFromReader: nil reader")
+	}
+
+	// Unbuffered, so the reader only ever gets one line ahead of the client
+	lines := make(chan any)
+	done := make(chan struct{})
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			close(done)
+			if closer, ok := r.(io.Closer); ok {
+				_ = closer.Close()
+			}
+		})
+	}
+	ctx.sseCleanup = stop
+
+	go func() {
+		defer close(lines)
+
+		br := bufio.NewReader(r)
+		for {
+			line, err := br.ReadString('\n')
+			line = strings.TrimRight(line, "\r\n")
+			if line != "" {
+				select {
+				case lines <- SSEvent{Type: "message", Data: line}: // not a bare string, which could be taken as the "close" signal
+				case <-done: // client is gone
+					return
+				}
+			}
+			if err != nil {
+				if !errors.Is(err, io.EOF) {
+					select {
+					case <-done: // a read failing as the stream is stopped is expected
+					default:
+						fmt.Printf("SSEFromReader: error reading source: %v\n", err)
+					}
+				}
+				return
+			}
+		}
+	}()
+
+	return ctx.SetSSE(lines, "message")
+}
@@ -3,6 +3,7 @@ package rweb_test
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"strings"
@@ -171,3 +172,416 @@ func TestSSEClientDisconnect(t *testing.T) {
 	}
 }
 
+// TestSSEClientDisconnectWithContext verifies that a handler using ctx.Context() still has
+// the stream ended on the client going, and its context canceled.
+func TestSSEClientDisconnectWithContext(t *testing.T) {
+	readyChan := make(chan struct{}, 1)
+	canceled := make(chan struct{})
+
+	s := rweb.NewServer(rweb.ServerOptions{
+		ReadyChan: readyChan,
+		Address:   "localhost:",
+	})
+
+	s.Get("/events", func(ctx rweb.Context) error {
+		goCtx := ctx.Context() // has the connection watched already
+		go func() {
+			<-goCtx.Done()
+			close(canceled)
+		}()
+		return ctx.SetSSE(make(chan any), "test-events")
+	})
+
+	go func() {
+		<-readyChan
+		defer syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+		addr := fmt.Sprintf("127.0.0.1:%s", s.GetListenPort())
+		conn, err := net.Dial("tcp", addr)
+		assert.Nil(t, err)
+		defer conn.Close()
+
+		_, err = fmt.Fprintf(conn, "GET /events HTTP/1.1\r\nHost: %s\r\nAccept: text/event-stream\r\n\r\n", addr)
+		assert.Nil(t, err)
+		reader := bufio.NewReader(conn)
+		statusLine, err := reader.ReadString('\n')
+		assert.Nil(t, err)
+		assert.Contains(t, statusLine, "200")
+
+		// The client is done sending - the server ends the stream, closing the connection
+		_ = conn.(*net.TCPConn).CloseWrite()
+		_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		_, err = io.ReadAll(reader)
+		assert.Nil(t, err)
+
+		select {
+		case <-canceled:
+		case <-time.After(time.Second):
+			t.Error("handler's context not canceled")
+		}
+	}()
+
+	_ = s.Run()
+}
+
+// TestSSEEventIDs verifies that event IDs and the retry directive are sent,
+// and that a reconnecting client's Last-Event-ID is available to the handler.
+func TestSSEEventIDs(t *testing.T) {
+	readyChan := make(chan struct{}, 1)
+
+	s := rweb.NewServerWithOptions(
+		rweb.WithAddress("localhost:"),
+		rweb.WithReadyChan(readyChan),
+		rweb.WithSSEConfig(rweb.SSECfg{RetryMillis: 3000}),
+	)
+
+	s.Get("/events", func(ctx rweb.Context) error {
+		// Replay the events after the last one the client saw
+		lastID := 0
+		if id := ctx.LastEventID(); id != "" {
+			_, _ = fmt.Sscanf(id, "%d", &lastID)
+		}
+
+		eventsChan := make(chan any, 4)
+		for id := lastID + 1; id <= 3; id++ {
+			eventsChan <- rweb.SSEvent{Type: "tick", Data: fmt.Sprintf("tick %d", id), ID: fmt.Sprintf("%d", id)}
+		}
+		close(eventsChan)
+		return ctx.SetSSE(eventsChan, "tick")
+	})
+
+	go func() {
+		defer syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+		<-readyChan // wait for server
+
+		addr := fmt.Sprintf("127.0.0.1:%s", s.GetListenPort())
+		conn, err := net.Dial("tcp", addr)
+		assert.Nil(t, err)
+		defer conn.Close()
+
+		// A reconnecting client that has seen event 1
+		_, err = fmt.Fprintf(conn, "GET /events HTTP/1.1\r\nHost: %s\r\nAccept: text/event-stream\r\nLast-Event-ID: 1\r\n\r\n", addr)
+		assert.Nil(t, err)
+
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		reader := bufio.NewReader(conn)
+
+		// Skip the response headers
+		for {
+			line, err := reader.ReadString('\n')
+			assert.Nil(t, err)
+			if line == "\r\n" {
+				break
+			}
+		}
+
+		var lines []string
+		for len(lines) < 9 {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				break
+			}
+			lines = append(lines, strings.TrimSuffix(line, "\n"))
+		}
+
+		assert.Equal(t, strings.Join(lines, "|"),
+			"retry: 3000||id: 2|event: tick|data: tick 2||id: 3|event: tick|data: tick 3")
+	}()
+
+	_ = s.Run()
+}
+
+func TestSSEKeepAlive(t *testing.T) {
+	readyChan := make(chan struct{}, 1)
+
+	s := rweb.NewServerWithOptions(
+		rweb.WithAddress("localhost:"),
+		rweb.WithReadyChan(readyChan),
+		rweb.WithSSEConfig(rweb.SSECfg{KeepAliveInterval: 50 * time.Millisecond}),
+	)
+
+	s.Get("/events", func(ctx rweb.Context) error {
+		eventsChan := make(chan any, 1)
+		go func() {
+			time.Sleep(180 * time.Millisecond) // idle long enough for keepalives
+			eventsChan <- "hello"
+			time.Sleep(180 * time.Millisecond)
+			close(eventsChan)
+		}()
+		return ctx.SetSSE(eventsChan, "greeting")
+	})
+
+	go func() {
+		defer syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+		<-readyChan // wait for server
+
+		addr := fmt.Sprintf("127.0.0.1:%s", s.GetListenPort())
+		conn, err := net.Dial("tcp", addr)
+		assert.Nil(t, err)
+		defer conn.Close()
+
+		_, err = fmt.Fprintf(conn, "GET /events HTTP/1.1\r\nHost: %s\r\nAccept: text/event-stream\r\n\r\n", addr)
+		assert.Nil(t, err)
+
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		reader := bufio.NewReader(conn)
+
+		// Skip the response headers
+		for {
+			line, err := reader.ReadString('\n')
+			assert.Nil(t, err)
+			if line == "\r\n" {
+				break
+			}
+		}
+
+		// Keepalives are sent while idle, before and after the real event
+		var keepAlivesBefore, keepAlivesAfter int
+		sawEvent := false
+		for keepAlivesAfter == 0 {
+			line, err := reader.ReadString('\n')
+			assert.Nil(t, err)
+			if err != nil {
+				return
+			}
+			switch line = strings.TrimSuffix(line, "\n"); {
+			case line == ":keepalive" && sawEvent:
+				keepAlivesAfter++
+			case line == ":keepalive":
+				keepAlivesBefore++
+			case line == "data: hello":
+				sawEvent = true
+			}
+		}
+		assert.True(t, keepAlivesBefore >= 2)
+	}()
+
+	err := s.Run()
+	assert.Nil(t, err)
+}
+
+func TestSSEFromReader(t *testing.T) {
+	readyChan := make(chan struct{}, 1)
+
+	s := rweb.NewServerWithOptions(
+		rweb.WithAddress("localhost:"),
+		rweb.WithReadyChan(readyChan),
+	)
+
+	pr, pw := io.Pipe()
+	s.Get("/tail", func(ctx rweb.Context) error {
+		return ctx.SSEFromReader(pr)
+	})
+
+	go func() {
+		defer syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+		<-readyChan // wait for server
+
+		addr := fmt.Sprintf("127.0.0.1:%s", s.GetListenPort())
+		conn, err := net.Dial("tcp", addr)
+		assert.Nil(t, err)
+		defer conn.Close()
+
+		_, err = fmt.Fprintf(conn, "GET /tail HTTP/1.1\r\nHost: %s\r\nAccept: text/event-stream\r\n\r\n", addr)
+		assert.Nil(t, err)
+
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		reader := bufio.NewReader(conn)
+
+		// Skip the response headers
+		for {
+			line, err := reader.ReadString('\n')
+			assert.Nil(t, err)
+			if line == "\r\n" {
+				break
+			}
+		}
+
+		readEvent := func() string {
+			var lines []string
+			for {
+				line, err := reader.ReadString('\n')
+				assert.Nil(t, err)
+				if line == "\n" || err != nil {
+					return strings.Join(lines, "|")
+				}
+				lines = append(lines, strings.TrimSuffix(line, "\n"))
+			}
+		}
+
+		// Each line is sent as soon as it is complete, partial writes are joined up
+		_, _ = io.WriteString(pw, "starting up\r\n")
+		assert.Equal(t, readEvent(), "event: message|data: starting up")
+		_, _ = io.WriteString(pw, "listening on ")
+		_, _ = io.WriteString(pw, ":8080\n\nclose\n")
+		assert.Equal(t, readEvent(), "event: message|data: listening on :8080")
+		assert.Equal(t, readEvent(), "event: message|data: close") // just another line
+
+		// Once the client disconnects, the source is closed
+		_ = conn.Close()
+		var writeErr error
+		for deadline := time.Now().Add(3 * time.Second); writeErr == nil && time.Now().Before(deadline); {
+			_, writeErr = io.WriteString(pw, "anyone there?\n")
+			time.Sleep(10 * time.Millisecond)
+		}
+		assert.Equal(t, writeErr, io.ErrClosedPipe)
+	}()
+
+	_ = s.Run()
+}
+
+func TestSSEMaxEventSize(t *testing.T) {
+	tests := []struct {
+		policy   rweb.SSEOversizePolicy
+		expected string
+	}{
+		{rweb.SSEOversizeTruncate, "data: small|data: 0123456789|data: héllo wö|data: last"},
+		{rweb.SSEOversizeDrop, "data: small|data: last"},
+		{rweb.SSEOversizeSplit, "data: small|data: 0123456789|data: abcdef|" +
+			"data: héllo wö|data: rld|data: last"},
+	}
+
+	for _, tt := range tests {
+		readyChan := make(chan struct{}, 1)
+		s := rweb.NewServerWithOptions(
+			rweb.WithAddress("localhost:"),
+			rweb.WithReadyChan(readyChan),
+			rweb.WithSSEConfig(rweb.SSECfg{MaxEventSize: 10, OversizePolicy: tt.policy}),
+		)
+
+		s.Get("/events", func(ctx rweb.Context) error {
+			eventsChan := make(chan any, 4)
+			eventsChan <- "small"
+			eventsChan <- "0123456789abcdef"
+			eventsChan <- "héllo wörld" // multi-byte characters aren't cut in half
+			eventsChan <- "last"
+			close(eventsChan)
+			return ctx.SetSSE(eventsChan, "update")
+		})
+
+		go func() {
+			defer syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+			<-readyChan // wait for server
+
+			addr := fmt.Sprintf("127.0.0.1:%s", s.GetListenPort())
+			conn, err := net.Dial("tcp", addr)
+			assert.Nil(t, err)
+			defer conn.Close()
+
+			_, err = fmt.Fprintf(conn, "GET /events HTTP/1.1\r\nHost: %s\r\nAccept: text/event-stream\r\n\r\n", addr)
+			assert.Nil(t, err)
+
+			_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			reader := bufio.NewReader(conn)
+
+			// Skip the response headers
+			for {
+				line, err := reader.ReadString('\n')
+				assert.Nil(t, err)
+				if line == "\r\n" {
+					break
+				}
+			}
+
+			var data []string
+			for !strings.HasSuffix(strings.Join(data, "|"), "data: last") {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					break
+				}
+				if strings.HasPrefix(line, "data: ") {
+					data = append(data, strings.TrimSuffix(line, "\n"))
+				}
+			}
+			assert.Equal(t, strings.Join(data, "|"), tt.expected)
+		}()
+
+		_ = s.Run()
+	}
+}
+
+func TestSSEBroadcaster(t *testing.T) {
+	readyChan := make(chan struct{}, 1)
+	source := make(chan any, 8)
+	b := rweb.NewSSEBroadcaster(source)
+
+	s := rweb.NewServer(rweb.ServerOptions{ReadyChan: readyChan, Address: "localhost:"})
+	s.Get("/events", s.SSEBroadcastHandler(b, "tick"))
+
+	waitForClients := func(n int) {
+		deadline := time.Now().Add(5 * time.Second)
+		for b.ClientCount() != n && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		assert.Equal(t, b.ClientCount(), n)
+	}
+
+	go func() {
+		defer syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+		<-readyChan // wait for server
+		url := fmt.Sprintf("http://127.0.0.1:%s/events", s.GetListenPort())
+
+		var scanners []*bufio.Scanner
+		var bodies []io.Closer
+		for range 2 {
+			resp, err := http.Get(url)
+			assert.Nil(t, err)
+			scanners = append(scanners, bufio.NewScanner(resp.Body))
+			bodies = append(bodies, resp.Body)
+		}
+		waitForClients(2)
+
+		// nextEvent returns the next event's "type|data"
+		nextEvent := func(sc *bufio.Scanner) string {
+			var typ string
+			for sc.Scan() {
+				line := sc.Text()
+				if v, ok := strings.CutPrefix(line, "event: "); ok {
+					typ = v
+				} else if v, ok := strings.CutPrefix(line, "data: "); ok {
+					return typ + "|" + v
+				}
+			}
+			return "EOF"
+		}
+
+		// Every client gets every event
+		source <- "one"
+		source <- rweb.SSEvent{Type: "price", Data: "42"}
+		for _, sc := range scanners {
+			assert.Equal(t, nextEvent(sc), "tick|one")
+			assert.Equal(t, nextEvent(sc), "price|42")
+		}
+		b.Broadcast("direct")
+		for _, sc := range scanners {
+			assert.Equal(t, nextEvent(sc), "tick|direct")
+		}
+
+		// Disconnected clients are removed
+		_ = bodies[0].Close()
+		waitForClients(1)
+
+		// Closing the source ends the remaining streams
+		source <- "last"
+		close(source)
+		assert.Equal(t, nextEvent(scanners[1]), "tick|last")
+		assert.Equal(t, nextEvent(scanners[1]), "EOF")
+		_ = bodies[1].Close()
+		<-b.Done()
+		assert.Equal(t, b.ClientCount(), 0)
+
+		// Late clients get an empty stream
+		resp, err := http.Get(url)
+		assert.Nil(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		assert.Equal(t, string(body), "")
+	}()
+
+	_ = s.Run()
+}
@@ -0,0 +1,74 @@
+package rweb
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+// StaticOptions configures how StaticFiles handles requests that map to a directory,
+// and who may have the files. By default requests for directories fail, as the directory can't be read as a file.
+type StaticOptions struct {
+	// DirListing serves a directory's index.html, or if it has none,
+	// a generated HTML page listing its contents with links to them (hidden "dot" files are left out).
+	DirListing bool
+	// NoDirListing serves a directory's index.html, but refuses to list a directory without one (403 Forbidden).
+	// It takes precedence over DirListing.
+	NoDirListing bool
+	// Authorize, when set, is asked before each file (or directory) is served whether the request may have it.
+	// filePath is the path of the file under the working directory, e.g. "private/docs/42/report.pdf".
+	// Requests it refuses get a 403 Forbidden.
+	Authorize func(ctx Context, filePath string) bool
+}
+
+// serveStaticDir responds to a static files request for the directory dir, per opts
+func serveStaticDir(ctx Context, dir string, opts StaticOptions) error {
+	body, err := readStaticFile(ctx, filepath.Join(dir, "index.html"), os.ReadFile)
+	if err == nil {
+		return File(ctx, "index.html", body)
+	}
+
+	if opts.NoDirListing || !opts.DirListing {
+		ctx.SetStatus(consts.StatusForbidden)
+		return ctx.WriteText(consts.StatusTextFromCode[consts.StatusForbidden])
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	// Links are absolute, as the request path may have lost its trailing slash
+	reqPath := strings.TrimSuffix(ctx.Request().Path(), "/")
+	title := html.EscapeString(reqPath + "/")
+
+	var sb strings.Builder
+	sb.WriteString("<!DOCTYPE html>\n<html>\n<head><meta charset=\"utf-8\"><title>Index of ")
+	sb.WriteString(title)
+	sb.WriteString("</title></head>\n<body>\n<h1>Index of ")
+	sb.WriteString(title)
+	sb.WriteString("</h1>\n<ul>\n")
+	if ctx.Request().Param("path") != "" { // below the root of the static route
+		fmt.Fprintf(&sb, "<li><a href=\"%s\">../</a></li>\n", html.EscapeString(path.Dir(reqPath)))
+	}
+	for _, entry := range entries { // sorted by name
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+		if entry.IsDir() {
+			name += "/"
+		}
+		fmt.Fprintf(&sb, "<li><a href=\"%s\">%s</a></li>\n",
+			html.EscapeString(reqPath+"/"+url.PathEscape(entry.Name())), html.EscapeString(name))
+	}
+	sb.WriteString("</ul>\n</body>\n</html>\n")
+
+	return ctx.WriteHTML(sb.String())
+}
@@ -0,0 +1,295 @@
+package rweb_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/rohanthewiz/assert"
+	"github.com/rohanthewiz/rweb"
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+// assetsFS stands in for an embed.FS in tests
+var assetsFS = fstest.MapFS{
+	"assets/css/site.css":  {Data: []byte("body{}")},
+	"assets/js/app.js":     {Data: []byte("console.log(42)")},
+	"assets/img/logo.png":  {Data: []byte("PNG")},
+	"public/robots.txt":    {Data: []byte("User-agent: *")},
+	"public/docs/index.md": {Data: []byte("# Docs")},
+}
+
+func TestStaticFS(t *testing.T) {
+	s := rweb.NewServer()
+
+	// Request path maps directly onto the FS
+	s.StaticFS("/assets", assetsFS, 0)
+
+	response := s.Request(consts.MethodGet, "/assets/css/site.css", nil, nil)
+	assert.Equal(t, http.StatusOK, response.Status())
+	assert.Equal(t, "body{}", string(response.Body()))
+	assert.Equal(t, "text/css; charset=utf-8", response.Header(consts.HeaderContentType))
+
+	response = s.Request(consts.MethodGet, "/assets/js/app.js", nil, nil)
+	assert.Equal(t, http.StatusOK, response.Status())
+	assert.Equal(t, "console.log(42)", string(response.Body()))
+
+	response = s.Request(consts.MethodGet, "/assets/img/logo.png", nil, nil)
+	assert.Equal(t, http.StatusOK, response.Status())
+	assert.Equal(t, consts.MIMEPNG, response.Header(consts.HeaderContentType))
+
+	// Missing files are errors
+	response = s.Request(consts.MethodGet, "/assets/css/missing.css", nil, nil)
+	assert.Equal(t, http.StatusInternalServerError, response.Status())
+}
+
+func TestStaticFSStripTokens(t *testing.T) {
+	s := rweb.NewServer()
+
+	// Strip "static" so /static/public/... maps to public/...
+	s.StaticFS("/static/public", assetsFS, 1)
+
+	response := s.Request(consts.MethodGet, "/static/public/robots.txt", nil, nil)
+	assert.Equal(t, http.StatusOK, response.Status())
+	assert.Equal(t, "User-agent: *", string(response.Body()))
+
+	// Serve a sub directory of the FS, stripping all request tokens
+	sub, err := fs.Sub(assetsFS, "public")
+	assert.Nil(t, err)
+	s.StaticFS("/files", sub, 1)
+
+	response = s.Request(consts.MethodGet, "/files/docs/index.md", nil, nil)
+	assert.Equal(t, http.StatusOK, response.Status())
+	assert.Equal(t, "# Docs", string(response.Body()))
+}
+
+func TestGroupStaticFS(t *testing.T) {
+	s := rweb.NewServer()
+
+	grp := s.Group("/v1")
+	grp.StaticFS("/assets", assetsFS, 1)
+
+	response := s.Request(consts.MethodGet, "/v1/assets/css/site.css", nil, nil)
+	assert.Equal(t, http.StatusOK, response.Status())
+	assert.True(t, strings.HasPrefix(string(response.Body()), "body"))
+}
+
+func TestStaticGzipSidecar(t *testing.T) {
+	gzipped := func(data string) []byte {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		_, _ = zw.Write([]byte(data))
+		_ = zw.Close()
+		return buf.Bytes()
+	}
+	appJS := "console.log('app')"
+
+	s := rweb.NewServer()
+	s.StaticFS("/assets", fstest.MapFS{
+		"assets/js/app.js":    {Data: []byte(appJS)},
+		"assets/js/app.js.gz": {Data: gzipped(appJS)},
+		"assets/css/site.css": {Data: []byte("body{}")},
+	}, 0)
+
+	acceptGzip := []rweb.Header{{Key: consts.HeaderAcceptEncoding, Value: "gzip, deflate, br"}}
+
+	response := s.Request(consts.MethodGet, "/assets/js/app.js", acceptGzip, nil)
+	assert.Equal(t, response.Status(), http.StatusOK)
+	assert.Equal(t, response.Header(consts.HeaderContentEncoding), "gzip")
+	assert.Equal(t, response.Header(consts.HeaderVary), consts.HeaderAcceptEncoding)
+	assert.Equal(t, response.Header(consts.HeaderContentType), "text/javascript; charset=utf-8")
+	zr, err := gzip.NewReader(bytes.NewReader(response.Body()))
+	assert.Nil(t, err)
+	body, err := io.ReadAll(zr)
+	assert.Nil(t, err)
+	assert.Equal(t, string(body), appJS)
+
+	// Clients not accepting gzip get the original
+	for _, headers := range [][]rweb.Header{nil, {{Key: consts.HeaderAcceptEncoding, Value: "gzip;q=0, br"}}} {
+		response = s.Request(consts.MethodGet, "/assets/js/app.js", headers, nil)
+		assert.Equal(t, response.Header(consts.HeaderContentEncoding), "")
+		assert.Equal(t, string(response.Body()), appJS)
+	}
+
+	// No sidecar - the original is served
+	response = s.Request(consts.MethodGet, "/assets/css/site.css", acceptGzip, nil)
+	assert.Equal(t, response.Header(consts.HeaderContentEncoding), "")
+	assert.Equal(t, string(response.Body()), "body{}")
+
+	// Likewise from the OS filesystem
+	dir, err := os.MkdirTemp(".", "static-gz-")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "app.js"), []byte(appJS), 0o644))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "app.js.gz"), gzipped(appJS), 0o644))
+
+	s.StaticFiles("/js", dir, 1)
+	response = s.Request(consts.MethodGet, "/js/app.js", acceptGzip, nil)
+	assert.Equal(t, response.Status(), http.StatusOK)
+	assert.Equal(t, response.Header(consts.HeaderContentEncoding), "gzip")
+	assert.Equal(t, string(response.Body()), string(gzipped(appJS)))
+
+	response = s.Request(consts.MethodGet, "/js/app.js", nil, nil)
+	assert.Equal(t, string(response.Body()), appJS)
+
+	// What the response varies by already (e.g. set by CORS middleware) is kept
+	s.Use(func(ctx rweb.Context) error {
+		ctx.Response().SetHeader(consts.HeaderVary, "Origin")
+		return ctx.Next()
+	})
+	response = s.Request(consts.MethodGet, "/assets/js/app.js", acceptGzip, nil)
+	assert.Equal(t, response.Header(consts.HeaderContentEncoding), "gzip")
+	assert.Equal(t, response.Header(consts.HeaderVary), "Origin, Accept-Encoding")
+}
+
+func TestStaticFilesDirectories(t *testing.T) {
+	dir, err := os.MkdirTemp(".", "static-dir-")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	for _, sub := range []string{"site", "files/reports", "files/.git"} {
+		assert.Nil(t, os.MkdirAll(filepath.Join(dir, sub), 0o755))
+	}
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "site", "index.html"), []byte("<h1>Home</h1>"), 0o644))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "files", "a&b.txt"), []byte("A and B"), 0o644))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "files", ".env"), []byte("SECRET=1"), 0o644))
+
+	s := rweb.NewServer()
+	s.StaticFiles("/listed", dir, 1, rweb.StaticOptions{DirListing: true})
+	s.StaticFiles("/unlisted", dir, 1, rweb.StaticOptions{NoDirListing: true})
+	s.StaticFiles("/plain", dir, 1)
+
+	// A directory's index.html is served in either mode
+	for _, prefix := range []string{"/listed", "/unlisted"} {
+		response := s.Request(consts.MethodGet, prefix+"/site", nil, nil)
+		assert.Equal(t, response.Status(), http.StatusOK)
+		assert.Equal(t, string(response.Body()), "<h1>Home</h1>")
+		assert.Equal(t, response.Header(consts.HeaderContentType), "text/html; charset=utf-8")
+	}
+
+	// Otherwise it's listed...
+	response := s.Request(consts.MethodGet, "/listed/files", nil, nil)
+	assert.Equal(t, response.Status(), http.StatusOK)
+	body := string(response.Body())
+	assert.True(t, strings.Contains(body, "<title>Index of /listed/files/</title>"))
+	assert.True(t, strings.Contains(body, `<a href="/listed">../</a>`))
+	assert.True(t, strings.Contains(body, `<a href="/listed/files/a&amp;b.txt">a&amp;b.txt</a>`))
+	assert.True(t, strings.Contains(body, `<a href="/listed/files/reports">reports/</a>`))
+	assert.False(t, strings.Contains(body, ".env"))
+	assert.False(t, strings.Contains(body, ".git"))
+
+	// ...and the links work
+	response = s.Request(consts.MethodGet, "/listed/files/a&b.txt", nil, nil)
+	assert.Equal(t, string(response.Body()), "A and B")
+
+	// ...or refused
+	response = s.Request(consts.MethodGet, "/unlisted/files", nil, nil)
+	assert.Equal(t, response.Status(), http.StatusForbidden)
+	assert.False(t, strings.Contains(string(response.Body()), "a&b.txt"))
+
+	// Files are unaffected
+	response = s.Request(consts.MethodGet, "/unlisted/files/a&b.txt", nil, nil)
+	assert.Equal(t, string(response.Body()), "A and B")
+
+	// Without options, directories can't be served
+	response = s.Request(consts.MethodGet, "/plain/site", nil, nil)
+	assert.Equal(t, response.Status(), http.StatusInternalServerError)
+}
+
+func TestStaticFilesTraversal(t *testing.T) {
+	dir, err := os.MkdirTemp(".", "static-traversal-")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	assert.Nil(t, os.MkdirAll(filepath.Join(dir, "public", "css"), 0o755))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "public", "css", "site.css"), []byte("body{}"), 0o644))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "public", "a b.txt"), []byte("spaced"), 0o644))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "secret.txt"), []byte("SECRET"), 0o644))
+
+	s := rweb.NewServer()
+	s.StaticFiles("/static", filepath.Join(dir, "public"), 1)
+	s.StaticFS("/assets", assetsFS, 0)
+
+	// Paths leading out of the directory, plainly or encoded, are refused
+	for _, path := range []string{
+		"/static/../secret.txt",
+		"/static/..%2Fsecret.txt",
+		"/static/..%2f..%2fgo.mod",
+		"/static/%2e%2e/secret.txt",
+		"/static/%2E%2E%2Fsecret.txt",
+		"/static/css/..%2F..%2Fsecret.txt",
+		"/static/css%2F..%2F..%2F..%2Fgo.mod",
+		"/static/css/site.css%00.png",
+		"/assets/..%2Fpublic/robots.txt",
+	} {
+		response := s.Request(consts.MethodGet, path, nil, nil)
+		assert.Equal(t, response.Status(), http.StatusForbidden)
+		assert.False(t, strings.Contains(string(response.Body()), "SECRET"))
+	}
+
+	// Paths staying inside are served, decoded
+	response := s.Request(consts.MethodGet, "/static/css/../css/site.css", nil, nil)
+	assert.Equal(t, response.Status(), http.StatusOK)
+	assert.Equal(t, string(response.Body()), "body{}")
+
+	response = s.Request(consts.MethodGet, "/static/css%2Fsite.css", nil, nil)
+	assert.Equal(t, string(response.Body()), "body{}")
+
+	response = s.Request(consts.MethodGet, "/static/a%20b.txt", nil, nil)
+	assert.Equal(t, string(response.Body()), "spaced")
+
+	response = s.Request(consts.MethodGet, "/assets/css/../css/site.css", nil, nil)
+	assert.Equal(t, string(response.Body()), "body{}")
+}
+
+func TestStaticFilesAuth(t *testing.T) {
+	dir, err := os.MkdirTemp(".", "static-auth-")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	for _, user := range []string{"ann", "bob"} {
+		assert.Nil(t, os.MkdirAll(filepath.Join(dir, user), 0o755))
+		assert.Nil(t, os.WriteFile(filepath.Join(dir, user, "notes.txt"), []byte(user+"'s notes"), 0o644))
+	}
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "ann", "index.html"), []byte("<h1>Ann</h1>"), 0o644))
+
+	var asked []string
+	s := rweb.NewServer()
+	s.StaticFilesAuth("/docs", dir, 1, func(ctx rweb.Context, filePath string) bool {
+		asked = append(asked, filePath)
+		user := ctx.Request().Header("X-User")
+		owner := strings.Split(strings.TrimPrefix(filePath, filepath.Base(dir)+"/"), "/")[0]
+		return user != "" && owner == user
+	}, rweb.StaticOptions{NoDirListing: true})
+
+	asAnn := []rweb.Header{{Key: "X-User", Value: "ann"}}
+
+	response := s.Request(consts.MethodGet, "/docs/ann/notes.txt", asAnn, nil)
+	assert.Equal(t, response.Status(), http.StatusOK)
+	assert.Equal(t, string(response.Body()), "ann's notes")
+	assert.Equal(t, response.Header(consts.HeaderContentType), "text/plain; charset=utf-8")
+	assert.Equal(t, asked[0], filepath.Base(dir)+"/ann/notes.txt")
+
+	// Other users' files, or no user
+	response = s.Request(consts.MethodGet, "/docs/bob/notes.txt", asAnn, nil)
+	assert.Equal(t, response.Status(), http.StatusForbidden)
+	assert.False(t, strings.Contains(string(response.Body()), "bob's notes"))
+	response = s.Request(consts.MethodGet, "/docs/ann/notes.txt", nil, nil)
+	assert.Equal(t, response.Status(), http.StatusForbidden)
+
+	// Directories are authorized too
+	response = s.Request(consts.MethodGet, "/docs/bob/", asAnn, nil)
+	assert.Equal(t, response.Status(), http.StatusForbidden)
+	response = s.Request(consts.MethodGet, "/docs/ann/", asAnn, nil)
+	assert.Equal(t, string(response.Body()), "<h1>Ann</h1>")
+
+	// Traversal is refused before authorization is asked
+	asked = nil
+	response = s.Request(consts.MethodGet, "/docs/ann/..%2F..%2Fgo.mod", asAnn, nil)
+	assert.Equal(t, response.Status(), http.StatusForbidden)
+	assert.Equal(t, len(asked), 0)
+}
@@ -0,0 +1,132 @@
+package rweb
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http/httputil"
+
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+// StreamJSON streams a JSON response using chunked transfer encoding.
+// fn is called once the response headers are written, and each value it encodes
+// is sent to the client straight away, so large datasets need not be held in memory.
+// As the status and headers are already sent when fn runs, an error from fn
+// can't change the response - the connection is closed, so the client sees an incomplete body.
+// Example:
+//
+//	return ctx.StreamJSON(func(enc *json.Encoder) error {
+//		for rows.Next() {
+//			// ... scan row
+//			if err := enc.Encode(row); err != nil {
+//				return err
+//			}
+//		}
+//		return rows.Err()
+//	})
+func (ctx *context) StreamJSON(fn func(enc *json.Encoder) error) error {
+	ctx.response.SetHeader(consts.HeaderContentType, consts.MIMEJSON)
+	ctx.streamFn = func(w io.Writer) error {
+		return fn(ctx.jsonEncoder().newEncoder(w))
+	}
+	return nil
+}
+
+// sendStream writes a streamed response body as HTTP/1.1 chunks, or as is when its length is known
+func (s *Server) sendStream(ctx *context, respWriter io.Writer) {
+	if _, ok := respWriter.(syntheticWriter); ok {
+		// Synthetic request (s.Request) - collect the body so the caller can inspect it
+		if err := ctx.streamFn(&ctx.response); err != nil {
+			fmt.Printf("Error streaming response for %q: %v\n", ctx.request.path, err)
+		}
+		return
+	}
+
+	if ctx.streamLen > 0 {
+		// Known length (e.g. ServeFile) - the body follows a Content-Length, so isn't chunked
+		if err := ctx.streamFn(countingWriter{w: respWriter, n: &ctx.response.sent}); err != nil {
+			fmt.Printf("Error streaming response for %q: %v\n", ctx.request.path, err)
+			// Close the connection, so the client knows the body is incomplete
+			if closer, ok := respWriter.(io.Closer); ok {
+				_ = closer.Close()
+			}
+		}
+		return
+	}
+
+	chunked := httputil.NewChunkedWriter(respWriter)
+
+	if err := ctx.streamFn(countingWriter{w: chunked, n: &ctx.response.sent}); err != nil {
+		fmt.Printf("Error streaming response for %q: %v\n", ctx.request.path, err)
+		// Abort without the final chunk, so the client knows the body is incomplete
+		if closer, ok := respWriter.(io.Closer); ok {
+			_ = closer.Close()
+		}
+		return
+	}
+
+	// Last (zero length) chunk, then the end of the (empty) trailer
+	_ = chunked.Close()
+	_, _ = io.WriteString(respWriter, consts.CRLF)
+}
+
+// Flush sends the response so far - status, headers and any buffered body - to the client,
+// and switches the response to chunked transfer encoding.
+// From then on, writes to the response go straight to the client as chunks,
+// so a long-running handler can report its progress as it goes:
+//
+//	for i, job := range jobs {
+//		job.Run()
+//		_ = ctx.WriteString(fmt.Sprintf("%d/%d done\n", i+1, len(jobs)))
+//		if err := ctx.Flush(); err != nil {
+//			return err // client gone
+//		}
+//	}
+//
+// Once flushed, the status and headers can no longer be changed,
+// and an error returned by the handler closes the connection, so the client sees an incomplete body.
+// Flush does nothing for HEAD and synthetic requests (Server.Request), under the Timeout middleware,
+// or for responses streamed otherwise (SSE, StreamJSON, WebSockets) - the body is then buffered as usual.
+func (ctx *context) Flush() error {
+	if ctx.response.stream != nil {
+		return nil // already flushed - writes aren't buffered
+	}
+	if (ctx.conn == nil && ctx.httpWriter == nil) || ctx.noFlush || ctx.request.method == consts.MethodHead || ctx.wsUpgraded || ctx.sseEventsChan != nil || ctx.streamFn != nil {
+		return nil
+	}
+
+	ctx.server.applyDefaultHeaders(ctx)
+	ctx.response.addServerTimingHeader()
+	if ctx.httpWriter != nil {
+		return ctx.flushNetHTTP()
+	}
+	if _, err := ctx.conn.Write(ctx.server.responseHead(ctx, true)); err != nil {
+		return err
+	}
+	ctx.response.stream = httputil.NewChunkedWriter(ctx.conn)
+
+	body := ctx.response.body
+	ctx.response.body = ctx.response.body[:0]
+	_, err := ctx.response.Write(body)
+	return err
+}
+
+// finishFlushed completes a flushed response, sending anything left in the body, then the last chunk
+func (s *Server) finishFlushed(ctx *context, respWriter io.Writer) {
+	if _, err := ctx.response.stream.Write(ctx.response.body); err != nil {
+		return
+	}
+	// Last (zero length) chunk, then the end of the (empty) trailer
+	_ = ctx.response.stream.Close()
+	_, _ = io.WriteString(respWriter, consts.CRLF)
+}
+
+// abortFlushed ends a flushed response whose handler failed.
+// The connection is closed without the last chunk, so the client knows the body is incomplete.
+func (s *Server) abortFlushed(ctx *context, respWriter io.Writer, err error) {
+	fmt.Printf("Error handling flushed response for %q: %v\n", ctx.request.path, err)
+	if closer, ok := respWriter.(io.Closer); ok {
+		_ = closer.Close()
+	}
+}
@@ -0,0 +1,98 @@
+package rweb_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/rohanthewiz/assert"
+	"github.com/rohanthewiz/rweb"
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+type streamItem struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestStreamJSON(t *testing.T) {
+	const itemCount = 5000
+
+	readyChan := make(chan struct{}, 1)
+	s := rweb.NewServerWithOptions(
+		rweb.WithAddress("localhost:"),
+		rweb.WithReadyChan(readyChan),
+	)
+
+	s.Get("/items", func(ctx rweb.Context) error {
+		return ctx.StreamJSON(func(enc *json.Encoder) error {
+			for i := range itemCount {
+				if err := enc.Encode(streamItem{ID: i, Name: fmt.Sprintf("item-%d", i)}); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+
+	s.Get("/broken", func(ctx rweb.Context) error {
+		return ctx.StreamJSON(func(enc *json.Encoder) error {
+			_ = enc.Encode(streamItem{ID: 1})
+			return errors.New("database went away")
+		})
+	})
+
+	go func() {
+		defer syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+		<-readyChan // wait for server
+		addr := fmt.Sprintf(":%s", s.GetListenPort())
+
+		conn, err := net.Dial(consts.ProtocolTCP, addr)
+		assert.Nil(t, err)
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+
+		// Items are decoded one at a time from the chunked body,
+		// and the connection is still usable for a following request
+		for range 2 {
+			_, err = io.WriteString(conn, "GET /items HTTP/1.1\r\nHost: localhost\r\n\r\n")
+			assert.Nil(t, err)
+			resp, err := http.ReadResponse(reader, nil)
+			assert.Nil(t, err)
+			assert.Equal(t, resp.StatusCode, 200)
+			assert.Equal(t, resp.Header.Get(consts.HeaderContentType), consts.MIMEJSON)
+			assert.Equal(t, strings.Join(resp.TransferEncoding, ","), "chunked")
+			assert.Equal(t, resp.ContentLength, int64(-1))
+
+			dec := json.NewDecoder(resp.Body)
+			count := 0
+			for ; dec.More(); count++ {
+				var item streamItem
+				assert.Nil(t, dec.Decode(&item))
+				assert.Equal(t, item.ID, count)
+				assert.Equal(t, item.Name, fmt.Sprintf("item-%d", count))
+			}
+			assert.Equal(t, count, itemCount)
+			_ = resp.Body.Close()
+		}
+
+		// A failing stream is cut off, so the client sees an incomplete body
+		_, err = io.WriteString(conn, "GET /broken HTTP/1.1\r\nHost: localhost\r\n\r\n")
+		assert.Nil(t, err)
+		resp, err := http.ReadResponse(reader, nil)
+		assert.Nil(t, err)
+		_, err = io.ReadAll(resp.Body)
+		assert.Equal(t, err, io.ErrUnexpectedEOF)
+	}()
+
+	err := s.Run()
+	assert.Nil(t, err)
+}
@@ -0,0 +1,80 @@
+package rweb
+
+import (
+	"io"
+)
+
+// ResponseRecorder records the response a handler makes on a context from NewTestContext,
+// for a unit test to inspect, much like the standard library's httptest.ResponseRecorder.
+type ResponseRecorder struct {
+	ctx *context
+}
+
+// NewTestContext builds a request context for calling a handler (or middleware) directly in a unit test,
+// without a connection or registering routes, and a recorder of the response the handler makes.
+// url may carry a query string. The body, if not nil, is read in full.
+// Set request headers with ctx.Request().SetHeader before calling the handler - a urlencoded form
+// or JSON body is then read as for a real request. In middleware, ctx.Next() continues to a handler doing nothing.
+// Example:
+//
+//	ctx, rec := rweb.NewTestContext("POST", "/orders?notify=1", strings.NewReader(`{"qty": 2}`))
+//	ctx.Request().SetHeader("Content-Type", "application/json")
+//	err := createOrder(ctx)
+//	// check err, rec.Code(), rec.Header("Location"), rec.BodyString()...
+func NewTestContext(method, url string, body io.Reader) (Context, *ResponseRecorder) {
+	s := NewServer()
+	// There is no router to reach - the handler under test is called directly
+	noop := func(Context) error { return nil }
+	s.handlers = []Handler{noop, noop}
+
+	ctx := s.newContext()
+	ctx.method = method
+	ctx.scheme, ctx.host, ctx.path, ctx.query = parseURL(url, s.options.URLOptions)
+	if body != nil {
+		if b, err := io.ReadAll(body); err == nil {
+			ctx.request.body = b
+		}
+	}
+	return ctx, &ResponseRecorder{ctx: ctx}
+}
+
+// Code returns the status of the response
+func (r *ResponseRecorder) Code() int {
+	return r.ctx.response.Status()
+}
+
+// Header returns the first value of a response header
+func (r *ResponseRecorder) Header(key string) string {
+	return r.ctx.response.Header(key)
+}
+
+// Headers returns all the values of a response header
+func (r *ResponseRecorder) Headers(key string) []string {
+	return r.ctx.response.Headers(key)
+}
+
+// Cookies returns the cookies set by the response
+func (r *ResponseRecorder) Cookies() []*Cookie {
+	return r.ctx.response.Cookies()
+}
+
+// Body returns the body of the response. A streamed body (e.g. from StreamJSON or ServeFile) is collected first.
+func (r *ResponseRecorder) Body() []byte {
+	if r.ctx.streamFn != nil {
+		streamFn := r.ctx.streamFn
+		r.ctx.streamFn = nil
+		_ = streamFn(&r.ctx.response)
+	}
+	return r.ctx.response.Body()
+}
+
+// BodyString returns the body of the response as a string
+func (r *ResponseRecorder) BodyString() string {
+	return string(r.Body())
+}
+
+// Result returns the response, for anything else to inspect
+func (r *ResponseRecorder) Result() Response {
+	r.Body() // complete any streamed body
+	return &r.ctx.response
+}
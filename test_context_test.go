@@ -0,0 +1,63 @@
+package rweb_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/rohanthewiz/assert"
+	"github.com/rohanthewiz/rweb"
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+func TestNewTestContext(t *testing.T) {
+	createOrder := func(ctx rweb.Context) error {
+		var order struct {
+			Qty int `json:"qty"`
+		}
+		if err := ctx.DecodeJSONLimited(&order, 1024); err != nil {
+			return ctx.JSONError(consts.StatusBadRequest, err.Error())
+		}
+		ctx.Response().SetHeader("Location", "/orders/7")
+		ctx.Response().AddHeader("X-Notify", ctx.Request().QueryParam("notify"))
+		return ctx.SetStatus(consts.StatusCreated).WriteJSON(map[string]int{"qty": order.Qty})
+	}
+
+	ctx, rec := rweb.NewTestContext(consts.MethodPost, "/orders?notify=1", strings.NewReader(`{"qty": 2}`))
+	ctx.Request().SetHeader(consts.HeaderContentType, "application/json")
+	assert.Nil(t, createOrder(ctx))
+	assert.Equal(t, rec.Code(), consts.StatusCreated)
+	assert.Equal(t, rec.Header("Location"), "/orders/7")
+	assert.Equal(t, rec.Headers("X-Notify")[0], "1")
+	assert.Equal(t, rec.BodyString(), `{"qty":2}`)
+
+	ctx, rec = rweb.NewTestContext(consts.MethodPost, "/orders", strings.NewReader(`{"qty":`))
+	assert.Nil(t, createOrder(ctx))
+	assert.Equal(t, rec.Code(), consts.StatusBadRequest)
+
+	// Forms, and the request details
+	ctx, rec = rweb.NewTestContext(consts.MethodPut, "https://example.com/items/3", strings.NewReader("name=ann"))
+	ctx.Request().SetHeader(consts.HeaderContentType, string(consts.BytFormData))
+	assert.Equal(t, ctx.Request().Path(), "/items/3")
+	assert.Equal(t, ctx.Request().Host(), "example.com")
+	assert.Equal(t, ctx.Request().FormValue("name"), "ann")
+	assert.Equal(t, rec.Code(), consts.StatusOK)
+	assert.Equal(t, rec.BodyString(), "")
+
+	// Middleware, continuing the chain
+	ctx, rec = rweb.NewTestContext(consts.MethodGet, "/", nil)
+	middleware := func(ctx rweb.Context) error {
+		ctx.Response().SetHeader("X-Seen", "yes")
+		return ctx.Next()
+	}
+	assert.Nil(t, middleware(ctx))
+	assert.Equal(t, rec.Header("X-Seen"), "yes")
+
+	// Streamed bodies are collected
+	ctx, rec = rweb.NewTestContext(consts.MethodGet, "/stream", nil)
+	assert.Nil(t, ctx.StreamJSON(func(enc *json.Encoder) error {
+		_ = enc.Encode(1)
+		return enc.Encode(2)
+	}))
+	assert.Equal(t, rec.BodyString(), "1\n2\n")
+}
@@ -0,0 +1,141 @@
+package rweb
+
+import (
+	gocontext "context"
+	"runtime/debug"
+	"time"
+
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+// Timeout returns a middleware that bounds the time taken by the rest of the chain.
+// If it doesn't complete within d, the client gets a 503 Service Unavailable and the handler is abandoned.
+//
+// The chain runs in a goroutine on a copy of the request context, whose ctx.Context()
+// has the deadline. An abandoned handler carries on until it returns - Go can't stop it -
+// so handlers must cooperate, passing ctx.Context() to downstream calls and checking it in long loops,
+// for the goroutine and its resources to be freed. Whatever an abandoned handler writes is discarded,
+// so the response is never written twice, even if the handler finishes right at the deadline.
+// An abandoned handler may also still be reading a streamed request body (see NextPart),
+// so the connection is closed after the 503, rather than kept for another request.
+// Timeout isn't suitable for SSE or WebSocket routes, whose handlers outlive the request.
+// Example:
+//
+//	s.Use(rweb.Timeout(5 * time.Second))
+func Timeout(d time.Duration) Handler {
+	return func(ctx Context) error {
+		base := baseContext(ctx)
+		if base == nil {
+			return ctx.Next()
+		}
+
+		goCtx, cancel := gocontext.WithTimeout(base.Context(), d)
+		defer cancel()
+
+		detached := base.detach()
+		detached.goCtx = goCtx
+		next := rebindContext(ctx, detached)
+
+		done := make(chan error, 1) // buffered, so an abandoned handler can still finish
+		go func() {
+			defer func() {
+				if rec := recover(); rec != nil {
+					done <- &PanicError{Value: rec, Stack: debug.Stack()}
+				}
+			}()
+			done <- next.Next()
+		}()
+
+		select {
+		case err := <-done:
+			base.adopt(detached)
+			return err
+
+		case <-goCtx.Done():
+			// Release any stream the abandoned handler leaves (e.g. a proxied body), once it returns
+			go func() {
+				<-done
+				detached.releaseStream()
+			}()
+
+			// The abandoned handler may be reading the body still, so it can't be skipped to reach another request
+			if base.request.bodyStream != nil {
+				base.request.bodyStream = nil
+				base.closeConn = true
+			}
+
+			// The response is as it was before the handler ran (e.g. with headers from outer middleware)
+			base.response.body = base.response.body[:0]
+			base.SetStatus(consts.StatusServiceUnavailable)
+			return base.WriteText(consts.StatusTextFromCode[consts.StatusServiceUnavailable])
+		}
+	}
+}
+
+// detach returns a copy of the context that can be handled in another goroutine,
+// sharing no mutable state with ctx, which may be reused for another request meanwhile
+func (ctx *context) detach() *context {
+	c := &context{
+		server:       ctx.server,
+		handlerIndex: ctx.handlerIndex,
+		conn:         ctx.conn,
+		httpReq:      ctx.httpReq,
+		goCtx:        ctx.goCtx,
+		noFlush:      true, // on timeout, the response is replaced
+		aborted:      ctx.aborted,
+	}
+
+	c.request = request{
+		scheme:        ctx.request.scheme,
+		host:          ctx.request.host,
+		method:        ctx.request.method,
+		path:          ctx.request.path,
+		query:         ctx.request.query,
+		ContentType:   append([]byte(nil), ctx.request.ContentType...),
+		headers:       append([]Header(nil), ctx.request.headers...),
+		body:          append([]byte(nil), ctx.request.body...),
+		params:        append(ctx.request.params[:0:0], ctx.request.params...),
+		raw:           append([]byte(nil), ctx.request.raw...),
+		multipartForm: ctx.request.multipartForm,
+		bodyStream:    ctx.request.bodyStream,
+		partReader:    ctx.request.partReader,
+	}
+
+	c.response = response{
+		status:  ctx.response.status,
+		headers: append([]Header(nil), ctx.response.headers...),
+		body:    append([]byte(nil), ctx.response.body...),
+		timings: append([]serverTiming(nil), ctx.response.timings...),
+
+		jsonEncoder: ctx.response.jsonEncoder,
+	}
+
+	c.data = make(map[string]any, len(ctx.data))
+	for k, v := range ctx.data {
+		c.data[k] = v
+	}
+	return c
+}
+
+// adopt takes on the response (and any data, and the route matched) a detached copy of the context produced
+func (ctx *context) adopt(c *context) {
+	ctx.request.partReader = c.request.partReader
+	ctx.route = c.route
+	ctx.response = c.response
+	ctx.data = c.data
+	ctx.aborted = c.aborted
+	ctx.streamFn = c.streamFn
+	ctx.streamLen = c.streamLen
+	ctx.streamCleanup = c.streamCleanup
+	ctx.sseEventsChan = c.sseEventsChan
+	ctx.sseEventName = c.sseEventName
+	ctx.sseCleanup = c.sseCleanup
+}
+
+// rebindContext returns ctx, including any group wrappers around it, with c in place of its base context
+func rebindContext(ctx Context, c *context) Context {
+	if w, ok := ctx.(*contextWrapper); ok {
+		return &contextWrapper{wrappedContext: rebindContext(w.wrappedContext, c), next: w.next, last: w.last}
+	}
+	return c
+}
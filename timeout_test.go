@@ -0,0 +1,183 @@
+package rweb_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/rohanthewiz/assert"
+	"github.com/rohanthewiz/rweb"
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+func TestTimeout(t *testing.T) {
+	s := rweb.NewServer()
+
+	// Outer middleware sees the handler's response as usual
+	s.Use(func(ctx rweb.Context) error {
+		ctx.Response().SetHeader("X-Outer", "1")
+		err := ctx.Next()
+		if who, ok := ctx.Get("handled-by").(string); ok {
+			ctx.Response().SetHeader("X-Handled-By", who)
+		}
+		return err
+	})
+	s.Use(rweb.Timeout(50 * time.Millisecond))
+
+	s.Get("/fast/:id", func(ctx rweb.Context) error {
+		_, hasDeadline := ctx.Context().Deadline()
+		ctx.Set("handled-by", "fast")
+		ctx.SetStatus(consts.StatusCreated)
+		return ctx.WriteString(ctx.Request().Param("id") + " " + ctx.Response().Header("X-Outer") +
+			" " + map[bool]string{true: "deadline"}[hasDeadline])
+	})
+
+	canceled := make(chan error, 1)
+	s.Get("/slow", func(ctx rweb.Context) error {
+		select {
+		case <-ctx.Context().Done(): // a cooperating handler gives up
+			canceled <- ctx.Context().Err()
+			return ctx.Context().Err()
+		case <-time.After(5 * time.Second):
+			return ctx.WriteString("too late")
+		}
+	})
+
+	finished := make(chan struct{})
+	s.Get("/stubborn", func(ctx rweb.Context) error {
+		time.Sleep(100 * time.Millisecond)
+		ctx.Set("handled-by", "stubborn")
+		defer close(finished)
+		return ctx.WriteString("too late")
+	})
+
+	s.Get("/fail", func(ctx rweb.Context) error {
+		return errors.New("failed")
+	})
+	s.Get("/panic", func(ctx rweb.Context) error {
+		panic("oops")
+	})
+
+	response := s.Request(consts.MethodGet, "/fast/7", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusCreated)
+	assert.Equal(t, string(response.Body()), "7 1 deadline")
+	assert.Equal(t, response.Header("X-Handled-By"), "fast")
+
+	start := time.Now()
+	response = s.Request(consts.MethodGet, "/slow", nil, nil)
+	assert.True(t, time.Since(start) < time.Second)
+	assert.Equal(t, response.Status(), consts.StatusServiceUnavailable)
+	assert.Equal(t, string(response.Body()), "Service Unavailable")
+	assert.Equal(t, response.Header("X-Outer"), "1")
+	assert.True(t, errors.Is(<-canceled, context.DeadlineExceeded))
+
+	// What an abandoned handler does later doesn't reach the response
+	response = s.Request(consts.MethodGet, "/stubborn", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusServiceUnavailable)
+	<-finished
+	assert.Equal(t, string(response.Body()), "Service Unavailable")
+	assert.Equal(t, response.Header("X-Handled-By"), "")
+
+	// Errors and panics are passed on to the error handler
+	response = s.Request(consts.MethodGet, "/fail", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusInternalServerError)
+	response = s.Request(consts.MethodGet, "/panic", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusInternalServerError)
+}
+
+func TestTimeoutInGroup(t *testing.T) {
+	s := rweb.NewServer()
+
+	var order []string
+	api := s.Group("/api", rweb.Timeout(50*time.Millisecond), func(ctx rweb.Context) error {
+		order = append(order, "group middleware")
+		return ctx.Next()
+	})
+	api.Get("/items", func(ctx rweb.Context) error {
+		order = append(order, "handler")
+		_, hasDeadline := ctx.Context().Deadline()
+		assert.True(t, hasDeadline)
+		return ctx.WriteString("items")
+	})
+	api.Get("/slow", func(ctx rweb.Context) error {
+		<-ctx.Context().Done()
+		return nil
+	})
+
+	response := s.Request(consts.MethodGet, "/api/items", nil, nil)
+	assert.Equal(t, string(response.Body()), "items")
+	assert.Equal(t, strings.Join(order, ","), "group middleware,handler")
+
+	response = s.Request(consts.MethodGet, "/api/slow", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusServiceUnavailable)
+}
+
+func TestTimeoutStreamedBody(t *testing.T) {
+	readyChan := make(chan struct{}, 1)
+	s := rweb.NewServerWithOptions(
+		rweb.WithAddress("localhost:"),
+		rweb.WithReadyChan(readyChan),
+		rweb.WithMultipartStreaming(1024),
+	)
+	s.Use(rweb.Timeout(50 * time.Millisecond))
+
+	readParts := func(ctx rweb.Context) error {
+		out := ""
+		for {
+			part, err := ctx.Request().NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			n, err := io.Copy(io.Discard, part)
+			if err != nil {
+				return err
+			}
+			out += fmt.Sprintf("%s %d;", part.FormName(), n)
+		}
+		return ctx.WriteString(out)
+	}
+	s.Post("/parts", readParts)
+	s.Post("/slow", func(ctx rweb.Context) error {
+		time.Sleep(100 * time.Millisecond)
+		return readParts(ctx)
+	})
+
+	go func() {
+		defer syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+		<-readyChan // wait for server
+		base := fmt.Sprintf("http://127.0.0.1:%s", s.GetListenPort())
+
+		post := func(path string) *http.Response {
+			body, contentType := multipartBody(t, "file", "big.bin", 64*1024)
+			resp, err := http.Post(base+path, contentType, body)
+			assert.Nil(t, err)
+			return resp
+		}
+
+		// The handler reads the streamed body as usual
+		resp := post("/parts")
+		respBody, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		assert.Equal(t, resp.StatusCode, consts.StatusOK)
+		assert.Equal(t, string(respBody), "file 65536;")
+		assert.False(t, resp.Close)
+
+		// A handler given up on may still be reading it, so the connection isn't kept
+		resp = post("/slow")
+		_ = resp.Body.Close()
+		assert.Equal(t, resp.StatusCode, consts.StatusServiceUnavailable)
+		assert.True(t, resp.Close)
+	}()
+
+	_ = s.Run()
+}
@@ -0,0 +1,80 @@
+package rweb
+
+import (
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/rohanthewiz/assert"
+)
+
+func TestTLSAddrDefault(t *testing.T) {
+	s := NewServer(ServerOptions{TLS: TLSCfg{UseTLS: true}})
+	assert.Equal(t, s.options.TLS.TLSAddr, ":443")
+
+	s = NewServer(ServerOptions{TLS: TLSCfg{UseTLS: true, TLSAddr: ":8443"}})
+	assert.Equal(t, s.options.TLS.TLSAddr, ":8443")
+
+	s = NewServer()
+	assert.Equal(t, s.options.TLS.TLSAddr, "")
+}
+
+func TestCheckListenAddrs(t *testing.T) {
+	tests := []struct {
+		httpAddr string
+		tlsAddr  string
+		clash    bool
+	}{
+		{":80", ":443", false},
+		{"", ":443", false},
+		{"", ":80", true},
+		{":8443", ":8443", true},
+		{"localhost:8080", "localhost:8080", true},
+		{":8080", "127.0.0.1:8080", true},
+		{"0.0.0.0:8080", "127.0.0.1:8080", true},
+		{"127.0.0.1:8080", "10.0.0.1:8080", false},
+		{"localhost:", "localhost:", false}, // ports chosen by the system
+		{":0", ":0", false},
+	}
+
+	for _, tt := range tests {
+		err := checkListenAddrs(tt.httpAddr, tt.tlsAddr)
+		assert.Equal(t, err != nil, tt.clash)
+	}
+
+	assert.NotNil(t, checkListenAddrs(":80", "443")) // not host:port
+
+	// Refused before anything is started
+	s := NewServer(ServerOptions{Address: ":8443", TLS: TLSCfg{UseTLS: true, TLSAddr: ":8443"}})
+	err := s.RunWithHttpsRedirect()
+	assert.NotNil(t, err)
+	assert.True(t, strings.Contains(err.Error(), "same port"))
+}
+
+func TestGetTLSListenAddr(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	writeTestCert(t, certFile, keyFile, "localhost", time.Now())
+
+	readyChan := make(chan struct{}, 1)
+	s := NewServer(ServerOptions{
+		ReadyChan: readyChan,
+		TLS:       TLSCfg{UseTLS: true, TLSAddr: "localhost:", CertFile: certFile, KeyFile: keyFile},
+	})
+	assert.Equal(t, s.GetTLSListenAddr(), "") // not yet listening
+
+	go func() {
+		defer syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+		<-readyChan // wait for server
+		addr := s.GetTLSListenAddr()
+		assert.True(t, strings.HasPrefix(addr, "127.0.0.1:") || strings.HasPrefix(addr, "[::1]:"))
+		assert.False(t, strings.HasSuffix(addr, ":0"))
+	}()
+
+	err := s.Run()
+	assert.Nil(t, err)
+}
@@ -0,0 +1,46 @@
+package rweb
+
+import (
+	"crypto/tls"
+	"fmt"
+	"slices"
+)
+
+// defaultTLSMinVersion is the oldest TLS version accepted unless TLSCfg.MinVersion says otherwise
+const defaultTLSMinVersion = tls.VersionTLS12
+
+// configureProtocol sets the TLS versions and cipher suites accepted, per the TLS options.
+// Unrecognized or insecure values are refused, rather than silently weakening (or breaking) the server.
+func configureProtocol(tlsConfig *tls.Config, cfg TLSCfg) error {
+	tlsConfig.MinVersion = defaultTLSMinVersion
+	if cfg.MinVersion != 0 {
+		switch cfg.MinVersion {
+		case tls.VersionTLS10, tls.VersionTLS11, tls.VersionTLS12, tls.VersionTLS13:
+			tlsConfig.MinVersion = cfg.MinVersion
+		default:
+			return fmt.Errorf("unrecognized TLS MinVersion 0x%04x - use one of the tls.VersionTLS1x constants", cfg.MinVersion)
+		}
+	}
+
+	if len(cfg.CipherSuites) == 0 {
+		return nil
+	}
+	if tlsConfig.MinVersion == tls.VersionTLS13 {
+		return fmt.Errorf("TLS CipherSuites can't be used with MinVersion TLS 1.3, whose cipher suites aren't configurable")
+	}
+
+	secure := tls.CipherSuites()
+	for _, id := range cfg.CipherSuites {
+		i := slices.IndexFunc(secure, func(suite *tls.CipherSuite) bool { return suite.ID == id })
+		switch {
+		case i < 0 && slices.ContainsFunc(tls.InsecureCipherSuites(), func(suite *tls.CipherSuite) bool { return suite.ID == id }):
+			return fmt.Errorf("TLS cipher suite %s is insecure", tls.CipherSuiteName(id))
+		case i < 0:
+			return fmt.Errorf("unrecognized TLS cipher suite 0x%04x", id)
+		case !slices.Contains(secure[i].SupportedVersions, tls.VersionTLS12):
+			return fmt.Errorf("TLS cipher suite %s is for TLS 1.3, whose cipher suites aren't configurable", secure[i].Name)
+		}
+	}
+	tlsConfig.CipherSuites = slices.Clone(cfg.CipherSuites)
+	return nil
+}
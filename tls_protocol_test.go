@@ -0,0 +1,35 @@
+package rweb
+
+import (
+	"crypto/tls"
+	"testing"
+
+	"github.com/rohanthewiz/assert"
+)
+
+func TestConfigureProtocol(t *testing.T) {
+	// Defaults
+	cfg := &tls.Config{}
+	assert.Nil(t, configureProtocol(cfg, TLSCfg{}))
+	assert.Equal(t, cfg.MinVersion, uint16(tls.VersionTLS12))
+	assert.Equal(t, len(cfg.CipherSuites), 0)
+
+	// TLS 1.3 only
+	cfg = &tls.Config{}
+	assert.Nil(t, configureProtocol(cfg, TLSCfg{MinVersion: tls.VersionTLS13}))
+	assert.Equal(t, cfg.MinVersion, uint16(tls.VersionTLS13))
+
+	// Restricted suites
+	suites := []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384, tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384}
+	cfg = &tls.Config{}
+	assert.Nil(t, configureProtocol(cfg, TLSCfg{CipherSuites: suites}))
+	assert.Equal(t, len(cfg.CipherSuites), 2)
+	assert.Equal(t, cfg.CipherSuites[0], suites[0])
+
+	// Refused
+	assert.NotNil(t, configureProtocol(&tls.Config{}, TLSCfg{MinVersion: 0x0300})) // SSL 3.0
+	assert.NotNil(t, configureProtocol(&tls.Config{}, TLSCfg{CipherSuites: []uint16{0xffff}}))
+	assert.NotNil(t, configureProtocol(&tls.Config{}, TLSCfg{CipherSuites: []uint16{tls.TLS_RSA_WITH_RC4_128_SHA}}))
+	assert.NotNil(t, configureProtocol(&tls.Config{}, TLSCfg{CipherSuites: []uint16{tls.TLS_AES_128_GCM_SHA256}}))
+	assert.NotNil(t, configureProtocol(&tls.Config{}, TLSCfg{MinVersion: tls.VersionTLS13, CipherSuites: suites}))
+}
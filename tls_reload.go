@@ -0,0 +1,109 @@
+package rweb
+
+import (
+	"crypto/tls"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// certReloader serves the latest certificate from disk for TLS handshakes.
+// The files are stat'ed at most once per interval, and reloaded only when
+// their modification times change, so handshakes stay cheap.
+type certReloader struct {
+	certFile string
+	keyFile  string
+	interval time.Duration
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	certMod   time.Time
+	keyMod    time.Time
+	lastCheck time.Time
+}
+
+// newCertReloader loads the initial certificate, failing if it can't be loaded
+func newCertReloader(certFile, keyFile string, interval time.Duration) (*certReloader, error) {
+	cr := &certReloader{certFile: certFile, keyFile: keyFile, interval: interval}
+
+	certMod, keyMod, err := cr.modTimes()
+	if err != nil {
+		return nil, err
+	}
+	if err = cr.load(certMod, keyMod); err != nil {
+		return nil, err
+	}
+	return cr, nil
+}
+
+// GetCertificate is used as tls.Config.GetCertificate.
+// If a renewed certificate fails to load (e.g. the key is written after the cert),
+// the previous certificate keeps being served and the load is retried on the next check.
+func (cr *certReloader) GetCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cr.mu.RLock()
+	cert, due := cr.cert, time.Since(cr.lastCheck) >= cr.interval
+	cr.mu.RUnlock()
+
+	if due {
+		cr.maybeReload()
+		cr.mu.RLock()
+		cert = cr.cert
+		cr.mu.RUnlock()
+	}
+	return cert, nil
+}
+
+// maybeReload reloads the certificate if either file changed since the last load
+func (cr *certReloader) maybeReload() {
+	cr.mu.Lock()
+	if time.Since(cr.lastCheck) < cr.interval { // another handshake got here first
+		cr.mu.Unlock()
+		return
+	}
+	cr.lastCheck = time.Now()
+	prevCertMod, prevKeyMod := cr.certMod, cr.keyMod
+	cr.mu.Unlock()
+
+	certMod, keyMod, err := cr.modTimes()
+	if err != nil {
+		log.Printf("[TLS] unable to check certificate files: %v\n", err)
+		return
+	}
+	if certMod.Equal(prevCertMod) && keyMod.Equal(prevKeyMod) {
+		return
+	}
+
+	if err = cr.load(certMod, keyMod); err != nil {
+		log.Printf("[TLS] unable to reload certificate, keeping the current one: %v\n", err)
+		return
+	}
+	log.Printf("[TLS] reloaded certificate from %s\n", cr.certFile)
+}
+
+// load reads the key pair and records the mod times it was read at
+func (cr *certReloader) load(certMod, keyMod time.Time) error {
+	cert, err := tls.LoadX509KeyPair(cr.certFile, cr.keyFile)
+	if err != nil {
+		return err
+	}
+
+	cr.mu.Lock()
+	cr.cert = &cert
+	cr.certMod, cr.keyMod = certMod, keyMod
+	cr.lastCheck = time.Now()
+	cr.mu.Unlock()
+	return nil
+}
+
+func (cr *certReloader) modTimes() (certMod, keyMod time.Time, err error) {
+	certInfo, err := os.Stat(cr.certFile)
+	if err != nil {
+		return
+	}
+	keyInfo, err := os.Stat(cr.keyFile)
+	if err != nil {
+		return
+	}
+	return certInfo.ModTime(), keyInfo.ModTime(), nil
+}
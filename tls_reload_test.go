@@ -0,0 +1,86 @@
+package rweb
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rohanthewiz/assert"
+)
+
+// writeTestCert writes a self-signed cert/key pair for commonName and sets the files' mod time
+func writeTestCert(t *testing.T, certFile, keyFile, commonName string, modTime time.Time) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	assert.Nil(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+
+	assert.Nil(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	assert.Nil(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	assert.Nil(t, os.Chtimes(certFile, modTime, modTime))
+	assert.Nil(t, os.Chtimes(keyFile, modTime, modTime))
+}
+
+func commonName(t *testing.T, cr *certReloader) string {
+	cert, err := cr.GetCertificate(nil)
+	assert.Nil(t, err)
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	assert.Nil(t, err)
+	return leaf.Subject.CommonName
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	start := time.Now().Add(-time.Hour)
+
+	writeTestCert(t, certFile, keyFile, "original", start)
+
+	const interval = 20 * time.Millisecond
+	cr, err := newCertReloader(certFile, keyFile, interval)
+	assert.Nil(t, err)
+	assert.Equal(t, commonName(t, cr), "original")
+
+	// Renew the certificate
+	writeTestCert(t, certFile, keyFile, "renewed", start.Add(time.Minute))
+
+	// Cached until the interval elapses
+	assert.Equal(t, commonName(t, cr), "original")
+
+	time.Sleep(2 * interval)
+	assert.Equal(t, commonName(t, cr), "renewed")
+
+	// A broken renewal keeps the current certificate
+	assert.Nil(t, os.WriteFile(keyFile, []byte("garbage"), 0600))
+	time.Sleep(2 * interval)
+	assert.Equal(t, commonName(t, cr), "renewed")
+
+	// and is picked up once fixed
+	writeTestCert(t, certFile, keyFile, "fixed", start.Add(2*time.Minute))
+	time.Sleep(2 * interval)
+	assert.Equal(t, commonName(t, cr), "fixed")
+}
+
+func TestCertReloaderMissingFiles(t *testing.T) {
+	dir := t.TempDir()
+	_, err := newCertReloader(filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"), time.Second)
+	assert.True(t, err != nil)
+}
@@ -0,0 +1,31 @@
+package rweb
+
+import "io"
+
+// UploadProgressFunc is called as a request body streams in from the client.
+// totalBytes comes from Content-Length, and is -1 for chunked bodies of unknown size.
+type UploadProgressFunc func(bytesRead, totalBytes int64)
+
+// UploadProgressHandler is consulted once per multipart request, after the headers are read
+// and before the body, to obtain a progress callback for that upload.
+// Returning nil skips progress reporting for the request.
+// This allows tracking a particular upload, e.g. by an id in the path or headers,
+// and pushing its progress to the browser over SSE or a WebSocket.
+type UploadProgressHandler func(method string, path string, headers []Header) UploadProgressFunc
+
+// progressReader reports the cumulative number of bytes read through it
+type progressReader struct {
+	reader     io.Reader
+	bytesRead  int64
+	totalBytes int64
+	onProgress UploadProgressFunc
+}
+
+func (pr *progressReader) Read(p []byte) (int, error) {
+	n, err := pr.reader.Read(p)
+	if n > 0 {
+		pr.bytesRead += int64(n)
+		pr.onProgress(pr.bytesRead, pr.totalBytes)
+	}
+	return n, err
+}
@@ -0,0 +1,296 @@
+package rweb_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+
+	"github.com/rohanthewiz/assert"
+	"github.com/rohanthewiz/rweb"
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+// multipartBody builds a multipart form with a single file of the given size
+func multipartBody(t *testing.T, field, filename string, size int) (*bytes.Buffer, string) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	part, err := writer.CreateFormFile(field, filename)
+	assert.Nil(t, err)
+	_, err = part.Write(bytes.Repeat([]byte("x"), size))
+	assert.Nil(t, err)
+	assert.Nil(t, writer.Close())
+
+	return body, writer.FormDataContentType()
+}
+
+func TestUploadProgress(t *testing.T) {
+	readyChan := make(chan struct{}, 1)
+
+	var mu sync.Mutex
+	var progress []int64
+	var total int64
+	var progressPath string
+
+	s := rweb.NewServerWithOptions(
+		rweb.WithAddress("localhost:"),
+		rweb.WithReadyChan(readyChan),
+		rweb.WithUploadProgress(func(method, path string, headers []rweb.Header) rweb.UploadProgressFunc {
+			progressPath = path
+			return func(bytesRead, totalBytes int64) {
+				mu.Lock()
+				defer mu.Unlock()
+				progress = append(progress, bytesRead)
+				total = totalBytes
+			}
+		}),
+	)
+
+	s.Post("/upload", func(ctx rweb.Context) error {
+		_, hdr, err := ctx.Request().GetFormFile("file")
+		if err != nil {
+			return err
+		}
+		return ctx.WriteString(fmt.Sprintf("%d", hdr.Size))
+	})
+
+	go func() {
+		defer syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+		<-readyChan // wait for server
+
+		const fileSize = 256 * 1024
+		body, contentType := multipartBody(t, "file", "big.bin", fileSize)
+		bodyLen := int64(body.Len())
+
+		resp, err := http.Post(fmt.Sprintf("http://127.0.0.1:%s/upload", s.GetListenPort()), contentType, body)
+		assert.Nil(t, err)
+		assert.Equal(t, resp.Status, consts.OK200)
+
+		respBody, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		assert.Equal(t, string(respBody), fmt.Sprintf("%d", fileSize))
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		assert.Equal(t, progressPath, "/upload")
+		assert.Equal(t, total, bodyLen)
+		assert.True(t, len(progress) > 1)
+		for i := 1; i < len(progress); i++ {
+			assert.True(t, progress[i] > progress[i-1])
+		}
+		assert.Equal(t, progress[len(progress)-1], bodyLen)
+	}()
+
+	_ = s.Run()
+}
+
+func TestSaveFormFile(t *testing.T) {
+	readyChan := make(chan struct{}, 1)
+	dir := t.TempDir()
+	destPath := filepath.Join(dir, "saved.bin")
+
+	s := rweb.NewServerWithOptions(
+		rweb.WithAddress("localhost:"),
+		rweb.WithReadyChan(readyChan),
+	)
+
+	s.Post("/upload", func(ctx rweb.Context) error {
+		written, err := ctx.Request().SaveFormFile("file", destPath)
+		if err != nil {
+			return err
+		}
+		return ctx.WriteString(fmt.Sprintf("%d", written))
+	})
+
+	s.Post("/upload-missing", func(ctx rweb.Context) error {
+		_, err := ctx.Request().SaveFormFile("other", filepath.Join(dir, "other.bin"))
+		return ctx.WriteString(err.Error())
+	})
+
+	go func() {
+		defer syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+		<-readyChan // wait for server
+
+		const fileSize = 100 * 1024
+		body, contentType := multipartBody(t, "file", "big.bin", fileSize)
+		resp, err := http.Post(fmt.Sprintf("http://127.0.0.1:%s/upload", s.GetListenPort()), contentType, body)
+		assert.Nil(t, err)
+		respBody, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		assert.Equal(t, string(respBody), fmt.Sprintf("%d", fileSize))
+
+		saved, err := os.ReadFile(destPath)
+		assert.Nil(t, err)
+		assert.True(t, bytes.Equal(saved, bytes.Repeat([]byte("x"), fileSize)))
+
+		// Missing field
+		body, contentType = multipartBody(t, "file", "small.bin", 10)
+		resp, err = http.Post(fmt.Sprintf("http://127.0.0.1:%s/upload-missing", s.GetListenPort()), contentType, body)
+		assert.Nil(t, err)
+		respBody, _ = io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		assert.Equal(t, string(respBody), "no file found for key: other")
+
+		_, err = os.Stat(filepath.Join(dir, "other.bin"))
+		assert.True(t, os.IsNotExist(err))
+	}()
+
+	_ = s.Run()
+}
+
+func TestMultipartLimits(t *testing.T) {
+	readyChan := make(chan struct{}, 1)
+	s := rweb.NewServerWithOptions(
+		rweb.WithAddress("localhost:"),
+		rweb.WithReadyChan(readyChan),
+		rweb.WithMultipartLimits(1024, 3, 2),
+	)
+
+	var handled int
+	s.Post("/upload", func(ctx rweb.Context) error {
+		handled++
+		return ctx.WriteString(fmt.Sprintf("ok %s", ctx.Request().FormValue("name")))
+	})
+
+	// form builds a multipart form with the given fields and number of small files
+	form := func(fields map[string]string, files int) (*bytes.Buffer, string) {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		for name, value := range fields {
+			assert.Nil(t, writer.WriteField(name, value))
+		}
+		for i := range files {
+			part, err := writer.CreateFormFile("file", fmt.Sprintf("f%d.txt", i))
+			assert.Nil(t, err)
+			_, _ = part.Write(bytes.Repeat([]byte("x"), 2048)) // files may exceed the memory limit, going to disk
+		}
+		assert.Nil(t, writer.Close())
+		return body, writer.FormDataContentType()
+	}
+
+	go func() {
+		defer syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+		<-readyChan // wait for server
+
+		post := func(body *bytes.Buffer, contentType string) (int, string) {
+			resp, err := http.Post("http://localhost:"+s.GetListenPort()+"/upload", contentType, body)
+			assert.Nil(t, err)
+			defer resp.Body.Close()
+			respBody, _ := io.ReadAll(resp.Body)
+			return resp.StatusCode, string(respBody)
+		}
+
+		// Within the limits
+		status, body := post(form(map[string]string{"name": "ann", "a": "1", "b": "2"}, 2))
+		assert.Equal(t, status, consts.StatusOK)
+		assert.Equal(t, body, "ok ann")
+		assert.Equal(t, handled, 1)
+
+		// Too many fields
+		status, body = post(form(map[string]string{"name": "ann", "a": "1", "b": "2", "c": "3"}, 0))
+		assert.Equal(t, status, consts.StatusRequestEntityTooLarge)
+		assert.Equal(t, body, "Request Entity Too Large")
+
+		// Too many files
+		status, _ = post(form(map[string]string{"name": "ann"}, 3))
+		assert.Equal(t, status, consts.StatusRequestEntityTooLarge)
+
+		// Fields too large for memory
+		status, _ = post(form(map[string]string{"name": string(bytes.Repeat([]byte("n"), 1025))}, 0))
+		assert.Equal(t, status, consts.StatusRequestEntityTooLarge)
+
+		// The handler never saw the refused forms
+		assert.Equal(t, handled, 1)
+	}()
+
+	err := s.Run()
+	assert.Nil(t, err)
+}
+
+func TestMultipartStreaming(t *testing.T) {
+	readyChan := make(chan struct{}, 1)
+	s := rweb.NewServerWithOptions(
+		rweb.WithAddress("localhost:"),
+		rweb.WithReadyChan(readyChan),
+		rweb.WithMultipartStreaming(1024),
+	)
+
+	s.Post("/parts", func(ctx rweb.Context) error {
+		out := fmt.Sprintf("body %d;", len(ctx.Request().Body()))
+		for {
+			part, err := ctx.Request().NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			n, err := io.Copy(io.Discard, part)
+			if err != nil {
+				return err
+			}
+			out += fmt.Sprintf(" %s %d;", part.FormName(), n)
+		}
+		return ctx.WriteString(out)
+	})
+	s.Post("/ignore", func(ctx rweb.Context) error {
+		return ctx.WriteString("ignored")
+	})
+
+	go func() {
+		defer syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+		<-readyChan // wait for server
+		base := fmt.Sprintf("http://127.0.0.1:%s", s.GetListenPort())
+		client := &http.Client{Transport: &http.Transport{MaxConnsPerHost: 1}}
+
+		post := func(path string, size int, chunked bool) string {
+			body, contentType := multipartBody(t, "file", "big.bin", size)
+			var reader io.Reader = body
+			if chunked {
+				reader = struct{ io.Reader }{body} // hides the length
+			}
+			resp, err := client.Post(base+path, contentType, reader)
+			assert.Nil(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, resp.StatusCode, consts.StatusOK)
+			respBody, _ := io.ReadAll(resp.Body)
+			return string(respBody)
+		}
+
+		// Over the threshold - streamed
+		assert.Equal(t, post("/parts", 512*1024, false), "body 0; file 524288;")
+		assert.Equal(t, post("/parts", 100, true), "body 0; file 100;")
+
+		// Under it - buffered, but readable by part too
+		assert.Equal(t, post("/parts", 100, false), fmt.Sprintf("body %d; file 100;", 100+bodyOverhead(t)))
+
+		// The connection survives bodies the handler ignores
+		assert.Equal(t, post("/ignore", 64*1024, false), "ignored")
+		assert.Equal(t, post("/ignore", 64*1024, true), "ignored")
+		assert.Equal(t, post("/parts", 2048, false), "body 0; file 2048;")
+	}()
+
+	_ = s.Run()
+
+	// Only multipart bodies have parts
+	response := s.Request(consts.MethodPost, "/parts", nil, nil)
+	assert.Equal(t, response.Status(), consts.StatusInternalServerError)
+}
+
+// bodyOverhead returns the size of a single file multipart body, less the file
+func bodyOverhead(t *testing.T) int {
+	body, _ := multipartBody(t, "file", "big.bin", 0)
+	return body.Len()
+}
@@ -5,13 +5,18 @@ import (
 	"crypto/sha1"
 	"encoding/base64"
 	"encoding/binary"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/rohanthewiz/rweb/consts"
 )
 
 // WebSocket opcodes as defined in RFC 6455
@@ -24,20 +29,37 @@ const (
 	wsPong         = 0xA
 )
 
-// WebSocket close codes
+// WebSocket close codes (RFC 6455, section 7.4.1), e.g. for ws.Close(rweb.CloseNormalClosure, "bye").
+// 1005, 1006 and 1015 are never sent, but reported for connections closed without a code, abnormally, or in the TLS handshake.
+const (
+	CloseNormalClosure           = 1000
+	CloseGoingAway               = 1001
+	CloseProtocolError           = 1002
+	CloseUnsupportedData         = 1003
+	CloseNoStatusReceived        = 1005
+	CloseAbnormalClosure         = 1006
+	CloseInvalidFramePayloadData = 1007
+	ClosePolicyViolation         = 1008
+	CloseMessageTooBig           = 1009
+	CloseMandatoryExtension      = 1010
+	CloseInternalServerErr       = 1011
+	CloseTLSHandshake            = 1015
+)
+
+// Internal names of the close codes
 const (
-	wsCloseNormalClosure           = 1000
-	wsCloseGoingAway               = 1001
-	wsCloseProtocolError           = 1002
-	wsCloseUnsupportedData         = 1003
-	wsCloseNoStatusReceived        = 1005
-	wsCloseAbnormalClosure         = 1006
-	wsCloseInvalidFramePayloadData = 1007
-	wsClosePolicyViolation         = 1008
-	wsCloseMessageTooBig           = 1009
-	wsCloseMandatoryExtension      = 1010
-	wsCloseInternalServerErr       = 1011
-	wsCloseTLSHandshake            = 1015
+	wsCloseNormalClosure           = CloseNormalClosure
+	wsCloseGoingAway               = CloseGoingAway
+	wsCloseProtocolError           = CloseProtocolError
+	wsCloseUnsupportedData         = CloseUnsupportedData
+	wsCloseNoStatusReceived        = CloseNoStatusReceived
+	wsCloseAbnormalClosure         = CloseAbnormalClosure
+	wsCloseInvalidFramePayloadData = CloseInvalidFramePayloadData
+	wsClosePolicyViolation         = ClosePolicyViolation
+	wsCloseMessageTooBig           = CloseMessageTooBig
+	wsCloseMandatoryExtension      = CloseMandatoryExtension
+	wsCloseInternalServerErr       = CloseInternalServerErr
+	wsCloseTLSHandshake            = CloseTLSHandshake
 )
 
 // WebSocket errors
@@ -47,6 +69,9 @@ var (
 	ErrWebSocketInvalidOpcode   = errors.New("invalid websocket opcode")
 	ErrWebSocketPayloadTooLarge = errors.New("websocket payload too large")
 	ErrWebSocketBadMask         = errors.New("websocket frame not masked")
+	ErrWebSocketLimitReached    = errors.New("websocket connection limit reached")
+	ErrWebSocketOriginRefused   = errors.New("websocket origin not allowed")
+	ErrWebSocketWriteTimeout    = errors.New("websocket write timed out")
 )
 
 // WebSocket GUID as per RFC 6455
@@ -54,13 +79,30 @@ const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
 
 // Default WebSocket configuration values
 const (
-	defaultMaxMessageSize  = 1024 * 1024 * 10 // 10MB
-	defaultPingInterval    = 30 * time.Second
-	defaultPongTimeout     = 10 * time.Second
-	defaultWriteTimeout    = 10 * time.Second
-	closeHandshakeTimeout  = 2 * time.Second  // max wait for peer's close frame response
+	defaultMaxMessageSize = 1024 * 1024 * 10 // 10MB
+	defaultPingInterval   = 30 * time.Second
+	defaultPongTimeout    = 10 * time.Second
+	defaultWriteTimeout   = 10 * time.Second
+	closeHandshakeTimeout = 2 * time.Second // max wait for peer's close frame response
+	defaultWSRetryAfter   = 5 * time.Second // back-off suggested when the connection limit is reached
 )
 
+// WSUpgradeOptions configures the upgrade of a connection to WebSocket
+type WSUpgradeOptions struct {
+	// Subprotocols lists the subprotocols (e.g. "graphql-ws") the server supports.
+	// The first one the client offers is agreed in the handshake.
+	// If the client offers none of them (or the list is empty), no subprotocol is used.
+	Subprotocols []string
+	// EnableCompression agrees to the permessage-deflate extension, if the client offers it,
+	// compressing the data messages sent, and decompressing those received.
+	// It pays off for larger, repetitive messages like JSON, at the cost of CPU
+	// and some memory per connection for the compression state.
+	EnableCompression bool
+	// CheckOrigin, when set, decides whether to allow an upgrade from the request's origin, e.g. rweb.SameOrigin.
+	// It takes precedence over the server's WebSocketCfg.CheckOrigin. Refused upgrades get a 403.
+	CheckOrigin func(ctx Context) bool
+}
+
 // WSMessage represents a WebSocket message
 type WSMessage struct {
 	Type MessageType
@@ -87,24 +129,36 @@ const (
 type WSConn struct {
 	conn           net.Conn
 	isServer       bool
-	closed         bool
-	closeMutex     sync.Mutex
-	writeMutex     sync.Mutex
+	closed         atomic.Bool // set once, by whichever of Close, handleClose, abort or a timed out write is first
+	writeMutex     sync.Mutex  // serializes frame writes, close frames included
 	maxMessageSize int64
 	closeHandlers  []func(code int, text string)
+	closeNotified  atomic.Bool // the close handlers have run
 	pingHandler    func([]byte) error
 	pongHandler    func([]byte) error
 	readDeadline   time.Time
-	writeDeadline  time.Time
+	readTimeout    time.Duration // when set, each frame must arrive within this of starting to wait for it
+	pongTimeout    time.Duration // with EnableAutoPing, how long the peer has to answer a ping
+	lastPong       atomic.Int64  // when the last pong was read, in Unix nanoseconds
+	writeDeadline  atomic.Int64  // set by SetWriteDeadline, in Unix nanoseconds, 0 if none
+	writeTimeout   time.Duration // when no write deadline is set, each frame must be written within this
+	subprotocol    string        // negotiated in the handshake
+	deflate        *wsDeflate    // permessage-deflate state, when negotiated in the handshake
+	readRSV1       bool          // the RSV1 bit of the last frame read, set on the first frame of a compressed message
 
 	// done is closed when the connection shuts down, enabling goroutines
 	// (e.g., ping tickers) to detect closure and exit cleanly.
 	done     chan struct{}
 	doneOnce sync.Once
 
+	// values carried over from the request context (see Get), and set on the connection
+	dataMu sync.RWMutex
+	data   map[string]any
+
 	// for managing fragmented messages
-	fragmentedMessage []byte
-	fragmentedType    MessageType
+	fragmentedMessage    []byte
+	fragmentedType       MessageType
+	fragmentedCompressed bool
 }
 
 // NewWSConn creates a new WebSocket connection from an existing net.Conn
@@ -114,6 +168,8 @@ func NewWSConn(conn net.Conn, isServer bool) *WSConn {
 		conn:           conn,
 		isServer:       isServer,
 		maxMessageSize: defaultMaxMessageSize,
+		pongTimeout:    defaultPongTimeout,
+		writeTimeout:   defaultWriteTimeout,
 		closeHandlers:  make([]func(int, string), 0),
 		done:           make(chan struct{}),
 	}
@@ -133,7 +189,7 @@ func NewWSConn(conn net.Conn, isServer bool) *WSConn {
 
 // performHandshake performs the WebSocket handshake on the server side
 // This validates the client's request and sends the appropriate response
-func performHandshake(ctx *context) error {
+func performHandshake(ctx *context, opts WSUpgradeOptions) error {
 	// Check for required headers
 	if ctx.request.Header("Upgrade") != "websocket" {
 		return errors.New("missing or invalid Upgrade header")
@@ -164,32 +220,115 @@ func performHandshake(ctx *context) error {
 	ctx.response.SetHeader("Connection", "Upgrade")
 	ctx.response.SetHeader("Sec-WebSocket-Accept", acceptKey)
 
-	// Handle optional protocol header
-	if protocol := ctx.request.Header("Sec-WebSocket-Protocol"); protocol != "" {
-		// For now, accept the first protocol offered
-		// In a real implementation, you'd validate against supported protocols
-		protocols := strings.Split(protocol, ",")
-		if len(protocols) > 0 {
-			ctx.response.SetHeader("Sec-WebSocket-Protocol", strings.TrimSpace(protocols[0]))
+	// Agree on a subprotocol, if the client offered any we support
+	if protocol := selectSubprotocol(ctx.request.Header("Sec-WebSocket-Protocol"), opts.Subprotocols); protocol != "" {
+		ctx.response.SetHeader("Sec-WebSocket-Protocol", protocol)
+	}
+
+	// Agree to compression, if enabled and offered
+	if opts.EnableCompression {
+		if extension := negotiateDeflate(ctx.request.Header(consts.HeaderSecWebSocketExtensions)); extension != "" {
+			ctx.response.SetHeader(consts.HeaderSecWebSocketExtensions, extension)
 		}
 	}
 
 	return nil
 }
 
+// selectSubprotocol returns the first of the client's offered subprotocols (a comma separated list)
+// that the server supports, or "" if there is none in common.
+func selectSubprotocol(offered string, supported []string) string {
+	for _, protocol := range strings.Split(offered, ",") {
+		protocol = strings.TrimSpace(protocol)
+		if protocol != "" && slices.Contains(supported, protocol) {
+			return protocol
+		}
+	}
+	return ""
+}
+
+// Compressed reports whether the permessage-deflate extension was agreed with the client during the handshake,
+// so messages are compressed.
+func (ws *WSConn) Compressed() bool {
+	return ws.deflate != nil
+}
+
+// Subprotocol returns the subprotocol agreed with the client during the handshake,
+// or "" if none was negotiated.
+func (ws *WSConn) Subprotocol() string {
+	return ws.subprotocol
+}
+
+// Get returns a value stored on the connection. The request context's values (ctx.Set),
+// e.g. the user set by auth middleware, are carried over when the connection is upgraded.
+// Returns nil if the key doesn't exist.
+func (ws *WSConn) Get(key string) any {
+	ws.dataMu.RLock()
+	defer ws.dataMu.RUnlock()
+	return ws.data[key]
+}
+
+// Set stores a value on the connection, for the life of the connection, e.g. for a hub to tell its clients apart.
+// Safe for concurrent use.
+func (ws *WSConn) Set(key string, value any) {
+	ws.dataMu.Lock()
+	defer ws.dataMu.Unlock()
+	if ws.data == nil {
+		ws.data = make(map[string]any)
+	}
+	ws.data[key] = value
+}
+
 // ReadMessage reads a complete message from the WebSocket connection
-// It handles fragmentation and returns the complete message
+// It handles fragmentation and returns the complete message.
+// An error ends the connection, so the close handlers run then (see OnClose).
 func (ws *WSConn) ReadMessage() (*WSMessage, error) {
+	msg, err := ws.readMessage()
+	if err != nil {
+		ws.notifyClose(wsCloseAbnormalClosure, err.Error())
+	}
+	return msg, err
+}
+
+// readMessage reads a complete message, assembling any fragments
+func (ws *WSConn) readMessage() (*WSMessage, error) {
 	for {
+		// (Re)apply the read deadline for every frame, so a timeout keeps being enforced,
+		// and control frames like pongs count as signs of life
+		if ws.readTimeout > 0 {
+			ws.readDeadline = time.Now().Add(ws.readTimeout)
+		}
+		if !ws.readDeadline.IsZero() {
+			if err := ws.conn.SetReadDeadline(ws.readDeadline); err != nil {
+				return nil, err
+			}
+		}
+
 		frameType, fin, data, err := ws.readFrame()
 		if err != nil {
+			if errors.Is(err, ErrWebSocketPayloadTooLarge) {
+				return nil, ws.messageTooBig()
+			}
 			return nil, err
 		}
 
+		// Only the first frame of a data message may be marked compressed
+		if ws.readRSV1 && frameType != wsText && frameType != wsBinary {
+			return nil, errors.New("unexpected compressed websocket frame")
+		}
+
 		switch frameType {
 		case wsText, wsBinary:
 			if fin {
 				// Unfragmented message — the common fast path
+				if ws.readRSV1 {
+					if data, err = ws.deflate.decompress(data, ws.maxMessageSize); err != nil {
+						if errors.Is(err, ErrWebSocketPayloadTooLarge) {
+							return nil, ws.messageTooBig()
+						}
+						return nil, err
+					}
+				}
 				return &WSMessage{
 					Type: MessageType(frameType),
 					Data: data,
@@ -197,6 +336,7 @@ func (ws *WSConn) ReadMessage() (*WSMessage, error) {
 			}
 			// Start of a fragmented message (FIN=0 on first frame per RFC 6455 §5.4)
 			ws.fragmentedType = MessageType(frameType)
+			ws.fragmentedCompressed = ws.readRSV1
 			ws.fragmentedMessage = append(ws.fragmentedMessage[:0], data...)
 
 		case wsContinuation:
@@ -204,6 +344,11 @@ func (ws *WSConn) ReadMessage() (*WSMessage, error) {
 			if ws.fragmentedMessage == nil {
 				return nil, errors.New("unexpected continuation frame")
 			}
+			// The limit applies to the whole message, not just each frame
+			if int64(len(ws.fragmentedMessage))+int64(len(data)) > ws.maxMessageSize {
+				ws.fragmentedMessage = nil
+				return nil, ws.messageTooBig()
+			}
 			ws.fragmentedMessage = append(ws.fragmentedMessage, data...)
 			if fin {
 				// Final fragment — assemble and return the complete message
@@ -212,6 +357,14 @@ func (ws *WSConn) ReadMessage() (*WSMessage, error) {
 					Data: ws.fragmentedMessage,
 				}
 				ws.fragmentedMessage = nil
+				if ws.fragmentedCompressed {
+					if msg.Data, err = ws.deflate.decompress(msg.Data, ws.maxMessageSize); err != nil {
+						if errors.Is(err, ErrWebSocketPayloadTooLarge) {
+							return nil, ws.messageTooBig()
+						}
+						return nil, err
+					}
+				}
 				return msg, nil
 			}
 			// More fragments expected — keep reading
@@ -239,7 +392,8 @@ func (ws *WSConn) ReadMessage() (*WSMessage, error) {
 			// Continue reading for the next message
 
 		case wsPong:
-			// Handle pong frame
+			// Handle pong frame - noting it for EnableAutoPing, whatever the handler
+			ws.lastPong.Store(time.Now().UnixNano())
 			if ws.pongHandler != nil {
 				if err := ws.pongHandler(data); err != nil {
 					return nil, err
@@ -253,18 +407,48 @@ func (ws *WSConn) ReadMessage() (*WSMessage, error) {
 	}
 }
 
+// messageTooBig closes the connection with 1009 (message too big), for a message over the max message size,
+// returning ErrWebSocketPayloadTooLarge. The rest of the message is left unread, so the connection can't go on.
+func (ws *WSConn) messageTooBig() error {
+	ws.abort(wsCloseMessageTooBig, "message too big")
+	return ErrWebSocketPayloadTooLarge
+}
+
 // WriteMessage writes a message to the WebSocket connection
 func (ws *WSConn) WriteMessage(messageType MessageType, data []byte) error {
 	ws.writeMutex.Lock()
 	defer ws.writeMutex.Unlock()
 
-	if ws.closed {
+	if ws.closed.Load() {
 		return ErrWebSocketAlreadyClosed
 	}
 
 	return ws.writeFrame(int(messageType), data)
 }
 
+// WriteJSON sends v, encoded as JSON, in a text message
+func (ws *WSConn) WriteJSON(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return ws.WriteMessage(TextMessage, data)
+}
+
+// ReadJSON reads the next data message (text or binary), and decodes it as JSON into v.
+// Returns ErrWebSocketAlreadyClosed if the peer closes the connection instead,
+// or the decoding error if the message isn't valid JSON for v.
+func (ws *WSConn) ReadJSON(v any) error {
+	msg, err := ws.ReadMessage()
+	if err != nil {
+		return err
+	}
+	if msg.Type == CloseMessage {
+		return ErrWebSocketAlreadyClosed
+	}
+	return json.Unmarshal(msg.Data, v)
+}
+
 // readFrame reads a single WebSocket frame, returning the opcode, FIN bit, and payload.
 // The FIN bit indicates whether this is the final fragment of a message (RFC 6455 §5.2).
 func (ws *WSConn) readFrame() (opcode int, fin bool, payload []byte, err error) {
@@ -274,10 +458,16 @@ func (ws *WSConn) readFrame() (opcode int, fin bool, payload []byte, err error)
 		return 0, false, nil, err
 	}
 
-	// Parse first byte — FIN (bit 0) and opcode (bits 4-7)
+	// Parse first byte — FIN (bit 0), RSV1-3 (bits 1-3) and opcode (bits 4-7)
 	fin = (header[0] & 0x80) != 0
+	ws.readRSV1 = (header[0] & 0x40) != 0
 	opcode = int(header[0] & 0x0F)
 
+	// RSV1 marks a compressed message, when permessage-deflate is in use. RSV2 and RSV3 are unused.
+	if header[0]&0x30 != 0 || ws.readRSV1 && ws.deflate == nil {
+		return 0, false, nil, errors.New("websocket frame has reserved bits set")
+	}
+
 	// Parse second byte
 	masked := (header[1] & 0x80) != 0
 	payloadLen := int64(header[1] & 0x7F)
@@ -335,72 +525,90 @@ func (ws *WSConn) readFrame() (opcode int, fin bool, payload []byte, err error)
 	return opcode, fin, payload, nil
 }
 
-// writeFrame writes a WebSocket frame
+// writeFrame writes a WebSocket frame, within the write deadline (see SetWriteDeadline and SetWriteTimeout).
+// If the write times out, the connection is marked closed, as by Close, so later writes and Close find it so.
+// The caller holds writeMutex.
 func (ws *WSConn) writeFrame(opcode int, data []byte) error {
-	if ws.writeDeadline.After(time.Now()) {
-		ws.conn.SetWriteDeadline(ws.writeDeadline)
+	err := ws.writeFrameBy(ws.frameWriteDeadline(), opcode, data)
+	if errors.Is(err, ErrWebSocketWriteTimeout) {
+		ws.closed.Store(true)
+		ws.doneOnce.Do(func() { close(ws.done) })
 	}
+	return err
+}
 
-	// Create frame header
-	header := make([]byte, 2)
-	header[0] = 0x80 | byte(opcode) // FIN = 1, opcode
+// frameWriteDeadline returns the deadline for writing a frame now - that set by SetWriteDeadline,
+// else the write timeout from now. The zero time means none.
+func (ws *WSConn) frameWriteDeadline() time.Time {
+	if deadline := ws.writeDeadline.Load(); deadline != 0 {
+		return time.Unix(0, deadline)
+	}
+	if ws.writeTimeout > 0 {
+		return time.Now().Add(ws.writeTimeout)
+	}
+	return time.Time{}
+}
+
+// writeFrameBy writes a WebSocket frame, failing with ErrWebSocketWriteTimeout if it isn't written by deadline.
+// A frame may then be partly written, so the connection is of no further use, and is closed.
+// The caller holds writeMutex, so frames aren't interleaved.
+func (ws *WSConn) writeFrameBy(deadline time.Time, opcode int, data []byte) error {
+	_ = ws.conn.SetWriteDeadline(deadline)
+
+	// Create frame header, with room for the longest one and the payload
+	frame := make([]byte, 2, 14+len(data))
+	frame[0] = 0x80 | byte(opcode) // FIN = 1, opcode
+
+	// With permessage-deflate, data messages are sent compressed, flagged by RSV1
+	if ws.deflate != nil && (opcode == wsText || opcode == wsBinary) {
+		compressed, err := ws.deflate.compress(data)
+		if err != nil {
+			return err
+		}
+		data = compressed
+		frame[0] |= 0x40
+	}
 
 	dataLen := len(data)
 	if !ws.isServer {
-		header[1] = 0x80 // Set mask bit for client frames
+		frame[1] = 0x80 // Set mask bit for client frames
 	}
 
 	// Determine payload length encoding
-	var extLen []byte
 	if dataLen < 126 {
-		header[1] |= byte(dataLen)
+		frame[1] |= byte(dataLen)
 	} else if dataLen <= 65535 {
-		header[1] |= 126
-		extLen = make([]byte, 2)
-		binary.BigEndian.PutUint16(extLen, uint16(dataLen))
+		frame[1] |= 126
+		frame = binary.BigEndian.AppendUint16(frame, uint16(dataLen))
 	} else {
-		header[1] |= 127
-		extLen = make([]byte, 8)
-		binary.BigEndian.PutUint64(extLen, uint64(dataLen))
+		frame[1] |= 127
+		frame = binary.BigEndian.AppendUint64(frame, uint64(dataLen))
 	}
 
-	// Write header
-	if _, err := ws.conn.Write(header); err != nil {
-		return err
-	}
-
-	// Write extended length if needed
-	if extLen != nil {
-		if _, err := ws.conn.Write(extLen); err != nil {
-			return err
-		}
-	}
-
-	// Write mask and masked data for client frames
+	// Mask and masked data for client frames
 	if !ws.isServer {
 		mask := make([]byte, 4)
 		if _, err := rand.Read(mask); err != nil {
 			return err
 		}
+		frame = append(frame, mask...)
 
-		if _, err := ws.conn.Write(mask); err != nil {
-			return err
-		}
-
-		// Write masked payload
-		masked := make([]byte, len(data))
 		for i := range data {
-			masked[i] = data[i] ^ mask[i%4]
-		}
-
-		if _, err := ws.conn.Write(masked); err != nil {
-			return err
+			frame = append(frame, data[i]^mask[i%4])
 		}
 	} else {
 		// Server frames are not masked
-		if _, err := ws.conn.Write(data); err != nil {
-			return err
+		frame = append(frame, data...)
+	}
+
+	// The whole frame in one write, so the deadline covers all of it
+	if _, err := ws.conn.Write(frame); err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			_ = ws.conn.Close()
+			return fmt.Errorf("%w: %w", ErrWebSocketWriteTimeout, err)
 		}
+		return err
 	}
 
 	return nil
@@ -408,28 +616,25 @@ func (ws *WSConn) writeFrame(opcode int, data []byte) error {
 
 // Close closes the WebSocket connection with the given code and reason
 func (ws *WSConn) Close(code int, reason string) error {
-	ws.closeMutex.Lock()
-	defer ws.closeMutex.Unlock()
+	ws.notifyClose(code, reason)
 
-	if ws.closed {
+	if !ws.closed.CompareAndSwap(false, true) {
 		return nil
 	}
+	ws.doneOnce.Do(func() { close(ws.done) })
 
-	// Send close frame
+	// Send close frame, after any frame being written
 	data := make([]byte, 2+len(reason))
 	binary.BigEndian.PutUint16(data[:2], uint16(code))
 	copy(data[2:], reason)
 
-	if err := ws.writeFrame(wsClose, data); err != nil {
-		// Even if writing the close frame fails, mark as closed
-		ws.closed = true
-		ws.doneOnce.Do(func() { close(ws.done) })
+	ws.writeMutex.Lock()
+	err := ws.writeFrameBy(ws.frameWriteDeadline(), wsClose, data)
+	ws.writeMutex.Unlock()
+	if err != nil {
 		return ws.conn.Close()
 	}
 
-	ws.closed = true
-	ws.doneOnce.Do(func() { close(ws.done) })
-
 	// Wait for the peer's close frame response using a read deadline
 	// instead of a blind sleep. Returns immediately when the frame arrives,
 	// or after closeHandshakeTimeout if the peer is unresponsive.
@@ -441,24 +646,19 @@ func (ws *WSConn) Close(code int, reason string) error {
 
 // handleClose handles an incoming close frame
 func (ws *WSConn) handleClose(code int, text string) {
-	ws.closeMutex.Lock()
-	defer ws.closeMutex.Unlock()
+	ws.notifyClose(code, text) // unless we closed first
 
-	if ws.closed {
+	if !ws.closed.CompareAndSwap(false, true) {
 		return
 	}
-
-	// Call close handlers
-	for _, handler := range ws.closeHandlers {
-		handler(code, text)
-	}
+	ws.doneOnce.Do(func() { close(ws.done) })
 
 	// Send close response
-	ws.closed = true
-	ws.doneOnce.Do(func() { close(ws.done) })
 	data := make([]byte, 2)
 	binary.BigEndian.PutUint16(data, uint16(code))
-	ws.writeFrame(wsClose, data)
+	ws.writeMutex.Lock()
+	ws.writeFrameBy(ws.frameWriteDeadline(), wsClose, data)
+	ws.writeMutex.Unlock()
 	ws.conn.Close()
 }
 
@@ -467,7 +667,7 @@ func (ws *WSConn) writePong(data []byte) error {
 	ws.writeMutex.Lock()
 	defer ws.writeMutex.Unlock()
 
-	if ws.closed {
+	if ws.closed.Load() {
 		return ErrWebSocketAlreadyClosed
 	}
 
@@ -479,7 +679,7 @@ func (ws *WSConn) WritePing(data []byte) error {
 	ws.writeMutex.Lock()
 	defer ws.writeMutex.Unlock()
 
-	if ws.closed {
+	if ws.closed.Load() {
 		return ErrWebSocketAlreadyClosed
 	}
 
@@ -496,12 +696,27 @@ func (ws *WSConn) SetPongHandler(handler func([]byte) error) {
 	ws.pongHandler = handler
 }
 
-// OnClose adds a close handler
+// OnClose adds a handler called once when the connection ends, however it ends: with the peer's code and text
+// when it closes, the code and reason given to Close, the code of an automatic close (e.g. 1001 on a pong timeout,
+// 1009 for a message too big), or 1006 (abnormal closure) when reading fails (the error's text is given)
+// or the handler returns without closing. Add handlers before reading or closing.
 func (ws *WSConn) OnClose(handler func(code int, text string)) {
 	ws.closeHandlers = append(ws.closeHandlers, handler)
 }
 
-// SetMaxMessageSize sets the maximum message size
+// notifyClose calls the close handlers, the first time the connection is found to be ending only.
+// Handlers may use the connection (e.g. Close it), as no lock is held.
+func (ws *WSConn) notifyClose(code int, text string) {
+	if !ws.closeNotified.CompareAndSwap(false, true) {
+		return
+	}
+	for _, handler := range ws.closeHandlers {
+		handler(code, text)
+	}
+}
+
+// SetMaxMessageSize sets the maximum message size, for whole messages however they are fragmented.
+// A larger message makes ReadMessage return ErrWebSocketPayloadTooLarge, closing the connection with 1009.
 func (ws *WSConn) SetMaxMessageSize(size int64) {
 	ws.maxMessageSize = size
 }
@@ -512,10 +727,116 @@ func (ws *WSConn) SetReadDeadline(t time.Time) error {
 	return ws.conn.SetReadDeadline(t)
 }
 
-// SetWriteDeadline sets the write deadline
+// SetReadTimeout sets how long ReadMessage waits for each frame from the peer.
+// Any frame, including a pong, resets the clock, so together with EnableAutoPing
+// a peer that has gone away is detected within the timeout:
+// ReadMessage returns a timeout error (a net.Error whose Timeout() is true).
+// A timeout of zero disables it.
+func (ws *WSConn) SetReadTimeout(timeout time.Duration) {
+	ws.readTimeout = timeout
+}
+
+// SetPongTimeout sets how long the peer has to answer each ping from EnableAutoPing (default 10s)
+// before the connection is closed as dead.
+func (ws *WSConn) SetPongTimeout(timeout time.Duration) {
+	if timeout > 0 {
+		ws.pongTimeout = timeout
+	}
+}
+
+// EnableAutoPing starts sending pings to the peer every interval (default 30s if interval <= 0),
+// until the connection is closed. Healthy peers answer with pongs. A peer that doesn't answer
+// within the pong timeout (see SetPongTimeout) has gone silent, so the connection is closed
+// with 1001 (going away), which ends any blocked ReadMessage with an error.
+// Pongs are seen by ReadMessage, so keep reading the connection, as a read loop does anyway.
+// Pongs also keep a read timeout (see SetReadTimeout) from expiring on connections that are just quiet.
+func (ws *WSConn) EnableAutoPing(interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultPingInterval
+	}
+	pongTimeout := ws.pongTimeout
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		pongDeadline := time.NewTimer(pongTimeout)
+		pongDeadline.Stop()
+		defer pongDeadline.Stop()
+
+		var awaiting int64 // when the oldest unanswered ping was sent, in Unix nanoseconds, 0 if none
+		for {
+			select {
+			case <-ws.done:
+				return
+
+			case <-ticker.C:
+				if awaiting != 0 && ws.lastPong.Load() >= awaiting {
+					awaiting = 0 // answered
+				}
+				sent := time.Now().UnixNano()
+				if err := ws.WritePing(nil); err != nil {
+					return
+				}
+				if awaiting == 0 {
+					awaiting = sent
+					pongDeadline.Reset(pongTimeout)
+				}
+
+			case <-pongDeadline.C:
+				if ws.lastPong.Load() < awaiting {
+					ws.abort(wsCloseGoingAway, "pong timeout")
+					return
+				}
+				awaiting = 0
+			}
+		}
+	}()
+}
+
+// abort closes the connection with a close frame, without waiting for the peer's answer,
+// as when the peer has gone silent. Unlike Close, it doesn't read from the connection,
+// so it is safe alongside a blocked ReadMessage, which returns an error once the connection is closed.
+// Nor does it wait on a write under way, as one blocked on a peer that stopped reading:
+// the connection is closed without a close frame, which couldn't follow the unfinished frame anyway.
+func (ws *WSConn) abort(code int, reason string) {
+	ws.notifyClose(code, reason)
+
+	if !ws.closed.CompareAndSwap(false, true) {
+		return
+	}
+	ws.doneOnce.Do(func() { close(ws.done) })
+
+	if ws.writeMutex.TryLock() {
+		data := make([]byte, 2+len(reason))
+		binary.BigEndian.PutUint16(data[:2], uint16(code))
+		copy(data[2:], reason)
+
+		_ = ws.writeFrameBy(time.Now().Add(closeHandshakeTimeout), wsClose, data) // a dead peer may not take the frame
+		ws.writeMutex.Unlock()
+	}
+
+	_ = ws.conn.Close()
+}
+
+// SetWriteDeadline sets the deadline for all writes (messages, pings, pongs and the close frame),
+// in place of the write timeout. It applies to a write already blocked, too.
+// A write not done by the deadline fails with ErrWebSocketWriteTimeout, closing the connection.
+// A zero t clears the deadline, restoring the write timeout.
 func (ws *WSConn) SetWriteDeadline(t time.Time) error {
-	ws.writeDeadline = t
-	return nil // Applied on next write
+	if t.IsZero() {
+		ws.writeDeadline.Store(0)
+		return nil // a write under way keeps its deadline
+	}
+	ws.writeDeadline.Store(t.UnixNano())
+	return ws.conn.SetWriteDeadline(t)
+}
+
+// SetWriteTimeout sets how long each frame may take to be written (default 10s), so a peer that
+// stops reading can't block writes indefinitely - the write fails with ErrWebSocketWriteTimeout,
+// closing the connection. A WSHub, for one, then drops the connection.
+// A timeout of zero disables it. Set it before writing.
+func (ws *WSConn) SetWriteTimeout(timeout time.Duration) {
+	ws.writeTimeout = timeout
 }
 
 // LocalAddr returns the local network address
@@ -0,0 +1,141 @@
+package rweb
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// permessage-deflate (RFC 7692) compresses the payload of each data message with DEFLATE.
+// Unless a side has agreed to "no context takeover", its compressor keeps its sliding window
+// between messages, so repetitive traffic (e.g. JSON with the same keys) compresses very well.
+const (
+	wsDeflateExtension = "permessage-deflate"
+	wsDeflateWindow    = 32 << 10 // the 15-bit window flate always uses
+	// wsDeflateTail is appended to a compressed message for decompression: the 0x00 0x00 0xff 0xff
+	// the sender strips from the end of the message, then an empty final block to end the stream
+	wsDeflateTail = "\x00\x00\xff\xff\x01\x00\x00\xff\xff"
+)
+
+// wsDeflate is the permessage-deflate state of a connection
+type wsDeflate struct {
+	// Whether the compression state is reset for every message we send, or the peer sends
+	writeNoContextTakeover bool
+	readNoContextTakeover  bool
+
+	writer    *flate.Writer
+	writeBuf  bytes.Buffer
+	reader    io.ReadCloser
+	readDict  []byte // the last wsDeflateWindow bytes of decompressed messages, with context takeover
+	readInput bytes.Reader
+}
+
+// negotiateDeflate chooses the first permessage-deflate offer in a Sec-WebSocket-Extensions header
+// whose parameters we can accept, returning the response to it, or "" if there is none.
+// flate only supports a full size window, so offers limiting the server's window are declined,
+// while those allowing for a smaller client window are fine (the client just uses the default).
+func negotiateDeflate(offers string) string {
+	for _, offer := range strings.Split(offers, ",") {
+		params := strings.Split(offer, ";")
+		if strings.TrimSpace(params[0]) != wsDeflateExtension {
+			continue
+		}
+
+		response := []string{wsDeflateExtension}
+		acceptable := true
+		for _, param := range params[1:] {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			name = strings.TrimSpace(name)
+			value = strings.Trim(strings.TrimSpace(value), `"`)
+
+			switch {
+			case name == "server_no_context_takeover" || name == "client_no_context_takeover":
+				if slices.Contains(response, name) {
+					acceptable = false // duplicates are invalid
+				}
+				response = append(response, name)
+			case name == "server_max_window_bits":
+				acceptable = acceptable && value == "15"
+			case name == "client_max_window_bits":
+				bits, err := strconv.Atoi(value)
+				acceptable = acceptable && (value == "" || err == nil && bits >= 8 && bits <= 15)
+			default:
+				acceptable = false
+			}
+		}
+		if acceptable {
+			return strings.Join(response, "; ")
+		}
+	}
+	return ""
+}
+
+// newWSDeflate returns the deflate state for a connection, from the negotiated extension parameters
+func newWSDeflate(negotiated string, isServer bool) *wsDeflate {
+	serverNoTakeover := strings.Contains(negotiated, "server_no_context_takeover")
+	clientNoTakeover := strings.Contains(negotiated, "client_no_context_takeover")
+
+	if isServer {
+		return &wsDeflate{writeNoContextTakeover: serverNoTakeover, readNoContextTakeover: clientNoTakeover}
+	}
+	return &wsDeflate{writeNoContextTakeover: clientNoTakeover, readNoContextTakeover: serverNoTakeover}
+}
+
+// compress returns the compressed payload of a message.
+// The result is only valid until the next call.
+func (d *wsDeflate) compress(data []byte) ([]byte, error) {
+	d.writeBuf.Reset()
+	if d.writer == nil {
+		var err error
+		if d.writer, err = flate.NewWriter(&d.writeBuf, flate.BestSpeed); err != nil {
+			return nil, err
+		}
+	} else if d.writeNoContextTakeover {
+		d.writer.Reset(&d.writeBuf)
+	}
+
+	if _, err := d.writer.Write(data); err != nil {
+		return nil, err
+	}
+	// A sync flush ends the message on a byte boundary, with an empty block (0x00 0x00 0xff 0xff)
+	// which is left off the wire
+	if err := d.writer.Flush(); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(d.writeBuf.Bytes(), []byte(wsDeflateTail[:4])), nil
+}
+
+// decompress returns the decompressed payload of a message, of at most maxSize bytes
+func (d *wsDeflate) decompress(data []byte, maxSize int64) ([]byte, error) {
+	if d.readNoContextTakeover {
+		d.readDict = nil
+	}
+
+	d.readInput.Reset(slices.Concat(data, []byte(wsDeflateTail)))
+	if d.reader == nil {
+		d.reader = flate.NewReaderDict(&d.readInput, d.readDict)
+	} else if err := d.reader.(flate.Resetter).Reset(&d.readInput, d.readDict); err != nil {
+		return nil, err
+	}
+
+	out, err := io.ReadAll(io.LimitReader(d.reader, maxSize+1))
+	if err != nil {
+		return nil, errors.Join(errors.New("invalid compressed websocket message"), err)
+	}
+	if int64(len(out)) > maxSize {
+		return nil, ErrWebSocketPayloadTooLarge
+	}
+
+	if !d.readNoContextTakeover {
+		// The peer's compressor can refer back to anything in its window
+		d.readDict = append(d.readDict, out...)
+		if n := len(d.readDict); n > wsDeflateWindow {
+			d.readDict = slices.Clone(d.readDict[n-wsDeflateWindow:])
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,136 @@
+package rweb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/rohanthewiz/assert"
+)
+
+func TestNegotiateDeflate(t *testing.T) {
+	tests := []struct {
+		offers   string
+		expected string
+	}{
+		{"", ""},
+		{"x-webkit-deflate-frame", ""},
+		{"permessage-deflate", "permessage-deflate"},
+		// What browsers offer
+		{"permessage-deflate; client_max_window_bits", "permessage-deflate"},
+		{"permessage-deflate; client_max_window_bits=10", "permessage-deflate"},
+		{"permessage-deflate; server_no_context_takeover; client_no_context_takeover",
+			"permessage-deflate; server_no_context_takeover; client_no_context_takeover"},
+		{"permessage-deflate; server_max_window_bits=15", "permessage-deflate"},
+		// We can't shrink our window, so fall back to the next offer
+		{"permessage-deflate; server_max_window_bits=10, permessage-deflate; server_no_context_takeover",
+			"permessage-deflate; server_no_context_takeover"},
+		{"permessage-deflate; server_max_window_bits=10", ""},
+		{"permessage-deflate; client_max_window_bits=16", ""},
+		{"permessage-deflate; unknown_param", ""},
+		{"permessage-deflate; server_no_context_takeover; server_no_context_takeover", ""},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, negotiateDeflate(tt.offers), tt.expected)
+	}
+}
+
+func TestWebSocketDeflateFrames(t *testing.T) {
+	// The examples of RFC 7692 section 7.2.3, as sent by a client (masked)
+	hello, _ := hex.DecodeString("f248cdc9c90700")
+	helloAgain, _ := hex.DecodeString("f200110000") // referring back to the first message
+
+	srvConn, cliConn := net.Pipe()
+	defer srvConn.Close()
+	defer cliConn.Close()
+
+	server := NewWSConn(srvConn, true)
+	server.deflate = newWSDeflate("permessage-deflate", true)
+
+	go func() {
+		_ = writeRawFrame(cliConn, wsText|0x40, true, true, hello)
+		_ = writeRawFrame(cliConn, wsText|0x40, true, true, helloAgain)
+		// Fragmented - only the first frame has RSV1
+		_ = writeRawFrame(cliConn, wsText|0x40, false, true, hello[:3])
+		_ = writeRawFrame(cliConn, wsContinuation, true, true, hello[3:])
+		// Uncompressed messages are fine too
+		_ = writeRawFrame(cliConn, wsBinary, true, true, []byte("raw"))
+	}()
+
+	for _, expected := range []string{"Hello", "Hello", "Hello", "raw"} {
+		msg, err := server.ReadMessage()
+		assert.Nil(t, err)
+		assert.Equal(t, string(msg.Data), expected)
+	}
+
+	// RSV1 on a control frame is a protocol error
+	go func() {
+		_ = writeRawFrame(cliConn, wsPing|0x40, true, true, nil)
+	}()
+	_, err := server.ReadMessage()
+	assert.NotNil(t, err)
+}
+
+func TestWebSocketDeflateRoundTrip(t *testing.T) {
+	for _, extension := range []string{
+		"permessage-deflate",
+		"permessage-deflate; server_no_context_takeover; client_no_context_takeover",
+	} {
+		srvConn, cliConn := net.Pipe()
+
+		server := NewWSConn(srvConn, true)
+		server.deflate = newWSDeflate(extension, true)
+		client := NewWSConn(cliConn, false)
+		client.deflate = newWSDeflate(extension, false)
+
+		payload := []byte(strings.Repeat(`{"type":"price","symbol":"ACME","bid":101.25,"ask":101.5}`, 20))
+		go func() {
+			for range 3 {
+				_ = server.WriteMessage(TextMessage, payload)
+			}
+		}()
+
+		for range 3 {
+			opcode, _, data, err := client.readFrame()
+			assert.Nil(t, err)
+			assert.Equal(t, opcode, wsText)
+			assert.True(t, client.readRSV1)
+			assert.True(t, len(data) < len(payload)/5)
+
+			data, err = client.deflate.decompress(data, client.maxMessageSize)
+			assert.Nil(t, err)
+			assert.True(t, bytes.Equal(data, payload))
+		}
+
+		// And the other way round
+		go func() {
+			_ = client.WriteMessage(BinaryMessage, payload)
+		}()
+		msg, err := server.ReadMessage()
+		assert.Nil(t, err)
+		assert.Equal(t, msg.Type, BinaryMessage)
+		assert.True(t, bytes.Equal(msg.Data, payload))
+
+		// The size limit applies to the decompressed message, and closes the connection with 1009
+		server.SetMaxMessageSize(100)
+		closeCode := make(chan int, 1)
+		go func() {
+			_ = client.WriteMessage(TextMessage, payload)
+			opcode, _, data, err := client.readFrame()
+			if err == nil && opcode == wsClose {
+				closeCode <- int(binary.BigEndian.Uint16(data[:2]))
+			}
+			close(closeCode)
+		}()
+		_, err = server.ReadMessage()
+		assert.Equal(t, err, ErrWebSocketPayloadTooLarge)
+		assert.Equal(t, <-closeCode, wsCloseMessageTooBig)
+
+		_ = srvConn.Close()
+		_ = cliConn.Close()
+	}
+}
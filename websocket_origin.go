@@ -0,0 +1,44 @@
+package rweb
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+// SameOrigin reports whether a request comes from a page of the server's own origin, per its Origin header,
+// for use as a WebSocket CheckOrigin. The Origin's host (and port) must match the Host the request was sent to.
+// Requests without an Origin are allowed, as browsers always send one - they're from other sorts of clients,
+// which can't be hijacked by another site.
+func SameOrigin(ctx Context) bool {
+	origin := ctx.Request().Header(consts.HeaderOrigin)
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false // e.g. "null", from sandboxed pages and file: URLs
+	}
+
+	host := ctx.Request().Header(consts.HeaderHost)
+	if host == "" {
+		host = ctx.Request().Host()
+	}
+	return strings.EqualFold(u.Host, host)
+}
+
+// webSocketOriginAllowed reports whether a WebSocket upgrade is allowed from the request's origin,
+// per the CheckOrigin of the upgrade options, else of the server's WebSocket config,
+// else SameOrigin if SameOriginOnly is set. By default, any origin is allowed.
+func (ctx *context) webSocketOriginAllowed(opt WSUpgradeOptions) bool {
+	checkOrigin := opt.CheckOrigin
+	if checkOrigin == nil && ctx.server != nil {
+		cfg := ctx.server.options.WebSocket
+		checkOrigin = cfg.CheckOrigin
+		if checkOrigin == nil && cfg.SameOriginOnly {
+			checkOrigin = SameOrigin
+		}
+	}
+	return checkOrigin == nil || checkOrigin(ctx)
+}
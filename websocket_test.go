@@ -2,8 +2,11 @@ package rweb
 
 import (
 	"encoding/binary"
+	"encoding/json"
+	"errors"
 	"io"
 	"net"
+	"strings"
 	"testing"
 	"time"
 )
@@ -126,6 +129,107 @@ func TestWebSocketFragmentedMessage(t *testing.T) {
 	}
 }
 
+func TestWebSocketFragmentedMessageTooLarge(t *testing.T) {
+	server, client := newTestPair()
+	defer server.conn.Close()
+	defer client.conn.Close()
+
+	server.SetMaxMessageSize(100)
+
+	// Client sends a message within the limit, then one of many small fragments, each within it,
+	// that together go over it
+	go func() {
+		writeRawFrame(client.conn, wsText, false, true, []byte(strings.Repeat("a", 50)))
+		writeRawFrame(client.conn, wsContinuation, true, true, []byte(strings.Repeat("a", 50)))
+
+		writeRawFrame(client.conn, wsText, false, true, []byte("0123456789"))
+		for range 20 {
+			if writeRawFrame(client.conn, wsContinuation, false, true, []byte("0123456789")) != nil {
+				return // closed by the server
+			}
+		}
+		writeRawFrame(client.conn, wsContinuation, true, true, []byte("0123456789"))
+	}()
+
+	msg, err := server.ReadMessage()
+	if err != nil {
+		t.Fatalf("ReadMessage error: %v", err)
+	}
+	if len(msg.Data) != 100 {
+		t.Fatalf("expected 100 bytes, got %d", len(msg.Data))
+	}
+
+	// The server closes the connection with 1009 (message too big)
+	closeCode := make(chan int, 1)
+	go func() {
+		for {
+			opcode, _, data, err := client.readFrame()
+			if err != nil {
+				return
+			}
+			if opcode == wsClose {
+				closeCode <- int(binary.BigEndian.Uint16(data[:2]))
+				return
+			}
+		}
+	}()
+
+	if _, err = server.ReadMessage(); !errors.Is(err, ErrWebSocketPayloadTooLarge) {
+		t.Fatalf("expected ErrWebSocketPayloadTooLarge, got: %v", err)
+	}
+	select {
+	case code := <-closeCode:
+		if code != wsCloseMessageTooBig {
+			t.Fatalf("expected close code %d, got %d", wsCloseMessageTooBig, code)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no close frame received")
+	}
+}
+
+func TestWebSocketJSON(t *testing.T) {
+	server, client := newTestPair()
+	defer server.conn.Close()
+	defer client.conn.Close()
+
+	type chatMsg struct {
+		Type    string `json:"type"`
+		Content string `json:"content"`
+	}
+
+	go func() {
+		client.WriteJSON(chatMsg{Type: "chat", Content: "hi"})
+		client.WriteMessage(TextMessage, []byte("not json"))
+		writeRawFrame(client.conn, wsClose, true, true, []byte{0x03, 0xE8})
+	}()
+
+	// Sent as a text message, and decoded back
+	var msg chatMsg
+	if err := server.ReadJSON(&msg); err != nil {
+		t.Fatalf("ReadJSON error: %v", err)
+	}
+	if msg.Type != "chat" || msg.Content != "hi" {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+
+	// Malformed JSON is an error
+	var syntaxErr *json.SyntaxError
+	if err := server.ReadJSON(&msg); !errors.As(err, &syntaxErr) {
+		t.Fatalf("expected a JSON syntax error, got: %v", err)
+	}
+
+	// As is the peer closing the connection (the close is answered, so drain it)
+	go client.readFrame()
+	if err := server.ReadJSON(&msg); !errors.Is(err, ErrWebSocketAlreadyClosed) {
+		t.Fatalf("expected ErrWebSocketAlreadyClosed, got: %v", err)
+	}
+
+	// Values that can't be encoded aren't sent
+	if err := server.WriteJSON(make(chan int)); err == nil {
+		t.Fatal("expected an error encoding a channel")
+	}
+}
+
 func TestWebSocketUnexpectedContinuation(t *testing.T) {
 	server, client := newTestPair()
 	defer server.conn.Close()
@@ -336,3 +440,322 @@ func TestWebSocketDoneChannelPingPattern(t *testing.T) {
 		t.Fatal("ping goroutine did not exit after Done() was closed")
 	}
 }
+
+// --- Read timeouts and auto ping ---
+
+func TestWebSocketReadTimeout(t *testing.T) {
+	server, client := newTestPair()
+	defer server.conn.Close()
+	defer client.conn.Close()
+
+	server.SetReadTimeout(50 * time.Millisecond)
+
+	// Messages arriving within the timeout of each other keep the connection alive,
+	// though together they take longer than the timeout
+	go func() {
+		for range 4 {
+			time.Sleep(20 * time.Millisecond)
+			client.WriteMessage(TextMessage, []byte("tick"))
+		}
+	}()
+	for range 4 {
+		msg, err := server.ReadMessage()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if string(msg.Data) != "tick" {
+			t.Fatalf("expected 'tick', got %q", msg.Data)
+		}
+	}
+
+	// A silent peer times out
+	start := time.Now()
+	_, err := server.ReadMessage()
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Fatalf("expected a timeout error, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("read timeout took too long: %v", elapsed)
+	}
+}
+
+func TestWebSocketAutoPing(t *testing.T) {
+	server, client := newTestPair()
+	defer server.conn.Close()
+	defer client.conn.Close()
+
+	server.SetReadTimeout(60 * time.Millisecond)
+	server.EnableAutoPing(15 * time.Millisecond)
+
+	// The client answers pings for a while, then goes silent
+	clientStop := time.Now().Add(200 * time.Millisecond)
+	client.SetPingHandler(func(data []byte) error {
+		if time.Now().After(clientStop) {
+			return nil
+		}
+		return client.writePong(data)
+	})
+	go func() {
+		for {
+			if _, err := client.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	// The pongs keep the server's reads alive beyond the read timeout, until the client stops
+	start := time.Now()
+	_, err := server.ReadMessage()
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Fatalf("expected a timeout error, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Fatalf("read timed out after %v, despite the client answering pings", elapsed)
+	}
+}
+
+func TestWebSocketAutoPingPongTimeout(t *testing.T) {
+	server, client := newTestPair()
+	defer server.conn.Close()
+	defer client.conn.Close()
+
+	server.SetPongTimeout(40 * time.Millisecond)
+	server.EnableAutoPing(10 * time.Millisecond)
+
+	// The client answers pings for a while, then goes silent, though still reading
+	clientStop := time.Now().Add(150 * time.Millisecond)
+	client.SetPingHandler(func(data []byte) error {
+		if time.Now().After(clientStop) {
+			return nil
+		}
+		return client.writePong(data)
+	})
+	closeCode := make(chan int, 1)
+	go func() {
+		for {
+			opcode, _, data, err := client.readFrame()
+			if err != nil {
+				return
+			}
+			switch opcode {
+			case wsPing:
+				client.pingHandler(data)
+			case wsClose:
+				closeCode <- int(binary.BigEndian.Uint16(data[:2]))
+				return
+			}
+		}
+	}()
+
+	// The server's read loop is ended once a pong is overdue
+	start := time.Now()
+	if _, err := server.ReadMessage(); err == nil {
+		t.Fatal("expected an error once the connection was closed")
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Fatalf("connection closed after %v, despite the client answering pings", elapsed)
+	}
+
+	select {
+	case code := <-closeCode:
+		if code != wsCloseGoingAway {
+			t.Fatalf("expected close code %d, got %d", wsCloseGoingAway, code)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no close frame received")
+	}
+
+	select {
+	case <-server.Done():
+	default:
+		t.Fatal("expected Done to be closed")
+	}
+}
+
+// --- Write deadlines ---
+
+func TestWebSocketWriteTimeout(t *testing.T) {
+	server, client := newTestPair()
+	defer client.conn.Close()
+
+	// The client never reads, so the write can't complete
+	server.SetWriteTimeout(50 * time.Millisecond)
+	start := time.Now()
+	err := server.WriteMessage(TextMessage, []byte("anyone there?"))
+	if !errors.Is(err, ErrWebSocketWriteTimeout) {
+		t.Fatalf("expected ErrWebSocketWriteTimeout, got: %v", err)
+	}
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Fatalf("expected a timeout net.Error too, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("write timeout took too long: %v", elapsed)
+	}
+
+	// The frame may be partly written, so the connection is done with
+	select {
+	case <-server.Done():
+	default:
+		t.Fatal("expected Done to be closed")
+	}
+	if err := server.WriteMessage(TextMessage, []byte("again")); !errors.Is(err, ErrWebSocketAlreadyClosed) {
+		t.Fatalf("expected ErrWebSocketAlreadyClosed after a timeout, got: %v", err)
+	}
+
+	// ...and closed, so Close has nothing left to do
+	start = time.Now()
+	if err := server.Close(CloseNormalClosure, "bye"); err != nil {
+		t.Fatalf("expected Close to find the connection closed, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Fatalf("Close after a write timeout took too long: %v", elapsed)
+	}
+}
+
+func TestWebSocketWriteTimeoutWithPongTimeout(t *testing.T) {
+	server, client := newTestPair()
+	defer client.conn.Close()
+
+	server.SetWriteTimeout(500 * time.Millisecond)
+	server.SetPongTimeout(100 * time.Millisecond)
+	server.EnableAutoPing(200 * time.Millisecond) // the pong is overdue before the next ping
+
+	// The client takes the first ping, then stops reading, never answering it
+	pinged := make(chan struct{})
+	go func() {
+		if opcode, _, _, err := client.readFrame(); err == nil && opcode == wsPing {
+			close(pinged)
+		}
+	}()
+	select {
+	case <-pinged:
+	case <-time.After(time.Second):
+		t.Fatal("no ping received")
+	}
+
+	// A write blocks on the client, while the pong timeout closes the connection -
+	// neither may wait on the other
+	done := make(chan error, 1)
+	go func() {
+		done <- server.WriteMessage(BinaryMessage, make([]byte, 1<<20))
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("expected the blocked write to fail")
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("write blocked past its timeout and the pong timeout")
+	}
+
+	select {
+	case <-server.Done():
+	case <-time.After(time.Second):
+		t.Fatal("expected Done to be closed")
+	}
+	if err := server.WriteMessage(TextMessage, []byte("again")); !errors.Is(err, ErrWebSocketAlreadyClosed) {
+		t.Fatalf("expected ErrWebSocketAlreadyClosed, got: %v", err)
+	}
+	if err := server.Close(CloseNormalClosure, "bye"); err != nil {
+		t.Fatalf("expected Close to find the connection closed, got: %v", err)
+	}
+}
+
+func TestWebSocketCloseAfterWrite(t *testing.T) {
+	server, client := newTestPair()
+	defer server.conn.Close()
+	defer client.conn.Close()
+
+	// A close frame waits for the message being written, so the client reads both whole
+	go func() {
+		_ = server.WriteMessage(TextMessage, []byte(strings.Repeat("x", 100_000)))
+	}()
+	time.Sleep(20 * time.Millisecond) // let the write start
+	closed := make(chan error, 1)
+	go func() {
+		closed <- server.Close(CloseNormalClosure, "bye")
+	}()
+
+	msg, err := client.ReadMessage()
+	if err != nil || len(msg.Data) != 100_000 {
+		t.Fatalf("expected the whole message, got %v", err)
+	}
+	opcode, _, data, err := client.readFrame()
+	if err != nil || opcode != wsClose || binary.BigEndian.Uint16(data[:2]) != CloseNormalClosure {
+		t.Fatalf("expected a close frame, got opcode %d, %v", opcode, err)
+	}
+	_ = writeRawFrame(client.conn, wsClose, true, true, data[:2])
+	if err := <-closed; err != nil {
+		t.Fatalf("unexpected Close error: %v", err)
+	}
+}
+
+func TestWebSocketWriteDeadline(t *testing.T) {
+	server, client := newTestPair()
+	defer server.conn.Close()
+	defer client.conn.Close()
+
+	// Writes within the deadline go through
+	server.SetWriteTimeout(0)
+	if err := server.SetWriteDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		if err := server.WriteMessage(TextMessage, []byte("in time")); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}()
+	msg, err := client.ReadMessage()
+	if err != nil || string(msg.Data) != "in time" {
+		t.Fatalf("expected 'in time', got %v, %v", msg, err)
+	}
+
+	// A deadline set while a write is blocked ends it
+	_ = server.SetWriteDeadline(time.Time{}) // no deadline, nor timeout
+	done := make(chan error, 1)
+	go func() {
+		done <- server.WriteMessage(TextMessage, []byte("stuck"))
+	}()
+	time.Sleep(20 * time.Millisecond)
+	_ = server.SetWriteDeadline(time.Now().Add(20 * time.Millisecond))
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrWebSocketWriteTimeout) {
+			t.Fatalf("expected ErrWebSocketWriteTimeout, got: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("blocked write not ended by the deadline")
+	}
+}
+
+func TestWebSocketOnCloseOnce(t *testing.T) {
+	server, client := newTestPair()
+	defer client.conn.Close()
+
+	var codes []int
+	server.OnClose(func(code int, text string) {
+		codes = append(codes, code)
+	})
+
+	// A message too big closes the connection, with 1009
+	server.SetMaxMessageSize(4)
+	go writeRawFrame(client.conn, wsText, true, true, []byte("too long"))
+	go io.Copy(io.Discard, client.conn) // take the close frame
+	if _, err := server.ReadMessage(); !errors.Is(err, ErrWebSocketPayloadTooLarge) {
+		t.Fatalf("expected ErrWebSocketPayloadTooLarge, got: %v", err)
+	}
+
+	// Closing, or reading, the closed connection doesn't call it again
+	_ = server.Close(wsCloseNormalClosure, "")
+	if _, err := server.ReadMessage(); err == nil {
+		t.Fatal("expected an error reading a closed connection")
+	}
+
+	if len(codes) != 1 || codes[0] != wsCloseMessageTooBig {
+		t.Fatalf("expected the close handler called once, with %d, got %v", wsCloseMessageTooBig, codes)
+	}
+}
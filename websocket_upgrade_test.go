@@ -0,0 +1,334 @@
+package rweb_test
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/rohanthewiz/assert"
+	"github.com/rohanthewiz/rweb"
+	"github.com/rohanthewiz/rweb/consts"
+)
+
+// dialWebSocket sends a WebSocket upgrade request for path and returns the connection and the HTTP response
+func dialWebSocket(t *testing.T, addr, path string, extraHeaders ...string) (net.Conn, *http.Response) {
+	conn, err := net.Dial(consts.ProtocolTCP, addr)
+	assert.Nil(t, err)
+
+	req := fmt.Sprintf("GET %s HTTP/1.1\r\nHost: %s\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n", path, addr)
+	for _, header := range extraHeaders {
+		req += header + "\r\n"
+	}
+	_, err = io.WriteString(conn, req+"\r\n")
+	assert.Nil(t, err)
+
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	assert.Nil(t, err)
+	_ = conn.SetReadDeadline(time.Time{})
+	// Frames may follow the response straight away, so keep reading through the buffer
+	return &bufferedConn{Conn: conn, reader: reader}, resp
+}
+
+// bufferedConn is a net.Conn whose reads go through a bufio.Reader that may hold data already
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
+
+func TestWebSocketConnectionLimit(t *testing.T) {
+	readyChan := make(chan struct{}, 1)
+	s := rweb.NewServerWithOptions(
+		rweb.WithAddress("localhost:"),
+		rweb.WithReadyChan(readyChan),
+		rweb.WithWebSocketConfig(rweb.WebSocketCfg{MaxConnections: 1, RetryAfter: 2500 * time.Millisecond}),
+	)
+
+	s.WebSocket("/ws", func(ws *rweb.WSConn) error {
+		for { // hold the connection until the client goes away
+			if _, err := ws.ReadMessage(); err != nil {
+				return nil
+			}
+		}
+	})
+
+	go func() {
+		defer syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+		<-readyChan // wait for server
+		addr := fmt.Sprintf("localhost:%s", s.GetListenPort())
+
+		first, resp := dialWebSocket(t, addr, "/ws")
+		assert.Equal(t, resp.StatusCode, consts.StatusSwitchingProtocols)
+
+		// At the limit, the upgrade is refused with a 503 telling the client when to retry
+		second, resp := dialWebSocket(t, addr, "/ws")
+		assert.Equal(t, resp.StatusCode, consts.StatusServiceUnavailable)
+		assert.Equal(t, resp.Header.Get(consts.HeaderRetryAfter), "3")
+		_ = second.Close()
+
+		// Once the first connection is gone, its slot is free again
+		_ = first.Close()
+		deadline := time.Now().Add(3 * time.Second)
+		for {
+			third, resp := dialWebSocket(t, addr, "/ws")
+			_ = third.Close()
+			if resp.StatusCode == consts.StatusSwitchingProtocols || time.Now().After(deadline) {
+				assert.Equal(t, resp.StatusCode, consts.StatusSwitchingProtocols)
+				break
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}()
+
+	err := s.Run()
+	assert.Nil(t, err)
+}
+
+func TestWebSocketSubprotocol(t *testing.T) {
+	readyChan := make(chan struct{}, 1)
+	s := rweb.NewServerWithOptions(
+		rweb.WithAddress("localhost:"),
+		rweb.WithReadyChan(readyChan),
+	)
+
+	protocols := make(chan string, 4)
+	wsHandler := func(ws *rweb.WSConn) error {
+		protocols <- ws.Subprotocol()
+		return nil
+	}
+	s.WebSocket("/graphql", wsHandler, rweb.WSUpgradeOptions{Subprotocols: []string{"graphql-transport-ws", "graphql-ws"}})
+	s.WebSocket("/plain", wsHandler)
+
+	go func() {
+		defer syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+		<-readyChan // wait for server
+		addr := fmt.Sprintf("localhost:%s", s.GetListenPort())
+
+		tests := []struct {
+			path     string
+			offered  string
+			expected string
+		}{
+			{"/graphql", "graphql-ws", "graphql-ws"},
+			{"/graphql", "chat, graphql-ws, graphql-transport-ws", "graphql-ws"}, // the client's preference
+			{"/graphql", "chat, mqtt", ""},                                       // nothing in common
+			{"/graphql", "", ""},
+			{"/plain", "graphql-ws", ""}, // no subprotocols supported
+		}
+
+		for _, tt := range tests {
+			var headers []string
+			if tt.offered != "" {
+				headers = append(headers, "Sec-WebSocket-Protocol: "+tt.offered)
+			}
+			conn, resp := dialWebSocket(t, addr, tt.path, headers...)
+			assert.Equal(t, resp.StatusCode, consts.StatusSwitchingProtocols)
+			assert.Equal(t, resp.Header.Get("Sec-WebSocket-Protocol"), tt.expected)
+			assert.Equal(t, <-protocols, tt.expected)
+			_ = conn.Close()
+		}
+	}()
+
+	err := s.Run()
+	assert.Nil(t, err)
+}
+
+func TestWebSocketCompression(t *testing.T) {
+	readyChan := make(chan struct{}, 1)
+	s := rweb.NewServerWithOptions(
+		rweb.WithAddress("localhost:"),
+		rweb.WithReadyChan(readyChan),
+	)
+
+	compressed := make(chan bool, 2)
+	wsHandler := func(ws *rweb.WSConn) error {
+		compressed <- ws.Compressed()
+		return ws.WriteMessage(rweb.TextMessage, []byte(strings.Repeat("hello compression ", 10)))
+	}
+	s.WebSocket("/deflate", wsHandler, rweb.WSUpgradeOptions{EnableCompression: true})
+	s.WebSocket("/plain", wsHandler)
+
+	go func() {
+		defer syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+		<-readyChan // wait for server
+		addr := fmt.Sprintf("localhost:%s", s.GetListenPort())
+
+		// Offered as a browser does
+		conn, resp := dialWebSocket(t, addr, "/deflate",
+			"Sec-WebSocket-Extensions: permessage-deflate; client_max_window_bits")
+		defer conn.Close()
+		assert.Equal(t, resp.StatusCode, consts.StatusSwitchingProtocols)
+		assert.Equal(t, resp.Header.Get(consts.HeaderSecWebSocketExtensions), "permessage-deflate")
+		assert.True(t, <-compressed)
+
+		// A compressed, unmasked text frame
+		header := make([]byte, 2)
+		_, err := io.ReadFull(conn, header)
+		assert.Nil(t, err)
+		assert.Equal(t, header[0], byte(0x80|0x40|0x1)) // FIN, RSV1, text
+		payload := make([]byte, header[1])
+		_, err = io.ReadFull(conn, payload)
+		assert.Nil(t, err)
+
+		data, err := io.ReadAll(flate.NewReader(io.MultiReader(bytes.NewReader(payload),
+			bytes.NewReader([]byte{0x00, 0x00, 0xff, 0xff, 0x01, 0x00, 0x00, 0xff, 0xff}))))
+		assert.Nil(t, err)
+		assert.Equal(t, string(data), strings.Repeat("hello compression ", 10))
+
+		// Not enabled for the route - not agreed
+		plain, resp := dialWebSocket(t, addr, "/plain", "Sec-WebSocket-Extensions: permessage-deflate")
+		defer plain.Close()
+		assert.Equal(t, resp.Header.Get(consts.HeaderSecWebSocketExtensions), "")
+		assert.False(t, <-compressed)
+		msg, err := rweb.NewWSConn(plain, false).ReadMessage()
+		assert.Nil(t, err)
+		assert.Equal(t, string(msg.Data), strings.Repeat("hello compression ", 10))
+	}()
+
+	err := s.Run()
+	assert.Nil(t, err)
+}
+
+func TestWebSocketCheckOrigin(t *testing.T) {
+	readyChan := make(chan struct{}, 1)
+	s := rweb.NewServerWithOptions(
+		rweb.WithAddress("localhost:"),
+		rweb.WithReadyChan(readyChan),
+		rweb.WithWebSocketConfig(rweb.WebSocketCfg{SameOriginOnly: true}),
+	)
+
+	echo := func(ws *rweb.WSConn) error {
+		return ws.Close(rweb.CloseNormalClosure, "bye")
+	}
+	s.WebSocket("/ws", echo)
+	s.WebSocket("/partners", echo, rweb.WSUpgradeOptions{CheckOrigin: func(ctx rweb.Context) bool {
+		return ctx.Request().Header(consts.HeaderOrigin) == "https://partner.example"
+	}})
+
+	go func() {
+		defer syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+		<-readyChan // wait for server
+		addr := fmt.Sprintf("localhost:%s", s.GetListenPort())
+
+		upgrade := func(path string, headers ...string) int {
+			conn, resp := dialWebSocket(t, addr, path, headers...)
+			defer conn.Close()
+			return resp.StatusCode
+		}
+
+		// Same origin, or no origin (not a browser)
+		assert.Equal(t, upgrade("/ws", "Origin: http://"+addr), consts.StatusSwitchingProtocols)
+		assert.Equal(t, upgrade("/ws"), consts.StatusSwitchingProtocols)
+
+		// Other sites
+		assert.Equal(t, upgrade("/ws", "Origin: https://evil.example"), consts.StatusForbidden)
+		assert.Equal(t, upgrade("/ws", "Origin: http://localhost:1"), consts.StatusForbidden)
+		assert.Equal(t, upgrade("/ws", "Origin: null"), consts.StatusForbidden)
+
+		// The route's own check takes precedence
+		assert.Equal(t, upgrade("/partners", "Origin: https://partner.example"), consts.StatusSwitchingProtocols)
+		assert.Equal(t, upgrade("/partners", "Origin: http://"+addr), consts.StatusForbidden)
+	}()
+
+	_ = s.Run()
+}
+
+func TestWebSocketContextValuesAndOnClose(t *testing.T) {
+	readyChan := make(chan struct{}, 1)
+	s := rweb.NewServerWithOptions(rweb.WithAddress("localhost:"), rweb.WithReadyChan(readyChan))
+
+	s.Use(func(ctx rweb.Context) error {
+		ctx.Set("user", "ann") // as auth middleware would
+		return ctx.Next()
+	})
+
+	type closing struct {
+		code int
+		text string
+	}
+	closes := make(chan closing, 10)
+	s.WebSocket("/ws", func(ws *rweb.WSConn) error {
+		ws.OnClose(func(code int, text string) {
+			closes <- closing{code, text}
+		})
+		user, _ := ws.Get("user").(string)
+		if err := ws.WriteMessage(rweb.TextMessage, []byte("hello "+user)); err != nil {
+			return nil
+		}
+		for { // until the client closes, or goes
+			msg, err := ws.ReadMessage()
+			if err != nil || msg.Type == rweb.CloseMessage {
+				return nil
+			}
+		}
+	})
+	s.WebSocket("/brief", func(ws *rweb.WSConn) error {
+		ws.OnClose(func(code int, text string) {
+			closes <- closing{code, text}
+		})
+		return nil // done without closing
+	})
+
+	go func() {
+		defer syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+		<-readyChan // wait for server
+		addr := fmt.Sprintf("localhost:%s", s.GetListenPort())
+		nextClose := func() closing {
+			select {
+			case c := <-closes:
+				return c
+			case <-time.After(5 * time.Second):
+				t.Error("close handler not called")
+				return closing{}
+			}
+		}
+
+		// The request's values reach the WebSocket handler
+		conn, _ := dialWebSocket(t, addr, "/ws")
+		client := rweb.NewWSConn(conn, false)
+		msg, err := client.ReadMessage()
+		assert.Nil(t, err)
+		assert.Equal(t, string(msg.Data), "hello ann")
+
+		// The client closes
+		assert.Nil(t, client.Close(rweb.CloseNormalClosure, "done"))
+		assert.Equal(t, nextClose(), closing{rweb.CloseNormalClosure, "done"})
+
+		// The client goes away - a read error
+		conn, _ = dialWebSocket(t, addr, "/ws")
+		_, err = rweb.NewWSConn(conn, false).ReadMessage()
+		assert.Nil(t, err)
+		_ = conn.Close()
+		assert.Equal(t, nextClose().code, rweb.CloseAbnormalClosure)
+
+		// The handler returns without closing
+		conn, _ = dialWebSocket(t, addr, "/brief")
+		assert.Equal(t, nextClose(), closing{rweb.CloseAbnormalClosure, ""})
+		_ = conn.Close()
+
+		// Each handler ran just once
+		time.Sleep(50 * time.Millisecond)
+		assert.Equal(t, len(closes), 0)
+	}()
+
+	_ = s.Run()
+}
@@ -0,0 +1,132 @@
+package rweb
+
+import (
+	"sync"
+)
+
+// wsHubMessage is a message queued for broadcast
+type wsHubMessage struct {
+	messageType MessageType
+	data        []byte
+}
+
+// WSHub manages a set of WebSocket connections and broadcasts messages to all of them.
+// Registration, unregistration and broadcasts are serialized through the Run loop,
+// so messages reach every client in the order they were broadcast.
+// Connections that fail a write are unregistered automatically - including those that stop reading,
+// once their write timeout expires (see WSConn.SetWriteTimeout), so one stuck client can't hold up the others for long.
+//
+// Typical usage:
+//
+//	hub := rweb.NewWSHub()
+//	go hub.Run()
+//
+//	s.WebSocket("/ws/chat", func(ws *rweb.WSConn) error {
+//		hub.Register(ws)
+//		defer hub.Unregister(ws)
+//		for {
+//			msg, err := ws.ReadMessage()
+//			if err != nil || msg.Type == rweb.CloseMessage {
+//				return nil
+//			}
+//			hub.Broadcast(msg.Type, msg.Data)
+//		}
+//	})
+type WSHub struct {
+	mu         sync.RWMutex
+	clients    map[*WSConn]struct{}
+	register   chan *WSConn
+	unregister chan *WSConn
+	broadcast  chan wsHubMessage
+	done       chan struct{}
+	closeOnce  sync.Once
+}
+
+// NewWSHub creates a WSHub. Start its loop with go hub.Run().
+func NewWSHub() *WSHub {
+	return &WSHub{
+		clients:    make(map[*WSConn]struct{}),
+		register:   make(chan *WSConn),
+		unregister: make(chan *WSConn),
+		broadcast:  make(chan wsHubMessage, 100),
+		done:       make(chan struct{}),
+	}
+}
+
+// Run processes registrations and broadcasts until Close is called.
+func (h *WSHub) Run() {
+	for {
+		select {
+		case <-h.done:
+			return
+
+		case ws := <-h.register:
+			h.mu.Lock()
+			h.clients[ws] = struct{}{}
+			h.mu.Unlock()
+
+		case ws := <-h.unregister:
+			h.mu.Lock()
+			delete(h.clients, ws)
+			h.mu.Unlock()
+
+		case msg := <-h.broadcast:
+			h.mu.RLock()
+			var failed []*WSConn
+			for ws := range h.clients {
+				if err := ws.WriteMessage(msg.messageType, msg.data); err != nil {
+					failed = append(failed, ws)
+				}
+			}
+			h.mu.RUnlock()
+
+			// The connection is broken, so the handler's reads will fail too - just stop writing to it
+			if len(failed) > 0 {
+				h.mu.Lock()
+				for _, ws := range failed {
+					delete(h.clients, ws)
+				}
+				h.mu.Unlock()
+			}
+		}
+	}
+}
+
+// Register adds a connection to the hub, so it receives subsequent broadcasts.
+func (h *WSHub) Register(ws *WSConn) {
+	select {
+	case h.register <- ws:
+	case <-h.done:
+	}
+}
+
+// Unregister removes a connection from the hub. Unknown connections are ignored.
+// It doesn't close the connection.
+func (h *WSHub) Unregister(ws *WSConn) {
+	select {
+	case h.unregister <- ws:
+	case <-h.done:
+	}
+}
+
+// Broadcast queues a message for all registered connections.
+// data must not be modified after the call, as it is written asynchronously.
+func (h *WSHub) Broadcast(messageType MessageType, data []byte) {
+	select {
+	case h.broadcast <- wsHubMessage{messageType: messageType, data: data}:
+	case <-h.done:
+	}
+}
+
+// ClientCount returns the number of registered connections.
+func (h *WSHub) ClientCount() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients)
+}
+
+// Close stops the Run loop. Connections are left open for their handlers to close.
+// Safe to call multiple times.
+func (h *WSHub) Close() {
+	h.closeOnce.Do(func() { close(h.done) })
+}
@@ -0,0 +1,97 @@
+package rweb_test
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/rohanthewiz/assert"
+	"github.com/rohanthewiz/rweb"
+)
+
+func TestWSHub(t *testing.T) {
+	hub := rweb.NewWSHub()
+	go hub.Run()
+	defer hub.Close()
+
+	readyChan := make(chan struct{}, 1)
+	s := rweb.NewServerWithOptions(rweb.WithAddress("localhost:"), rweb.WithReadyChan(readyChan))
+
+	s.WebSocket("/ws/chat", func(ws *rweb.WSConn) error {
+		hub.Register(ws)
+		defer hub.Unregister(ws)
+
+		if err := ws.WriteMessage(rweb.TextMessage, []byte("ready")); err != nil {
+			return err
+		}
+		for {
+			msg, err := ws.ReadMessage()
+			if err != nil || msg.Type == rweb.CloseMessage {
+				return nil
+			}
+			hub.Broadcast(msg.Type, msg.Data)
+		}
+	})
+
+	// waitForCount polls, as (un)registration completes asynchronously
+	waitForCount := func(count int) {
+		deadline := time.Now().Add(5 * time.Second)
+		for hub.ClientCount() != count && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		assert.Equal(t, hub.ClientCount(), count)
+	}
+
+	go func() {
+		defer syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+		<-readyChan // wait for server
+		addr := fmt.Sprintf("localhost:%s", s.GetListenPort())
+
+		var clients []*rweb.WSConn
+		for range 2 {
+			conn, resp := dialWebSocket(t, addr, "/ws/chat")
+			defer conn.Close()
+			assert.Equal(t, resp.StatusCode, 101)
+			_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+			ws := rweb.NewWSConn(conn, false)
+			msg, err := ws.ReadMessage()
+			assert.Nil(t, err)
+			assert.Equal(t, string(msg.Data), "ready")
+			clients = append(clients, ws)
+		}
+		assert.Equal(t, hub.ClientCount(), 2)
+
+		// A message from one client reaches all of them
+		assert.Nil(t, clients[0].WriteMessage(rweb.TextMessage, []byte("hello all")))
+		for _, ws := range clients {
+			msg, err := ws.ReadMessage()
+			assert.Nil(t, err)
+			assert.Equal(t, msg.Type, rweb.TextMessage)
+			assert.Equal(t, string(msg.Data), "hello all")
+		}
+
+		// Clients leaving are unregistered
+		assert.Nil(t, clients[1].Close(rweb.CloseNormalClosure, "bye"))
+		waitForCount(1)
+
+		// A connection that can't be written to is dropped on the next broadcast
+		local, remote := net.Pipe()
+		_ = remote.Close()
+		hub.Register(rweb.NewWSConn(local, true))
+		waitForCount(2)
+		hub.Broadcast(rweb.BinaryMessage, []byte{1, 2, 3})
+		waitForCount(1)
+
+		msg, err := clients[0].ReadMessage()
+		assert.Nil(t, err)
+		assert.Equal(t, msg.Type, rweb.BinaryMessage)
+		assert.Equal(t, len(msg.Data), 3)
+	}()
+
+	err := s.Run()
+	assert.Nil(t, err)
+}